@@ -0,0 +1,71 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/batch"
+	"github.com/ossf/criticality_score/internal/infile"
+)
+
+// runController splits the repositories in the input file into shards in q.
+func runController(ctx context.Context, logger *zap.Logger, q *batch.Queue) {
+	if flag.NArg() != 1 {
+		logger.Error("A single input file must be specified.")
+		os.Exit(2)
+	}
+	logger = logger.With(
+		zap.String("filename", flag.Arg(0)),
+		zap.String("job_id", *jobIDFlag),
+	)
+
+	r, err := infile.Open(ctx, flag.Arg(0))
+	if err != nil {
+		logger.Error("Failed to open input file", zap.Error(err))
+		os.Exit(2)
+	}
+	defer r.Close()
+
+	job, err := q.Create(ctx, *jobIDFlag, r, *shardSizeFlag)
+	if err != nil {
+		logger.Error("Failed to create job", zap.Error(err))
+		os.Exit(2)
+	}
+	logger.With(
+		zap.Int("repos", job.Repos),
+		zap.Int("shards", job.Shards),
+	).Info("Created job")
+}
+
+// runStatus writes the progress of the job in q to stdout as JSON.
+func runStatus(ctx context.Context, logger *zap.Logger, q *batch.Queue) {
+	status, err := q.Status(ctx)
+	if err != nil {
+		logger.Error("Failed to get job status", zap.Error(err))
+		os.Exit(2)
+	}
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	if err := e.Encode(status); err != nil {
+		logger.Error("Failed to write job status", zap.Error(err))
+		os.Exit(2)
+	}
+}
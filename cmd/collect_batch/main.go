@@ -0,0 +1,158 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ossf/criticality_score/internal/batch"
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/envflag"
+	log "github.com/ossf/criticality_score/internal/log"
+	"github.com/ossf/criticality_score/internal/signalio"
+)
+
+const (
+	defaultLogLevel = zapcore.InfoLevel
+	jobIDDateFormat = "20060102-1504"
+)
+
+var (
+	queueFlag             = flag.String("queue", "", "the local directory or bucket `url` used to store the shards, leases and results.")
+	shardSizeFlag         = flag.Int("shard-size", 10, "the maximum number of repositories in each shard.")
+	jobIDFlag             = flag.String("job-id", time.Now().UTC().Format(jobIDDateFormat), "the `id` of the job created by the controller.")
+	workerIDFlag          = flag.String("worker-id", defaultWorkerID(), "the `id` used to identify this worker in leases.")
+	leaseFlag             = flag.Duration("lease", batch.DefaultLeaseTTL, "the `duration` a shard is leased for before it can be claimed by another worker.")
+	pollFlag              = flag.Duration("poll", 30*time.Second, "the `duration` to wait before checking for shards again when all incomplete shards are leased.")
+	gcpProjectFlag        = flag.String("gcp-project-id", "", "the Google Cloud Project ID to use. Auto-detects by default.")
+	depsdevDisableFlag    = flag.Bool("depsdev-disable", false, "disables the collection of signals from deps.dev.")
+	depsdevDatasetFlag    = flag.String("depsdev-dataset", collector.DefaultGCPDatasetName, "the BigQuery dataset name to use.")
+	depsdevTTLFlag        = flag.Int("depsdev-expiration", 0, "the default expiration (`hours`) to use for deps.dev tables. No expiration by default.")
+	scoringDisableFlag    = flag.Bool("scoring-disable", false, "disables the generation of scores.")
+	scoringConfigFlag     = flag.String("scoring-config", "", "path to a YAML file for configuring the scoring algorithm.")
+	scoringColumnNameFlag = flag.String("scoring-column", "", "manually specify the name for the column used to hold the score.")
+	workersFlag           = flag.Int("workers", 1, "the number of shards to collect concurrently.")
+	roleFlag              role
+	logLevel              = defaultLogLevel
+	logEnv                log.Env
+	formatType            signalio.WriterType
+
+	// Maps environment variables to the flags they correspond to.
+	envFlagMap = envflag.Map{
+		"CRITICALITY_SCORE_LOG_ENV":         "log-env",
+		"CRITICALITY_SCORE_LOG_LEVEL":       "log",
+		"CRITICALITY_SCORE_ROLE":            "role",
+		"CRITICALITY_SCORE_QUEUE":           "queue",
+		"CRITICALITY_SCORE_SHARD_SIZE":      "shard-size",
+		"CRITICALITY_SCORE_JOB_ID":          "job-id",
+		"CRITICALITY_SCORE_WORKER_ID":       "worker-id",
+		"CRITICALITY_SCORE_LEASE":           "lease",
+		"CRITICALITY_SCORE_POLL":            "poll",
+		"CRITICALITY_SCORE_FORMAT":          "format",
+		"CRITICALITY_SCORE_WORKERS":         "workers",
+		"CRITICALITY_SCORE_GCP_PROJECT_ID":  "gcp-project-id",
+		"CRITICALITY_SCORE_DEPSDEV_DISABLE": "depsdev-disable",
+		"CRITICALITY_SCORE_DEPSDEV_DATASET": "depsdev-dataset",
+		"CRITICALITY_SCORE_SCORING_CONFIG":  "scoring-config",
+		"CRITICALITY_SCORE_SCORING_COLUMN":  "scoring-column",
+	}
+)
+
+// defaultWorkerID returns an id for the worker that is unique across the
+// machines in a small cluster.
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// initFlags prepares any runtime flags, usage information and parses the flags.
+func initFlags() {
+	flag.TextVar(&roleFlag, "role", roleWorker, "the `role` to run. Can be 'controller', 'worker' or 'status'.")
+	flag.Var(&logLevel, "log", "set the `level` of logging.")
+	flag.TextVar(&logEnv, "log-env", log.DefaultEnv, "set logging `env`.")
+	flag.TextVar(&formatType, "format", signalio.WriterTypeCSV, "set the format of the results. Choices are csv, json or text.")
+	flag.Usage = func() {
+		cmdName := path.Base(os.Args[0])
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage:\n")
+		fmt.Fprintf(w, "  %s -role=controller -queue=URL [FLAGS]... FILE\n", cmdName)
+		fmt.Fprintf(w, "  %s -role=worker -queue=URL [FLAGS]...\n", cmdName)
+		fmt.Fprintf(w, "  %s -role=status -queue=URL\n\n", cmdName)
+		fmt.Fprintf(w, "Collects signals for a list of project repository urls using a pool of\n")
+		fmt.Fprintf(w, "workers, coordinated through a local directory or bucket.\n\n")
+		fmt.Fprintf(w, "The controller splits the urls in FILE into shards. FILE must be either\n")
+		fmt.Fprintf(w, "a file or - to read from stdin. Workers claim and collect shards until\n")
+		fmt.Fprintf(w, "every shard is complete. Status reports the progress of the job.\n")
+		fmt.Fprintf(w, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	envflag.Parse(envFlagMap)
+}
+
+func main() {
+	initFlags()
+
+	logger, err := log.NewLogger(logEnv, logLevel)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	if *queueFlag == "" {
+		logger.Error("A queue must be specified with -queue.")
+		os.Exit(2)
+	}
+	if *leaseFlag <= 0 {
+		logger.Error("The -lease duration must be greater than 0.")
+		os.Exit(2)
+	}
+	logger = logger.With(
+		zap.Stringer("role", roleFlag),
+		zap.String("queue", *queueFlag),
+	)
+
+	ctx := context.Background()
+
+	q, err := batch.Open(ctx, *queueFlag)
+	if err != nil {
+		logger.Error("Failed to open queue", zap.Error(err))
+		os.Exit(2)
+	}
+	defer q.Close()
+	q.LeaseTTL = *leaseFlag
+
+	switch roleFlag {
+	case roleController:
+		runController(ctx, logger, q)
+	case roleWorker:
+		// Bump the # idle conns per host
+		http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost = *workersFlag * 5
+		runWorker(ctx, logger, q)
+	case roleStatus:
+		runStatus(ctx, logger, q)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+)
+
+// role is the part of the pipeline run by the command.
+type role int
+
+const (
+	roleController = role(iota)
+	roleWorker
+	roleStatus
+)
+
+var errorUnknownRole = errors.New("unknown role")
+
+// String implements the fmt.Stringer interface.
+func (r role) String() string {
+	text, err := r.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (r role) MarshalText() ([]byte, error) {
+	switch r {
+	case roleController:
+		return []byte("controller"), nil
+	case roleWorker:
+		return []byte("worker"), nil
+	case roleStatus:
+		return []byte("status"), nil
+	default:
+		return []byte{}, errorUnknownRole
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *role) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("controller")):
+		*r = roleController
+	case bytes.Equal(text, []byte("worker")):
+		*r = roleWorker
+	case bytes.Equal(text, []byte("status")):
+		*r = roleStatus
+	default:
+		return errorUnknownRole
+	}
+	return nil
+}
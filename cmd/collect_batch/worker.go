@@ -0,0 +1,309 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/batch"
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/infile"
+	"github.com/ossf/criticality_score/internal/scorer"
+	"github.com/ossf/criticality_score/internal/signalio"
+	"github.com/ossf/criticality_score/internal/workerpool"
+)
+
+// shardWorker collects the shards claimed from a queue.
+type shardWorker struct {
+	logger          *zap.Logger
+	q               *batch.Queue
+	job             *batch.Job
+	c               *collector.Collector
+	s               *scorer.Scorer
+	scoreColumnName string
+}
+
+// runWorker claims and collects shards from q until every shard is complete.
+func runWorker(ctx context.Context, logger *zap.Logger, q *batch.Queue) {
+	logger = logger.With(zap.String("worker_id", *workerIDFlag))
+
+	job, err := q.Job(ctx)
+	if err != nil {
+		logger.Error("Failed to read job", zap.Error(err))
+		os.Exit(2)
+	}
+	logger = logger.With(zap.String("job_id", job.ID))
+
+	s := getScorer(logger)
+	scoreColumnName := ""
+	if s != nil {
+		scoreColumnName = s.Name()
+		if *scoringColumnNameFlag != "" {
+			scoreColumnName = *scoringColumnNameFlag
+		}
+	}
+
+	opts := []collector.Option{
+		collector.EnableAllSources(),
+		collector.GCPProject(*gcpProjectFlag),
+		collector.GCPDatasetName(*depsdevDatasetFlag),
+		collector.GCPDatasetTTL(time.Hour * time.Duration(*depsdevTTLFlag)),
+	}
+	if *depsdevDisableFlag {
+		opts = append(opts, collector.DisableSource(collector.SourceTypeDepsDev))
+	}
+	c, err := collector.New(ctx, logger, opts...)
+	if err != nil {
+		logger.Error("Failed to create collector", zap.Error(err))
+		os.Exit(2)
+	}
+
+	w := &shardWorker{
+		logger:          logger,
+		q:               q,
+		job:             job,
+		c:               c,
+		s:               s,
+		scoreColumnName: scoreColumnName,
+	}
+	wait := workerpool.WorkerPool(*workersFlag, func(worker int) {
+		w.run(ctx, worker)
+	})
+	wait()
+
+	summary, err := q.Finish(ctx)
+	if err != nil {
+		logger.Error("Failed to write job summary", zap.Error(err))
+		os.Exit(2)
+	}
+	if summary == nil {
+		logger.Error("Job is not complete")
+		os.Exit(2)
+	}
+	logger.With(
+		zap.Int("written", summary.Written),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("failed", summary.Failed),
+	).Info("Job complete")
+}
+
+// run claims and collects shards until every shard in the job is complete.
+func (w *shardWorker) run(ctx context.Context, worker int) {
+	logger := w.logger.With(zap.Int("worker", worker))
+	id := fmt.Sprintf("%s-%d", *workerIDFlag, worker)
+	for {
+		l, err := w.q.Claim(ctx, id)
+		if errors.Is(err, batch.ErrorNoShards) {
+			status, err := w.q.Status(ctx)
+			if err != nil {
+				logger.Error("Failed to get job status", zap.Error(err))
+				os.Exit(2)
+			}
+			if status.Complete() {
+				return
+			}
+			// Wait for the shards leased by other workers to either be
+			// completed, or for their leases to expire.
+			logger.With(
+				zap.Int("leased", status.Leased),
+				zap.Duration("wait", *pollFlag),
+			).Debug("Waiting for leased shards")
+			time.Sleep(*pollFlag)
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to claim shard", zap.Error(err))
+			os.Exit(2)
+		}
+
+		shardLogger := logger.With(zap.Int("shard", l.Shard))
+		err = w.collect(ctx, shardLogger, l)
+		if errors.Is(err, batch.ErrorLeaseLost) {
+			// Another worker has claimed the shard after the lease expired.
+			shardLogger.Warn("Lease lost", zap.Error(err))
+			continue
+		}
+		if err != nil {
+			shardLogger.Error("Failed to collect shard", zap.Error(err))
+			// Release the lease so another worker can retry the shard, and
+			// wait before claiming another so a persistent failure doesn't
+			// spin.
+			if err := l.Release(ctx); err != nil {
+				shardLogger.Warn("Failed to release lease", zap.Error(err))
+			}
+			time.Sleep(*pollFlag)
+		}
+	}
+}
+
+// collect collects the signals for each repository in the shard leased by l,
+// writes the results and marks the shard as complete.
+//
+// Repositories that fail to be collected are recorded as failed in the shard's
+// Record, rather than failing the whole shard.
+//
+// The lease is renewed in the background while the shard is collected. If the
+// lease is lost collection stops, as another worker has claimed the shard.
+func (w *shardWorker) collect(ctx context.Context, logger *zap.Logger, l *batch.Lease) error {
+	record := batch.Record{Started: time.Now().UTC()}
+	logger.Info("Collecting shard")
+
+	repos, err := w.q.Repos(ctx, l.Shard)
+	if err != nil {
+		return err
+	}
+	record.Repos = len(repos)
+
+	ctx, cancel := context.WithCancel(ctx)
+	var renewErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(w.q.LeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Renew(ctx); err != nil && ctx.Err() == nil {
+					renewErr = err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	stopRenewing := func() error {
+		cancel()
+		wg.Wait()
+		return renewErr
+	}
+	defer stopRenewing()
+
+	extras := []string{}
+	if w.s != nil {
+		extras = append(extras, w.scoreColumnName)
+	}
+	var buf bytes.Buffer
+	out := formatType.New(&buf, w.c.EmptySets(), extras...)
+
+	for _, rawURL := range repos {
+		repoLogger := logger.With(zap.String("repo", rawURL))
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			repoLogger.Warn("Failed to parse repo URL", zap.Error(err))
+			record.Skipped++
+			continue
+		}
+		ss, err := w.c.Collect(ctx, u, w.job.ID)
+		if err != nil {
+			if errors.Is(err, collector.ErrUncollectableRepo) {
+				repoLogger.Warn("Repo cannot be collected", zap.Error(err))
+				record.Skipped++
+				continue
+			}
+			if ctx.Err() != nil {
+				// Collection was stopped because the lease was lost.
+				if renewErr := stopRenewing(); renewErr != nil {
+					return fmt.Errorf("renewing lease: %w", renewErr)
+				}
+				return fmt.Errorf("collecting %s: %w", rawURL, err)
+			}
+			repoLogger.Error("Failed to collect repo", zap.Error(err))
+			record.Failed = append(record.Failed, rawURL)
+			continue
+		}
+
+		// If scoring is enabled, prepare the extra data to be output.
+		extras := []signalio.Field{}
+		if w.s != nil {
+			extras = append(extras, signalio.Field{
+				Key:   w.scoreColumnName,
+				Value: fmt.Sprintf("%.5f", w.s.Score(ss)),
+			})
+		}
+		if err := out.WriteSignals(ss, extras...); err != nil {
+			return fmt.Errorf("writing signals: %w", err)
+		}
+		record.Written++
+	}
+
+	if err := stopRenewing(); err != nil {
+		return fmt.Errorf("renewing lease: %w", err)
+	}
+
+	// Write the results before completing the shard, so a complete shard
+	// always has results.
+	rw, err := w.q.NewResultWriter(ctx, l.Shard, formatType.String())
+	if err != nil {
+		return err
+	}
+	if _, err := rw.Write(buf.Bytes()); err != nil {
+		rw.Close()
+		return fmt.Errorf("writing results: %w", err)
+	}
+	if err := rw.Close(); err != nil {
+		return fmt.Errorf("closing results: %w", err)
+	}
+	if err := w.q.Complete(ctx, l, record); err != nil {
+		return err
+	}
+	logger.With(
+		zap.Int("written", record.Written),
+		zap.Int("skipped", record.Skipped),
+		zap.Int("failed", len(record.Failed)),
+	).Info("Shard complete")
+	return nil
+}
+
+// getScorer prepares a Scorer based on the flags passed to the command.
+//
+// nil will be returned if scoring is disabled.
+func getScorer(logger *zap.Logger) *scorer.Scorer {
+	if *scoringDisableFlag {
+		logger.Info("Scoring disabled")
+		return nil
+	}
+	if *scoringConfigFlag == "" {
+		logger.Info("Preparing default scorer")
+		return scorer.FromDefaultConfig()
+	}
+	logger = logger.With(zap.String("filename", *scoringConfigFlag))
+	logger.Info("Preparing scorer from config")
+	cf, err := infile.Open(context.Background(), *scoringConfigFlag)
+	if err != nil {
+		logger.Error("Failed to open scoring config file", zap.Error(err))
+		os.Exit(2)
+	}
+	defer cf.Close()
+
+	s, err := scorer.FromConfig(scorer.NameFromFilepath(*scoringConfigFlag), cf)
+	if err != nil {
+		logger.Error("Failed to initialize scorer", zap.Error(err))
+		os.Exit(2)
+	}
+	return s
+}
@@ -146,6 +146,30 @@ func main() {
 		csvBucketURL = ""
 	}
 
+	// Extract the bucket URL used for shard status and quarantined repos.
+	quarantineBucketURL := criticalityConfig["quarantine-bucket-url"]
+
+	// Extract the limits on retrying repos that fail to be collected.
+	retry := retryConfig{
+		attempts:     defaultRepoAttempts,
+		initialDelay: defaultInitialRetryDelay,
+		shardBudget:  defaultShardRetryBudget,
+	}
+	if v := criticalityConfig["repo-attempts"]; v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 1 {
+			logger.With(zap.Error(err)).Fatal("Invalid 'repo-attempts' setting: " + v)
+		}
+		retry.attempts = i
+	}
+	if v := criticalityConfig["shard-retry-budget"]; v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			logger.With(zap.Error(err)).Fatal("Invalid 'shard-retry-budget' setting: " + v)
+		}
+		retry.shardBudget = i
+	}
+
 	// The GitHub authentication server may be unavailable if it is starting
 	// at the same time. Wait until it can be reached.
 	waitForRPCServer(logger, os.Getenv("GITHUB_AUTH_SERVER"), githubAuthServerMaxAttemps)
@@ -161,7 +185,7 @@ func main() {
 		collector.GCPDatasetTTL(gcpDatasetTTL),
 	}
 
-	w, err := NewWorker(context.Background(), logger, scoringEnabled, scoringConfigFile, scoringColumnName, csvBucketURL, opts)
+	w, err := NewWorker(context.Background(), logger, scoringEnabled, scoringConfigFile, scoringColumnName, csvBucketURL, quarantineBucketURL, retry, opts)
 	if err != nil {
 		// Fatal exits.
 		logger.With(zap.Error(err)).Fatal("Failed to create worker")
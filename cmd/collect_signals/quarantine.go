@@ -0,0 +1,154 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ossf/scorecard/v4/cron/data"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+const (
+	defaultRepoAttempts     = 3
+	defaultShardRetryBudget = 20
+
+	defaultInitialRetryDelay = 5 * time.Second
+
+	quarantineSuffix = ".quarantine.json"
+	statusSuffix     = ".status.json"
+)
+
+// errNoQuarantineBucket is returned when repos need to be quarantined but
+// quarantine-bucket-url is not set.
+var errNoQuarantineBucket = errors.New("repos were quarantined but quarantine-bucket-url is not set")
+
+// repoCollector is the part of collector.Collector used to collect a repo.
+type repoCollector interface {
+	Collect(ctx context.Context, u *url.URL, jobID string) ([]signal.Set, error)
+}
+
+// retryConfig limits how failures to collect a repo are retried.
+type retryConfig struct {
+	// attempts is the maximum number of times to try collecting each repo.
+	attempts int
+
+	// initialDelay is the delay before the first retry of a repo. It is
+	// doubled before each subsequent retry.
+	initialDelay time.Duration
+
+	// shardBudget is the maximum number of retries across all the repos in a
+	// shard, so a shard with many failing repos still finishes in a bounded
+	// time.
+	shardBudget int
+}
+
+// quarantinedRepo records a repo that could not be collected after retrying.
+type quarantinedRepo struct {
+	Repo     string    `json:"repo"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// shardStatus is written for each shard processed, summarizing the outcome
+// for each of the repos in the shard.
+type shardStatus struct {
+	Shard         int32     `json:"shard"`
+	JobTime       time.Time `json:"job_time"`
+	Repos         int       `json:"repos"`
+	Written       int       `json:"written"`
+	Uncollectable int       `json:"uncollectable"`
+	Quarantined   []string  `json:"quarantined"`
+	Retries       int       `json:"retries"`
+	Started       time.Time `json:"started"`
+	Finished      time.Time `json:"finished"`
+	CommitID      string    `json:"worker_commit_id,omitempty"`
+}
+
+// collectRepo collects the signals for u using c, retrying any failure other
+// than collector.ErrUncollectableRepo with an increasing delay.
+//
+// The repo is tried up to retry.attempts times, as long as the shard's retry
+// budget in status is not used up. The number of attempts made is returned
+// along with the last error.
+func collectRepo(ctx context.Context, logger *zap.Logger, c repoCollector, retry retryConfig, u *url.URL, jobID string, status *shardStatus) ([]signal.Set, int, error) {
+	delay := retry.initialDelay
+	attempt := 0
+	for {
+		attempt++
+		ss, err := c.Collect(ctx, u, jobID)
+		if err == nil || errors.Is(err, collector.ErrUncollectableRepo) {
+			return ss, attempt, err
+		}
+		if attempt >= retry.attempts || status.Retries >= retry.shardBudget {
+			return nil, attempt, err
+		}
+		logger.With(
+			zap.Error(err),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", delay),
+		).Warn("Failed to collect repo. Retrying.")
+		status.Retries++
+		select {
+		case <-ctx.Done():
+			return nil, attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// writeShardStatus writes the status of the shard, and the quarantined repos
+// if there are any, to the quarantine bucket beside the shard's filename.
+//
+// The files are not written to the result buckets, as every file in those is
+// expected to be a shard or shard metadata. If the quarantine bucket is not
+// set nothing is written, unless there are quarantined repos, in which case
+// errNoQuarantineBucket is returned so that the shard fails rather than
+// silently dropping them.
+func (w *collectWorker) writeShardStatus(ctx context.Context, filename string, status *shardStatus, quarantined []quarantinedRepo) error {
+	if w.quarantineBucketURL == "" {
+		if len(quarantined) > 0 {
+			return errNoQuarantineBucket
+		}
+		return nil
+	}
+	if len(quarantined) > 0 {
+		b, err := json.MarshalIndent(quarantined, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding quarantine: %w", err)
+		}
+		if err := data.WriteToBlobStore(ctx, w.quarantineBucketURL, filename+quarantineSuffix, b); err != nil {
+			return fmt.Errorf("writing quarantine: %w", err)
+		}
+	}
+	b, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding status: %w", err)
+	}
+	if err := data.WriteToBlobStore(ctx, w.quarantineBucketURL, filename+statusSuffix, b); err != nil {
+		return fmt.Errorf("writing status: %w", err)
+	}
+	return nil
+}
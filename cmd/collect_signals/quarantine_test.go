@@ -0,0 +1,169 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+var errCollect = errors.New("collect failed")
+
+// fakeCollector returns each of errs in turn from Collect, and nil once they
+// are used up. The time of each call is recorded.
+type fakeCollector struct {
+	errs  []error
+	calls []time.Time
+}
+
+func (c *fakeCollector) Collect(ctx context.Context, u *url.URL, jobID string) ([]signal.Set, error) {
+	c.calls = append(c.calls, time.Now())
+	if len(c.errs) == 0 {
+		return nil, nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return nil, err
+}
+
+func TestCollectRepo(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name         string
+		errs         []error
+		retry        retryConfig
+		retries      int
+		wantAttempts int
+		wantRetries  int
+		wantErr      error
+	}{
+		{
+			name:         "success",
+			retry:        retryConfig{attempts: 3, shardBudget: 20},
+			wantAttempts: 1,
+		},
+		{
+			name:         "uncollectable is not retried",
+			errs:         []error{collector.ErrUncollectableRepo},
+			retry:        retryConfig{attempts: 3, shardBudget: 20},
+			wantAttempts: 1,
+			wantErr:      collector.ErrUncollectableRepo,
+		},
+		{
+			name:         "success after retry",
+			errs:         []error{errCollect},
+			retry:        retryConfig{attempts: 3, shardBudget: 20},
+			wantAttempts: 2,
+			wantRetries:  1,
+		},
+		{
+			name:         "attempts used up",
+			errs:         []error{errCollect, errCollect, errCollect, errCollect},
+			retry:        retryConfig{attempts: 3, shardBudget: 20},
+			wantAttempts: 3,
+			wantRetries:  2,
+			wantErr:      errCollect,
+		},
+		{
+			name:         "budget used up by earlier repos",
+			errs:         []error{errCollect},
+			retry:        retryConfig{attempts: 3, shardBudget: 5},
+			retries:      5,
+			wantAttempts: 1,
+			wantRetries:  5,
+			wantErr:      errCollect,
+		},
+		{
+			name:         "budget used up by this repo",
+			errs:         []error{errCollect, errCollect, errCollect},
+			retry:        retryConfig{attempts: 3, shardBudget: 1},
+			wantAttempts: 2,
+			wantRetries:  1,
+			wantErr:      errCollect,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &fakeCollector{errs: test.errs}
+			status := &shardStatus{Retries: test.retries}
+			u, _ := url.Parse("https://github.com/ossf/criticality_score")
+
+			_, attempts, err := collectRepo(context.Background(), zap.NewNop(), c, test.retry, u, "", status)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("collectRepo() = %v, want %v", err, test.wantErr)
+			}
+			if attempts != test.wantAttempts || len(c.calls) != test.wantAttempts {
+				t.Fatalf("collectRepo() attempts = %d with %d calls, want %d", attempts, len(c.calls), test.wantAttempts)
+			}
+			if status.Retries != test.wantRetries {
+				t.Fatalf("status.Retries = %d, want %d", status.Retries, test.wantRetries)
+			}
+		})
+	}
+}
+
+func TestCollectRepoBackoff(t *testing.T) {
+	delay := 10 * time.Millisecond
+	c := &fakeCollector{errs: []error{errCollect, errCollect}}
+	retry := retryConfig{attempts: 3, initialDelay: delay, shardBudget: 20}
+	u, _ := url.Parse("https://github.com/ossf/criticality_score")
+
+	if _, _, err := collectRepo(context.Background(), zap.NewNop(), c, retry, u, "", &shardStatus{}); err != nil {
+		t.Fatalf("collectRepo() = %v, want no error", err)
+	}
+	if len(c.calls) != 3 {
+		t.Fatalf("collectRepo() made %d calls, want 3", len(c.calls))
+	}
+	// The delay is doubled before each retry.
+	for i, want := range []time.Duration{delay, 2 * delay} {
+		if got := c.calls[i+1].Sub(c.calls[i]); got < want {
+			t.Fatalf("delay before retry %d = %v, want at least %v", i+1, got, want)
+		}
+	}
+}
+
+func TestCollectRepoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &fakeCollector{errs: []error{errCollect}}
+	retry := retryConfig{attempts: 3, initialDelay: time.Hour, shardBudget: 20}
+	u, _ := url.Parse("https://github.com/ossf/criticality_score")
+
+	_, _, err := collectRepo(ctx, zap.NewNop(), c, retry, u, "", &shardStatus{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("collectRepo() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWriteShardStatusNoBucket(t *testing.T) {
+	w := &collectWorker{}
+	ctx := context.Background()
+
+	if err := w.writeShardStatus(ctx, "shard-0000001", &shardStatus{}, nil); err != nil {
+		t.Fatalf("writeShardStatus() = %v, want no error", err)
+	}
+	quarantined := []quarantinedRepo{{Repo: "https://github.com/ossf/criticality_score", Error: "failed", Attempts: 3}}
+	if err := w.writeShardStatus(ctx, "shard-0000001", &shardStatus{}, quarantined); !errors.Is(err, errNoQuarantineBucket) {
+		t.Fatalf("writeShardStatus() = %v, want %v", err, errNoQuarantineBucket)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"os"
+	"time"
 
 	githubstats "github.com/ossf/scorecard/v4/clients/githubrepo/stats"
 	"github.com/ossf/scorecard/v4/cron/data"
@@ -17,6 +17,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/infile"
 	"github.com/ossf/criticality_score/internal/scorer"
 	"github.com/ossf/criticality_score/internal/signalio"
 )
@@ -27,12 +28,14 @@ const (
 )
 
 type collectWorker struct {
-	logger          *zap.Logger
-	exporter        monitoring.Exporter
-	c               *collector.Collector
-	s               *scorer.Scorer
-	scoreColumnName string
-	csvBucketURL    string
+	logger              *zap.Logger
+	exporter            monitoring.Exporter
+	c                   *collector.Collector
+	s                   *scorer.Scorer
+	scoreColumnName     string
+	csvBucketURL        string
+	quarantineBucketURL string
+	retry               retryConfig
 }
 
 // Process implements the worker.Worker interface.
@@ -65,6 +68,24 @@ func (w *collectWorker) Process(ctx context.Context, req *data.ScorecardBatchReq
 	var csvOutput bytes.Buffer
 	csvOut := signalio.CSVWriter(&csvOutput, w.c.EmptySets(), extras...)
 
+	status := &shardStatus{
+		Shard:    req.GetShardNum(),
+		JobTime:  jobTime,
+		Repos:    len(req.GetRepos()),
+		CommitID: commitID,
+		Started:  time.Now().UTC(),
+	}
+	var quarantined []quarantinedRepo
+	quarantine := func(rawURL string, attempts int, err error) {
+		quarantined = append(quarantined, quarantinedRepo{
+			Repo:     rawURL,
+			Error:    err.Error(),
+			Attempts: attempts,
+			Time:     time.Now().UTC(),
+		})
+		status.Quarantined = append(status.Quarantined, rawURL)
+	}
+
 	// Iterate through the repos in this shard.
 	for _, repo := range req.GetRepos() {
 		rawURL := repo.GetUrl()
@@ -82,15 +103,28 @@ func (w *collectWorker) Process(ctx context.Context, req *data.ScorecardBatchReq
 		if err != nil {
 			// TODO: record a metric
 			repoLogger.With(zap.Error(err)).Warn("Failed to parse repo URL")
+			// The URL will never parse, so retrying the shard can't help.
+			status.Uncollectable++
 			continue
 		}
-		ss, err := w.c.Collect(ctx, u, jobID)
+		ss, attempts, err := collectRepo(ctx, repoLogger, w.c, w.retry, u, jobID, status)
 		if err != nil {
 			if errors.Is(err, collector.ErrUncollectableRepo) {
 				repoLogger.With(zap.Error(err)).Warn("Repo is uncollectable")
+				status.Uncollectable++
 				continue
 			}
-			return fmt.Errorf("failed during signal collection: %w", err)
+			if ctx.Err() != nil {
+				return fmt.Errorf("failed during signal collection: %w", err)
+			}
+			// Quarantine the repo rather than failing the shard, so the
+			// rest of the shard is not collected again when it is retried.
+			repoLogger.With(
+				zap.Error(err),
+				zap.Int("attempts", attempts),
+			).Error("Failed to collect repo. Quarantining.")
+			quarantine(rawURL, attempts, err)
+			continue
 		}
 
 		// If scoring is enabled, prepare the extra data to be output.
@@ -125,6 +159,14 @@ func (w *collectWorker) Process(ctx context.Context, req *data.ScorecardBatchReq
 		if err := csvOut.WriteSignals(ss, extras...); err != nil {
 			return fmt.Errorf("failed writing signals: %w", err)
 		}
+		status.Written++
+	}
+
+	// Write the status before the results, as the presence of the results
+	// indicates the shard is complete.
+	status.Finished = time.Now().UTC()
+	if err := w.writeShardStatus(ctx, filename, status, quarantined); err != nil {
+		return fmt.Errorf("error writing shard status: %w", err)
 	}
 
 	// Write to the csv bucket if it is set.
@@ -140,7 +182,11 @@ func (w *collectWorker) Process(ctx context.Context, req *data.ScorecardBatchReq
 		return fmt.Errorf("error writing json to blob store: %w", err)
 	}
 
-	logger.Info("Shard written successfully")
+	logger.With(
+		zap.Int("written", status.Written),
+		zap.Int("quarantined", len(quarantined)),
+		zap.Int("retries", status.Retries),
+	).Info("Shard written successfully")
 
 	return nil
 }
@@ -168,7 +214,7 @@ func getScorer(logger *zap.Logger, scoringEnabled bool, scoringConfigFile string
 	}
 	logger.With(zap.String("filename", scoringConfigFile)).Info("Scoring: using config file")
 
-	f, err := os.Open(scoringConfigFile)
+	f, err := infile.Open(context.Background(), scoringConfigFile)
 	if err != nil {
 		return nil, fmt.Errorf("opening config: %w", err)
 	}
@@ -200,7 +246,7 @@ func getMetricsExporter() (monitoring.Exporter, error) {
 	return exporter, nil
 }
 
-func NewWorker(ctx context.Context, logger *zap.Logger, scoringEnabled bool, scoringConfigFile, scoringColumn, csvBucketURL string, collectOpts []collector.Option) (*collectWorker, error) {
+func NewWorker(ctx context.Context, logger *zap.Logger, scoringEnabled bool, scoringConfigFile, scoringColumn, csvBucketURL, quarantineBucketURL string, retry retryConfig, collectOpts []collector.Option) (*collectWorker, error) {
 	logger.Info("Initializing worker")
 
 	c, err := collector.New(ctx, logger, collectOpts...)
@@ -225,11 +271,13 @@ func NewWorker(ctx context.Context, logger *zap.Logger, scoringEnabled bool, sco
 	}
 
 	return &collectWorker{
-		logger:          logger,
-		c:               c,
-		s:               s,
-		scoreColumnName: scoringColumn,
-		exporter:        exporter,
-		csvBucketURL:    csvBucketURL,
+		logger:              logger,
+		c:                   c,
+		s:                   s,
+		scoreColumnName:     scoringColumn,
+		exporter:            exporter,
+		csvBucketURL:        csvBucketURL,
+		quarantineBucketURL: quarantineBucketURL,
+		retry:               retry,
 	}, nil
 }
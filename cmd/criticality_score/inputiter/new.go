@@ -21,6 +21,7 @@ import (
 	"net/url"
 	"os"
 
+	"github.com/ossf/criticality_score/internal/cloudstorage"
 	"github.com/ossf/criticality_score/internal/infile"
 )
 
@@ -47,7 +48,8 @@ func errWithFilename(err error) bool {
 //
 // If only one arg is specified, the code will treat it as a file and attempt to
 // open it. If the file doesn't exist, and is parseable as a URL the arg will be
-// treated as a repo.
+// treated as a repo. The file may also be a bucket URL (e.g.
+// "gs://bucket/repos.txt"), which is never treated as a repo.
 //
 // If more than one arg is specified they are all considered to be repos.
 //
@@ -70,9 +72,10 @@ func New(args []string) (IterCloser[string], error) {
 				scanner: bufio.NewScanner(r),
 			}, nil
 		}
-		if urlParseFailed || !errWithFilename(err) {
+		if urlParseFailed || !errWithFilename(err) || cloudstorage.IsBucketURL(fileOrRepo) {
 			// Only report errors if the file doesn't appear to be a URL, if the
-			// filename doesn't exist, or the filename is invalid.
+			// filename doesn't exist, or the filename is invalid. Bucket URLs
+			// are never repos.
 			return nil, err
 		}
 	}
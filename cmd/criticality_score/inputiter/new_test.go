@@ -15,9 +15,11 @@
 package inputiter_test
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"golang.org/x/exp/slices"
@@ -151,6 +153,91 @@ func TestNew_URLFile(t *testing.T) {
 	}
 }
 
+func TestNew_CompressedURLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt.gz")
+	want := []string{
+		"https://github.com/ossf/criticality_score",
+		"https://github.com/ossf/scorecard",
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	w := gzip.NewWriter(f)
+	for _, url := range want {
+		if _, err := fmt.Fprintln(w, url); err != nil {
+			t.Fatalf("Failed to write to test file: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to write to test file: %v", err)
+	}
+	f.Close()
+
+	i, err := inputiter.New([]string{path})
+	if err != nil {
+		t.Fatalf("New() = %v; want no error", err)
+	}
+	defer i.Close()
+
+	var got []string
+	for i.Next() {
+		got = append(got, i.Item())
+	}
+
+	if err := i.Err(); err != nil {
+		t.Errorf("Err() = %v; want no err", err)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("Iterator return %v; want %v", got, want)
+	}
+}
+
+func TestNew_BucketURLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	want := []string{"https://github.com/ossf/criticality_score"}
+	if err := os.WriteFile(path, []byte(want[0]+"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	i, err := inputiter.New([]string{fileURL(path)})
+	if err != nil {
+		t.Fatalf("New() = %v; want no error", err)
+	}
+	defer i.Close()
+
+	var got []string
+	for i.Next() {
+		got = append(got, i.Item())
+	}
+	if err := i.Err(); err != nil {
+		t.Errorf("Err() = %v; want no err", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Iterator return %v; want %v", got, want)
+	}
+}
+
+func TestNew_MissingBucketURLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	i, err := inputiter.New([]string{fileURL(path)})
+	if err == nil {
+		i.Close()
+		t.Fatal("New() = nil; want an error")
+	}
+}
+
+// fileURL returns a file:// URL for the local path name.
+func fileURL(name string) string {
+	p := filepath.ToSlash(name)
+	if !strings.HasPrefix(p, "/") {
+		// Windows paths start with a drive letter.
+		p = "/" + p
+	}
+	return "file://" + p
+}
+
 func TestNew_InvalidURL(t *testing.T) {
 	want := ":this.is/not/a/url"
 	i, err := inputiter.New([]string{want})
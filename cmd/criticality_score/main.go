@@ -19,6 +19,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -30,8 +31,12 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/ossf/criticality_score/cmd/criticality_score/inputiter"
+	"github.com/ossf/criticality_score/internal/cloudstorage"
 	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	"github.com/ossf/criticality_score/internal/infile"
 	log "github.com/ossf/criticality_score/internal/log"
+	"github.com/ossf/criticality_score/internal/marker"
 	"github.com/ossf/criticality_score/internal/outfile"
 	"github.com/ossf/criticality_score/internal/scorer"
 	"github.com/ossf/criticality_score/internal/signalio"
@@ -45,14 +50,17 @@ var (
 	depsdevDisableFlag    = flag.Bool("depsdev-disable", false, "disables the collection of signals from deps.dev.")
 	depsdevDatasetFlag    = flag.String("depsdev-dataset", collector.DefaultGCPDatasetName, "the BigQuery dataset name to use.")
 	depsdevTTLFlag        = flag.Int("depsdev-expiration", 0, "the default expiration (`hours`) to use for deps.dev tables. No expiration by default.")
+	depsdevPackagesFlag   = flag.String("depsdev-packages-out", "", "writes the packages contributing to each repo's dependent count to `FILE`.")
 	scoringDisableFlag    = flag.Bool("scoring-disable", false, "disables the generation of scores.")
 	scoringConfigFlag     = flag.String("scoring-config", "", "path to a YAML file for configuring the scoring algorithm.")
 	scoringColumnNameFlag = flag.String("scoring-column", "", "manually specify the name for the column used to hold the score.")
+	inputManifestFlag     = flag.String("input-manifest", "", "verifies the input FILE against the manifest marker `file` written by enumerate_github before collection starts.")
 	workersFlag           = flag.Int("workers", 1, "the total number of concurrent workers to use.")
 	versionFlag           = flag.Bool("version", false, "display the version of this command.")
 	logLevel              = defaultLogLevel
 	logEnv                log.Env
 	formatType            signalio.WriterType
+	depsdevPackagesFormat depsdev.PackageWriterType
 )
 
 // initFlags prepares any runtime flags, usage information and parses the flags.
@@ -60,7 +68,9 @@ func initFlags() {
 	flag.Var(&logLevel, "log", "set the `level` of logging.")
 	flag.TextVar(&logEnv, "log-env", log.DefaultEnv, "set logging `env`.")
 	flag.TextVar(&formatType, "format", signalio.WriterTypeText, "set the output format. Choices are text, json or csv.")
+	flag.TextVar(&depsdevPackagesFormat, "depsdev-packages-format", depsdev.PackageWriterTypeCSV, "set the format for -depsdev-packages-out. Choices are csv or json.")
 	outfile.DefineFlags(flag.CommandLine, "out", "force", "append", "OUTFILE")
+	outfile.DefineRotationFlags(flag.CommandLine, "out", "OUTFILE")
 	flag.Usage = func() {
 		cmdName := path.Base(os.Args[0])
 		w := flag.CommandLine.Output()
@@ -98,7 +108,7 @@ func getScorer(logger *zap.Logger) *scorer.Scorer {
 		zap.String("filename", *scoringConfigFlag),
 	)
 	logger.Info("Preparing scorer from config")
-	cf, err := os.Open(*scoringConfigFlag)
+	cf, err := infile.Open(context.Background(), *scoringConfigFlag)
 	if err != nil {
 		logger.With(
 			zap.Error(err),
@@ -127,6 +137,29 @@ func generateScoreColumnName(s *scorer.Scorer) string {
 	return s.Name()
 }
 
+// verifyInput checks the input file against the -input-manifest marker file,
+// exiting if it is incomplete or has changed.
+func verifyInput(ctx context.Context, logger *zap.Logger) {
+	logger = logger.With(zap.String("manifest_filename", *inputManifestFlag))
+	if flag.NArg() != 1 || flag.Arg(0) == "-" {
+		logger.Error("A single input file must be specified to verify against the manifest.")
+		os.Exit(2)
+	}
+	m, err := marker.Verify(ctx, *inputManifestFlag, flag.Arg(0))
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+			zap.String("filename", flag.Arg(0)),
+		).Error("Failed to verify input against manifest")
+		os.Exit(2)
+	}
+	logger.With(
+		zap.String("run_id", m.RunID),
+		zap.Int("rows", m.Rows),
+		zap.Int64("bytes", m.Bytes),
+	).Info("Verified input against manifest")
+}
+
 func main() {
 	initFlags()
 
@@ -148,6 +181,10 @@ func main() {
 
 	ctx := context.Background()
 
+	if *inputManifestFlag != "" {
+		verifyInput(ctx, logger)
+	}
+
 	// Bump the # idle conns per host
 	http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost = *workersFlag * 5
 
@@ -160,6 +197,20 @@ func main() {
 	if *depsdevDisableFlag {
 		opts = append(opts, collector.DisableSource(collector.SourceTypeDepsDev))
 	}
+	// pw is closed explicitly once collection finishes, as closing commits
+	// the upload for bucket URLs.
+	var pw io.WriteCloser
+	if *depsdevPackagesFlag != "" && !*depsdevDisableFlag {
+		pw, err = cloudstorage.NewWriter(ctx, *depsdevPackagesFlag)
+		if err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("filename", *depsdevPackagesFlag),
+			).Error("Failed to open file for deps.dev packages")
+			os.Exit(2)
+		}
+		opts = append(opts, collector.DepsDevPackageWriter(depsdevPackagesFormat.New(pw)))
+	}
 
 	c, err := collector.New(ctx, logger, opts...)
 	if err != nil {
@@ -179,22 +230,24 @@ func main() {
 	}
 	defer iter.Close()
 
-	// Open the out-file for writing
-	w, err := outfile.Open(context.Background())
+	// Prepare the output writer
+	extras := []string{}
+	if s != nil {
+		extras = append(extras, scoreColumnName)
+	}
+
+	// Open the out-file for writing, rotating it if -out-max-rows or
+	// -out-max-bytes are set.
+	out, err := outfile.NewRotator(context.Background(), outfile.DefaultOpener, func(w io.Writer) signalio.Writer {
+		return formatType.New(w, c.EmptySets(), extras...)
+	})
 	if err != nil {
 		logger.With(
 			zap.Error(err),
 		).Error("Failed to open file for output")
 		os.Exit(2)
 	}
-	defer w.Close()
-
-	// Prepare the output writer
-	extras := []string{}
-	if s != nil {
-		extras = append(extras, scoreColumnName)
-	}
-	out := formatType.New(w, c.EmptySets(), extras...)
+	defer out.Close()
 
 	// Start the workers that process a channel of repo urls.
 	repos := make(chan *url.URL)
@@ -227,7 +280,10 @@ func main() {
 			}
 
 			// Write the signals to storage.
-			if err := out.WriteSignals(ss, extras...); err != nil {
+			err = out.Write(func(w signalio.Writer) error {
+				return w.WriteSignals(ss, extras...)
+			})
+			if err != nil {
 				l.With(
 					zap.Error(err),
 				).Error("Failed to write signal set")
@@ -267,5 +323,21 @@ func main() {
 	// Wait until all the workers have finished.
 	wait()
 
+	if err := out.Close(); err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to close output")
+		os.Exit(2)
+	}
+	if pw != nil {
+		if err := pw.Close(); err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("filename", *depsdevPackagesFlag),
+			).Error("Failed to close file for deps.dev packages")
+			os.Exit(2)
+		}
+	}
+
 	// TODO: track metrics as we are running to measure coverage of data
 }
@@ -0,0 +1,285 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aggregate combines the CSV shards written by collect_signals into
+// a single output, streaming the shards rather than holding them in memory.
+package aggregate
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	DefaultURLColumn  = "repo.url"
+	DefaultDateColumn = "collection_date"
+)
+
+var ErrorMissingColumn = errors.New("missing column")
+
+// Shard is a CSV file to be aggregated.
+type Shard struct {
+	Name string
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Writer receives the aggregated output.
+type Writer interface {
+	// WriteHeader is called with the name of every column before any rows
+	// are written.
+	WriteHeader(columns []string) error
+
+	// WriteRow is called with each row, which has a value for every column.
+	WriteRow(row []string) error
+
+	// Flush is called once all the rows have been written.
+	Flush() error
+}
+
+// Options controls how shards are aggregated.
+type Options struct {
+	// URLColumn is the column used to identify duplicate repositories.
+	URLColumn string
+
+	// DateColumn is the column used to choose the latest record for a
+	// repository. If it is empty, or a shard does not have it, later shards
+	// are considered more recent.
+	DateColumn string
+
+	// SortColumn, if set, is the column holding the score to sort the output
+	// by, in descending order.
+	SortColumn string
+
+	// SortChunkRows and SortDir control the external merge sort used when
+	// SortColumn is set. See NewSorter.
+	SortChunkRows int
+	SortDir       string
+}
+
+// Stats records the outcome of Aggregate.
+type Stats struct {
+	Shards     int
+	Columns    int
+	Rows       int
+	Duplicates int
+	Written    int
+}
+
+// Aggregate writes the rows in each shard to w.
+//
+// The columns of the output are the union of the columns of each shard, and
+// any columns missing from a shard are left empty. Repositories are identified
+// by the CanonicalURL of the URLColumn, and only the latest record for each
+// repository is written.
+//
+// Each shard is read twice: once to find the columns and the latest record
+// for each repository, and again to write the records.
+func Aggregate(ctx context.Context, shards []Shard, w Writer, opts Options) (Stats, error) {
+	if opts.URLColumn == "" {
+		opts.URLColumn = DefaultURLColumn
+	}
+	stats := Stats{Shards: len(shards)}
+
+	// First pass: collect the columns and find the latest records.
+	var columns Columns
+	latest := make(map[string]record)
+	for i, shard := range shards {
+		err := readShard(ctx, shard, func(header []string) error {
+			columns.Add(header)
+			return nil
+		}, func(header []string) (func(row []string, n int) error, error) {
+			urlIdx := indexOf(header, opts.URLColumn)
+			if urlIdx < 0 {
+				return nil, fmt.Errorf("%w %s in shard %s", ErrorMissingColumn, opts.URLColumn, shard.Name)
+			}
+			dateIdx := indexOf(header, opts.DateColumn)
+			return func(row []string, n int) error {
+				stats.Rows++
+				r := record{shard: i, row: n}
+				if dateIdx >= 0 {
+					r.date = parseDate(row[dateIdx])
+				}
+				key := CanonicalURL(row[urlIdx])
+				if prev, ok := latest[key]; ok {
+					stats.Duplicates++
+					if !r.after(prev) {
+						return nil
+					}
+				}
+				latest[key] = r
+				return nil
+			}, nil
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+	stats.Columns = len(columns.Names())
+	if opts.SortColumn != "" && columns.Index(opts.SortColumn) < 0 {
+		return stats, fmt.Errorf("%w %s", ErrorMissingColumn, opts.SortColumn)
+	}
+
+	if err := w.WriteHeader(columns.Names()); err != nil {
+		return stats, fmt.Errorf("writing header: %w", err)
+	}
+	emit := func(row []string) error {
+		stats.Written++
+		return w.WriteRow(row)
+	}
+	var sorter *Sorter
+	if opts.SortColumn != "" {
+		sorter = NewSorter(columns.Index(opts.SortColumn), opts.SortChunkRows, opts.SortDir)
+		defer sorter.Close()
+	}
+
+	// Second pass: write the latest records.
+	for i, shard := range shards {
+		err := readShard(ctx, shard, nil, func(header []string) (func(row []string, n int) error, error) {
+			urlIdx := indexOf(header, opts.URLColumn)
+			mapping := columns.Mapping(header)
+			return func(row []string, n int) error {
+				if r := latest[CanonicalURL(row[urlIdx])]; r.shard != i || r.row != n {
+					return nil
+				}
+				if sorter != nil {
+					return sorter.Add(mapping(row))
+				}
+				return emit(mapping(row))
+			}, nil
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+	if sorter != nil {
+		if err := sorter.Merge(emit); err != nil {
+			return stats, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return stats, fmt.Errorf("flushing output: %w", err)
+	}
+	return stats, nil
+}
+
+// readShard reads the CSV shard, calling onHeader with the header if it is
+// not nil, and then calling the function returned by onRows with each row
+// and its position in the shard.
+func readShard(ctx context.Context, shard Shard, onHeader func([]string) error, onRows func([]string) (func([]string, int) error, error)) error {
+	f, err := shard.Open(ctx)
+	if err != nil {
+		return fmt.Errorf("opening shard %s: %w", shard.Name, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		// An empty shard has no rows.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading header row for shard %s: %w", shard.Name, err)
+	}
+	if onHeader != nil {
+		if err := onHeader(header); err != nil {
+			return err
+		}
+	}
+	onRow, err := onRows(header)
+	if err != nil {
+		return err
+	}
+	for n := 0; ; n++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading shard %s: %w", shard.Name, err)
+		}
+		if err := onRow(row, n); err != nil {
+			return err
+		}
+	}
+}
+
+// record is the position of a row, and its date if known.
+type record struct {
+	shard int
+	row   int
+	date  time.Time
+}
+
+// after returns true if r is more recent than other. Rows without a date are
+// compared by their position.
+func (r record) after(other record) bool {
+	if !r.date.IsZero() && !other.date.IsZero() && !r.date.Equal(other.date) {
+		return r.date.After(other.date)
+	}
+	if r.shard != other.shard {
+		return r.shard > other.shard
+	}
+	return r.row > other.row
+}
+
+func parseDate(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func indexOf(header []string, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// csvWriter implements Writer for CSV output.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// CSVWriter returns a Writer that writes CSV to w.
+func CSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+// WriteHeader implements the Writer interface.
+func (w *csvWriter) WriteHeader(columns []string) error {
+	return w.w.Write(columns)
+}
+
+// WriteRow implements the Writer interface.
+func (w *csvWriter) WriteRow(row []string) error {
+	return w.w.Write(row)
+}
+
+// Flush implements the Writer interface.
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
+)
+
+func testShards(contents ...string) []aggregate.Shard {
+	var shards []aggregate.Shard
+	for i, c := range contents {
+		c := c
+		shards = append(shards, aggregate.Shard{
+			Name: "shard-" + string(rune('a'+i)),
+			Open: func(context.Context) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(c)), nil
+			},
+		})
+	}
+	return shards
+}
+
+func runAggregate(t *testing.T, shards []aggregate.Shard, opts aggregate.Options) ([][]string, aggregate.Stats) {
+	t.Helper()
+	var out bytes.Buffer
+	stats, err := aggregate.Aggregate(context.Background(), shards, aggregate.CSVWriter(&out), opts)
+	if err != nil {
+		t.Fatalf("Aggregate() = %v, want no error", err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() = %v, want no error", err)
+	}
+	return rows, stats
+}
+
+func TestAggregateUnionColumns(t *testing.T) {
+	shards := testShards(
+		"repo.url,repo.star_count,default_score\n"+
+			"https://github.com/a/a,10,0.5\n",
+		// A new signal was added part way through the run.
+		"repo.url,repo.star_count,repo.license,default_score\n"+
+			"https://github.com/b/b,20,MIT,0.7\n",
+		// Empty shards are ignored.
+		"",
+	)
+	got, stats := runAggregate(t, shards, aggregate.Options{})
+	want := [][]string{
+		{"repo.url", "repo.star_count", "default_score", "repo.license"},
+		{"https://github.com/a/a", "10", "0.5", ""},
+		{"https://github.com/b/b", "20", "0.7", "MIT"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Aggregate() mismatch (-want +got):\n%s", diff)
+	}
+	wantStats := aggregate.Stats{Shards: 3, Columns: 4, Rows: 2, Written: 2}
+	if diff := cmp.Diff(wantStats, stats); diff != "" {
+		t.Fatalf("Aggregate() stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAggregateDeduplicate(t *testing.T) {
+	shards := testShards(
+		"repo.url,collection_date,default_score\n"+
+			"https://github.com/a/a,2022-06-14T00:00:00Z,0.1\n"+
+			"https://github.com/b/b,2022-06-21T00:00:00Z,0.2\n"+
+			"https://github.com/c/c,,0.3\n",
+		"repo.url,collection_date,default_score\n"+
+			// Newer than the first record.
+			"https://GitHub.com/A/a.git,2022-06-21T00:00:00Z,0.4\n"+
+			// Older than the first record.
+			"https://github.com/b/b/,2022-06-14T00:00:00Z,0.5\n"+
+			// No date, so the later shard wins.
+			"http://www.github.com/c/c,,0.6\n",
+	)
+	got, stats := runAggregate(t, shards, aggregate.Options{DateColumn: aggregate.DefaultDateColumn})
+	want := [][]string{
+		{"repo.url", "collection_date", "default_score"},
+		{"https://github.com/b/b", "2022-06-21T00:00:00Z", "0.2"},
+		{"https://GitHub.com/A/a.git", "2022-06-21T00:00:00Z", "0.4"},
+		{"http://www.github.com/c/c", "", "0.6"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Aggregate() mismatch (-want +got):\n%s", diff)
+	}
+	if stats.Duplicates != 3 || stats.Written != 3 {
+		t.Fatalf("Aggregate() stats = %+v, want 3 duplicates and 3 written", stats)
+	}
+}
+
+func TestAggregateSort(t *testing.T) {
+	shards := testShards(
+		"repo.url,default_score\n"+
+			"https://github.com/a/a,0.2\n"+
+			"https://github.com/b/b,0.9\n"+
+			"https://github.com/c/c,\n",
+		"repo.url,default_score\n"+
+			"https://github.com/d/d,0.5\n"+
+			"https://github.com/e/e,0.9\n"+
+			"https://github.com/f/f,0.1\n",
+	)
+	want := [][]string{
+		{"repo.url", "default_score"},
+		{"https://github.com/b/b", "0.9"},
+		{"https://github.com/e/e", "0.9"},
+		{"https://github.com/d/d", "0.5"},
+		{"https://github.com/a/a", "0.2"},
+		{"https://github.com/f/f", "0.1"},
+		{"https://github.com/c/c", ""},
+	}
+	// Test both an in-memory sort, and a merge of several chunks.
+	for _, chunkRows := range []int{100, 2} {
+		got, _ := runAggregate(t, shards, aggregate.Options{
+			SortColumn:    "default_score",
+			SortChunkRows: chunkRows,
+			SortDir:       t.TempDir(),
+		})
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("Aggregate(chunkRows=%d) mismatch (-want +got):\n%s", chunkRows, diff)
+		}
+	}
+}
+
+func TestAggregateMissingColumn(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards []aggregate.Shard
+		opts   aggregate.Options
+	}{
+		{
+			name:   "url",
+			shards: testShards("name,default_score\na,0.1\n"),
+		},
+		{
+			name:   "sort",
+			shards: testShards("repo.url,default_score\na,0.1\n"),
+			opts:   aggregate.Options{SortColumn: "other_score"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			_, err := aggregate.Aggregate(context.Background(), test.shards, aggregate.CSVWriter(&out), test.opts)
+			if !errors.Is(err, aggregate.ErrorMissingColumn) {
+				t.Fatalf("Aggregate() = %v, want %v", err, aggregate.ErrorMissingColumn)
+			}
+		})
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "https://github.com/ossf/scorecard", want: "https://github.com/ossf/scorecard"},
+		{in: "http://www.GitHub.com/OSSF/Scorecard/", want: "https://github.com/ossf/scorecard"},
+		{in: "https://github.com/ossf/scorecard.git?x=1#y", want: "https://github.com/ossf/scorecard"},
+		{in: " not a url ", want: "not a url"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			if got := aggregate.CanonicalURL(test.in); got != test.want {
+				t.Fatalf("CanonicalURL() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"net/url"
+	"strings"
+)
+
+// CanonicalURL returns a normalized form of a repository url, so that
+// different ways of writing the same url can be identified as duplicates.
+//
+// The scheme is always https, the host and path are lowercased, and any
+// "www." prefix, query, fragment, trailing slash and ".git" suffix are
+// removed. If rawURL can not be parsed it is returned trimmed and lowercased.
+func CanonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	p := strings.ToLower(strings.TrimRight(u.Path, "/"))
+	p = strings.TrimSuffix(p, ".git")
+	return "https://" + host + p
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+// Columns is the union of the columns in a set of CSV headers, in the order
+// they were first seen.
+type Columns struct {
+	names []string
+	index map[string]int
+}
+
+// Add adds any columns in header that have not been seen before.
+func (c *Columns) Add(header []string) {
+	if c.index == nil {
+		c.index = make(map[string]int)
+	}
+	for _, name := range header {
+		if _, ok := c.index[name]; !ok {
+			c.index[name] = len(c.names)
+			c.names = append(c.names, name)
+		}
+	}
+}
+
+// Names returns the name of each column.
+func (c *Columns) Names() []string {
+	return c.names
+}
+
+// Index returns the position of the column name, or -1 if it has not been
+// seen.
+func (c *Columns) Index(name string) int {
+	if i, ok := c.index[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// Mapping returns a function that converts a row with the columns in header
+// into a row with every column in c. Columns missing from header are left
+// empty.
+//
+// All the columns in header must have been added to c.
+func (c *Columns) Mapping(header []string) func(row []string) []string {
+	positions := make([]int, len(header))
+	for i, name := range header {
+		positions[i] = c.index[name]
+	}
+	return func(row []string) []string {
+		out := make([]string, len(c.names))
+		for i, v := range row {
+			out[positions[i]] = v
+		}
+		return out
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// jsonLinesWriter implements RecordWriter for JSON Lines output.
+type jsonLinesWriter struct {
+	w *bufio.Writer
+	e *json.Encoder
+}
+
+// JSONLinesWriter returns a RecordWriter that writes each record to w as a
+// line of JSON.
+func JSONLinesWriter(w io.Writer) RecordWriter {
+	bw := bufio.NewWriter(w)
+	return &jsonLinesWriter{w: bw, e: json.NewEncoder(bw)}
+}
+
+// WriteSchema implements the RecordWriter interface.
+func (w *jsonLinesWriter) WriteSchema(*Schema) error {
+	return nil
+}
+
+// WriteRecord implements the RecordWriter interface.
+func (w *jsonLinesWriter) WriteRecord(rec map[string]any) error {
+	return w.e.Encode(rec)
+}
+
+// Flush implements the RecordWriter interface.
+func (w *jsonLinesWriter) Flush() error {
+	return w.w.Flush()
+}
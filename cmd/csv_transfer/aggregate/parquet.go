@@ -0,0 +1,84 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"errors"
+	"io"
+
+	"github.com/ossf/criticality_score/internal/parquet"
+)
+
+// parquetCreatedBy is recorded as the application that wrote the Parquet file.
+const parquetCreatedBy = "criticality_score csv_transfer"
+
+var parquetTypes = map[FieldType]parquet.Type{
+	TypeBoolean:   parquet.Boolean,
+	TypeInteger:   parquet.Int64,
+	TypeFloat:     parquet.Double,
+	TypeString:    parquet.String,
+	TypeTimestamp: parquet.Timestamp,
+}
+
+// parquetWriter implements RecordWriter for Parquet output.
+type parquetWriter struct {
+	w  io.Writer
+	pw *parquet.Writer
+}
+
+// ParquetWriter returns a RecordWriter that writes the records to w as a
+// Parquet file. Records in the schema become groups of columns.
+func ParquetWriter(w io.Writer) RecordWriter {
+	return &parquetWriter{w: w}
+}
+
+// WriteSchema implements the RecordWriter interface.
+func (w *parquetWriter) WriteSchema(s *Schema) error {
+	pw, err := parquet.NewWriter(w.w, parquetFields(s.Fields))
+	if err != nil {
+		return err
+	}
+	pw.CreatedBy = parquetCreatedBy
+	w.pw = pw
+	return nil
+}
+
+func parquetFields(fields []*Field) []*parquet.Field {
+	var out []*parquet.Field
+	for _, f := range fields {
+		pf := &parquet.Field{Name: f.Name, Type: parquetTypes[f.Type]}
+		if f.Type == TypeRecord {
+			pf.Fields = parquetFields(f.Fields)
+		}
+		out = append(out, pf)
+	}
+	return out
+}
+
+// WriteRecord implements the RecordWriter interface.
+func (w *parquetWriter) WriteRecord(rec map[string]any) error {
+	if w.pw == nil {
+		return errors.New("parquet schema not written")
+	}
+	return w.pw.Write(rec)
+}
+
+// Flush implements the RecordWriter interface.
+func (w *parquetWriter) Flush() error {
+	if w.pw == nil {
+		return errors.New("parquet schema not written")
+	}
+	return w.pw.Close()
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordWriter receives the aggregated output of JSON shards.
+type RecordWriter interface {
+	// WriteSchema is called with the schema of the records before any
+	// records are written.
+	WriteSchema(s *Schema) error
+
+	// WriteRecord is called with each record. The values in the record have
+	// been converted to match the schema, and are one of bool, int64,
+	// float64, string, time.Time or map[string]any. Null values are left out.
+	WriteRecord(rec map[string]any) error
+
+	// Flush is called once all the records have been written.
+	Flush() error
+}
+
+// AggregateRecords writes the records in each JSON shard to w. Each shard
+// holds a JSON object for each repository, with the signals in nested objects
+// for each namespace.
+//
+// The schema of the output is inferred from every record, and the values are
+// converted to match it. Repositories are identified by the CanonicalURL of
+// the URLColumn, which is a path such as "repo.url", and only the latest
+// record for each repository is written. Records are written in the order
+// they are read, so the SortColumn option is ignored.
+//
+// Like Aggregate, each shard is read twice.
+func AggregateRecords(ctx context.Context, shards []Shard, w RecordWriter, opts Options) (Stats, error) {
+	if opts.URLColumn == "" {
+		opts.URLColumn = DefaultURLColumn
+	}
+	urlPath := strings.Split(opts.URLColumn, ".")
+	var datePath []string
+	if opts.DateColumn != "" {
+		datePath = strings.Split(opts.DateColumn, ".")
+	}
+	stats := Stats{Shards: len(shards)}
+
+	// First pass: infer the schema and find the latest records.
+	schema := &Schema{}
+	latest := make(map[string]record)
+	for i, shard := range shards {
+		err := readRecords(ctx, shard, func(rec map[string]any, n int) error {
+			schema.observe(rec)
+			url, ok := lookup(rec, urlPath).(string)
+			if !ok {
+				return fmt.Errorf("%w %s in record %d of shard %s", ErrorMissingColumn, opts.URLColumn, n, shard.Name)
+			}
+			stats.Rows++
+			r := record{shard: i, row: n}
+			if date, ok := lookup(rec, datePath).(string); ok {
+				r.date = parseDate(date)
+			}
+			key := CanonicalURL(url)
+			if prev, ok := latest[key]; ok {
+				stats.Duplicates++
+				if !r.after(prev) {
+					return nil
+				}
+			}
+			latest[key] = r
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+	schema.Fields = finish(schema.Fields)
+	stats.Columns = columns(schema.Fields)
+
+	if err := w.WriteSchema(schema); err != nil {
+		return stats, fmt.Errorf("writing schema: %w", err)
+	}
+
+	// Second pass: write the latest records.
+	for i, shard := range shards {
+		err := readRecords(ctx, shard, func(rec map[string]any, n int) error {
+			url, _ := lookup(rec, urlPath).(string)
+			if r := latest[CanonicalURL(url)]; r.shard != i || r.row != n {
+				return nil
+			}
+			stats.Written++
+			return w.WriteRecord(schema.coerce(rec))
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return stats, fmt.Errorf("flushing output: %w", err)
+	}
+	return stats, nil
+}
+
+// readRecords calls onRecord with each record in the JSON shard and its
+// position in the shard.
+func readRecords(ctx context.Context, shard Shard, onRecord func(map[string]any, int) error) error {
+	f, err := shard.Open(ctx)
+	if err != nil {
+		return fmt.Errorf("opening shard %s: %w", shard.Name, err)
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(bufio.NewReader(f))
+	d.UseNumber()
+	for n := 0; ; n++ {
+		var rec map[string]any
+		err := d.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading shard %s: %w", shard.Name, err)
+		}
+		if err := onRecord(rec, n); err != nil {
+			return err
+		}
+	}
+}
+
+// lookup returns the value at path in rec, or nil if there is none.
+func lookup(rec map[string]any, path []string) any {
+	if len(path) == 0 {
+		return nil
+	}
+	var v any = rec
+	for _, name := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+type multiRecordWriter []RecordWriter
+
+// MultiRecordWriter returns a RecordWriter that passes everything written to
+// each of ws, stopping at the first error.
+func MultiRecordWriter(ws ...RecordWriter) RecordWriter {
+	return multiRecordWriter(ws)
+}
+
+// WriteSchema implements the RecordWriter interface.
+func (m multiRecordWriter) WriteSchema(s *Schema) error {
+	for _, w := range m {
+		if err := w.WriteSchema(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRecord implements the RecordWriter interface.
+func (m multiRecordWriter) WriteRecord(rec map[string]any) error {
+	for _, w := range m {
+		if err := w.WriteRecord(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush implements the RecordWriter interface.
+func (m multiRecordWriter) Flush() error {
+	for _, w := range m {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
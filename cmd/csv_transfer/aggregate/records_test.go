@@ -0,0 +1,233 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
+)
+
+// recordWriter records everything written to it.
+type recordWriter struct {
+	schema  *aggregate.Schema
+	records []map[string]any
+	flushed bool
+}
+
+func (w *recordWriter) WriteSchema(s *aggregate.Schema) error {
+	w.schema = s
+	return nil
+}
+
+func (w *recordWriter) WriteRecord(rec map[string]any) error {
+	w.records = append(w.records, rec)
+	return nil
+}
+
+func (w *recordWriter) Flush() error {
+	w.flushed = true
+	return nil
+}
+
+func runAggregateRecords(t *testing.T, shards []aggregate.Shard) (*recordWriter, aggregate.Stats) {
+	t.Helper()
+	w := &recordWriter{}
+	stats, err := aggregate.AggregateRecords(context.Background(), shards, w, aggregate.Options{
+		URLColumn:  aggregate.DefaultURLColumn,
+		DateColumn: aggregate.DefaultDateColumn,
+	})
+	if err != nil {
+		t.Fatalf("AggregateRecords() = %v, want no error", err)
+	}
+	if !w.flushed {
+		t.Fatal("AggregateRecords() did not flush")
+	}
+	return w, stats
+}
+
+func TestAggregateRecordsSchema(t *testing.T) {
+	shards := testShards(
+		`{"repo":{"url":"https://github.com/a/a","stars":1,"score":1,"created":"2020-01-01T00:00:00Z"},"flag":true,"empty":null,"obj":{}}`+"\n"+
+			`{"repo":{"url":"https://github.com/b/b","stars":2,"score":1.5,"created":"2021-01-01T00:00:00Z"},"flag":"yes"}`+"\n",
+		`{"repo":{"url":"https://github.com/c/c","license":"MIT"},"default_score":0.5}`+"\n",
+	)
+	w, stats := runAggregateRecords(t, shards)
+
+	data, err := json.Marshal(w.schema)
+	if err != nil {
+		t.Fatalf("Marshal() = %v, want no error", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() = %v, want no error", err)
+	}
+	field := func(name, typ string, fields ...any) map[string]any {
+		f := map[string]any{"name": name, "type": typ, "mode": "NULLABLE"}
+		if len(fields) > 0 {
+			f["fields"] = fields
+		}
+		return f
+	}
+	want := []map[string]any{
+		field("default_score", "FLOAT"),
+		field("empty", "STRING"),
+		field("flag", "STRING"),
+		field("repo", "RECORD",
+			field("created", "TIMESTAMP"),
+			field("license", "STRING"),
+			field("score", "FLOAT"),
+			field("stars", "INTEGER"),
+			field("url", "STRING"),
+		),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("schema mismatch (-want +got):\n%s", diff)
+	}
+
+	wantRecords := []map[string]any{
+		{
+			"repo": map[string]any{
+				"url":     "https://github.com/a/a",
+				"stars":   int64(1),
+				"score":   1.0,
+				"created": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			"flag": "true",
+		},
+		{
+			"repo": map[string]any{
+				"url":     "https://github.com/b/b",
+				"stars":   int64(2),
+				"score":   1.5,
+				"created": time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			"flag": "yes",
+		},
+		{
+			"repo":          map[string]any{"url": "https://github.com/c/c", "license": "MIT"},
+			"default_score": 0.5,
+		},
+	}
+	if diff := cmp.Diff(wantRecords, w.records); diff != "" {
+		t.Fatalf("records mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(aggregate.Stats{Shards: 2, Columns: 8, Rows: 3, Written: 3}, stats); diff != "" {
+		t.Fatalf("stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAggregateRecordsDuplicates(t *testing.T) {
+	shards := testShards(
+		`{"repo":{"url":"https://github.com/a/a"},"n":1,"collection_date":"2022-01-02T00:00:00Z"}`+"\n"+
+			`{"repo":{"url":"https://github.com/b/b"},"n":2}`+"\n",
+		`{"repo":{"url":"http://www.github.com/A/a.git"},"n":3,"collection_date":"2022-01-01T00:00:00Z"}`+"\n"+
+			`{"repo":{"url":"https://github.com/b/b/"},"n":4}`+"\n",
+	)
+	w, stats := runAggregateRecords(t, shards)
+	var got []int64
+	for _, rec := range w.records {
+		got = append(got, rec["n"].(int64))
+	}
+	if diff := cmp.Diff([]int64{1, 4}, got); diff != "" {
+		t.Fatalf("records mismatch (-want +got):\n%s", diff)
+	}
+	if stats.Duplicates != 2 {
+		t.Fatalf("Duplicates = %d, want 2", stats.Duplicates)
+	}
+}
+
+func TestAggregateRecordsMissingURL(t *testing.T) {
+	shards := testShards(`{"repo":{"name":"a"}}` + "\n")
+	_, err := aggregate.AggregateRecords(context.Background(), shards, &recordWriter{}, aggregate.Options{})
+	if !errors.Is(err, aggregate.ErrorMissingColumn) {
+		t.Fatalf("AggregateRecords() = %v, want %v", err, aggregate.ErrorMissingColumn)
+	}
+}
+
+func TestJSONLinesWriter(t *testing.T) {
+	shards := testShards(
+		`{"repo":{"url":"https://github.com/a/a","stars":9007199254740993},"collection_date":"2022-01-02T03:04:05Z"}` + "\n",
+	)
+	var out bytes.Buffer
+	if _, err := aggregate.AggregateRecords(context.Background(), shards, aggregate.JSONLinesWriter(&out), aggregate.Options{}); err != nil {
+		t.Fatalf("AggregateRecords() = %v, want no error", err)
+	}
+	want := `{"collection_date":"2022-01-02T03:04:05Z","repo":{"stars":9007199254740993,"url":"https://github.com/a/a"}}` + "\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Fatalf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	shards := testShards(
+		`{"repo":{"url":"https://github.com/a/a","stars":1},"default_score":0.5}` + "\n" +
+			`{"repo":{"url":"https://github.com/b/b"},"default_score":null}` + "\n",
+	)
+	var out bytes.Buffer
+	stats, err := aggregate.AggregateRecords(context.Background(), shards, aggregate.ParquetWriter(&out), aggregate.Options{})
+	if err != nil {
+		t.Fatalf("AggregateRecords() = %v, want no error", err)
+	}
+	if stats.Written != 2 {
+		t.Fatalf("Written = %d, want 2", stats.Written)
+	}
+	data := out.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("output is not a Parquet file")
+	}
+}
+
+func TestMultiRecordWriter(t *testing.T) {
+	shards := testShards(`{"repo":{"url":"https://github.com/a/a"}}` + "\n")
+	a, b := &recordWriter{}, &recordWriter{}
+	if _, err := aggregate.AggregateRecords(context.Background(), shards, aggregate.MultiRecordWriter(a, b), aggregate.Options{}); err != nil {
+		t.Fatalf("AggregateRecords() = %v, want no error", err)
+	}
+	if diff := cmp.Diff(a.records, b.records); diff != "" || len(a.records) != 1 || !a.flushed || !b.flushed {
+		t.Fatalf("writers received different output:\n%s", diff)
+	}
+}
+
+func TestSchemaWriter(t *testing.T) {
+	shards := testShards(`{"repo":{"url":"https://github.com/a/a"}}` + "\n")
+	var out bytes.Buffer
+	if _, err := aggregate.AggregateRecords(context.Background(), shards, aggregate.SchemaWriter(&out), aggregate.Options{}); err != nil {
+		t.Fatalf("AggregateRecords() = %v, want no error", err)
+	}
+	var got aggregate.Schema
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal() = %v, want no error", err)
+	}
+	want := aggregate.Schema{Fields: []*aggregate.Field{{
+		Name: "repo",
+		Type: aggregate.TypeRecord,
+		Mode: "NULLABLE",
+		Fields: []*aggregate.Field{
+			{Name: "url", Type: aggregate.TypeString, Mode: "NULLABLE"},
+		},
+	}}}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(aggregate.Field{})); diff != "" {
+		t.Fatalf("schema mismatch (-want +got):\n%s", diff)
+	}
+}
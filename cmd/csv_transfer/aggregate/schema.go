@@ -0,0 +1,295 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FieldType is the type of a field in a record. The names match the types
+// used in BigQuery schemas.
+type FieldType string
+
+const (
+	TypeBoolean   FieldType = "BOOLEAN"
+	TypeInteger   FieldType = "INTEGER"
+	TypeFloat     FieldType = "FLOAT"
+	TypeString    FieldType = "STRING"
+	TypeTimestamp FieldType = "TIMESTAMP"
+	TypeRecord    FieldType = "RECORD"
+)
+
+// modeNullable is the only mode used, as any field may be missing.
+const modeNullable = "NULLABLE"
+
+// Field describes a field in a record, and matches the format of a field in
+// a BigQuery JSON schema file.
+type Field struct {
+	Name   string    `json:"name"`
+	Type   FieldType `json:"type"`
+	Mode   string    `json:"mode"`
+	Fields []*Field  `json:"fields,omitempty"`
+
+	// known is false until a value that is not null has been seen.
+	known bool
+}
+
+// Schema describes the records in the JSON shards. It is inferred from the
+// records read from the shards.
+//
+// A Schema is marshalled as a BigQuery JSON schema.
+type Schema struct {
+	Fields []*Field
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.Fields == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s.Fields)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.Fields)
+}
+
+// observe updates the schema with the fields in rec.
+//
+// Values decoded with json.Decoder.UseNumber are needed to tell integers from
+// floats. Strings holding an RFC 3339 time are timestamps. When the values of
+// a field have different types the field is widened, first to a FLOAT if
+// they are all numbers, and otherwise to a STRING.
+func (s *Schema) observe(rec map[string]any) {
+	s.Fields = observe(s.Fields, rec)
+}
+
+func observe(fields []*Field, rec map[string]any) []*Field {
+	for name, v := range rec {
+		f := findField(fields, name)
+		if f == nil {
+			f = &Field{Name: name, Mode: modeNullable}
+			fields = append(fields, f)
+		}
+		f.observe(v)
+	}
+	return fields
+}
+
+func findField(fields []*Field, name string) *Field {
+	for _, f := range fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (f *Field) observe(v any) {
+	t := typeOf(v)
+	if t == "" {
+		return
+	}
+	switch {
+	case !f.known:
+		f.Type = t
+		f.known = true
+	case f.Type == t:
+	case isNumber(f.Type) && isNumber(t):
+		f.Type = TypeFloat
+	default:
+		f.Type = TypeString
+	}
+	if m, ok := v.(map[string]any); ok && f.Type == TypeRecord {
+		f.Fields = observe(f.Fields, m)
+	}
+	if f.Type != TypeRecord {
+		f.Fields = nil
+	}
+}
+
+func isNumber(t FieldType) bool {
+	return t == TypeInteger || t == TypeFloat
+}
+
+// typeOf returns the type of a value decoded from JSON, or an empty string if
+// v is nil.
+func typeOf(v any) FieldType {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		return TypeBoolean
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return TypeInteger
+		}
+		return TypeFloat
+	case float64:
+		return TypeFloat
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return TypeTimestamp
+		}
+		return TypeString
+	case map[string]any:
+		return TypeRecord
+	default:
+		return TypeString
+	}
+}
+
+// finish sorts the fields by name, and sets the type of any field that was
+// only ever null to a STRING. Records without any fields are removed, as
+// they can not be represented in a schema.
+func finish(fields []*Field) []*Field {
+	var out []*Field
+	for _, f := range fields {
+		if !f.known {
+			f.Type = TypeString
+		}
+		if f.Type == TypeRecord {
+			if f.Fields = finish(f.Fields); len(f.Fields) == 0 {
+				continue
+			}
+		}
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// columns returns the number of fields in the schema that are not records.
+func columns(fields []*Field) int {
+	n := 0
+	for _, f := range fields {
+		if f.Type == TypeRecord {
+			n += columns(f.Fields)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// coerce returns a copy of rec with each value converted to the type of its
+// field in the schema. Values are converted to bool, int64, float64, string,
+// time.Time or a map[string]any for records. Fields not in the schema, and
+// null values, are left out.
+func (s *Schema) coerce(rec map[string]any) map[string]any {
+	return coerce(s.Fields, rec)
+}
+
+func coerce(fields []*Field, rec map[string]any) map[string]any {
+	out := make(map[string]any)
+	for _, f := range fields {
+		v, ok := rec[f.Name]
+		if !ok || v == nil {
+			continue
+		}
+		if c := f.coerce(v); c != nil {
+			out[f.Name] = c
+		}
+	}
+	return out
+}
+
+func (f *Field) coerce(v any) any {
+	switch f.Type {
+	case TypeBoolean:
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case TypeInteger:
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i
+			}
+		}
+	case TypeFloat:
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Float64(); err == nil {
+				return n
+			}
+		case float64:
+			return v
+		}
+	case TypeTimestamp:
+		if s, ok := v.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t
+			}
+		}
+	case TypeRecord:
+		if m, ok := v.(map[string]any); ok {
+			return coerce(f.Fields, m)
+		}
+	case TypeString:
+		switch v := v.(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		case bool:
+			return strconv.FormatBool(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Sprint(v)
+			}
+			return string(data)
+		}
+	}
+	// The value does not match the schema, so it is treated as null.
+	return nil
+}
+
+// schemaWriter implements RecordWriter by writing only the schema.
+type schemaWriter struct {
+	w io.Writer
+}
+
+// SchemaWriter returns a RecordWriter that writes the schema to w as a
+// BigQuery JSON schema file, and ignores the records.
+func SchemaWriter(w io.Writer) RecordWriter {
+	return &schemaWriter{w: w}
+}
+
+// WriteSchema implements the RecordWriter interface.
+func (w *schemaWriter) WriteSchema(s *Schema) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteRecord implements the RecordWriter interface.
+func (w *schemaWriter) WriteRecord(map[string]any) error {
+	return nil
+}
+
+// Flush implements the RecordWriter interface.
+func (w *schemaWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// DefaultChunkRows is the default number of rows a Sorter holds in memory
+// before writing them to a temporary file.
+const DefaultChunkRows = 100000
+
+// Sorter sorts rows by a score column in descending order, using an external
+// merge sort so that the rows do not need to fit in memory.
+//
+// Rows are held in memory until there are ChunkRows of them, at which point
+// they are sorted and written to a temporary file. Merge then combines the
+// sorted files. Rows with equal scores keep the order they were added in, and
+// rows with a missing or invalid score are sorted last.
+type Sorter struct {
+	column    int
+	chunkRows int
+	dir       string
+	rows      []sortRow
+	files     []*os.File
+}
+
+type sortRow struct {
+	row   []string
+	score float64
+}
+
+// NewSorter returns a Sorter for rows whose score is in column. Temporary
+// files are created in dir, or the default temporary directory if dir is
+// empty.
+func NewSorter(column, chunkRows int, dir string) *Sorter {
+	if chunkRows <= 0 {
+		chunkRows = DefaultChunkRows
+	}
+	return &Sorter{
+		column:    column,
+		chunkRows: chunkRows,
+		dir:       dir,
+	}
+}
+
+// Add adds row to the Sorter.
+func (s *Sorter) Add(row []string) error {
+	s.rows = append(s.rows, sortRow{row: row, score: s.score(row)})
+	if len(s.rows) >= s.chunkRows {
+		return s.spill()
+	}
+	return nil
+}
+
+func (s *Sorter) score(row []string) float64 {
+	if s.column >= len(row) {
+		return math.Inf(-1)
+	}
+	f, err := strconv.ParseFloat(row[s.column], 64)
+	if err != nil || math.IsNaN(f) {
+		return math.Inf(-1)
+	}
+	return f
+}
+
+func (s *Sorter) sortRows() {
+	sort.SliceStable(s.rows, func(i, j int) bool {
+		return s.rows[i].score > s.rows[j].score
+	})
+}
+
+// spill sorts the rows in memory and writes them to a temporary file.
+func (s *Sorter) spill() error {
+	s.sortRows()
+	f, err := os.CreateTemp(s.dir, "csv_transfer_sort_*.csv")
+	if err != nil {
+		return fmt.Errorf("creating sort chunk: %w", err)
+	}
+	s.files = append(s.files, f)
+	w := csv.NewWriter(f)
+	for _, r := range s.rows {
+		if err := w.Write(r.row); err != nil {
+			return fmt.Errorf("writing sort chunk: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("writing sort chunk: %w", err)
+	}
+	s.rows = s.rows[:0]
+	return nil
+}
+
+// Merge calls emit with each row that was added, in sorted order.
+func (s *Sorter) Merge(emit func(row []string) error) error {
+	if len(s.files) == 0 {
+		s.sortRows()
+		for _, r := range s.rows {
+			if err := emit(r.row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(s.rows) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+
+	h := &mergeHeap{}
+	for i, f := range s.files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("reading sort chunk: %w", err)
+		}
+		c := &chunk{r: csv.NewReader(f), order: i}
+		c.r.FieldsPerRecord = -1
+		if ok, err := c.next(s); err != nil {
+			return err
+		} else if ok {
+			heap.Push(h, c)
+		}
+	}
+	for h.Len() > 0 {
+		c := (*h)[0]
+		if err := emit(c.head.row); err != nil {
+			return err
+		}
+		ok, err := c.next(s)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// Close removes any temporary files.
+func (s *Sorter) Close() error {
+	var err error
+	for _, f := range s.files {
+		f.Close()
+		if e := os.Remove(f.Name()); e != nil && err == nil {
+			err = e
+		}
+	}
+	s.files = nil
+	return err
+}
+
+// chunk is a sorted temporary file being merged.
+type chunk struct {
+	r     *csv.Reader
+	head  sortRow
+	order int
+}
+
+// next reads the next row of the chunk into head, returning false if there
+// are no more rows.
+func (c *chunk) next(s *Sorter) (bool, error) {
+	row, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading sort chunk: %w", err)
+	}
+	c.head = sortRow{row: row, score: s.score(row)}
+	return true, nil
+}
+
+// mergeHeap implements heap.Interface, ordering chunks by the score of their
+// next row.
+type mergeHeap []*chunk
+
+// Len implements the heap.Interface interface.
+func (h mergeHeap) Len() int { return len(h) }
+
+// Less implements the heap.Interface interface.
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].head.score != h[j].head.score {
+		return h[i].head.score > h[j].head.score
+	}
+	// Earlier chunks contain rows that were added first.
+	return h[i].order < h[j].order
+}
+
+// Swap implements the heap.Interface interface.
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Push implements the heap.Interface interface.
+func (h *mergeHeap) Push(x any) { *h = append(*h, x.(*chunk)) }
+
+// Pop implements the heap.Interface interface.
+func (h *mergeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return c
+}
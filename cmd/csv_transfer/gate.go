@@ -0,0 +1,216 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"gocloud.dev/blob"
+
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
+	"github.com/ossf/criticality_score/cmd/csv_transfer/quality"
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+	"github.com/ossf/criticality_score/internal/infile"
+)
+
+const (
+	// reportSuffix is appended to the key of a quarantined aggregate for the
+	// report explaining why it was quarantined.
+	reportSuffix = ".report.json"
+
+	// qualitySuffix is appended to the key of a published aggregate for the
+	// report of the checks it passed.
+	qualitySuffix = ".quality.json"
+)
+
+var (
+	errQuarantined        = errors.New("aggregate quarantined")
+	errAlreadyQuarantined = errors.New("aggregate already quarantined")
+)
+
+// qualityGate checks each aggregate before it is published. Aggregates are
+// first written to the quarantine bucket, and are only copied to the
+// destination bucket if they pass the checks.
+type qualityGate struct {
+	config              *quality.Config
+	quarantineBucketURL string
+}
+
+// publish aggregates shards into key in the destination bucket if the
+// aggregate passes the quality checks.
+//
+// If the checks fail the aggregate is left in the quarantine bucket, a report
+// is written beside it, and errQuarantined is returned. If a report already
+// exists for key, errAlreadyQuarantined is returned.
+func (g *qualityGate) publish(ctx context.Context, logger *zap.Logger, shards []aggregate.Shard, destBucketURL, key string, opts aggregate.Options) (aggregate.Stats, error) {
+	q, err := cloudstorage.OpenBucket(ctx, g.quarantineBucketURL)
+	if err != nil {
+		return aggregate.Stats{}, fmt.Errorf("opening quarantine bucket: %w", err)
+	}
+	defer q.Close()
+
+	if exists, err := q.Exists(ctx, key+reportSuffix); err != nil {
+		return aggregate.Stats{}, fmt.Errorf("checking for quarantine report: %w", err)
+	} else if exists {
+		return aggregate.Stats{}, errAlreadyQuarantined
+	}
+
+	dest, err := blob.OpenBucket(ctx, destBucketURL)
+	if err != nil {
+		return aggregate.Stats{}, fmt.Errorf("opening destination bucket: %w", err)
+	}
+	defer dest.Close()
+
+	// Stage the aggregate in the quarantine bucket while profiling it.
+	var profiler *quality.Profiler
+	stats, err := writeBlob(ctx, q, key, func(w io.Writer) (aggregate.Stats, error) {
+		profiler = quality.NewProfiler(g.config, aggregate.CSVWriter(w))
+		return aggregate.Aggregate(ctx, shards, profiler, opts)
+	})
+	if err != nil {
+		return aggregate.Stats{}, fmt.Errorf("aggregating shards: %w", err)
+	}
+
+	previous, err := previousReport(ctx, dest, key)
+	if err != nil {
+		return aggregate.Stats{}, fmt.Errorf("reading previous quality report: %w", err)
+	}
+	var prevProfile *quality.Profile
+	if previous != nil {
+		prevProfile = previous.Profile
+	}
+	report := quality.Check(g.config, profiler.Profile(stats), prevProfile)
+
+	if !report.Passed {
+		if err := writeJSON(ctx, q, key+reportSuffix, report); err != nil {
+			return aggregate.Stats{}, fmt.Errorf("writing quarantine report: %w", err)
+		}
+		for _, res := range report.Failures() {
+			logger.With(
+				zap.String("check", res.Check),
+				zap.String("message", res.Message),
+			).Warn("Quality check failed")
+		}
+		return stats, fmt.Errorf("%w: %d checks failed", errQuarantined, len(report.Failures()))
+	}
+
+	// Copy the staged aggregate to the destination, then write the report
+	// beside it so it can be compared against the next aggregate.
+	if _, err := writeBlob(ctx, dest, key, func(w io.Writer) (aggregate.Stats, error) {
+		r, err := q.NewReader(ctx, key, nil)
+		if err != nil {
+			return aggregate.Stats{}, err
+		}
+		defer r.Close()
+		_, err = io.Copy(w, r)
+		return aggregate.Stats{}, err
+	}); err != nil {
+		return aggregate.Stats{}, fmt.Errorf("publishing aggregate: %w", err)
+	}
+	if err := writeJSON(ctx, dest, key+qualitySuffix, report); err != nil {
+		return aggregate.Stats{}, fmt.Errorf("writing quality report: %w", err)
+	}
+	if err := q.Delete(ctx, key); err != nil {
+		// The aggregate has been published, so only log the failure.
+		logger.With(zap.Error(err)).Warn("Failed to delete staged aggregate")
+	}
+	return stats, nil
+}
+
+// writeBlob calls fn to write the blob key in b. Cancelling the context
+// before closing the writer aborts the write, so a partial blob is never
+// stored if fn fails.
+func writeBlob(ctx context.Context, b *blob.Bucket, key string, fn func(io.Writer) (aggregate.Stats, error)) (aggregate.Stats, error) {
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w, err := b.NewWriter(writeCtx, key, nil)
+	if err != nil {
+		return aggregate.Stats{}, err
+	}
+	stats, err := fn(w)
+	if err != nil {
+		cancel()
+		w.Close()
+		return aggregate.Stats{}, err
+	}
+	return stats, w.Close()
+}
+
+func writeJSON(ctx context.Context, b *blob.Bucket, key string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.WriteAll(ctx, key, data, &blob.WriterOptions{ContentType: "application/json"})
+}
+
+// previousReport returns the quality report of the latest aggregate published
+// before key, or nil if there is none.
+//
+// Keys start with the creation time of the shards, so sorting them orders the
+// aggregates by time.
+func previousReport(ctx context.Context, b *blob.Bucket, key string) (*quality.Report, error) {
+	suffix := "/" + path.Base(key) + qualitySuffix
+	var keys []string
+	iter := b.List(nil)
+	for {
+		obj, err := iter.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(obj.Key, suffix) && obj.Key < key {
+			keys = append(keys, obj.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Strings(keys)
+	data, err := b.ReadAll(ctx, keys[len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	r := &quality.Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", keys[len(keys)-1], err)
+	}
+	return r, nil
+}
+
+func loadQualityGate(configPath, quarantineBucketURL string) (*qualityGate, error) {
+	if quarantineBucketURL == "" {
+		return nil, errors.New("quarantine bucket url must be set")
+	}
+	f, err := infile.Open(context.Background(), configPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := quality.LoadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", configPath, err)
+	}
+	return &qualityGate{config: c, quarantineBucketURL: quarantineBucketURL}, nil
+}
@@ -15,25 +15,28 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ossf/scorecard/v4/cron/config"
 	"github.com/ossf/scorecard/v4/cron/data"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"golang.org/x/exp/slices"
+	"gocloud.dev/blob"
 
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
 	"github.com/ossf/criticality_score/internal/log"
 )
 
 const defaultLogLevel = zapcore.InfoLevel
 
-func processShardSet(ctx context.Context, logger *zap.Logger, summary *data.ShardSummary, srcBucket, destBucket, destFilename string, threshold float64) (int, error) {
+func processShardSet(ctx context.Context, logger *zap.Logger, summary *data.ShardSummary, srcBucket, destBucket, destFilename string, threshold float64, opts aggregate.Options, gate *qualityGate, records *recordTransfer) (int, error) {
 	logger = logger.With(zap.Time("creation_time", summary.CreationTime()))
 	if summary.IsTransferred() || !summary.IsCompleted(threshold) {
 		logger.With(
@@ -45,31 +48,74 @@ func processShardSet(ctx context.Context, logger *zap.Logger, summary *data.Shar
 
 	logger.Info("Transferring...")
 
-	// Scan the prefix for all the keys with a given prefix.
-	// This will also include the
-	keys, err := data.GetBlobKeysWithPrefix(ctx, srcBucket, data.GetBlobFilename("", summary.CreationTime()))
+	src, shards, err := openShards(ctx, logger, srcBucket, summary.CreationTime())
 	if err != nil {
-		return 0, fmt.Errorf("fetching blob keys by prefix: %w", err)
+		return 0, err
+	}
+	defer src.Close()
+
+	key := data.GetBlobFilename(destFilename, summary.CreationTime())
+	var stats aggregate.Stats
+	if gate != nil {
+		stats, err = gate.publish(ctx, logger, shards, destBucket, key, opts)
+		if errors.Is(err, errAlreadyQuarantined) {
+			logger.Info("Skipping quarantined aggregate")
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+	} else {
+		stats, err = transfer(ctx, shards, destBucket, key, opts)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	var out bytes.Buffer
-	w := csv.NewWriter(&out)
+	// Aggregate the JSON shards once the CSV aggregate has been published.
+	if records != nil {
+		if err := records.transfer(ctx, logger, summary.CreationTime(), len(shards), destBucket, opts); err != nil {
+			return 0, fmt.Errorf("transferring records: %w", err)
+		}
+	}
 
-	// Stores the first header row we encounter. It is used to generate the
-	// header row for the aggregate CSV, and to validate all the CSV files
-	// have the same columns.
-	var firstHeader []string
+	// Mark the summary as completed so it doesn't get reprocessed again.
+	if err := summary.MarkTransferred(ctx, srcBucket); err != nil {
+		return 0, fmt.Errorf("marking shards as transferred: %w", err)
+	}
 
-	// Store the total number of records processed.
-	var total int
+	logger.With(
+		zap.Int("total_shards", stats.Shards),
+		zap.Int("total_columns", stats.Columns),
+		zap.Int("total_rows", stats.Rows),
+		zap.Int("duplicate_rows", stats.Duplicates),
+		zap.Int("total_records", stats.Written),
+	).Info("Transfer complete")
+	return stats.Written, nil
+}
 
-	// Iterate through keys.
+// openShards opens the bucket at bucketURL and returns the CSV or JSON shards
+// created at t.
+func openShards(ctx context.Context, logger *zap.Logger, bucketURL string, t time.Time) (*blob.Bucket, []aggregate.Shard, error) {
+	// Scan the prefix for all the keys with a given prefix.
+	// This will also include the
+	keys, err := data.GetBlobKeysWithPrefix(ctx, bucketURL, data.GetBlobFilename("", t))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching blob keys by prefix: %w", err)
+	}
+
+	src, err := blob.OpenBucket(ctx, bucketURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening source bucket: %w", err)
+	}
+
+	var shards []aggregate.Shard
 	for _, key := range keys {
 		keyLogger := logger.With(zap.String("blob_key", key))
 
 		_, filename, err := data.ParseBlobFilename(key)
 		if err != nil {
-			return 0, fmt.Errorf("parsing key %s into blob filename: %w", key, err)
+			src.Close()
+			return nil, nil, fmt.Errorf("parsing key %s into blob filename: %w", key, err)
 		}
 
 		// Filter out any keys that don't point to the shard files containing the data.
@@ -78,59 +124,33 @@ func processShardSet(ctx context.Context, logger *zap.Logger, summary *data.Shar
 			continue
 		}
 
-		keyLogger.Debug("Processing key")
-
-		// Grab the shard CSV data.
-		content, err := data.GetBlobContent(ctx, srcBucket, key)
-		if err != nil {
-			return 0, fmt.Errorf("fetching blob content for key %s: %w", key, err)
-		}
-
-		// Wrap the data in a buffer and CSV Reader to make it easy to consume.
-		b := bytes.NewBuffer(content)
-		r := csv.NewReader(b)
-
-		header, err := r.Read()
-		if err != nil {
-			return 0, fmt.Errorf("reading header row for key %s: %w", key, err)
-		}
-
-		// Ensure the CSV headers are consistent.
-		if firstHeader == nil {
-			firstHeader = header
-			if err := w.Write(header); err != nil {
-				return 0, fmt.Errorf("writing header row from %s: %w", key, err)
-			}
-		}
-
-		// Ensure the headers are consistent to avoid columns not matching.
-		if !slices.Equal(firstHeader, header) {
-			return 0, fmt.Errorf("key %s header does not match first header", key)
-		}
-
-		records, err := r.ReadAll()
-		if err != nil {
-			return 0, fmt.Errorf("reading all records for key %s: %w", key, err)
-		}
-		total += len(records)
-
-		if err := w.WriteAll(records); err != nil {
-			return 0, fmt.Errorf("writing all records for key %s: %w", key, err)
-		}
+		key := key
+		shards = append(shards, aggregate.Shard{
+			Name: key,
+			Open: func(ctx context.Context) (io.ReadCloser, error) {
+				return src.NewReader(ctx, key, nil)
+			},
+		})
 	}
+	return src, shards, nil
+}
 
-	// Store the aggregated CSV file.
-	if err := data.WriteToBlobStore(ctx, destBucket, data.GetBlobFilename(destFilename, summary.CreationTime()), out.Bytes()); err != nil {
-		return 0, fmt.Errorf("writing aggregate csv: %w", err)
+// transfer streams the aggregate of shards directly to key in the destination
+// bucket.
+func transfer(ctx context.Context, shards []aggregate.Shard, destBucket, key string, opts aggregate.Options) (aggregate.Stats, error) {
+	dest, err := blob.OpenBucket(ctx, destBucket)
+	if err != nil {
+		return aggregate.Stats{}, fmt.Errorf("opening destination bucket: %w", err)
 	}
+	defer dest.Close()
 
-	// Mark the summary as completed so it doesn't get reprocessed again.
-	if err := summary.MarkTransferred(ctx, srcBucket); err != nil {
-		return 0, fmt.Errorf("marking shards as transferred: %w", err)
+	stats, err := writeBlob(ctx, dest, key, func(w io.Writer) (aggregate.Stats, error) {
+		return aggregate.Aggregate(ctx, shards, aggregate.CSVWriter(w), opts)
+	})
+	if err != nil {
+		return aggregate.Stats{}, fmt.Errorf("aggregating shards: %w", err)
 	}
-
-	logger.With(zap.Int("total_records", total)).Info("Transfer complete")
-	return total, nil
+	return stats, nil
 }
 
 func main() {
@@ -176,6 +196,42 @@ func main() {
 		logger.Fatal("Failed to get CSV transfer filename")
 	}
 
+	// Sorting is optional. The column is usually the score column.
+	opts := aggregate.Options{
+		URLColumn:  aggregate.DefaultURLColumn,
+		DateColumn: aggregate.DefaultDateColumn,
+		SortColumn: criticalityConfig["csv-transfer-sort-column"],
+		SortDir:    criticalityConfig["csv-transfer-sort-dir"],
+	}
+	if v := criticalityConfig["csv-transfer-sort-chunk-rows"]; v != "" {
+		opts.SortChunkRows, err = strconv.Atoi(v)
+		if err != nil {
+			logger.With(zap.Error(err)).Fatal("Failed to get CSV transfer sort chunk rows")
+		}
+	}
+
+	// Quality checks are optional. When they are configured, aggregates are
+	// staged in the quarantine bucket and only published if they pass.
+	var gate *qualityGate
+	if path := criticalityConfig["csv-transfer-quality-config"]; path != "" {
+		gate, err = loadQualityGate(path, criticalityConfig["csv-transfer-quarantine-bucket-url"])
+		if err != nil {
+			logger.With(zap.Error(err)).Fatal("Failed to load CSV transfer quality config")
+		}
+	}
+
+	// JSON Lines and Parquet aggregates of the JSON shards are optional.
+	records := &recordTransfer{
+		jsonlFilename:   criticalityConfig["csv-transfer-jsonl-filename"],
+		parquetFilename: criticalityConfig["csv-transfer-parquet-filename"],
+	}
+	if records.jsonlFilename == "" && records.parquetFilename == "" {
+		records = nil
+	} else if records.srcBucket, err = config.GetResultDataBucketURL(); err != nil {
+		// Fatal exits.
+		logger.With(zap.Error(err)).Fatal("Failed to get result data bucket URL")
+	}
+
 	logger = logger.With(
 		zap.String("src_bucket", srcBucketURL),
 		zap.String("dest_bucket", destBucketURL),
@@ -199,7 +255,7 @@ func main() {
 	logger.With(zap.Int("number_shard_sets", numShardSets)).Info("Found shards")
 
 	for _, summary := range shards {
-		if n, err := processShardSet(ctx, logger, summary, srcBucketURL, destBucketURL, destFilename, completionThreshold); err != nil {
+		if n, err := processShardSet(ctx, logger, summary, srcBucketURL, destBucketURL, destFilename, completionThreshold, opts, gate, records); err != nil {
 			// Show an error, but continue processing.
 			logger.With(
 				zap.Error(err),
@@ -0,0 +1,136 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Check   string `json:"check"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of all the checks run against a dataset.
+type Report struct {
+	Passed  bool     `json:"passed"`
+	Results []Result `json:"results"`
+	Profile *Profile `json:"profile"`
+
+	// Previous is the profile of the previous dataset, if one was used to
+	// check for drift.
+	Previous *Profile `json:"previous,omitempty"`
+}
+
+// Failures returns the results of the checks that failed.
+func (r *Report) Failures() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if !res.Passed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+func (r *Report) add(check string, passed bool, format string, args ...any) {
+	r.Results = append(r.Results, Result{
+		Check:   check,
+		Passed:  passed,
+		Message: fmt.Sprintf(format, args...),
+	})
+	if !passed {
+		r.Passed = false
+	}
+}
+
+// Check runs the checks in c against the profile p of a dataset.
+//
+// previous is the profile of the last dataset that was published, and is used
+// to detect drift in the scores. If it is nil the drift check passes.
+func Check(c *Config, p, previous *Profile) *Report {
+	r := &Report{Passed: true, Profile: p, Previous: previous}
+
+	if c.MinRows > 0 {
+		r.add("min_rows", p.Rows >= c.MinRows, "%d rows, want at least %d", p.Rows, c.MinRows)
+	}
+
+	for _, column := range sortedKeys(c.FillRates) {
+		want := c.FillRates[column]
+		got := rate(p.Filled[column], p.Rows)
+		r.add("fill_rate:"+column, got >= want, "%.4f of rows filled, want at least %.4f", got, want)
+	}
+
+	if c.MaxDuplicateRate != nil {
+		got := rate(p.Duplicates, p.InputRows)
+		r.add("duplicate_rate", got <= *c.MaxDuplicateRate, "%d of %d input rows were duplicates (%.4f), want at most %.4f", p.Duplicates, p.InputRows, got, *c.MaxDuplicateRate)
+	}
+
+	for _, column := range sortedKeys(c.Ranges) {
+		n := p.OutOfRange[column]
+		r.add("range:"+column, n == 0, "%d values out of range", n)
+	}
+
+	if c.Drift != nil {
+		checkDrift(r, c.Drift, p.Scores, previous)
+	}
+	return r
+}
+
+func checkDrift(r *Report, d *Drift, cur *Summary, previous *Profile) {
+	name := "drift:" + d.Column
+	switch {
+	case previous == nil || previous.Scores == nil || previous.Scores.Count == 0:
+		r.add(name, true, "no previous scores to compare against")
+		return
+	case previous.Scores.Column != d.Column:
+		r.add(name, true, "previous scores are for %s", previous.Scores.Column)
+		return
+	case cur == nil || cur.Count == 0:
+		r.add(name, false, "no scores found")
+		return
+	}
+	prev := previous.Scores
+	if d.MaxMeanChange > 0 {
+		change := math.Abs(cur.Mean - prev.Mean)
+		r.add(name+":mean", change <= d.MaxMeanChange, "mean changed from %.4f to %.4f, want a change of at most %.4f", prev.Mean, cur.Mean, d.MaxMeanChange)
+	}
+	if d.MaxQuantileChange > 0 {
+		for _, q := range sortedKeys(quantiles) {
+			change := math.Abs(cur.Quantiles[q] - prev.Quantiles[q])
+			r.add(name+":"+q, change <= d.MaxQuantileChange, "%s changed from %.4f to %.4f, want a change of at most %.4f", q, prev.Quantiles[q], cur.Quantiles[q], d.MaxQuantileChange)
+		}
+	}
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
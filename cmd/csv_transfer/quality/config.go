@@ -0,0 +1,94 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quality checks an aggregated dataset before it is published.
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Range is the range of values allowed in a column. Either bound may be
+// omitted.
+type Range struct {
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// contains returns true if v is within r.
+func (r Range) contains(v float64) bool {
+	return (r.Min == nil || v >= *r.Min) && (r.Max == nil || v <= *r.Max)
+}
+
+// Drift limits how much the distribution of a score column may change from
+// the previous aggregate.
+type Drift struct {
+	Column string `yaml:"column"`
+
+	// MaxMeanChange is the largest allowed absolute change in the mean.
+	MaxMeanChange float64 `yaml:"max_mean_change"`
+
+	// MaxQuantileChange is the largest allowed absolute change in each of
+	// the quantiles.
+	MaxQuantileChange float64 `yaml:"max_quantile_change"`
+}
+
+// Config is used to specify the checks that an aggregated dataset must pass.
+// Checks that are not set are skipped.
+//
+// This structure is used for parsing a YAML file.
+type Config struct {
+	// MinRows is the minimum number of rows.
+	MinRows int `yaml:"min_rows"`
+
+	// FillRates is the minimum fraction of rows that must have a value for
+	// each column.
+	FillRates map[string]float64 `yaml:"fill_rates"`
+
+	// MaxDuplicateRate is the maximum fraction of the rows read from the
+	// shards that may be duplicates of another repository.
+	MaxDuplicateRate *float64 `yaml:"max_duplicate_rate"`
+
+	// Ranges are the values allowed in each column. Values that are not
+	// numbers are also out of range.
+	Ranges map[string]Range `yaml:"ranges"`
+
+	Drift *Drift `yaml:"drift"`
+}
+
+// LoadConfig will parse the YAML data from the reader and return a Config.
+func LoadConfig(r io.Reader) (*Config, error) {
+	c := &Config{}
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+	if err := d.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for column, rate := range c.FillRates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("fill rate for %s must be between 0 and 1", column)
+		}
+	}
+	if c.MaxDuplicateRate != nil && (*c.MaxDuplicateRate < 0 || *c.MaxDuplicateRate > 1) {
+		return nil, errors.New("max_duplicate_rate must be between 0 and 1")
+	}
+	if c.Drift != nil && c.Drift.Column == "" {
+		return nil, errors.New("drift column must be set")
+	}
+	return c, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quality
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
+)
+
+// quantiles are the quantiles recorded in a Summary, keyed by name.
+var quantiles = map[string]float64{
+	"p10": 0.10,
+	"p50": 0.50,
+	"p90": 0.90,
+	"p99": 0.99,
+}
+
+// Summary describes the distribution of the values in a column.
+type Summary struct {
+	Column    string             `json:"column"`
+	Count     int                `json:"count"`
+	Mean      float64            `json:"mean"`
+	Quantiles map[string]float64 `json:"quantiles"`
+}
+
+// Profile holds the measurements of a dataset used by the checks.
+type Profile struct {
+	// InputRows is the number of rows read from the shards, and Duplicates
+	// is the number of those that were duplicates.
+	InputRows  int `json:"input_rows"`
+	Duplicates int `json:"duplicates"`
+
+	// Rows is the number of rows in the dataset.
+	Rows int `json:"rows"`
+
+	// Filled is the number of rows with a value for each column.
+	Filled map[string]int `json:"filled"`
+
+	// OutOfRange is the number of values outside the configured Range for
+	// each column.
+	OutOfRange map[string]int `json:"out_of_range,omitempty"`
+
+	// Scores summarizes the drift column, if one is configured.
+	Scores *Summary `json:"scores,omitempty"`
+}
+
+// Profiler implements aggregate.Writer, measuring each row as it is passed
+// to the wrapped Writer.
+type Profiler struct {
+	w       aggregate.Writer
+	c       *Config
+	profile Profile
+	columns []string
+	ranges  map[int]Range
+	score   int
+	scores  []float64
+}
+
+// NewProfiler returns a Profiler that measures the rows written to w, for the
+// checks in c.
+func NewProfiler(c *Config, w aggregate.Writer) *Profiler {
+	return &Profiler{
+		w: w,
+		c: c,
+		profile: Profile{
+			Filled:     make(map[string]int),
+			OutOfRange: make(map[string]int),
+		},
+		score: -1,
+	}
+}
+
+// WriteHeader implements the aggregate.Writer interface.
+func (p *Profiler) WriteHeader(columns []string) error {
+	p.columns = columns
+	p.ranges = make(map[int]Range)
+	for i, name := range columns {
+		if r, ok := p.c.Ranges[name]; ok {
+			p.ranges[i] = r
+		}
+		if p.c.Drift != nil && p.c.Drift.Column == name {
+			p.score = i
+		}
+	}
+	return p.w.WriteHeader(columns)
+}
+
+// WriteRow implements the aggregate.Writer interface.
+func (p *Profiler) WriteRow(row []string) error {
+	p.profile.Rows++
+	for i, v := range row {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		p.profile.Filled[p.columns[i]]++
+		if r, ok := p.ranges[i]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err != nil || !r.contains(f) {
+				p.profile.OutOfRange[p.columns[i]]++
+			}
+		}
+		if i == p.score {
+			if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(f) {
+				p.scores = append(p.scores, f)
+			}
+		}
+	}
+	return p.w.WriteRow(row)
+}
+
+// Flush implements the aggregate.Writer interface.
+func (p *Profiler) Flush() error {
+	return p.w.Flush()
+}
+
+// Profile returns the measurements of the rows written, along with the
+// number of input rows and duplicates reported by aggregate.Aggregate.
+func (p *Profiler) Profile(stats aggregate.Stats) *Profile {
+	profile := p.profile
+	profile.InputRows = stats.Rows
+	profile.Duplicates = stats.Duplicates
+	if p.c.Drift != nil {
+		profile.Scores = summarize(p.c.Drift.Column, p.scores)
+	}
+	return &profile
+}
+
+func summarize(column string, values []float64) *Summary {
+	s := &Summary{
+		Column:    column,
+		Count:     len(values),
+		Quantiles: make(map[string]float64),
+	}
+	if len(values) == 0 {
+		return s
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	total := 0.0
+	for _, v := range sorted {
+		total += v
+	}
+	s.Mean = total / float64(len(sorted))
+	for name, q := range quantiles {
+		s.Quantiles[name] = sorted[int(q*float64(len(sorted)-1))]
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quality_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
+	"github.com/ossf/criticality_score/cmd/csv_transfer/quality"
+)
+
+const testConfig = `
+min_rows: 3
+max_duplicate_rate: 0.25
+fill_rates:
+  repo.url: 1
+  repo.language: 0.5
+ranges:
+  default_score:
+    min: 0
+    max: 1
+drift:
+  column: default_score
+  max_mean_change: 0.1
+  max_quantile_change: 0.2
+`
+
+func loadTestConfig(t *testing.T, s string) *quality.Config {
+	t.Helper()
+	c, err := quality.LoadConfig(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v, want no error", err)
+	}
+	return c
+}
+
+func profile(t *testing.T, c *quality.Config, shards ...string) *quality.Profile {
+	t.Helper()
+	var in []aggregate.Shard
+	for i, s := range shards {
+		s := s
+		in = append(in, aggregate.Shard{
+			Name: "shard-" + string(rune('a'+i)),
+			Open: func(context.Context) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(s)), nil
+			},
+		})
+	}
+	var out bytes.Buffer
+	p := quality.NewProfiler(c, aggregate.CSVWriter(&out))
+	stats, err := aggregate.Aggregate(context.Background(), in, p, aggregate.Options{URLColumn: aggregate.DefaultURLColumn})
+	if err != nil {
+		t.Fatalf("Aggregate() = %v, want no error", err)
+	}
+	if out.Len() == 0 {
+		t.Fatal("Profiler did not write to the wrapped writer")
+	}
+	return p.Profile(stats)
+}
+
+func failures(r *quality.Report) []string {
+	var names []string
+	for _, res := range r.Failures() {
+		names = append(names, res.Check)
+	}
+	return names
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "fill rate too high", config: "fill_rates:\n  a: 2\n"},
+		{name: "negative duplicate rate", config: "max_duplicate_rate: -1\n"},
+		{name: "drift without column", config: "drift:\n  max_mean_change: 1\n"},
+		{name: "unknown field", config: "max_rows: 1\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := quality.LoadConfig(strings.NewReader(test.config)); err == nil {
+				t.Fatal("LoadConfig() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadConfigEmpty(t *testing.T) {
+	c := loadTestConfig(t, "")
+	r := quality.Check(c, &quality.Profile{}, nil)
+	if !r.Passed || len(r.Results) != 0 {
+		t.Fatalf("Check() = %+v, want no results", r)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	c := loadTestConfig(t, testConfig)
+	p := profile(t, c,
+		"repo.url,repo.language,default_score\n"+
+			"https://github.com/a/a,Go,0.1\n"+
+			"https://github.com/b/b,,0.2\n",
+		"repo.url,repo.language,default_score\n"+
+			"https://github.com/c/c,C,0.3\n"+
+			"https://github.com/a/a,Go,1.5\n"+
+			"https://github.com/d/d,Go,bad\n")
+
+	want := &quality.Profile{
+		InputRows:  5,
+		Duplicates: 1,
+		Rows:       4,
+		Filled:     map[string]int{"repo.url": 4, "repo.language": 3, "default_score": 4},
+		OutOfRange: map[string]int{"default_score": 2},
+		Scores: &quality.Summary{
+			Column:    "default_score",
+			Count:     3,
+			Mean:      (0.2 + 0.3 + 1.5) / 3,
+			Quantiles: map[string]float64{"p10": 0.2, "p50": 0.3, "p90": 0.3, "p99": 0.3},
+		},
+	}
+	if diff := cmp.Diff(want, p); diff != "" {
+		t.Fatalf("Profile() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := loadTestConfig(t, testConfig)
+	good := "repo.url,repo.language,default_score\n" +
+		"https://github.com/a/a,Go,0.1\n" +
+		"https://github.com/b/b,,0.2\n" +
+		"https://github.com/c/c,C,0.3\n"
+	previous := profile(t, c, good)
+
+	tests := []struct {
+		name     string
+		shards   []string
+		previous *quality.Profile
+		want     []string
+	}{
+		{
+			name:     "passes",
+			shards:   []string{good},
+			previous: previous,
+		},
+		{
+			name:   "passes without previous",
+			shards: []string{good},
+		},
+		{
+			name: "too few rows",
+			shards: []string{"repo.url,repo.language,default_score\n" +
+				"https://github.com/a/a,Go,0.1\n"},
+			want: []string{"min_rows"},
+		},
+		{
+			name: "low fill rate",
+			shards: []string{"repo.url,repo.language,default_score\n" +
+				"https://github.com/a/a,,0.1\n" +
+				"https://github.com/b/b,,0.2\n" +
+				"https://github.com/c/c,C,0.3\n"},
+			want: []string{"fill_rate:repo.language"},
+		},
+		{
+			name: "missing column",
+			shards: []string{"repo.url,default_score\n" +
+				"https://github.com/a/a,0.1\n" +
+				"https://github.com/b/b,0.2\n" +
+				"https://github.com/c/c,0.3\n"},
+			want: []string{"fill_rate:repo.language"},
+		},
+		{
+			name: "too many duplicates",
+			shards: []string{good, "repo.url,repo.language,default_score\n" +
+				"https://github.com/a/a,Go,0.1\n" +
+				"https://github.com/b/b,Go,0.2\n"},
+			want: []string{"duplicate_rate"},
+		},
+		{
+			name: "out of range",
+			shards: []string{"repo.url,repo.language,default_score\n" +
+				"https://github.com/a/a,Go,-0.1\n" +
+				"https://github.com/b/b,Go,0.2\n" +
+				"https://github.com/c/c,C,0.3\n"},
+			want: []string{"range:default_score"},
+		},
+		{
+			name: "drift",
+			shards: []string{"repo.url,repo.language,default_score\n" +
+				"https://github.com/a/a,Go,0.6\n" +
+				"https://github.com/b/b,Go,0.7\n" +
+				"https://github.com/c/c,C,0.8\n"},
+			previous: previous,
+			want:     []string{"drift:default_score:mean", "drift:default_score:p10", "drift:default_score:p50", "drift:default_score:p90", "drift:default_score:p99"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := quality.Check(c, profile(t, c, test.shards...), test.previous)
+			if diff := cmp.Diff(test.want, failures(r)); diff != "" {
+				t.Fatalf("Check() failures mismatch (-want +got):\n%s", diff)
+			}
+			if got, want := r.Passed, len(test.want) == 0; got != want {
+				t.Fatalf("Check() passed = %v, want %v", got, want)
+			}
+		})
+	}
+}
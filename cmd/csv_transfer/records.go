@@ -0,0 +1,123 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ossf/scorecard/v4/cron/data"
+	"go.uber.org/zap"
+	"gocloud.dev/blob"
+
+	"github.com/ossf/criticality_score/cmd/csv_transfer/aggregate"
+)
+
+// schemaSuffix is appended to the key of a JSON Lines or Parquet aggregate for
+// its BigQuery schema file.
+const schemaSuffix = ".schema.json"
+
+// recordTransfer aggregates the JSON shards written by collect_signals into
+// JSON Lines and Parquet files.
+type recordTransfer struct {
+	// srcBucket holds the JSON shards.
+	srcBucket string
+
+	// jsonlFilename and parquetFilename are the names of the aggregates. An
+	// empty name disables the format.
+	jsonlFilename   string
+	parquetFilename string
+}
+
+// transfer aggregates the JSON shards created at t into destBucket, along with
+// a schema file for each aggregate.
+//
+// csvShards is the number of CSV shards that were aggregated. As the JSON
+// shards are written after the CSV shards, an error is returned if there are
+// fewer JSON shards so they will be retried later.
+func (r *recordTransfer) transfer(ctx context.Context, logger *zap.Logger, t time.Time, csvShards int, destBucket string, opts aggregate.Options) error {
+	src, shards, err := openShards(ctx, logger, r.srcBucket, t)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if len(shards) < csvShards {
+		return fmt.Errorf("found %d json shards, want %d", len(shards), csvShards)
+	}
+
+	dest, err := blob.OpenBucket(ctx, destBucket)
+	if err != nil {
+		return fmt.Errorf("opening destination bucket: %w", err)
+	}
+	defer dest.Close()
+
+	// Cancelling the context before closing the writers aborts the writes, so
+	// partial files are never stored.
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var outs []io.WriteCloser
+	var writers []aggregate.RecordWriter
+	open := func(filename string, newWriter func(io.Writer) aggregate.RecordWriter) error {
+		key := data.GetBlobFilename(filename, t)
+		out, err := dest.NewWriter(writeCtx, key, nil)
+		if err != nil {
+			return fmt.Errorf("creating writer for %s: %w", key, err)
+		}
+		outs = append(outs, out)
+		schemaOut, err := dest.NewWriter(writeCtx, key+schemaSuffix, nil)
+		if err != nil {
+			return fmt.Errorf("creating writer for %s: %w", key+schemaSuffix, err)
+		}
+		outs = append(outs, schemaOut)
+		writers = append(writers, newWriter(out), aggregate.SchemaWriter(schemaOut))
+		return nil
+	}
+	abort := func(err error) error {
+		cancel()
+		for _, out := range outs {
+			out.Close()
+		}
+		return err
+	}
+	if r.jsonlFilename != "" {
+		if err := open(r.jsonlFilename, aggregate.JSONLinesWriter); err != nil {
+			return abort(err)
+		}
+	}
+	if r.parquetFilename != "" {
+		if err := open(r.parquetFilename, aggregate.ParquetWriter); err != nil {
+			return abort(err)
+		}
+	}
+
+	stats, err := aggregate.AggregateRecords(ctx, shards, aggregate.MultiRecordWriter(writers...), opts)
+	if err != nil {
+		return abort(fmt.Errorf("aggregating json shards: %w", err))
+	}
+	for _, out := range outs {
+		if err := out.Close(); err != nil {
+			return abort(fmt.Errorf("writing aggregate: %w", err))
+		}
+	}
+
+	logger.With(
+		zap.Int("total_json_shards", stats.Shards),
+		zap.Int("total_fields", stats.Columns),
+		zap.Int("total_json_records", stats.Written),
+	).Info("Record transfer complete")
+	return nil
+}
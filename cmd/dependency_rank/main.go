@@ -0,0 +1,214 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The dependency_rank command adds a transitive criticality signal to the
+// signals generated by the criticality_score or collect_signals commands.
+//
+// A project dependency graph is built from a deps.dev snapshot, either from
+// a local CSV file of edges, or from BigQuery. The PageRank of each project is
+// calculated over the graph and added as a new column to the signals CSV so
+// that it can be weighted by the scorer command.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	"github.com/ossf/criticality_score/internal/depgraph"
+	"github.com/ossf/criticality_score/internal/infile"
+	log "github.com/ossf/criticality_score/internal/log"
+	"github.com/ossf/criticality_score/internal/outfile"
+)
+
+const (
+	defaultLogLevel   = zapcore.InfoLevel
+	defaultColumnName = "depsdev.dependent_rank"
+	urlColumnName     = "repo.url"
+)
+
+var (
+	edgesFlag          = flag.String("edges", "", "the `file` containing the dependency edges. If not set, edges are read from BigQuery.")
+	gcpProjectFlag     = flag.String("gcp-project-id", "", "the Google Cloud Project ID to use. Auto-detects by default.")
+	depsdevDatasetFlag = flag.String("depsdev-dataset", collector.DefaultGCPDatasetName, "the BigQuery dataset name to use.")
+	depsdevTTLFlag     = flag.Int("depsdev-expiration", 0, "the default expiration (`hours`) to use for deps.dev tables. No expiration by default.")
+	depsdevKeyFlag     = flag.String("depsdev-table-key", "", "the `key` used to name the BigQuery edges table. Tables with the same key are reused.")
+	dampingFlag        = flag.Float64("damping", depgraph.DefaultDamping, "the PageRank damping factor.")
+	iterationsFlag     = flag.Int("iterations", depgraph.DefaultMaxIterations, "the maximum number of PageRank iterations.")
+	columnNameFlag     = flag.String("column", defaultColumnName, "the name of the output column")
+	logLevel           = defaultLogLevel
+	logEnv             log.Env
+)
+
+func init() {
+	flag.Var(&logLevel, "log", "set the `level` of logging.")
+	flag.TextVar(&logEnv, "log-env", log.DefaultEnv, "set logging `env`.")
+	outfile.DefineFlags(flag.CommandLine, "out", "force", "append", "OUT_FILE")
+	flag.Usage = func() {
+		cmdName := path.Base(os.Args[0])
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage:\n  %s [FLAGS]... IN_CSV\n\n", cmdName)
+		fmt.Fprintf(w, "Adds the dependency rank of each project to the signals in IN_CSV.\n")
+		fmt.Fprintf(w, "IN_CSV must be either a csv file or - to read from stdin.\n")
+		fmt.Fprintf(w, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// loadGraph returns the dependency graph from either the -edges file, or from
+// BigQuery.
+func loadGraph(ctx context.Context, logger *zap.Logger) (*depgraph.Graph, error) {
+	if *edgesFlag == "" {
+		logger.Info("Loading dependency graph from BigQuery")
+		return depsdev.LoadGraph(ctx, logger, *gcpProjectFlag, *depsdevDatasetFlag, time.Hour*time.Duration(*depsdevTTLFlag), *depsdevKeyFlag)
+	}
+	logger.With(
+		zap.String("filename", *edgesFlag),
+	).Info("Loading dependency graph from file")
+	f, err := infile.Open(ctx, *edgesFlag)
+	if err != nil {
+		return nil, fmt.Errorf("opening edges: %w", err)
+	}
+	defer f.Close()
+	g := depgraph.New()
+	if err := depgraph.ReadCSV(g, f); err != nil {
+		return nil, fmt.Errorf("reading edges: %w", err)
+	}
+	return g, nil
+}
+
+func main() {
+	flag.Parse()
+
+	logger, err := log.NewLogger(logEnv, logLevel)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	if flag.NArg() != 1 {
+		logger.Error("Must have an input file specified.")
+		os.Exit(2)
+	}
+	inFilename := flag.Args()[0]
+
+	ctx := context.Background()
+
+	// Open the in-file for reading
+	fr, err := infile.Open(ctx, inFilename)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+			zap.String("filename", inFilename),
+		).Error("Failed to open input file")
+		os.Exit(2)
+	}
+	defer fr.Close()
+	r := csv.NewReader(fr)
+
+	inHeader, err := r.Read()
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to read CSV header row")
+		os.Exit(2)
+	}
+	urlIdx := -1
+	for i, h := range inHeader {
+		if h == *columnNameFlag {
+			logger.Error("Header already contains field " + *columnNameFlag)
+			os.Exit(2)
+		}
+		if h == urlColumnName {
+			urlIdx = i
+		}
+	}
+	if urlIdx == -1 {
+		logger.Error("Header is missing field " + urlColumnName)
+		os.Exit(2)
+	}
+
+	g, err := loadGraph(ctx, logger)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to load dependency graph")
+		os.Exit(2)
+	}
+	logger.With(
+		zap.Int("projects", g.Len()),
+	).Info("Calculating dependency rank")
+	ranks := g.PageRank(*dampingFlag, *iterationsFlag, depgraph.DefaultTolerance)
+
+	// Open the out-file for writing
+	fw, err := outfile.Open(ctx)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to open file for output")
+		os.Exit(2)
+	}
+	defer fw.Close()
+	w := csv.NewWriter(fw)
+	defer w.Flush()
+
+	if err := w.Write(append(inHeader, *columnNameFlag)); err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to write CSV header row")
+		os.Exit(2)
+	}
+
+	// Ranks sum to 1 across the graph, so scale them by the number of projects
+	// to make 1.0 the rank of an average project.
+	scale := float64(g.Len())
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.With(
+				zap.Error(err),
+			).Error("Failed to read CSV row")
+			os.Exit(2)
+		}
+		value := ""
+		if u, err := url.Parse(row[urlIdx]); err == nil {
+			if p, ok := depsdev.ProjectForURL(u); ok {
+				if rank, ok := ranks[p]; ok {
+					value = fmt.Sprintf("%.5f", rank*scale)
+				}
+			}
+		}
+		if err := w.Write(append(row, value)); err != nil {
+			logger.With(
+				zap.Error(err),
+			).Error("Failed to write CSV row")
+			os.Exit(2)
+		}
+	}
+}
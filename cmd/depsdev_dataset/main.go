@@ -0,0 +1,215 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The depsdev_dataset command maintains the BigQuery dataset used to store
+// the deps.dev tables created by the criticality_score, collect_signals and
+// dependency_rank commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	log "github.com/ossf/criticality_score/internal/log"
+)
+
+const (
+	defaultLogLevel = zapcore.InfoLevel
+	timeFormat      = "2006-01-02 15:04"
+)
+
+var (
+	gcpProjectFlag     = flag.String("gcp-project-id", "", "the Google Cloud Project ID to use. Auto-detects by default.")
+	depsdevDatasetFlag = flag.String("depsdev-dataset", collector.DefaultGCPDatasetName, "the BigQuery dataset name to use.")
+	keepFlag           = flag.Int("keep", 0, "prune: the `number` of tables of each kind to keep. No limit by default.")
+	maxAgeFlag         = flag.Duration("max-age", 0, "prune: delete tables older than `duration`. No limit by default.")
+	dryRunFlag         = flag.Bool("dry-run", false, "prune: list the tables that would be deleted without deleting them.")
+	warmPackagesFlag   = flag.Bool("warm-packages", false, "warm: also create the per-package dependent counts table.")
+	sinceFlag          = flag.Duration("since", 30*24*time.Hour, "costs: report usage for queries run in the last `duration`.")
+	logLevel           = defaultLogLevel
+	logEnv             log.Env
+)
+
+var errUsage = errors.New("invalid usage")
+
+func init() {
+	flag.Var(&logLevel, "log", "set the `level` of logging.")
+	flag.TextVar(&logEnv, "log-env", log.DefaultEnv, "set logging `env`.")
+	flag.Usage = func() {
+		cmdName := path.Base(os.Args[0])
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage:\n  %s [FLAGS]... COMMAND [ARGS]...\n\n", cmdName)
+		fmt.Fprintf(w, "Maintains the BigQuery dataset containing deps.dev tables.\n\n")
+		fmt.Fprintf(w, "Commands:\n")
+		fmt.Fprintf(w, "  list              lists the tables in the dataset\n")
+		fmt.Fprintf(w, "  prune             deletes tables using -keep and -max-age\n")
+		fmt.Fprintf(w, "  warm JOB_ID...    creates the tables for the given jobs\n")
+		fmt.Fprintf(w, "  costs             reports the BigQuery bytes scanned for each job\n")
+		fmt.Fprintf(w, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(timeFormat)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func list(ctx context.Context, m *depsdev.DatasetManager, w io.Writer) error {
+	tables, err := m.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tJOB\tCREATED\tSNAPSHOT\tROWS\tSIZE\tEXPIRES")
+	for _, t := range tables {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			t.Name, t.Key, formatTime(t.Created), formatTime(t.Snapshot), t.NumRows, formatBytes(t.NumBytes), formatTime(t.Expiration))
+	}
+	return tw.Flush()
+}
+
+func prune(ctx context.Context, m *depsdev.DatasetManager, logger *zap.Logger) error {
+	if *keepFlag <= 0 && *maxAgeFlag <= 0 {
+		return fmt.Errorf("%w: prune requires -keep or -max-age", errUsage)
+	}
+	tables, err := m.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range depsdev.TablesToPrune(tables, *keepFlag, *maxAgeFlag, time.Now()) {
+		l := logger.With(
+			zap.String("table", t.Name),
+			zap.Time("created", t.Created),
+		)
+		if *dryRunFlag {
+			l.Info("Would delete table")
+			continue
+		}
+		l.Info("Deleting table")
+		if err := m.DeleteTable(ctx, t.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func warm(ctx context.Context, m *depsdev.DatasetManager, logger *zap.Logger, jobIDs []string) error {
+	if len(jobIDs) == 0 {
+		return fmt.Errorf("%w: warm requires at least one JOB_ID", errUsage)
+	}
+	for _, jobID := range jobIDs {
+		logger.With(zap.String("job_id", jobID)).Info("Warming tables")
+		if err := m.Warm(ctx, jobID, *warmPackagesFlag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func costs(ctx context.Context, m *depsdev.DatasetManager, w io.Writer) error {
+	costs, err := m.Costs(ctx, time.Now().Add(-*sinceFlag))
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tQUERIES\tPROCESSED\tBILLED\tFIRST QUERY\tLAST QUERY")
+	var total depsdev.TableCost
+	for _, c := range costs {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			c.Table, c.Queries, formatBytes(c.BytesProcessed), formatBytes(c.BytesBilled), formatTime(c.FirstQuery), formatTime(c.LastQuery))
+		total.Queries += c.Queries
+		total.BytesProcessed += c.BytesProcessed
+		total.BytesBilled += c.BytesBilled
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%s\t%s\t\t\n", total.Queries, formatBytes(total.BytesProcessed), formatBytes(total.BytesBilled))
+	return tw.Flush()
+}
+
+func main() {
+	flag.Parse()
+
+	logger, err := log.NewLogger(logEnv, logLevel)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	if flag.NArg() < 1 {
+		logger.Error("Must have a command specified.")
+		os.Exit(2)
+	}
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "list", "prune", "warm", "costs":
+	default:
+		logger.Error("Unknown command " + cmd)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	m, err := depsdev.NewDatasetManager(ctx, logger, *gcpProjectFlag, *depsdevDatasetFlag)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to open deps.dev dataset")
+		os.Exit(2)
+	}
+
+	switch cmd {
+	case "list":
+		err = list(ctx, m, os.Stdout)
+	case "prune":
+		err = prune(ctx, m, logger)
+	case "warm":
+		err = warm(ctx, m, logger, args)
+	case "costs":
+		err = costs(ctx, m, os.Stdout)
+	}
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+			zap.String("command", cmd),
+		).Error("Command failed")
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/checkpoint"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+	"github.com/ossf/criticality_score/internal/infile"
+)
+
+// checkpointInterval is the minimum time between writes of the -checkpoint
+// file, other than when a search range is completed.
+const checkpointInterval = 30 * time.Second
+
+// checkpointer records the progress of each search range to the -checkpoint
+// file.
+//
+// The progress of a search range is only recorded once every repository found
+// before it has been written to the output, so a resumed enumeration never
+// misses repositories. Repositories written after the last checkpoint will be
+// found again when resuming, so the urls of the repositories already written
+// are tracked to avoid writing duplicates.
+//
+// checkpointer is not safe to use from multiple goroutines.
+type checkpointer struct {
+	ctx       context.Context
+	logger    *zap.Logger
+	state     *checkpoint.State
+	lastWrite time.Time
+
+	// seen holds the url of each repository in the output when resuming, and
+	// each repository written since.
+	seen map[string]bool
+
+	// existing is the number of repositories in the output when resuming.
+	existing int
+}
+
+func checkpointParams() checkpoint.Params {
+	return checkpoint.Params{
+		Query:    *queryFlag,
+		MinStars: *minStarsFlag,
+		Start:    startDateFlag.Time(),
+		End:      endDateFlag.Time(),
+		Format:   format.String(),
+	}
+}
+
+// newCheckpointer returns a checkpointer for the -checkpoint file. If -resume
+// is set the state is read from the file, and the run ID is restored so the
+// output is appended to the same file.
+func newCheckpointer(ctx context.Context, logger *zap.Logger) (*checkpointer, error) {
+	c := &checkpointer{
+		ctx:    ctx,
+		logger: logger.With(zap.String("checkpoint", *checkpointFlag)),
+	}
+	if !*resumeFlag {
+		c.state = checkpoint.New(checkpointParams(), runID)
+		return c, nil
+	}
+	state, err := checkpoint.Read(ctx, *checkpointFlag)
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	if err := state.Check(checkpointParams()); err != nil {
+		return nil, err
+	}
+	c.state = state
+	runID = state.RunID
+	c.logger.With(
+		zap.Int("done", len(state.Done)),
+		zap.Int("in_progress", len(state.InProgress)),
+		zap.Time("updated", state.UpdatedAt),
+	).Info("Resuming enumeration")
+	return c, nil
+}
+
+// open records the name of the output file, and when resuming reads every
+// repository already written to it. It returns true if the output already
+// has contents that should be appended to.
+func (c *checkpointer) open(name string) (bool, error) {
+	if !*resumeFlag {
+		c.state.Output = name
+		return false, nil
+	}
+	if c.state.Output != name {
+		return false, fmt.Errorf("output %s does not match checkpoint output %s", name, c.state.Output)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return false, err
+	}
+	if fi.Size() == 0 {
+		return false, nil
+	}
+	f, err := infile.Open(c.ctx, name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	repos, err := repowriter.ReadRepos(f, format)
+	if err != nil {
+		return false, fmt.Errorf("reading output: %w", err)
+	}
+	c.existing = len(repos)
+	c.seen = make(map[string]bool, len(repos))
+	for _, r := range repos {
+		c.seen[r.URL] = true
+	}
+	c.logger.With(
+		zap.Int("repo_count", len(repos)),
+	).Info("Appending to existing output")
+	return true, nil
+}
+
+// add returns true if url has not been written to the output before.
+func (c *checkpointer) add(url string) bool {
+	if c.seen == nil {
+		return true
+	}
+	if c.seen[url] {
+		return false
+	}
+	c.seen[url] = true
+	return true
+}
+
+// update records p as the progress of the search range key, writing the
+// -checkpoint file if the range is complete or enough time has passed.
+func (c *checkpointer) update(key string, p githubsearch.Progress) {
+	c.state.Update(key, p)
+	if p.Done() || time.Since(c.lastWrite) >= checkpointInterval {
+		c.write()
+	}
+}
+
+// write writes the -checkpoint file. Failures are logged, as enumeration can
+// continue without checkpoints.
+func (c *checkpointer) write() {
+	if err := c.state.Write(c.ctx, *checkpointFlag); err != nil {
+		c.logger.Error("Failed to write checkpoint", zap.Error(err))
+		return
+	}
+	c.lastWrite = time.Now()
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkpoint saves the progress of an enumeration, so that it can be
+// resumed after a failure.
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+)
+
+var ErrorParamsMismatch = errors.New("enumeration parameters do not match the checkpoint")
+
+// Params are the parameters that determine which repositories are enumerated.
+// An enumeration can only be resumed with the same Params.
+type Params struct {
+	Query    string    `json:"query"`
+	MinStars int       `json:"min_stars"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Format   string    `json:"format"`
+}
+
+// State is the progress of an enumeration, which is split into search ranges
+// identified by a key.
+//
+// State is not safe to use from multiple goroutines.
+type State struct {
+	Params Params `json:"params"`
+
+	// RunID and Output are the run ID and the name of the output file of the
+	// enumeration, so that a resumed enumeration appends to the same file.
+	RunID  string `json:"run_id"`
+	Output string `json:"output"`
+
+	// Done holds the key of each search range that has been completed.
+	Done map[string]bool `json:"done"`
+
+	// InProgress holds the progress of each search range that has been
+	// started but not completed.
+	InProgress map[string]githubsearch.Progress `json:"in_progress"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// New returns the State for an enumeration with params that has not started.
+func New(params Params, runID string) *State {
+	return &State{
+		Params:     params,
+		RunID:      runID,
+		Done:       make(map[string]bool),
+		InProgress: make(map[string]githubsearch.Progress),
+	}
+}
+
+// Check returns ErrorParamsMismatch if params differ from the State's Params.
+func (s *State) Check(params Params) error {
+	if !s.Params.Start.Equal(params.Start) || !s.Params.End.Equal(params.End) ||
+		s.Params.Query != params.Query || s.Params.MinStars != params.MinStars || s.Params.Format != params.Format {
+		return fmt.Errorf("%w: got %+v, want %+v", ErrorParamsMismatch, params, s.Params)
+	}
+	return nil
+}
+
+// IsDone returns true if the search range key has been completed.
+func (s *State) IsDone(key string) bool {
+	return s.Done[key]
+}
+
+// Progress returns the progress of the search range key, if it has been
+// started but not completed.
+func (s *State) Progress(key string) (githubsearch.Progress, bool) {
+	p, ok := s.InProgress[key]
+	return p, ok
+}
+
+// Update records p as the progress of the search range key.
+func (s *State) Update(key string, p githubsearch.Progress) {
+	if p.Done() {
+		delete(s.InProgress, key)
+		s.Done[key] = true
+	} else {
+		s.InProgress[key] = p
+	}
+}
+
+// Read returns the State stored in the file or bucket url name.
+func Read(ctx context.Context, name string) (*State, error) {
+	r, err := cloudstorage.NewReader(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	s := New(Params{}, "")
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("decoding checkpoint: %w", err)
+	}
+	return s, nil
+}
+
+// Write stores s in the file or bucket url name, replacing any existing
+// State. The file is replaced atomically, so a failure while writing leaves
+// the previous State intact.
+func (s *State) Write(ctx context.Context, name string) error {
+	s.UpdatedAt = time.Now().UTC()
+	w, err := cloudstorage.NewWriter(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		w.Close()
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+	return w.Close()
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/checkpoint"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/internal/githubapi/pagination"
+)
+
+var (
+	testDay    = time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)
+	testParams = checkpoint.Params{Query: "is:public", MinStars: 10, Start: testDay, End: testDay, Format: "text"}
+)
+
+func TestUpdate(t *testing.T) {
+	s := checkpoint.New(testParams, "20220614-0000")
+	started := githubsearch.NewProgress(testDay, testDay.Add(24*time.Hour), 10, -1)
+	started.Position = &pagination.Position{After: "abc", Offset: 2}
+
+	s.Update("2022-06-14", started)
+	if s.IsDone("2022-06-14") {
+		t.Fatal("IsDone() = true, want false")
+	}
+	got, ok := s.Progress("2022-06-14")
+	if !ok {
+		t.Fatal("Progress() = false, want true")
+	}
+	if diff := cmp.Diff(started, got); diff != "" {
+		t.Fatalf("Progress() mismatch (-want +got):\n%s", diff)
+	}
+
+	s.Update("2022-06-14", githubsearch.Progress{})
+	if !s.IsDone("2022-06-14") {
+		t.Fatal("IsDone() = false, want true")
+	}
+	if _, ok := s.Progress("2022-06-14"); ok {
+		t.Fatal("Progress() = true, want false")
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "state.json")
+
+	want := checkpoint.New(testParams, "20220614-0000")
+	want.Output = "out.txt"
+	want.Update("2022-06-13", githubsearch.Progress{})
+	want.Update("2022-06-14", githubsearch.NewProgress(testDay, testDay.Add(24*time.Hour), 10, -1))
+	if err := want.Write(ctx, name); err != nil {
+		t.Fatalf("Write() = %v, want no error", err)
+	}
+
+	got, err := checkpoint.Read(ctx, name)
+	if err != nil {
+		t.Fatalf("Read() = %v, want no error", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Read() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := checkpoint.New(testParams, "")
+	if err := s.Check(testParams); err != nil {
+		t.Fatalf("Check() = %v, want no error", err)
+	}
+	changed := testParams
+	changed.MinStars = 20
+	if err := s.Check(changed); !errors.Is(err, checkpoint.ErrorParamsMismatch) {
+		t.Fatalf("Check() = %v, want %v", err, checkpoint.ErrorParamsMismatch)
+	}
+}
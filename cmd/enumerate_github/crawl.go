@@ -0,0 +1,162 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/crawler"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	"github.com/ossf/criticality_score/internal/depgraph"
+	"github.com/ossf/criticality_score/internal/infile"
+)
+
+// readSeeds returns the project for each repository url in the -crawl file.
+//
+// Blank lines and lines starting with "#" are ignored. Urls that do not refer
+// to a supported host are logged and skipped.
+func readSeeds(ctx context.Context, logger *zap.Logger) ([]depgraph.Project, error) {
+	in, err := infile.Open(ctx, *crawlFlag)
+	if err != nil {
+		return nil, fmt.Errorf("opening seeds: %w", err)
+	}
+	defer in.Close()
+
+	var seeds []depgraph.Project
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil {
+			logger.With(zap.String("url", line), zap.Error(err)).Warn("Skipping invalid seed url")
+			continue
+		}
+		p, ok := depsdev.ProjectForURL(u)
+		if !ok {
+			logger.With(zap.String("url", line)).Warn("Skipping seed url for unsupported host")
+			continue
+		}
+		seeds = append(seeds, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading seeds: %w", err)
+	}
+	return seeds, nil
+}
+
+// loadCrawlGraph returns the dependency graph from either the -crawl-edges
+// file, or from BigQuery if -crawl-depsdev is set. If neither is set nil is
+// returned.
+func loadCrawlGraph(ctx context.Context, logger *zap.Logger) (*depgraph.Graph, error) {
+	switch {
+	case *crawlEdgesFlag != "":
+		logger.With(
+			zap.String("filename", *crawlEdgesFlag),
+		).Info("Loading dependency graph from file")
+		f, err := infile.Open(ctx, *crawlEdgesFlag)
+		if err != nil {
+			return nil, fmt.Errorf("opening edges: %w", err)
+		}
+		defer f.Close()
+		g := depgraph.New()
+		if err := depgraph.ReadCSV(g, f); err != nil {
+			return nil, fmt.Errorf("reading edges: %w", err)
+		}
+		return g, nil
+	case *crawlDepsDevFlag:
+		logger.Info("Loading dependency graph from BigQuery")
+		return depsdev.LoadGraph(ctx, logger, *gcpProjectFlag, *depsdevDatasetFlag, time.Hour*time.Duration(*depsdevTTLFlag), *depsdevKeyFlag)
+	default:
+		return nil, nil
+	}
+}
+
+// crawl follows the dependencies of each seed repository in the -crawl file,
+// up to -crawl-depth edges away, and writes each repository reached to out.
+// The total number of repositories written is returned.
+func crawl(ctx context.Context, client *githubv4.Client, logger *zap.Logger, out io.Writer) int {
+	seeds, err := readSeeds(ctx, logger)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+			zap.String("filename", *crawlFlag),
+		).Error("Failed to read seed repositories")
+		os.Exit(2)
+	}
+
+	var srcs []crawler.Source
+	g, err := loadCrawlGraph(ctx, logger)
+	if err != nil {
+		logger.Error("Failed to load dependency graph", zap.Error(err))
+		os.Exit(2)
+	}
+	if g != nil {
+		srcs = append(srcs, crawler.GraphSource(g))
+	}
+	if *crawlManifestsFlag {
+		s := githubsearch.NewSearcher(ctx, client, logger)
+		srcs = append(srcs, crawler.ManifestSource(s, logger))
+	}
+	if len(srcs) == 0 {
+		logger.Error("-crawl requires at least one of -crawl-edges, -crawl-depsdev or -crawl-manifests")
+		os.Exit(2)
+	}
+
+	w := crawlFormat.New(out)
+
+	logger.With(
+		zap.String("filename", *crawlFlag),
+		zap.Int("seeds", len(seeds)),
+		zap.Int("depth", *crawlDepthFlag),
+		zap.Int("workers", *workersFlag),
+	).Info("Starting dependency crawl")
+
+	totalRepos := 0
+	depths := make(map[int]int)
+	err = crawler.Crawl(ctx, logger, crawler.Sources(srcs...), seeds, *crawlDepthFlag, *workersFlag, func(r crawler.Result) {
+		if err := w.WriteCrawl(r); err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("repo", r.URL),
+			).Error("Failed to write crawl result")
+			os.Exit(2)
+		}
+		depths[r.Depth]++
+		totalRepos++
+	})
+	if err != nil {
+		logger.Error("Dependency crawl failed", zap.Error(err))
+		os.Exit(1)
+	}
+
+	logger.With(
+		zap.Any("depths", depths),
+	).Info("Finished dependency crawl")
+	return totalRepos
+}
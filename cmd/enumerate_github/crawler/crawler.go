@@ -0,0 +1,177 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crawler enumerates repositories by following their dependencies,
+// starting from a set of seed repositories.
+package crawler
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	"github.com/ossf/criticality_score/internal/depgraph"
+	"github.com/ossf/criticality_score/internal/workerpool"
+)
+
+// Source returns the projects a project directly depends on.
+//
+// Implementations must be safe to call from multiple goroutines.
+type Source interface {
+	Dependencies(ctx context.Context, p depgraph.Project) ([]depgraph.Project, error)
+}
+
+type graphSource struct {
+	g *depgraph.Graph
+}
+
+// GraphSource returns a Source that uses the dependency edges in g, such as
+// those from a deps.dev snapshot. g must not be modified during a crawl.
+func GraphSource(g *depgraph.Graph) Source {
+	return &graphSource{g: g}
+}
+
+// Dependencies implements the Source interface.
+func (s *graphSource) Dependencies(ctx context.Context, p depgraph.Project) ([]depgraph.Project, error) {
+	return s.g.Dependencies(p), nil
+}
+
+type multiSource []Source
+
+// Sources returns a Source that combines the dependencies returned by each of
+// srcs, in order, without duplicates.
+func Sources(srcs ...Source) Source {
+	return multiSource(srcs)
+}
+
+// Dependencies implements the Source interface.
+func (s multiSource) Dependencies(ctx context.Context, p depgraph.Project) ([]depgraph.Project, error) {
+	seen := make(map[depgraph.Project]bool)
+	var deps []depgraph.Project
+	for _, src := range s {
+		d, err := src.Dependencies(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range d {
+			if !seen[dep] {
+				seen[dep] = true
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// Result is a repository reached during a crawl.
+type Result struct {
+	Project depgraph.Project
+	URL     string
+
+	// Depth is the number of dependency edges between a seed and the
+	// repository. Seeds have a depth of 0.
+	Depth int
+
+	// Path is the url of each repository the repository was found through,
+	// starting with a seed and ending with the repository itself.
+	Path []string
+}
+
+// Crawl calls emit for each seed, and each repository reachable from a seed
+// by following at most maxDepth dependency edges returned by src.
+//
+// The crawl is breadth first, so each repository is emitted once, with the
+// shortest path to it. The dependencies of each depth are found using the
+// given number of concurrent workers. emit is only called from the calling
+// goroutine.
+//
+// Projects that can not be mapped to a repository url are ignored.
+func Crawl(ctx context.Context, logger *zap.Logger, src Source, seeds []depgraph.Project, maxDepth, workers int, emit func(Result)) error {
+	seen := make(map[depgraph.Project]bool)
+	var frontier []Result
+	for _, p := range seeds {
+		u, ok := depsdev.URLForProject(p)
+		if !ok || seen[p] {
+			continue
+		}
+		seen[p] = true
+		r := Result{Project: p, URL: u, Path: []string{u}}
+		emit(r)
+		frontier = append(frontier, r)
+	}
+
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		logger.With(
+			zap.Int("depth", depth),
+			zap.Int("frontier", len(frontier)),
+			zap.Int("reached", len(seen)),
+		).Info("Crawling dependencies")
+
+		deps, err := dependencies(ctx, src, frontier, workers)
+		if err != nil {
+			return err
+		}
+		var next []Result
+		for i, parent := range frontier {
+			for _, p := range deps[i] {
+				if seen[p] {
+					continue
+				}
+				seen[p] = true
+				u, ok := depsdev.URLForProject(p)
+				if !ok {
+					continue
+				}
+				path := make([]string, len(parent.Path), len(parent.Path)+1)
+				copy(path, parent.Path)
+				r := Result{Project: p, URL: u, Depth: depth, Path: append(path, u)}
+				emit(r)
+				next = append(next, r)
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// dependencies returns the dependencies of each result in frontier, in the
+// same order.
+func dependencies(ctx context.Context, src Source, frontier []Result, workers int) ([][]depgraph.Project, error) {
+	deps := make([][]depgraph.Project, len(frontier))
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var firstErr error
+	wait := workerpool.WorkerPool(workers, func(worker int) {
+		for i := range indexes {
+			d, err := src.Dependencies(ctx, frontier[i].Project)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				continue
+			}
+			deps[i] = d
+		}
+	})
+	for i := range frontier {
+		indexes <- i
+	}
+	close(indexes)
+	wait()
+	return deps, firstErr
+}
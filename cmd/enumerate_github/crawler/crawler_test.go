@@ -0,0 +1,100 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/crawler"
+	"github.com/ossf/criticality_score/internal/depgraph"
+)
+
+type fakeFetcher map[string]map[string]string
+
+func (f fakeFetcher) Manifests(owner, name string) (map[string]string, error) {
+	m, ok := f[owner+"/"+name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return m, nil
+}
+
+func crawl(t *testing.T, src crawler.Source, maxDepth int, seeds ...depgraph.Project) []crawler.Result {
+	t.Helper()
+	var got []crawler.Result
+	err := crawler.Crawl(context.Background(), zap.NewNop(), src, seeds, maxDepth, 2, func(r crawler.Result) {
+		got = append(got, r)
+	})
+	if err != nil {
+		t.Fatalf("Crawl() = %v, want no error", err)
+	}
+	return got
+}
+
+func result(depth int, path ...string) crawler.Result {
+	name := path[len(path)-1]
+	var urls []string
+	for _, p := range path {
+		urls = append(urls, "https://github.com/"+p)
+	}
+	return crawler.Result{Project: gh(name), URL: urls[len(urls)-1], Depth: depth, Path: urls}
+}
+
+func TestCrawl(t *testing.T) {
+	g := depgraph.New()
+	g.AddEdge(gh("app/a"), gh("lib/b"))
+	g.AddEdge(gh("app/a"), gh("lib/c"))
+	g.AddEdge(gh("lib/b"), gh("lib/c"))
+	g.AddEdge(gh("lib/b"), gh("lib/d"))
+	g.AddEdge(gh("lib/d"), gh("lib/e"))
+	g.AddEdge(gh("lib/d"), gh("app/a"))
+
+	got := crawl(t, crawler.GraphSource(g), 2, gh("app/a"))
+	want := []crawler.Result{
+		result(0, "app/a"),
+		result(1, "app/a", "lib/b"),
+		result(1, "app/a", "lib/c"),
+		result(2, "app/a", "lib/b", "lib/d"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Crawl() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCrawlSources(t *testing.T) {
+	g := depgraph.New()
+	g.AddEdge(gh("app/a"), gh("lib/b"))
+	f := fakeFetcher{
+		"app/a": {"package.json": `{"dependencies": {"b": "lib/b", "c": "github:lib/c"}}`},
+		"lib/c": {"go.mod": "require github.com/lib/d v1.0.0\n"},
+	}
+	src := crawler.Sources(crawler.GraphSource(g), crawler.ManifestSource(f, zap.NewNop()))
+
+	got := crawl(t, src, 5, gh("app/a"))
+	want := []crawler.Result{
+		result(0, "app/a"),
+		result(1, "app/a", "lib/b"),
+		result(1, "app/a", "lib/c"),
+		result(2, "app/a", "lib/c", "lib/d"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Crawl() mismatch (-want +got):\n%s", diff)
+	}
+}
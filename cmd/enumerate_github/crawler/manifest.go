@@ -0,0 +1,227 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	"github.com/ossf/criticality_score/internal/depgraph"
+)
+
+var (
+	// cargoGitRe matches git dependencies in a Cargo.toml file.
+	cargoGitRe = regexp.MustCompile(`\bgit\s*=\s*"([^"]+)"`)
+
+	// goMajorVersionRe matches the major version suffix of a Go module path.
+	goMajorVersionRe = regexp.MustCompile(`/v[0-9]+$`)
+
+	// pythonDirectRefRe matches PEP 508 direct references to git repositories
+	// in Python manifests, such as "name @ git+https://github.com/o/r".
+	pythonDirectRefRe = regexp.MustCompile(`@\s*(git\+[^\s"';,]+)`)
+)
+
+// ManifestFetcher returns the contents of the package manifests in the root
+// of the GitHub repository owner/name, keyed by filename.
+type ManifestFetcher interface {
+	Manifests(owner, name string) (map[string]string, error)
+}
+
+type manifestSource struct {
+	f      ManifestFetcher
+	logger *zap.Logger
+}
+
+// ManifestSource returns a Source that fetches the package manifests for
+// GitHub repositories using f, and parses them with ParseManifest.
+//
+// Failures to fetch the manifests for a repository are logged, and the
+// repository is treated as having no dependencies.
+func ManifestSource(f ManifestFetcher, logger *zap.Logger) Source {
+	return &manifestSource{f: f, logger: logger}
+}
+
+// Dependencies implements the Source interface.
+func (s *manifestSource) Dependencies(ctx context.Context, p depgraph.Project) ([]depgraph.Project, error) {
+	if p.Type != "GITHUB" {
+		return nil, nil
+	}
+	owner, name, ok := strings.Cut(p.Name, "/")
+	if !ok {
+		return nil, nil
+	}
+	manifests, err := s.f.Manifests(owner, name)
+	if err != nil {
+		s.logger.With(
+			zap.String("project", p.Name),
+			zap.Error(err),
+		).Warn("Failed to fetch manifests")
+		return nil, nil
+	}
+	var deps []depgraph.Project
+	for filename, contents := range manifests {
+		for _, d := range ParseManifest(filename, contents) {
+			if d != p {
+				deps = append(deps, d)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// ParseManifest returns the projects referenced as dependencies in the
+// contents of the manifest filename.
+//
+// Only dependencies that refer directly to a repository are returned, as the
+// dependencies on registry packages are available from deps.dev. These are:
+//   - modules hosted on GitHub, GitLab or Bitbucket in go.mod,
+//   - git and hosted shorthand dependencies (e.g. "github:owner/repo") in
+//     package.json,
+//   - git dependencies in Cargo.toml,
+//   - direct references to git repositories in Python manifests.
+func ParseManifest(filename, contents string) []depgraph.Project {
+	var refs []string
+	switch filename {
+	case githubsearch.ManifestGoMod:
+		refs = goModRequires(contents)
+	case githubsearch.ManifestPackageJSON:
+		refs = packageJSONRefs(contents)
+	case githubsearch.ManifestCargoToml:
+		refs = submatches(cargoGitRe, contents)
+	case githubsearch.ManifestPyprojectToml, githubsearch.ManifestSetupCfg, githubsearch.ManifestSetupPy:
+		refs = submatches(pythonDirectRefRe, contents)
+	}
+	seen := make(map[depgraph.Project]bool)
+	var deps []depgraph.Project
+	for _, ref := range refs {
+		if p, ok := ProjectForRef(ref); ok && !seen[p] {
+			seen[p] = true
+			deps = append(deps, p)
+		}
+	}
+	return deps
+}
+
+func submatches(re *regexp.Regexp, contents string) []string {
+	var refs []string
+	for _, m := range re.FindAllStringSubmatch(contents, -1) {
+		refs = append(refs, m[1])
+	}
+	return refs
+}
+
+// goModRequires returns the module path of each requirement in a go.mod file.
+func goModRequires(contents string) []string {
+	var refs []string
+	inBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			refs = append(refs, fields[0])
+		case fields[0] == "require" && len(fields) >= 2 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "require" && len(fields) >= 2:
+			refs = append(refs, fields[1])
+		}
+	}
+	return refs
+}
+
+// packageJSONRefs returns each dependency in a package.json file that refers
+// to a repository rather than a version.
+func packageJSONRefs(contents string) []string {
+	var pkg struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal([]byte(contents), &pkg); err != nil {
+		return nil
+	}
+	var refs []string
+	for _, deps := range []map[string]string{pkg.Dependencies, pkg.OptionalDependencies} {
+		for _, spec := range deps {
+			spec = strings.TrimSpace(spec)
+			switch {
+			case strings.HasPrefix(spec, "github:"):
+				refs = append(refs, "github.com/"+strings.TrimPrefix(spec, "github:"))
+			case strings.HasPrefix(spec, "gitlab:"):
+				refs = append(refs, "gitlab.com/"+strings.TrimPrefix(spec, "gitlab:"))
+			case strings.HasPrefix(spec, "bitbucket:"):
+				refs = append(refs, "bitbucket.org/"+strings.TrimPrefix(spec, "bitbucket:"))
+			case strings.Contains(spec, "://") || strings.HasPrefix(spec, "git@"):
+				refs = append(refs, spec)
+			case isGitHubShorthand(spec):
+				// npm treats "owner/repo" as a GitHub repository.
+				refs = append(refs, "github.com/"+spec)
+			}
+		}
+	}
+	return refs
+}
+
+func isGitHubShorthand(spec string) bool {
+	if strings.ContainsAny(spec, ":@ ") || strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") {
+		return false
+	}
+	return strings.Count(strings.SplitN(spec, "#", 2)[0], "/") == 1
+}
+
+// ProjectForRef returns the deps.dev project for a reference to a repository,
+// which may be a url, an scp-like git address (e.g. "git@github.com:o/r.git"),
+// or a host and path (e.g. "github.com/o/r/v2").
+func ProjectForRef(ref string) (depgraph.Project, bool) {
+	ref = strings.TrimPrefix(ref, "git+")
+	if strings.HasPrefix(ref, "git@") {
+		ref = "ssh://" + strings.Replace(ref, ":", "/", 1)
+	}
+	if !strings.Contains(ref, "://") {
+		ref = "https://" + ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return depgraph.Project{}, false
+	}
+	p, ok := depsdev.ProjectForURL(u)
+	if !ok {
+		return depgraph.Project{}, false
+	}
+	// Remove any revision (e.g. "o/r.git@main"), any trailing ".git" or Go
+	// major version, and any path within a GitHub or Bitbucket repository,
+	// such as a Go module in a subdirectory.
+	p.Name, _, _ = strings.Cut(p.Name, "@")
+	p.Name = goMajorVersionRe.ReplaceAllString(strings.TrimSuffix(p.Name, ".git"), "")
+	if p.Type != "GITLAB" {
+		parts := strings.SplitN(p.Name, "/", 3)
+		if len(parts) < 2 {
+			return depgraph.Project{}, false
+		}
+		p.Name = strings.TrimSuffix(parts[0]+"/"+parts[1], ".git")
+	}
+	return p, true
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawler_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/crawler"
+	"github.com/ossf/criticality_score/internal/depgraph"
+)
+
+func gh(name string) depgraph.Project {
+	return depgraph.Project{Type: "GITHUB", Name: name}
+}
+
+func TestParseManifest(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name     string
+		filename string
+		contents string
+		want     []depgraph.Project
+	}{
+		{
+			name:     "go.mod",
+			filename: "go.mod",
+			contents: `module github.com/example/example
+
+go 1.19
+
+require github.com/google/go-cmp v0.5.9
+
+require (
+	github.com/ossf/scorecard/v4 v4.10.5
+	github.com/ossf/scorecard/v4 v4.10.5 // indirect
+	gitlab.com/group/subgroup/project/v2 v2.0.0
+	bitbucket.org/owner/repo/sub/pkg v1.0.0
+	golang.org/x/exp v0.0.0
+)
+`,
+			want: []depgraph.Project{
+				gh("google/go-cmp"),
+				gh("ossf/scorecard"),
+				{Type: "GITLAB", Name: "group/subgroup/project"},
+				{Type: "BITBUCKET", Name: "owner/repo"},
+			},
+		},
+		{
+			name:     "package.json",
+			filename: "package.json",
+			contents: `{
+  "name": "example",
+  "dependencies": {
+    "a": "^1.0.0",
+    "b": "github:owner/b#v1",
+    "c": "owner/c",
+    "d": "git+https://github.com/owner/d.git",
+    "e": "git@github.com:owner/e.git",
+    "f": "file:../f",
+    "g": "gitlab:group/g"
+  },
+  "devDependencies": {
+    "h": "owner/h"
+  }
+}`,
+			want: []depgraph.Project{
+				gh("owner/b"),
+				gh("owner/c"),
+				gh("owner/d"),
+				gh("owner/e"),
+				{Type: "GITLAB", Name: "group/g"},
+			},
+		},
+		{
+			name:     "Cargo.toml",
+			filename: "Cargo.toml",
+			contents: `[package]
+name = "example"
+repository = "https://github.com/example/example"
+
+[dependencies]
+serde = "1.0"
+regex = { git = "https://github.com/rust-lang/regex" }
+`,
+			want: []depgraph.Project{gh("rust-lang/regex")},
+		},
+		{
+			name:     "pyproject.toml",
+			filename: "pyproject.toml",
+			contents: `[project]
+dependencies = [
+  "requests>=2",
+  "example @ git+https://github.com/owner/example.git@main",
+]
+`,
+			want: []depgraph.Project{gh("owner/example")},
+		},
+		{
+			name:     "invalid package.json",
+			filename: "package.json",
+			contents: "{",
+		},
+		{
+			name:     "unknown manifest",
+			filename: "README.md",
+			contents: "github.com/owner/repo",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := crawler.ParseManifest(test.filename, test.contents)
+			sortProjects := cmpopts.SortSlices(func(a, b depgraph.Project) bool { return a.Name < b.Name })
+			if diff := cmp.Diff(test.want, got, sortProjects, cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("ParseManifest() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
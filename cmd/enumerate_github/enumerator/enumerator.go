@@ -0,0 +1,94 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package enumerator defines the interface used to enumerate repositories
+// from a source code host.
+package enumerator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+)
+
+// Repo is a single repository, along with the metadata returned when it was
+// enumerated. Any of the metadata may be unset if it is not known.
+type Repo struct {
+	URL       string
+	Stars     int
+	Language  string
+	CreatedAt time.Time
+	PushedAt  time.Time
+
+	// Ecosystem and Package identify the package the repository was found
+	// through, if it was enumerated from a package registry.
+	Ecosystem string
+	Package   string
+}
+
+// Enumerator is implemented for each source code host that repositories can
+// be enumerated from.
+type Enumerator interface {
+	// Enumerate calls emitter once for each repository found. emitter is only
+	// ever called from a single goroutine.
+	Enumerate(ctx context.Context, emitter func(repo Repo)) error
+}
+
+// Host identifies a source code host with an Enumerator implementation.
+type Host int
+
+const (
+	// HostGitHub enumerates repositories using GitHub's search API.
+	HostGitHub = Host(iota)
+
+	// HostGitLab enumerates projects using GitLab's projects API.
+	HostGitLab
+)
+
+var ErrorUnknownHost = errors.New("unknown host")
+
+// String implements the fmt.Stringer interface.
+func (h Host) String() string {
+	text, err := h.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (h Host) MarshalText() ([]byte, error) {
+	switch h {
+	case HostGitHub:
+		return []byte("github"), nil
+	case HostGitLab:
+		return []byte("gitlab"), nil
+	default:
+		return []byte{}, ErrorUnknownHost
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (h *Host) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("github")):
+		*h = HostGitHub
+	case bytes.Equal(text, []byte("gitlab")):
+		*h = HostGitLab
+	default:
+		return ErrorUnknownHost
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enumerator_test
+
+import (
+	"testing"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+)
+
+func TestHostUnmarshalText(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		input   string
+		want    enumerator.Host
+		wantErr bool
+	}{
+		{input: "github", want: enumerator.HostGitHub},
+		{input: "gitlab", want: enumerator.HostGitLab},
+		{input: "bitbucket", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var got enumerator.Host
+			err := got.UnmarshalText([]byte(test.input))
+			if test.wantErr && err == nil {
+				t.Fatal("UnmarshalText() = nil, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("UnmarshalText() = %v, want no error", err)
+			}
+			if got != test.want {
+				t.Fatalf("UnmarshalText() parsed %s, want %s", got, test.want)
+			}
+		})
+	}
+}
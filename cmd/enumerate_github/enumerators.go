@@ -0,0 +1,97 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/shurcooL/githubv4"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/gitlabsearch"
+)
+
+// gitLabTokenEnvVar is the environment variable used to authenticate with
+// GitLab. The same variable is used by scorecard.
+const gitLabTokenEnvVar = "GITLAB_AUTH_TOKEN"
+
+// githubEnumerator implements enumerator.Enumerator by searching each day
+// between -start and -end using GitHub's search API.
+type githubEnumerator struct {
+	client *githubv4.Client
+	logger *zap.Logger
+}
+
+// Enumerate implements the enumerator.Enumerator interface.
+func (e *githubEnumerator) Enumerate(ctx context.Context, emitter func(enumerator.Repo)) error {
+	search(ctx, e.client, e.logger, repoFilter, func(ranges chan searchRange) {
+		// Work happens here. Iterate through the dates from today, until the start date.
+		for created := endDateFlag.Time(); !startDateFlag.Time().After(created); created = created.Add(-oneDay) {
+			r := searchRange{
+				query:    searchQuery(),
+				start:    created,
+				end:      created.Add(oneDay),
+				minStars: *minStarsFlag,
+				maxStars: -1,
+			}
+			if checkpoints != nil {
+				r.key = created.Format(githubDateFormat)
+				if checkpoints.state.IsDone(r.key) {
+					e.logger.With(
+						zap.String("created", r.key),
+					).Debug("Skipping completed day")
+					continue
+				}
+				if p, ok := checkpoints.state.Progress(r.key); ok {
+					r.progress = &p
+				}
+			}
+			e.logger.With(
+				zap.String("created", created.Format(githubDateFormat)),
+			).Info("Scheduling day for enumeration")
+			ranges <- r
+		}
+	}, emitter)
+	return nil
+}
+
+// gitlabEnumerator implements enumerator.Enumerator by listing the projects
+// with their last activity between -start and -end using GitLab's projects
+// API.
+type gitlabEnumerator struct {
+	s *gitlabsearch.Searcher
+}
+
+func newGitLabEnumerator(logger *zap.Logger) *gitlabEnumerator {
+	httpClient := &http.Client{
+		Transport: gitlabsearch.NewRetryRoundTripper(http.DefaultTransport, logger),
+	}
+	options := []gitlabsearch.Option{
+		gitlabsearch.BaseURL(*gitlabURLFlag),
+		gitlabsearch.PerPage(reposPerPage),
+	}
+	if token := os.Getenv(gitLabTokenEnvVar); token != "" {
+		options = append(options, gitlabsearch.Token(token))
+	}
+	return &gitlabEnumerator{s: gitlabsearch.NewSearcher(httpClient, logger, options...)}
+}
+
+// Enumerate implements the enumerator.Enumerator interface.
+func (e *gitlabEnumerator) Enumerate(ctx context.Context, emitter func(enumerator.Repo)) error {
+	return e.s.ReposByActivity(ctx, startDateFlag.Time(), endDateFlag.Time().Add(oneDay), *minStarsFlag, emitter)
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/internal/infile"
+)
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readOwners reads the list of orgs or users in filename.
+func readOwners(ctx context.Context, filename string) (map[string]bool, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	r, err := infile.Open(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	owners, err := githubsearch.ReadOwners(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return owners, nil
+}
+
+// searchQuery returns -query with the qualifiers needed for the search to
+// return the repositories allowed by -forks.
+func searchQuery() string {
+	return githubsearch.ForkQuery(*queryFlag, forksFlag)
+}
+
+// newRepoFilter returns a filter based on the filter flags, or nil if none of
+// them are set.
+func newRepoFilter(ctx context.Context) (*githubsearch.RepoFilter, error) {
+	f := &githubsearch.RepoFilter{
+		Forks:     forksFlag,
+		Archived:  archivedFlag,
+		Mirrors:   mirrorsFlag,
+		Languages: splitList(*languagesFlag),
+		Topics:    splitList(*topicsFlag),
+		Licenses:  splitList(*licensesFlag),
+	}
+	var err error
+	if f.AllowOwners, err = readOwners(ctx, *ownerAllowlistFlag); err != nil {
+		return nil, err
+	}
+	if f.DenyOwners, err = readOwners(ctx, *ownerDenylistFlag); err != nil {
+		return nil, err
+	}
+	if f.Forks == githubsearch.InclusionInclude &&
+		f.Archived == githubsearch.InclusionInclude &&
+		f.Mirrors == githubsearch.InclusionInclude &&
+		len(f.Languages) == 0 && len(f.Topics) == 0 && len(f.Licenses) == 0 &&
+		len(f.AllowOwners) == 0 && len(f.DenyOwners) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubsearch
+
+import (
+	"fmt"
+
+	"github.com/shurcooL/githubv4"
+	"go.uber.org/zap"
+)
+
+// Manifest filenames that are fetched from the root of each candidate.
+const (
+	ManifestPackageJSON   = "package.json"
+	ManifestCargoToml     = "Cargo.toml"
+	ManifestPyprojectToml = "pyproject.toml"
+	ManifestSetupCfg      = "setup.cfg"
+	ManifestSetupPy       = "setup.py"
+	ManifestGoMod         = "go.mod"
+)
+
+type manifestBlob struct {
+	Blob struct {
+		Text string
+	} `graphql:"... on Blob"`
+}
+
+type candidateRepo struct {
+	URL            string
+	Name           string
+	StargazerCount int
+	PackageJSON    manifestBlob `graphql:"packageJSON: object(expression: \"HEAD:package.json\")"`
+	CargoToml      manifestBlob `graphql:"cargoToml: object(expression: \"HEAD:Cargo.toml\")"`
+	PyprojectToml  manifestBlob `graphql:"pyprojectToml: object(expression: \"HEAD:pyproject.toml\")"`
+	SetupCfg       manifestBlob `graphql:"setupCfg: object(expression: \"HEAD:setup.cfg\")"`
+	SetupPy        manifestBlob `graphql:"setupPy: object(expression: \"HEAD:setup.py\")"`
+	GoMod          manifestBlob `graphql:"goMod: object(expression: \"HEAD:go.mod\")"`
+}
+
+type candidateQuery struct {
+	Search struct {
+		Nodes []struct {
+			Repository candidateRepo `graphql:"...on Repository"`
+		}
+	} `graphql:"search(type: REPOSITORY, query: $query, first: $perPage)"`
+}
+
+// Candidate is a repository returned by a search, along with the package
+// manifests found in the root of the repository.
+type Candidate struct {
+	URL   string
+	Name  string
+	Stars int
+
+	// Manifests maps the filename of each manifest found to its contents.
+	Manifests map[string]string
+}
+
+// Candidates returns up to limit repositories matching query, in the order
+// returned by GitHub's search.
+func (re *Searcher) Candidates(query string, limit int) ([]Candidate, error) {
+	re.logger.With(
+		zap.String("query", query),
+	).Debug("Searching GitHub for candidates")
+	vars := map[string]any{
+		"query":   githubv4.String(query),
+		"perPage": githubv4.Int(limit),
+	}
+	var q candidateQuery
+	if err := re.client.Query(re.ctx, &q, vars); err != nil {
+		return nil, fmt.Errorf("candidate search query '%s' failed: %w", query, err)
+	}
+	var candidates []Candidate
+	for _, n := range q.Search.Nodes {
+		r := n.Repository
+		candidates = append(candidates, Candidate{
+			URL:       r.URL,
+			Name:      r.Name,
+			Stars:     r.StargazerCount,
+			Manifests: r.manifests(),
+		})
+	}
+	return candidates, nil
+}
+
+// manifests returns the contents of each manifest found in r, keyed by
+// filename.
+func (r candidateRepo) manifests() map[string]string {
+	manifests := make(map[string]string)
+	for name, b := range map[string]manifestBlob{
+		ManifestPackageJSON:   r.PackageJSON,
+		ManifestCargoToml:     r.CargoToml,
+		ManifestPyprojectToml: r.PyprojectToml,
+		ManifestSetupCfg:      r.SetupCfg,
+		ManifestSetupPy:       r.SetupPy,
+		ManifestGoMod:         r.GoMod,
+	} {
+		if b.Blob.Text != "" {
+			manifests[name] = b.Blob.Text
+		}
+	}
+	return manifests
+}
+
+type manifestQuery struct {
+	Repository candidateRepo `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// Manifests returns the contents of the package manifests found in the root
+// of the repository owner/name, keyed by filename.
+func (re *Searcher) Manifests(owner, name string) (map[string]string, error) {
+	vars := map[string]any{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(name),
+	}
+	var q manifestQuery
+	if err := re.client.Query(re.ctx, &q, vars); err != nil {
+		return nil, fmt.Errorf("manifest query for '%s/%s' failed: %w", owner, name, err)
+	}
+	return q.Repository.manifests(), nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubsearch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Inclusion controls whether repositories with a given property are included.
+type Inclusion int
+
+const (
+	// InclusionInclude includes repositories with or without the property.
+	InclusionInclude = Inclusion(iota)
+
+	// InclusionExclude excludes repositories with the property.
+	InclusionExclude
+
+	// InclusionOnly only includes repositories with the property.
+	InclusionOnly
+)
+
+var ErrorUnknownInclusion = errors.New("unknown inclusion")
+
+// String implements the fmt.Stringer interface.
+func (i Inclusion) String() string {
+	text, err := i.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (i Inclusion) MarshalText() ([]byte, error) {
+	switch i {
+	case InclusionInclude:
+		return []byte("include"), nil
+	case InclusionExclude:
+		return []byte("exclude"), nil
+	case InclusionOnly:
+		return []byte("only"), nil
+	default:
+		return []byte{}, ErrorUnknownInclusion
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (i *Inclusion) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("include")):
+		*i = InclusionInclude
+	case bytes.Equal(text, []byte("exclude")):
+		*i = InclusionExclude
+	case bytes.Equal(text, []byte("only")):
+		*i = InclusionOnly
+	default:
+		return ErrorUnknownInclusion
+	}
+	return nil
+}
+
+func (i Inclusion) allows(has bool) bool {
+	switch i {
+	case InclusionExclude:
+		return !has
+	case InclusionOnly:
+		return has
+	default:
+		return true
+	}
+}
+
+// ForkQuery returns the search query q with the qualifier needed for forks to
+// be returned when forks is InclusionInclude or InclusionOnly.
+//
+// GitHub leaves forks out of repository search results unless the query
+// contains "fork:true", so without it forks could never be included. If q
+// already has a fork qualifier it is returned unchanged.
+func ForkQuery(q string, forks Inclusion) string {
+	if forks == InclusionExclude || strings.Contains(q, "fork:") {
+		return q
+	}
+	return q + " fork:true"
+}
+
+// The reasons a repository can be excluded by a RepoFilter.
+const (
+	ExcludedFork     = "fork"
+	ExcludedArchived = "archived"
+	ExcludedMirror   = "mirror"
+	ExcludedLanguage = "language"
+	ExcludedTopic    = "topic"
+	ExcludedLicense  = "license"
+	ExcludedOwner    = "owner"
+)
+
+// RepoFilter filters the repositories returned by a search, for properties
+// that can not be expressed in a GitHub search query.
+//
+// A RepoFilter is safe to share between Searchers, and counts the number of
+// repositories excluded for each reason.
+type RepoFilter struct {
+	Forks    Inclusion
+	Archived Inclusion
+	Mirrors  Inclusion
+
+	// Languages, Topics and Licenses, if not empty, require a repository to
+	// have one of the primary languages, at least one of the topics, and one
+	// of the SPDX license IDs respectively. They are compared ignoring case.
+	Languages []string
+	Topics    []string
+	Licenses  []string
+
+	// AllowOwners, if not empty, only includes repositories owned by the
+	// orgs or users in the set. DenyOwners excludes repositories owned by
+	// the orgs or users in the set. Keys must be lowercase.
+	AllowOwners map[string]bool
+	DenyOwners  map[string]bool
+
+	mu       sync.Mutex
+	excluded map[string]int
+}
+
+// Excluded returns the number of repositories excluded for each reason.
+func (f *RepoFilter) Excluded() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	excluded := make(map[string]int, len(f.excluded))
+	for k, v := range f.excluded {
+		excluded[k] = v
+	}
+	return excluded
+}
+
+// include returns true if r should be included. Otherwise the reason r was
+// excluded is counted.
+func (f *RepoFilter) include(r repo) bool {
+	reason := f.reason(r)
+	if reason == "" {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.excluded == nil {
+		f.excluded = make(map[string]int)
+	}
+	f.excluded[reason]++
+	return false
+}
+
+// reason returns why r is excluded, or an empty string if it is included.
+func (f *RepoFilter) reason(r repo) string {
+	owner := strings.ToLower(r.Owner.Login)
+	switch {
+	case !f.Forks.allows(r.IsFork):
+		return ExcludedFork
+	case !f.Archived.allows(r.IsArchived):
+		return ExcludedArchived
+	case !f.Mirrors.allows(r.IsMirror):
+		return ExcludedMirror
+	case len(f.AllowOwners) > 0 && !f.AllowOwners[owner], f.DenyOwners[owner]:
+		return ExcludedOwner
+	case len(f.Languages) > 0 && !containsFold(f.Languages, r.PrimaryLanguage.Name):
+		return ExcludedLanguage
+	case len(f.Licenses) > 0 && !containsFold(f.Licenses, r.LicenseInfo.SpdxID):
+		return ExcludedLicense
+	case len(f.Topics) > 0 && !hasTopic(f.Topics, r):
+		return ExcludedTopic
+	default:
+		return ""
+	}
+}
+
+func hasTopic(topics []string, r repo) bool {
+	for _, n := range r.RepositoryTopics.Nodes {
+		if containsFold(topics, n.Topic.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOwners reads a list of orgs or users from r, one per line, for use in
+// RepoFilter.AllowOwners or RepoFilter.DenyOwners. Blank lines and lines
+// starting with "#" are ignored.
+func ReadOwners(r io.Reader) (map[string]bool, error) {
+	owners := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		owner := strings.TrimSpace(scanner.Text())
+		if owner == "" || strings.HasPrefix(owner, "#") {
+			continue
+		}
+		owners[strings.ToLower(owner)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubsearch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testRepo(owner, language, license string, topics ...string) repo {
+	r := repo{URL: "https://github.com/" + owner + "/example"}
+	r.Owner.Login = owner
+	r.PrimaryLanguage.Name = language
+	r.LicenseInfo.SpdxID = license
+	for _, topic := range topics {
+		n := r.RepositoryTopics.Nodes
+		r.RepositoryTopics.Nodes = append(n, struct{ Topic struct{ Name string } }{})
+		r.RepositoryTopics.Nodes[len(n)].Topic.Name = topic
+	}
+	return r
+}
+
+func TestRepoFilterReason(t *testing.T) {
+	fork := testRepo("example", "Go", "MIT")
+	fork.IsFork = true
+	archived := testRepo("example", "Go", "MIT")
+	archived.IsArchived = true
+
+	//nolint:govet
+	tests := []struct {
+		name   string
+		filter *RepoFilter
+		r      repo
+		want   string
+	}{
+		{
+			name:   "no filter",
+			filter: &RepoFilter{},
+			r:      fork,
+		},
+		{
+			name:   "exclude forks",
+			filter: &RepoFilter{Forks: InclusionExclude},
+			r:      fork,
+			want:   ExcludedFork,
+		},
+		{
+			name:   "only forks",
+			filter: &RepoFilter{Forks: InclusionOnly},
+			r:      archived,
+			want:   ExcludedFork,
+		},
+		{
+			name:   "exclude archived",
+			filter: &RepoFilter{Archived: InclusionExclude},
+			r:      archived,
+			want:   ExcludedArchived,
+		},
+		{
+			name:   "language match",
+			filter: &RepoFilter{Languages: []string{"rust", "go"}},
+			r:      testRepo("example", "Go", "MIT"),
+		},
+		{
+			name:   "language mismatch",
+			filter: &RepoFilter{Languages: []string{"rust"}},
+			r:      testRepo("example", "Go", "MIT"),
+			want:   ExcludedLanguage,
+		},
+		{
+			name:   "no language",
+			filter: &RepoFilter{Languages: []string{"go"}},
+			r:      testRepo("example", "", "MIT"),
+			want:   ExcludedLanguage,
+		},
+		{
+			name:   "license mismatch",
+			filter: &RepoFilter{Licenses: []string{"apache-2.0"}},
+			r:      testRepo("example", "Go", "MIT"),
+			want:   ExcludedLicense,
+		},
+		{
+			name:   "topic match",
+			filter: &RepoFilter{Topics: []string{"security"}},
+			r:      testRepo("example", "Go", "MIT", "cli", "Security"),
+		},
+		{
+			name:   "topic mismatch",
+			filter: &RepoFilter{Topics: []string{"security"}},
+			r:      testRepo("example", "Go", "MIT", "cli"),
+			want:   ExcludedTopic,
+		},
+		{
+			name:   "owner allowed",
+			filter: &RepoFilter{AllowOwners: map[string]bool{"ossf": true}},
+			r:      testRepo("OSSF", "Go", "MIT"),
+		},
+		{
+			name:   "owner not allowed",
+			filter: &RepoFilter{AllowOwners: map[string]bool{"ossf": true}},
+			r:      testRepo("example", "Go", "MIT"),
+			want:   ExcludedOwner,
+		},
+		{
+			name:   "owner denied",
+			filter: &RepoFilter{DenyOwners: map[string]bool{"example": true}},
+			r:      testRepo("Example", "Go", "MIT"),
+			want:   ExcludedOwner,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.reason(test.r); got != test.want {
+				t.Fatalf("reason() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestForkQuery(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name  string
+		q     string
+		forks Inclusion
+		want  string
+	}{
+		{name: "include", q: "is:public", forks: InclusionInclude, want: "is:public fork:true"},
+		{name: "only", q: "is:public", forks: InclusionOnly, want: "is:public fork:true"},
+		{name: "exclude", q: "is:public", forks: InclusionExclude, want: "is:public"},
+		{name: "already qualified", q: "is:public fork:false", forks: InclusionInclude, want: "is:public fork:false"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ForkQuery(test.q, test.forks); got != test.want {
+				t.Fatalf("ForkQuery() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRepoFilterExcluded(t *testing.T) {
+	f := &RepoFilter{Languages: []string{"go"}, DenyOwners: map[string]bool{"example": true}}
+	f.include(testRepo("ossf", "Go", ""))
+	f.include(testRepo("ossf", "C", ""))
+	f.include(testRepo("ossf", "Rust", ""))
+	f.include(testRepo("example", "Go", ""))
+
+	want := map[string]int{ExcludedLanguage: 2, ExcludedOwner: 1}
+	if diff := cmp.Diff(want, f.Excluded()); diff != "" {
+		t.Fatalf("Excluded() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadOwners(t *testing.T) {
+	got, err := ReadOwners(strings.NewReader("# Comment\nossf\n\n  Example \n"))
+	if err != nil {
+		t.Fatalf("ReadOwners() = %v, want no error", err)
+	}
+	want := map[string]bool{"ossf": true, "example": true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ReadOwners() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubsearch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+	"github.com/ossf/criticality_score/internal/githubapi/pagination"
+)
+
+const (
+	// maxSearchResults is the maximum number of results GitHub will return for
+	// a single search query, regardless of the total number of matches.
+	maxSearchResults = 1000
+
+	// minPartitionSpan is the shortest created time range a partition is split
+	// into before the star range is split instead.
+	minPartitionSpan = time.Minute
+
+	createdTimeFormat = "2006-01-02T15:04:05Z"
+)
+
+// Partition is a subset of the search space, limited to repositories created
+// in [Start, End) with between MinStars and MaxStars stars.
+type Partition struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	MinStars int       `json:"min_stars"`
+	// MaxStars is the maximum number of stars, or -1 for no maximum.
+	MaxStars int `json:"max_stars"`
+}
+
+func (p Partition) query(baseQuery string) string {
+	// The created range is inclusive, so exclude the last second.
+	q := baseQuery + fmt.Sprintf(" created:%s..%s",
+		p.Start.UTC().Format(createdTimeFormat),
+		p.End.Add(-time.Second).UTC().Format(createdTimeFormat))
+	return buildQuery(q, p.MinStars, p.MaxStars)
+}
+
+// split divides p into two smaller partitions.
+//
+// The created range is halved until it is shorter than minPartitionSpan, after
+// which the star range is halved. topStars is the number of stars of the most
+// starred repository in p, and is used if p has no maximum. The lower half of
+// the star range is extended by overlap stars to avoid missing repositories
+// whose star count changes during enumeration.
+//
+// If p can not be split any further false is returned.
+func (p Partition) split(topStars, overlap int) ([]Partition, bool) {
+	if span := p.End.Sub(p.Start); span > minPartitionSpan {
+		mid := p.Start.Add(span / 2).Truncate(time.Second)
+		return []Partition{
+			{Start: p.Start, End: mid, MinStars: p.MinStars, MaxStars: p.MaxStars},
+			{Start: mid, End: p.End, MinStars: p.MinStars, MaxStars: p.MaxStars},
+		}, true
+	}
+	maxStars := p.MaxStars
+	if maxStars < 0 {
+		maxStars = topStars
+	}
+	if maxStars <= p.MinStars {
+		return nil, false
+	}
+	mid := p.MinStars + (maxStars-p.MinStars)/2
+	lowerMax := mid + overlap
+	if lowerMax >= maxStars {
+		// The overlap would stop the lower half from being any smaller.
+		lowerMax = mid
+	}
+	return []Partition{
+		{Start: p.Start, End: p.End, MinStars: p.MinStars, MaxStars: lowerMax},
+		// Keep the upper half unbounded if p was, so repositories that gain
+		// stars are still included.
+		{Start: p.Start, End: p.End, MinStars: mid + 1, MaxStars: p.MaxStars},
+	}, true
+}
+
+// Progress records how far a search by partition has got, so that it can be
+// continued later using ResumeReposByPartition.
+type Progress struct {
+	// Pending are the partitions that have not been completed. The last
+	// partition is the next to be searched.
+	Pending []Partition `json:"pending"`
+
+	// Position is the position in the results of the last pending partition,
+	// if it has been started.
+	Position *pagination.Position `json:"position,omitempty"`
+
+	// Incomplete is set if a completed partition had too many results to
+	// list.
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// NewProgress returns the Progress for a search of repositories with between
+// minStars and maxStars, created between start and end, that has not started.
+// If maxStars is -1 there is no maximum.
+func NewProgress(start, end time.Time, minStars, maxStars int) Progress {
+	return Progress{
+		Pending: []Partition{{Start: start, End: end, MinStars: minStars, MaxStars: maxStars}},
+	}
+}
+
+// Done returns true if there are no partitions left to search.
+func (p Progress) Done() bool {
+	return len(p.Pending) == 0
+}
+
+// ReposByPartition will call emitter once for each repository returned when
+// searching for baseQuery with between minStars and maxStars, created between
+// start and end. If maxStars is -1 there is no maximum.
+//
+// The emitter function is called with the repository's Url and metadata.
+//
+// The algorithm works to overcome the approx 1000 repository limit returned by
+// a single search by adaptively partitioning the search:
+//   - Each partition is queried, and if it has fewer than 1000 results all the
+//     repositories are returned.
+//   - Otherwise the partition's created range is bisected, until the range is
+//     shorter than a minute.
+//   - After that the partition's star range is bisected, using the star count
+//     of the most starred repository as the upper bound if needed.
+//
+// Repositories returned by more than one partition are only emitted once.
+// Repositories excluded by the Searcher's filter are not emitted.
+//
+// If a partition still has too many results but can not be split any further,
+// the first 1000 results are emitted and ErrorUnableToListAllResult is
+// returned after all other partitions are complete.
+func (re *Searcher) ReposByPartition(baseQuery string, start, end time.Time, minStars, maxStars, overlap int, emitter func(enumerator.Repo)) error {
+	return re.ResumeReposByPartition(baseQuery, NewProgress(start, end, minStars, maxStars), overlap, emitter, nil)
+}
+
+// ResumeReposByPartition continues the search for baseQuery described by
+// progress, in the same way as ReposByPartition.
+//
+// If checkpoint is not nil it is called with the progress of the search after
+// each page of results has been passed to emitter, and after each partition
+// is split or completed. Repositories emitted after the last checkpoint may be
+// emitted again if the search is resumed from it.
+func (re *Searcher) ResumeReposByPartition(baseQuery string, progress Progress, overlap int, emitter func(enumerator.Repo), checkpoint func(Progress)) error {
+	repos := make(map[string]empty)
+	pending := append([]Partition(nil), progress.Pending...)
+	pos := progress.Position
+	incomplete := progress.Incomplete
+
+	save := func(pos *pagination.Position) {
+		if checkpoint != nil {
+			checkpoint(Progress{
+				Pending:    append([]Partition(nil), pending...),
+				Position:   pos,
+				Incomplete: incomplete,
+			})
+		}
+	}
+
+	for len(pending) > 0 {
+		p := pending[len(pending)-1]
+		q := p.query(baseQuery)
+
+		var c *pagination.Cursor
+		var err error
+		if pos != nil {
+			// The partition was already started, so it is known not to need
+			// splitting.
+			re.logger.With(
+				zap.String("query", q),
+				zap.Int("offset", pos.Offset),
+			).Debug("Resuming partition")
+			c, err = re.resumeRepoQuery(q, *pos)
+			pos = nil
+			if err != nil {
+				return err
+			}
+		} else {
+			c, err = re.runRepoQuery(q)
+			if err != nil {
+				return err
+			}
+			total := c.Total()
+
+			if total > maxSearchResults {
+				// Results are sorted by stars, so the first result has the most.
+				topStars := 0
+				if obj, err := c.Next(); err == nil && obj != nil {
+					topStars = obj.(repo).StargazerCount
+				}
+				if parts, ok := p.split(topStars, overlap); ok {
+					re.logger.With(
+						zap.Int("total_available", total),
+						zap.String("query", q),
+					).Debug("Splitting partition")
+					pending = append(pending[:len(pending)-1], parts...)
+					save(nil)
+					continue
+				}
+				re.logger.With(
+					zap.Error(ErrorUnableToListAllResult),
+					zap.Int("total_available", total),
+					zap.String("query", q),
+				).Error("Too many repositories for partition")
+				incomplete = true
+				// Re-run the query to emit as many results as possible.
+				if c, err = re.runRepoQuery(q); err != nil {
+					return err
+				}
+			}
+		}
+
+		seen := 0
+		for {
+			obj, err := c.Next()
+			if obj == nil && errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			repo := obj.(repo)
+			seen++
+			if _, ok := repos[repo.URL]; !ok {
+				repos[repo.URL] = empty{}
+				if re.filter == nil || re.filter.include(repo) {
+					emitter(repo.metadata())
+				}
+			}
+			if seen%re.perPage == 0 {
+				cur := c.Position()
+				save(&cur)
+			}
+		}
+		pending = pending[:len(pending)-1]
+		save(nil)
+		re.logger.With(
+			zap.Int("total_available", c.Total()),
+			zap.Int("total_returned", seen),
+			zap.Int("unique_repos", len(repos)),
+			zap.String("query", q),
+		).Debug("Finished iterating through results")
+	}
+	if incomplete {
+		return ErrorUnableToListAllResult
+	}
+	return nil
+}
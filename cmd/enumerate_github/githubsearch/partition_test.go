@@ -0,0 +1,210 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package githubsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shurcooL/githubv4"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+	"github.com/ossf/criticality_score/internal/githubapi/pagination"
+)
+
+var testDay = time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)
+
+func TestPartitionQuery(t *testing.T) {
+	p := Partition{Start: testDay, End: testDay.Add(24 * time.Hour), MinStars: 10, MaxStars: -1}
+	want := "is:public created:2022-06-14T00:00:00Z..2022-06-14T23:59:59Z sort:stars stars:>=10"
+	if got := p.query("is:public"); got != want {
+		t.Fatalf("query() = %q, want %q", got, want)
+	}
+
+	p.MaxStars = 20
+	want = "is:public created:2022-06-14T00:00:00Z..2022-06-14T23:59:59Z sort:stars stars:10..20"
+	if got := p.query("is:public"); got != want {
+		t.Fatalf("query() = %q, want %q", got, want)
+	}
+}
+
+func TestPartitionSplitCreated(t *testing.T) {
+	p := Partition{Start: testDay, End: testDay.Add(24 * time.Hour), MinStars: 10, MaxStars: -1}
+	got, ok := p.split(100, 5)
+	if !ok {
+		t.Fatal("split() = false, want true")
+	}
+	mid := testDay.Add(12 * time.Hour)
+	want := []Partition{
+		{Start: testDay, End: mid, MinStars: 10, MaxStars: -1},
+		{Start: mid, End: testDay.Add(24 * time.Hour), MinStars: 10, MaxStars: -1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("split() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPartitionSplitStars(t *testing.T) {
+	end := testDay.Add(minPartitionSpan)
+	//nolint:govet
+	tests := []struct {
+		name     string
+		p        Partition
+		topStars int
+		overlap  int
+		want     []Partition
+		wantOK   bool
+	}{
+		{
+			name:     "unbounded",
+			p:        Partition{Start: testDay, End: end, MinStars: 10, MaxStars: -1},
+			topStars: 110,
+			overlap:  5,
+			want: []Partition{
+				{Start: testDay, End: end, MinStars: 10, MaxStars: 65},
+				{Start: testDay, End: end, MinStars: 61, MaxStars: -1},
+			},
+			wantOK: true,
+		},
+		{
+			name:     "bounded",
+			p:        Partition{Start: testDay, End: end, MinStars: 10, MaxStars: 20},
+			topStars: 1000,
+			overlap:  0,
+			want: []Partition{
+				{Start: testDay, End: end, MinStars: 10, MaxStars: 15},
+				{Start: testDay, End: end, MinStars: 16, MaxStars: 20},
+			},
+			wantOK: true,
+		},
+		{
+			name:     "overlap too large",
+			p:        Partition{Start: testDay, End: end, MinStars: 10, MaxStars: 12},
+			topStars: 12,
+			overlap:  5,
+			want: []Partition{
+				{Start: testDay, End: end, MinStars: 10, MaxStars: 11},
+				{Start: testDay, End: end, MinStars: 12, MaxStars: 12},
+			},
+			wantOK: true,
+		},
+		{
+			name:     "single star value",
+			p:        Partition{Start: testDay, End: end, MinStars: 10, MaxStars: -1},
+			topStars: 10,
+			overlap:  5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := test.p.split(test.topStars, test.overlap)
+			if ok != test.wantOK {
+				t.Fatalf("split() = %t, want %t", ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("split() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// fakeSearch serves GitHub GraphQL search queries, returning the same repos
+// for every query. The end cursor of each page is the index of the next repo.
+func fakeSearch(t *testing.T, repos []string) *githubv4.Client {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables struct {
+				PerPage   int     `json:"perPage"`
+				EndCursor *string `json:"endCursor"`
+			} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		start := 0
+		if req.Variables.EndCursor != nil {
+			start, _ = strconv.Atoi(*req.Variables.EndCursor)
+		}
+		end := start + req.Variables.PerPage
+		if end > len(repos) {
+			end = len(repos)
+		}
+		var nodes []map[string]any
+		for _, u := range repos[start:end] {
+			nodes = append(nodes, map[string]any{"url": u, "stargazerCount": 10})
+		}
+		fmt.Fprintf(w, `{"data":{"search":{"nodes":%s,"pageInfo":{"endCursor":"%d","hasNextPage":%t},"repositoryCount":%d}}}`,
+			mustJSON(t, nodes), end, end < len(repos), len(repos))
+	}))
+	t.Cleanup(s.Close)
+	return githubv4.NewEnterpriseClient(s.URL, s.Client())
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() = %v", err)
+	}
+	return string(b)
+}
+
+func TestResumeReposByPartition(t *testing.T) {
+	repos := []string{"https://github.com/a/a", "https://github.com/b/b", "https://github.com/c/c", "https://github.com/d/d", "https://github.com/e/e"}
+	s := NewSearcher(context.Background(), fakeSearch(t, repos), zap.NewNop(), PerPage(2))
+
+	var got []string
+	var checkpoints []Progress
+	emit := func(r enumerator.Repo) { got = append(got, r.URL) }
+	start := NewProgress(testDay, testDay.Add(24*time.Hour), 10, -1)
+	err := s.ResumeReposByPartition("is:public", start, 5, emit, func(p Progress) {
+		checkpoints = append(checkpoints, p)
+	})
+	if err != nil {
+		t.Fatalf("ResumeReposByPartition() = %v, want no error", err)
+	}
+	if diff := cmp.Diff(repos, got); diff != "" {
+		t.Fatalf("ResumeReposByPartition() mismatch (-want +got):\n%s", diff)
+	}
+	wantCheckpoints := []Progress{
+		{Pending: start.Pending, Position: &pagination.Position{Offset: 2}},
+		{Pending: start.Pending, Position: &pagination.Position{After: "2", Offset: 2}},
+		{},
+	}
+	if diff := cmp.Diff(wantCheckpoints, checkpoints); diff != "" {
+		t.Fatalf("checkpoints mismatch (-want +got):\n%s", diff)
+	}
+	if !checkpoints[2].Done() {
+		t.Fatal("Done() = false, want true")
+	}
+
+	// Resuming from a checkpoint only emits the remaining repos.
+	got = nil
+	if err := s.ResumeReposByPartition("is:public", checkpoints[1], 5, emit, nil); err != nil {
+		t.Fatalf("ResumeReposByPartition() = %v, want no error", err)
+	}
+	if diff := cmp.Diff(repos[4:], got); diff != "" {
+		t.Fatalf("ResumeReposByPartition() mismatch (-want +got):\n%s", diff)
+	}
+}
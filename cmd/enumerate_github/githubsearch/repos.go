@@ -15,13 +15,13 @@
 package githubsearch
 
 import (
-	"errors"
 	"fmt"
-	"io"
+	"time"
 
 	"github.com/shurcooL/githubv4"
 	"go.uber.org/zap"
 
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
 	"github.com/ossf/criticality_score/internal/githubapi/pagination"
 )
 
@@ -30,6 +30,38 @@ import (
 type repo struct {
 	URL            string
 	StargazerCount int
+	CreatedAt      time.Time
+	PushedAt       time.Time
+	IsFork         bool
+	IsArchived     bool
+	IsMirror       bool
+	Owner          struct {
+		Login string
+	}
+	PrimaryLanguage struct {
+		Name string
+	}
+	LicenseInfo struct {
+		SpdxID string `graphql:"spdxId"`
+	}
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string
+			}
+		}
+	} `graphql:"repositoryTopics(first: 20)"`
+}
+
+// metadata returns r as an enumerator.Repo.
+func (r repo) metadata() enumerator.Repo {
+	return enumerator.Repo{
+		URL:       r.URL,
+		Stars:     r.StargazerCount,
+		Language:  r.PrimaryLanguage.Name,
+		CreatedAt: r.CreatedAt,
+		PushedAt:  r.PushedAt,
+	}
 }
 
 // repoQuery is a GraphQL query for iterating over repositories in GitHub.
@@ -81,7 +113,7 @@ func (q *repoQuery) NextPageVars() map[string]any {
 
 func buildQuery(q string, minStars, maxStars int) string {
 	q = q + " sort:stars "
-	if maxStars > 0 {
+	if maxStars >= 0 {
 		return q + fmt.Sprintf("stars:%d..%d", minStars, maxStars)
 	} else {
 		return q + fmt.Sprintf("stars:>=%d", minStars)
@@ -103,87 +135,19 @@ func (re *Searcher) runRepoQuery(q string) (*pagination.Cursor, error) {
 	return c, nil
 }
 
-// ReposByStars will call emitter once for each repository returned when searching for baseQuery
-// with at least minStars, order from the most stars, to the least.
-//
-// The emitter function is called with the repository's Url.
-//
-// The algorithm works to overcome the approx 1000 repository limit returned by a single search
-// across 10 pages by:
-// - Ordering GitHub's repositories from most stars to least.
-// - Iterating through all the repositories returned by each query.
-// - Getting the number of stars for the last repository returned.
-// - Using this star value, plus an overlap, to be the maximum star limit.
-//
-// The algorithm fails if the last star value plus overlap has the same or larger value as the
-// previous iteration.
-func (re *Searcher) ReposByStars(baseQuery string, minStars, overlap int, emitter func(string)) error {
-	repos := make(map[string]empty)
-	maxStars := -1
-	stars := 0
-
-	for {
-		q := buildQuery(baseQuery, minStars, maxStars)
-		fmt.Println("q: ", q)
-		c, err := re.runRepoQuery(q)
-		if err != nil {
-			return err
-		}
-
-		total := c.Total()
-		seen := 0
-		stars = 0
-		for {
-			obj, err := c.Next()
-			if obj == nil && errors.Is(err, io.EOF) {
-				break
-			} else if err != nil {
-				return err
-			}
-			repo := obj.(repo)
-			seen++
-			stars = repo.StargazerCount
-			fmt.Println(repo.URL)
-			if _, ok := repos[repo.URL]; !ok {
-				repos[repo.URL] = empty{}
-				emitter(repo.URL)
-			}
-			break
-		}
-		remaining := total - seen
-		re.logger.With(
-			zap.Int("total_available", total),
-			zap.Int("total_returned", seen),
-			zap.Int("total_remaining", remaining),
-			zap.Int("unique_repos", len(repos)),
-			zap.Int("last_stars", stars),
-			zap.String("query", q),
-		).Debug("Finished iterating through results")
-		newMaxStars := stars + overlap
-		switch {
-		case remaining <= 0:
-			// nothing remains, we are done.
-			return nil
-		case maxStars == -1, newMaxStars < maxStars:
-			maxStars = newMaxStars
-		default:
-			// the gap between "stars" and "maxStars" is less than "overlap", so we can't
-			// safely step any lower without skipping stars.
-			re.logger.With(
-				zap.Error(ErrorUnableToListAllResult),
-				zap.Int("min_stars", minStars),
-				zap.Int("stars", stars),
-				zap.Int("max_stars", maxStars),
-				zap.Int("overlap", overlap),
-			).Error("Too many repositories for current range")
-			return ErrorUnableToListAllResult
-		}
-		return nil
+// resumeRepoQuery runs the query q, starting from the result at pos.
+func (re *Searcher) resumeRepoQuery(q string, pos pagination.Position) (*pagination.Cursor, error) {
+	re.logger.With(
+		zap.String("query", q),
+		zap.String("after", pos.After),
+	).Debug("Resuming GitHub search")
+	vars := map[string]any{
+		"query":   githubv4.String(q),
+		"perPage": githubv4.Int(re.perPage),
+	}
+	c, err := pagination.Resume(re.ctx, re.client, &repoQuery{}, vars, pos)
+	if err != nil {
+		return nil, fmt.Errorf("repo search query '%s' failed: %w", q, err)
 	}
+	return c, nil
 }
-
-//func (re *Searcher) ReposByStars(baseQuery string, minStars, overlap int, emitter func(string)) (err error) {
-//	q := baseQuery + "sort:stars"
-//
-//	return
-//}
@@ -32,6 +32,7 @@ type Searcher struct {
 	client  *githubv4.Client
 	logger  *zap.Logger
 	perPage int
+	filter  *RepoFilter
 }
 
 type Option interface {
@@ -48,6 +49,11 @@ func PerPage(perPage int) Option {
 	return option(func(s *Searcher) { s.perPage = perPage })
 }
 
+// Filter will exclude repositories from the results that do not match f.
+func Filter(f *RepoFilter) Option {
+	return option(func(s *Searcher) { s.filter = f })
+}
+
 func NewSearcher(ctx context.Context, client *githubv4.Client, logger *zap.Logger, options ...Option) *Searcher {
 	s := &Searcher{
 		ctx:     ctx,
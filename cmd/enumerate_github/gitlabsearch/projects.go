@@ -0,0 +1,181 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+)
+
+const (
+	// maxOffsetResults is the maximum number of results GitLab will return
+	// using offset pagination for a single list of projects.
+	maxOffsetResults = 50000
+
+	// minWindowSpan is the shortest last activity time range a window is
+	// split into.
+	minWindowSpan = time.Hour
+)
+
+// project is the subset of fields returned by the projects API that are used.
+type project struct {
+	WebURL         string    `json:"web_url"`
+	StarCount      int       `json:"star_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// window limits the projects listed to those with their last activity in
+// [start, end).
+type window struct {
+	start, end time.Time
+}
+
+// listProjects returns a single page of public projects with their last
+// activity in w, sorted by star count. The number of the next page is also
+// returned, or 0 if this is the last page.
+func (re *Searcher) listProjects(ctx context.Context, w window, page int) ([]project, int, error) {
+	v := url.Values{}
+	v.Set("visibility", "public")
+	v.Set("simple", "true")
+	v.Set("order_by", "star_count")
+	v.Set("sort", "desc")
+	v.Set("last_activity_after", w.start.UTC().Format(time.RFC3339))
+	v.Set("last_activity_before", w.end.UTC().Format(time.RFC3339))
+	v.Set("per_page", strconv.Itoa(re.perPage))
+	v.Set("page", strconv.Itoa(page))
+	u := re.baseURL + "/api/v4/projects?" + v.Encode()
+
+	re.logger.With(
+		zap.String("url", u),
+	).Debug("Listing GitLab projects")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if re.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", re.token)
+	}
+	resp, err := re.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("project list '%s' failed: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("project list '%s' failed: %s", u, resp.Status)
+	}
+	var projects []project
+	if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
+		return nil, 0, fmt.Errorf("project list '%s' failed: %w", u, err)
+	}
+	// X-Next-Page is empty on the last page.
+	next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return projects, next, nil
+}
+
+// ReposByActivity will call emitter once for each public project with at
+// least minStars, with its last activity between start and end.
+//
+// The emitter function is called with the project's web URL and metadata. The
+// project's last activity is used as its pushed date.
+//
+// GitLab will only return a limited number of projects for a single list
+// using offset pagination, so the last activity range is divided into
+// windows:
+//   - Each window's projects are listed by star count, until a project with
+//     fewer than minStars is reached.
+//   - If the limit is reached first, the window is bisected and each half is
+//     listed again, until the window is shorter than an hour.
+//
+// Projects returned by more than one window are only emitted once.
+//
+// If a window still has too many results but can not be split any further,
+// the projects that could be listed are emitted and ErrorUnableToListAllResult
+// is returned after all other windows are complete.
+func (re *Searcher) ReposByActivity(ctx context.Context, start, end time.Time, minStars int, emitter func(enumerator.Repo)) error {
+	repos := make(map[string]empty)
+	pending := []window{{start: start, end: end}}
+	incomplete := false
+
+	for len(pending) > 0 {
+		w := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		complete := false
+		seen := 0
+		for page := 1; !complete; {
+			if (page-1)*re.perPage >= re.maxResults {
+				break
+			}
+			projects, next, err := re.listProjects(ctx, w, page)
+			if err != nil {
+				return err
+			}
+			for _, p := range projects {
+				if p.StarCount < minStars {
+					complete = true
+					break
+				}
+				seen++
+				if _, ok := repos[p.WebURL]; !ok {
+					repos[p.WebURL] = empty{}
+					emitter(enumerator.Repo{
+						URL:       p.WebURL,
+						Stars:     p.StarCount,
+						CreatedAt: p.CreatedAt,
+						PushedAt:  p.LastActivityAt,
+					})
+				}
+			}
+			if next == 0 {
+				complete = true
+			}
+			page = next
+		}
+		logger := re.logger.With(
+			zap.Time("start", w.start),
+			zap.Time("end", w.end),
+			zap.Int("total_returned", seen),
+			zap.Int("unique_repos", len(repos)),
+		)
+		if complete {
+			logger.Debug("Finished listing window")
+			continue
+		}
+		if span := w.end.Sub(w.start); span > minWindowSpan {
+			logger.Debug("Splitting window")
+			mid := w.start.Add(span / 2).Truncate(time.Second)
+			pending = append(pending, window{start: w.start, end: mid}, window{start: mid, end: w.end})
+			continue
+		}
+		logger.With(
+			zap.Error(ErrorUnableToListAllResult),
+		).Error("Too many projects for window")
+		incomplete = true
+	}
+	if incomplete {
+		return ErrorUnableToListAllResult
+	}
+	return nil
+}
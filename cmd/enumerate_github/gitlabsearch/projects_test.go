@@ -0,0 +1,155 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+)
+
+var testStart = time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)
+
+type testProject struct {
+	url          string
+	stars        int
+	lastActivity time.Time
+}
+
+// newTestServer returns a server implementing the parts of GitLab's projects
+// API used by the Searcher.
+func newTestServer(t *testing.T, projects []testProject) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/api/v4/projects" || q.Get("order_by") != "star_count" || q.Get("sort") != "desc" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		after, _ := time.Parse(time.RFC3339, q.Get("last_activity_after"))
+		before, _ := time.Parse(time.RFC3339, q.Get("last_activity_before"))
+		perPage, _ := strconv.Atoi(q.Get("per_page"))
+		page, _ := strconv.Atoi(q.Get("page"))
+
+		var matches []project
+		for _, p := range projects {
+			if !p.lastActivity.Before(after) && p.lastActivity.Before(before) {
+				matches = append(matches, project{WebURL: p.url, StarCount: p.stars, LastActivityAt: p.lastActivity})
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].StarCount > matches[j].StarCount })
+
+		start := (page - 1) * perPage
+		if start > len(matches) {
+			start = len(matches)
+		}
+		end := start + perPage
+		if end < len(matches) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		} else {
+			end = len(matches)
+		}
+		json.NewEncoder(w).Encode(matches[start:end])
+	}))
+}
+
+func testProjects(n int) []testProject {
+	var projects []testProject
+	for i := 0; i < n; i++ {
+		projects = append(projects, testProject{
+			url:          "https://gitlab.com/example/project" + strconv.Itoa(i),
+			stars:        100 - i,
+			lastActivity: testStart.Add(time.Duration(i) * 3 * time.Hour),
+		})
+	}
+	return projects
+}
+
+func collect(t *testing.T, s *Searcher, start, end time.Time, minStars int) ([]string, error) {
+	t.Helper()
+	var got []string
+	err := s.ReposByActivity(context.Background(), start, end, minStars, func(repo enumerator.Repo) {
+		got = append(got, repo.URL)
+	})
+	return got, err
+}
+
+func TestReposByActivity(t *testing.T) {
+	projects := testProjects(5)
+	srv := newTestServer(t, projects)
+	defer srv.Close()
+
+	s := NewSearcher(srv.Client(), zap.NewNop(), BaseURL(srv.URL), PerPage(2))
+	got, err := collect(t, s, testStart, testStart.Add(24*time.Hour), 97)
+	if err != nil {
+		t.Fatalf("ReposByActivity() = %v, want no error", err)
+	}
+	want := []string{projects[0].url, projects[1].url, projects[2].url, projects[3].url}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ReposByActivity() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReposByActivitySplitsWindow(t *testing.T) {
+	projects := testProjects(8)
+	srv := newTestServer(t, projects)
+	defer srv.Close()
+
+	s := NewSearcher(srv.Client(), zap.NewNop(), BaseURL(srv.URL), PerPage(1))
+	s.maxResults = 2
+	got, err := collect(t, s, testStart, testStart.Add(24*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("ReposByActivity() = %v, want no error", err)
+	}
+	var want []string
+	for _, p := range projects {
+		want = append(want, p.url)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ReposByActivity() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReposByActivityUnableToListAll(t *testing.T) {
+	projects := testProjects(3)
+	for i := range projects {
+		projects[i].lastActivity = testStart
+	}
+	srv := newTestServer(t, projects)
+	defer srv.Close()
+
+	s := NewSearcher(srv.Client(), zap.NewNop(), BaseURL(srv.URL), PerPage(1))
+	s.maxResults = 2
+	got, err := collect(t, s, testStart, testStart.Add(minWindowSpan), 0)
+	if !errors.Is(err, ErrorUnableToListAllResult) {
+		t.Fatalf("ReposByActivity() = %v, want %v", err, ErrorUnableToListAllResult)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ReposByActivity() emitted %d repos, want 2", len(got))
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabsearch
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/retry"
+)
+
+// NewRetryRoundTripper returns a RoundTripper that retries requests to GitLab
+// that were rate limited or failed with a server error.
+func NewRetryRoundTripper(rt http.RoundTripper, logger *zap.Logger) http.RoundTripper {
+	s := &strategies{logger: logger}
+	return retry.NewRoundTripper(rt,
+		retry.InitialDelay(time.Minute),
+		retry.RetryAfter(s.RetryAfter),
+		retry.Strategy(s.RateLimit),
+		retry.Strategy(s.ServerError),
+	)
+}
+
+type strategies struct {
+	logger *zap.Logger
+}
+
+// RateLimit implements retry.RetryStrategyFn.
+func (s *strategies) RateLimit(r *http.Response) (retry.RetryStrategy, error) {
+	if r.StatusCode != http.StatusTooManyRequests {
+		return retry.NoRetry, nil
+	}
+	s.logger.With(zap.Stringer("url", r.Request.URL)).Warn("429: rate limit hit")
+	return retry.RetryWithInitialDelay, nil
+}
+
+// ServerError implements retry.RetryStrategyFn.
+func (s *strategies) ServerError(r *http.Response) (retry.RetryStrategy, error) {
+	if r.StatusCode < 500 || 600 <= r.StatusCode {
+		return retry.NoRetry, nil
+	}
+	s.logger.With(
+		zap.Stringer("url", r.Request.URL),
+		zap.String("status", r.Status),
+	).Warn("5xx: detected")
+	return retry.RetryImmediate, nil
+}
+
+// RetryAfter implements retry.RetryAfterFn.
+//
+// GitLab sets Retry-After to the number of seconds to wait when a request is
+// rate limited.
+func (s *strategies) RetryAfter(r *http.Response) time.Duration {
+	if v := r.Header.Get("Retry-After"); v != "" {
+		s.logger.Warn("Detected Retry-After header.")
+		retryAfterSeconds, _ := strconv.ParseInt(v, 10, 64) // Error handling is noop.
+		return time.Duration(retryAfterSeconds) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gitlabsearch enumerates public projects using GitLab's projects
+// API.
+package gitlabsearch
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// DefaultBaseURL is the URL of the GitLab instance used if no other is set.
+const DefaultBaseURL = "https://gitlab.com"
+
+// empty is a convenience wrapper for the empty struct.
+type empty struct{}
+
+var ErrorUnableToListAllResult = errors.New("unable to list all results")
+
+type Searcher struct {
+	client     *http.Client
+	logger     *zap.Logger
+	baseURL    string
+	token      string
+	perPage    int
+	maxResults int
+}
+
+type Option interface {
+	set(*Searcher)
+}
+
+// option implements Option.
+type option func(*Searcher)
+
+func (o option) set(s *Searcher) { o(s) }
+
+// BaseURL sets the URL of the GitLab instance to search.
+func BaseURL(u string) Option {
+	return option(func(s *Searcher) { s.baseURL = strings.TrimSuffix(u, "/") })
+}
+
+// Token sets the personal access token used to authenticate requests.
+func Token(token string) Option {
+	return option(func(s *Searcher) { s.token = token })
+}
+
+// PerPage will set how many results will per requested per page for each request.
+func PerPage(perPage int) Option {
+	return option(func(s *Searcher) { s.perPage = perPage })
+}
+
+func NewSearcher(client *http.Client, logger *zap.Logger, options ...Option) *Searcher {
+	s := &Searcher{
+		client:     client,
+		logger:     logger,
+		baseURL:    DefaultBaseURL,
+		perPage:    100,
+		maxResults: maxOffsetResults,
+	}
+	for _, o := range options {
+		o.set(s)
+	}
+	return s
+}
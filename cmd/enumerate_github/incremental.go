@@ -0,0 +1,230 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+	"github.com/ossf/criticality_score/internal/marker"
+	"github.com/ossf/criticality_score/internal/outfile"
+)
+
+const (
+	changeNew     = "new"
+	changeDropped = "dropped"
+)
+
+var changesHeader = []string{"repo", "change"}
+
+// scheduleYears returns a schedule function for search that sends one range
+// for each year between -start and -end, newest first.
+func scheduleYears(logger *zap.Logger, query string, minStars, maxStars int) func(chan searchRange) {
+	return func(ranges chan searchRange) {
+		start := startDateFlag.Time()
+		for end := endDateFlag.Time().Add(oneDay); end.After(start); {
+			created := end.AddDate(-1, 0, 0)
+			if created.Before(start) {
+				created = start
+			}
+			logger.With(
+				zap.String("created", created.Format(githubDateFormat)),
+				zap.String("end", end.Format(githubDateFormat)),
+			).Info("Scheduling range for enumeration")
+			ranges <- searchRange{
+				query:    query,
+				start:    created,
+				end:      end,
+				minStars: minStars,
+				maxStars: maxStars,
+			}
+			end = created
+		}
+	}
+}
+
+// previousOutput returns the output file of the previous run, located using
+// the -incremental marker file.
+//
+// outName is the output file of this run. It must not be the same as the
+// previous output file, as the previous output is read while writing outName.
+func previousOutput(ctx context.Context, outName string) (string, error) {
+	prevFile, err := marker.Read(ctx, markerType, *incrementalFlag, outName)
+	if err != nil {
+		return "", fmt.Errorf("read previous marker file: %w", err)
+	}
+	if prevFile == outName {
+		return "", fmt.Errorf("the output file %s is the previous output file", outName)
+	}
+	return prevFile, nil
+}
+
+// enumerateIncremental updates the output of a previous run in prevFile, by
+// only searching for repositories pushed since the previous run.
+//
+// Repositories in the previous output are written to out in the same order,
+// followed by any new repositories. Repositories that have dropped below
+// -min-stars are removed. The total number of repositories written is
+// returned.
+//
+// Dropped repositories are only found by searching for repositories pushed
+// since the previous run with stars within -incremental-dropped-stars of
+// -min-stars. A repository that loses stars without being pushed to is never
+// found, so it remains in the output until a full enumeration is run.
+func enumerateIncremental(ctx context.Context, client *githubv4.Client, logger *zap.Logger, out io.Writer, prevFile string) int {
+	logger = logger.With(zap.String("previous_filename", prevFile))
+
+	r, err := cloudstorage.NewReader(ctx, prevFile)
+	if err != nil {
+		logger.Error("Failed to open previous output", zap.Error(err))
+		os.Exit(2)
+	}
+	prev, err := repowriter.ReadRepos(r, format)
+	if err != nil {
+		logger.Error("Failed to read previous output", zap.Error(err))
+		os.Exit(2)
+	}
+	since := r.ModTime()
+	r.Close()
+	if !incrementalSinceFlag.Time().IsZero() {
+		since = incrementalSinceFlag.Time()
+	}
+
+	// Repositories have their pushed date set when they are created, so this
+	// also matches newly created repositories.
+	query := fmt.Sprintf("%s pushed:>=%s", searchQuery(), since.UTC().Format(time.RFC3339))
+
+	logger.With(
+		zap.String("since", since.UTC().Format(time.RFC3339)),
+		zap.Int("previous_repos", len(prev)),
+		zap.Int("min_stars", *minStarsFlag),
+		zap.Int("dropped_stars", *incrementalDroppedStarsFlag),
+		zap.Int("workers", *workersFlag),
+	).Info("Starting incremental enumeration")
+
+	previous := make(map[string]bool, len(prev))
+	for _, repo := range prev {
+		previous[repo.URL] = true
+	}
+
+	// Find repositories with enough stars that were not previously included.
+	found := make(map[string]enumerator.Repo)
+	var added []enumerator.Repo
+	search(ctx, client, logger, repoFilter, scheduleYears(logger, query, *minStarsFlag, -1), func(repo enumerator.Repo) {
+		found[repo.URL] = repo
+		if !previous[repo.URL] {
+			added = append(added, repo)
+		}
+	})
+
+	// Find previously included repositories that are now below -min-stars.
+	// The filter is not applied so that this search does not add to the
+	// excluded counts, which only cover the search above.
+	dropped := make(map[string]bool)
+	if minStars := *minStarsFlag - *incrementalDroppedStarsFlag; *incrementalDroppedStarsFlag > 0 && *minStarsFlag > 0 {
+		if minStars < 0 {
+			minStars = 0
+		}
+		search(ctx, client, logger, nil, scheduleYears(logger, query, minStars, *minStarsFlag-1), func(repo enumerator.Repo) {
+			// A repository may have gained stars between the two searches.
+			if _, ok := found[repo.URL]; previous[repo.URL] && !ok {
+				dropped[repo.URL] = true
+			}
+		})
+	}
+
+	w := format.New(out)
+	totalRepos := 0
+	for _, repo := range prev {
+		if dropped[repo.URL] {
+			continue
+		}
+		// Prefer the latest metadata for repositories found again.
+		if latest, ok := found[repo.URL]; ok {
+			repo = latest
+		}
+		w.Write(repo)
+		totalRepos++
+	}
+	for _, repo := range added {
+		w.Write(repo)
+		totalRepos++
+	}
+
+	if *incrementalChangesFlag != "" {
+		// Allow the changes file to use the run-id token, like the output.
+		changesFile := outfile.DefaultOpener.FilenameTransform(*incrementalChangesFlag)
+		if err := writeChanges(ctx, changesFile, prev, added, dropped); err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("changes_filename", changesFile),
+			).Error("Failed to write changes file")
+			os.Exit(2)
+		}
+	}
+
+	logger.With(
+		zap.Int("new", len(added)),
+		zap.Int("dropped", len(dropped)),
+	).Info("Finished incremental enumeration")
+	return totalRepos
+}
+
+// writeChanges writes a CSV file to filename marking each added repository as
+// new, and each dropped repository as dropped.
+func writeChanges(ctx context.Context, filename string, prev, added []enumerator.Repo, dropped map[string]bool) error {
+	f, err := cloudstorage.NewWriter(ctx, filename)
+	if err != nil {
+		return err
+	}
+	if err := writeChangesCSV(f, prev, added, dropped); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeChangesCSV writes the changes written by writeChanges to w.
+func writeChangesCSV(w io.Writer, prev, added []enumerator.Repo, dropped map[string]bool) error {
+	c := csv.NewWriter(w)
+	if err := c.Write(changesHeader); err != nil {
+		return err
+	}
+	for _, repo := range added {
+		if err := c.Write([]string{repo.URL, changeNew}); err != nil {
+			return err
+		}
+	}
+	for _, repo := range prev {
+		if !dropped[repo.URL] {
+			continue
+		}
+		if err := c.Write([]string{repo.URL, changeDropped}); err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
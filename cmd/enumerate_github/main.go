@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,12 +33,17 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
 	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
-	"github.com/ossf/criticality_score/cmd/enumerate_github/marker"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/gitlabsearch"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/registry"
 	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/compress"
 	"github.com/ossf/criticality_score/internal/envflag"
 	"github.com/ossf/criticality_score/internal/githubapi"
 	log "github.com/ossf/criticality_score/internal/log"
+	"github.com/ossf/criticality_score/internal/marker"
 	"github.com/ossf/criticality_score/internal/outfile"
 	"github.com/ossf/criticality_score/internal/workerpool"
 )
@@ -56,35 +62,112 @@ var (
 	epochDate = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)
 	runID     = time.Now().UTC().Format(runIDDateFormat)
 
-	markerFileFlag      = flag.String("marker", "", "writes the path to the file where results were written.")
-	minStarsFlag        = flag.Int("min-stars", 10, "only enumerates repositories with this or more of stars.")
-	starOverlapFlag     = flag.Int("star-overlap", 5, "the number of stars to overlap between queries.")
-	requireMinStarsFlag = flag.Bool("require-min-stars", false, "abort if -min-stars can't be reached during enumeration.")
-	queryFlag           = flag.String("query", "is:public", "sets the base query to use for enumeration.")
-	workersFlag         = flag.Int("workers", 1, "the total number of concurrent workers to use.")
-	startDateFlag       = dateFlag(epochDate)
-	endDateFlag         = dateFlag(time.Now().UTC().Truncate(oneDay))
-	logLevel            = defaultLogLevel
-	logEnv              log.Env
-	format              repowriter.WriterType
-	markerType          marker.Type
+	markerFileFlag              = flag.String("marker", "", "writes the path to the file where results were written.")
+	minStarsFlag                = flag.Int("min-stars", 10, "only enumerates repositories with this or more of stars.")
+	starOverlapFlag             = flag.Int("star-overlap", 5, "the number of stars to overlap between queries.")
+	requireMinStarsFlag         = flag.Bool("require-min-stars", false, "abort if -min-stars can't be reached during enumeration.")
+	queryFlag                   = flag.String("query", "is:public", "sets the base query to use for enumeration.")
+	workersFlag                 = flag.Int("workers", 1, "the total number of concurrent workers to use.")
+	resolveFlag                 = flag.String("resolve", "", "resolves each package name in `file` to candidate repositories, instead of enumerating.")
+	resolveReviewFlag           = flag.String("resolve-review", "", "writes ambiguous, unmatched and unresolved package names to `file` for manual review.")
+	resolveCandidatesFlag       = flag.Int("resolve-candidates", 5, "the maximum `number` of ranked candidates to output for each package name.")
+	resolveMinConfidenceFlag    = flag.Float64("resolve-min-confidence", 0.5, "the minimum confidence for a package name to be matched to a repository.")
+	incrementalFlag             = flag.String("incremental", "", "only enumerates repositories pushed since the previous run, whose output is located using the marker `file`, and merges them with the previous output.")
+	incrementalChangesFlag      = flag.String("incremental-changes", "", "writes each new and dropped repository found by -incremental to `file`.")
+	incrementalDroppedStarsFlag = flag.Int("incremental-dropped-stars", 10, "the `number` of stars below -min-stars to search for repositories dropped by -incremental.")
+	languagesFlag               = flag.String("languages", "", "only includes repositories with one of the comma separated primary `languages`.")
+	topicsFlag                  = flag.String("topics", "", "only includes repositories with at least one of the comma separated `topics`.")
+	licensesFlag                = flag.String("licenses", "", "only includes repositories with one of the comma separated SPDX license `ids`.")
+	ownerAllowlistFlag          = flag.String("owner-allowlist", "", "only includes repositories owned by the orgs or users listed in `file`.")
+	ownerDenylistFlag           = flag.String("owner-denylist", "", "excludes repositories owned by the orgs or users listed in `file`.")
+	crawlFlag                   = flag.String("crawl", "", "crawls the dependencies of each seed repository url in `file`, instead of enumerating.")
+	crawlEdgesFlag              = flag.String("crawl-edges", "", "the `file` containing the dependency edges to crawl.")
+	crawlDepsDevFlag            = flag.Bool("crawl-depsdev", false, "crawls the dependency edges read from the deps.dev BigQuery dataset.")
+	crawlManifestsFlag          = flag.Bool("crawl-manifests", false, "crawls the dependencies declared in the manifests of each GitHub repository.")
+	crawlDepthFlag              = flag.Int("crawl-depth", 2, "the maximum `number` of dependency edges to follow from a seed repository.")
+	checkpointFlag              = flag.String("checkpoint", "", "records the progress of the enumeration to `file`, so it can be resumed with -resume.")
+	resumeFlag                  = flag.Bool("resume", false, "resumes the enumeration recorded in the -checkpoint file, appending to the same output.")
+	packagesFlag                = flag.String("packages", "", "enumerates the repositories of the top packages in each ecosystem of the registry dump `file`, instead of searching.")
+	packagesDepsDevFlag         = flag.Bool("packages-depsdev", false, "enumerates the repositories of the top packages in each ecosystem of the deps.dev BigQuery dataset, instead of searching.")
+	packagesTopFlag             = flag.Int("packages-top", 1000, "the `number` of top packages to enumerate in each ecosystem.")
+	gcpProjectFlag              = flag.String("gcp-project-id", "", "the Google Cloud Project ID to use. Auto-detects by default.")
+	depsdevDatasetFlag          = flag.String("depsdev-dataset", collector.DefaultGCPDatasetName, "the BigQuery dataset name to use.")
+	depsdevTTLFlag              = flag.Int("depsdev-expiration", 0, "the default expiration (`hours`) to use for deps.dev tables. No expiration by default.")
+	depsdevKeyFlag              = flag.String("depsdev-table-key", "", "the `key` used to name the BigQuery edges table. Tables with the same key are reused.")
+	gitlabURLFlag               = flag.String("gitlab-url", gitlabsearch.DefaultBaseURL, "the `url` of the GitLab instance to enumerate when -host is 'gitlab'.")
+	startDateFlag               = dateFlag(epochDate)
+	endDateFlag                 = dateFlag(time.Now().UTC().Truncate(oneDay))
+	incrementalSinceFlag        dateFlag
+	logLevel                    = defaultLogLevel
+	logEnv                      log.Env
+	format                      repowriter.WriterType
+	resolveFormat               repowriter.ResolutionWriterType
+	crawlFormat                 repowriter.CrawlWriterType
+	packagesFormat              registry.Format
+	markerType                  marker.Type
+	hostFlag                    enumerator.Host
+	forksFlag                   githubsearch.Inclusion
+	archivedFlag                githubsearch.Inclusion
+	mirrorsFlag                 githubsearch.Inclusion
+
+	// repoFilter is set if any of the filter flags are set.
+	repoFilter *githubsearch.RepoFilter
+
+	// checkpoints is set if -checkpoint is set.
+	checkpoints *checkpointer
+
+	// appendOutput is set if -resume is set and the output already has
+	// repositories written to it.
+	appendOutput bool
 
 	// Maps environment variables to the flags they correspond to.
 	envFlagMap = envflag.Map{
-		"CRITICALITY_SCORE_LOG_ENV":            "log-env",
-		"CRITICALITY_SCORE_LOG_LEVEL":          "log",
-		"CRITICALITY_SCORE_FORMAT":             "format",
-		"CRITICALITY_SCORE_WORKERS":            "workers",
-		"CRITICALITY_SCORE_START_DATE":         "start",
-		"CRITICALITY_SCORE_END_DATE":           "end",
-		"CRITICALITY_SCORE_OUTFILE":            "out",
-		"CRITICALITY_SCORE_OUTFILE_FORCE":      "force",
-		"CRITICALITY_SCORE_MARKER":             "marker",
-		"CRITICALITY_SCORE_MARKER_TYPE":        "marker-type",
-		"CRITICALITY_SCORE_QUERY":              "query",
-		"CRITICALITY_SCORE_STARS_MIN":          "min-stars",
-		"CRITICALITY_SCORE_STARS_OVERLAP":      "star-overlap",
-		"CRITICALITY_SCORE_STARS_MIN_REQUIRED": "require-min-stars",
+		"CRITICALITY_SCORE_LOG_ENV":             "log-env",
+		"CRITICALITY_SCORE_LOG_LEVEL":           "log",
+		"CRITICALITY_SCORE_FORMAT":              "format",
+		"CRITICALITY_SCORE_WORKERS":             "workers",
+		"CRITICALITY_SCORE_START_DATE":          "start",
+		"CRITICALITY_SCORE_END_DATE":            "end",
+		"CRITICALITY_SCORE_OUTFILE":             "out",
+		"CRITICALITY_SCORE_OUTFILE_FORCE":       "force",
+		"CRITICALITY_SCORE_OUTFILE_MAX_ROWS":    "out-max-rows",
+		"CRITICALITY_SCORE_OUTFILE_MAX_BYTES":   "out-max-bytes",
+		"CRITICALITY_SCORE_MARKER":              "marker",
+		"CRITICALITY_SCORE_MARKER_TYPE":         "marker-type",
+		"CRITICALITY_SCORE_QUERY":               "query",
+		"CRITICALITY_SCORE_STARS_MIN":           "min-stars",
+		"CRITICALITY_SCORE_STARS_OVERLAP":       "star-overlap",
+		"CRITICALITY_SCORE_STARS_MIN_REQUIRED":  "require-min-stars",
+		"CRITICALITY_SCORE_RESOLVE":             "resolve",
+		"CRITICALITY_SCORE_RESOLVE_FORMAT":      "resolve-format",
+		"CRITICALITY_SCORE_RESOLVE_REVIEW":      "resolve-review",
+		"CRITICALITY_SCORE_HOST":                "host",
+		"CRITICALITY_SCORE_GITLAB_URL":          "gitlab-url",
+		"CRITICALITY_SCORE_FORKS":               "forks",
+		"CRITICALITY_SCORE_ARCHIVED":            "archived",
+		"CRITICALITY_SCORE_MIRRORS":             "mirrors",
+		"CRITICALITY_SCORE_LANGUAGES":           "languages",
+		"CRITICALITY_SCORE_TOPICS":              "topics",
+		"CRITICALITY_SCORE_LICENSES":            "licenses",
+		"CRITICALITY_SCORE_OWNER_ALLOWLIST":     "owner-allowlist",
+		"CRITICALITY_SCORE_OWNER_DENYLIST":      "owner-denylist",
+		"CRITICALITY_SCORE_INCREMENTAL":         "incremental",
+		"CRITICALITY_SCORE_INCREMENTAL_SINCE":   "incremental-since",
+		"CRITICALITY_SCORE_INCREMENTAL_CHANGES": "incremental-changes",
+		"CRITICALITY_SCORE_CRAWL":               "crawl",
+		"CRITICALITY_SCORE_CRAWL_EDGES":         "crawl-edges",
+		"CRITICALITY_SCORE_CRAWL_DEPSDEV":       "crawl-depsdev",
+		"CRITICALITY_SCORE_CRAWL_MANIFESTS":     "crawl-manifests",
+		"CRITICALITY_SCORE_CRAWL_DEPTH":         "crawl-depth",
+		"CRITICALITY_SCORE_CRAWL_FORMAT":        "crawl-format",
+		"CRITICALITY_SCORE_CHECKPOINT":          "checkpoint",
+		"CRITICALITY_SCORE_RESUME":              "resume",
+		"CRITICALITY_SCORE_PACKAGES":            "packages",
+		"CRITICALITY_SCORE_PACKAGES_FORMAT":     "packages-format",
+		"CRITICALITY_SCORE_PACKAGES_DEPSDEV":    "packages-depsdev",
+		"CRITICALITY_SCORE_PACKAGES_TOP":        "packages-top",
+		"CRITICALITY_SCORE_GCP_PROJECT_ID":      "gcp-project-id",
+		"CRITICALITY_SCORE_DEPSDEV_DATASET":     "depsdev-dataset",
 	}
 )
 
@@ -112,39 +195,96 @@ func (d *dateFlag) Time() time.Time {
 func init() {
 	flag.Var(&startDateFlag, "start", "the start `date` to enumerate back to. Must be at or after 2008-01-01.")
 	flag.Var(&endDateFlag, "end", "the end `date` to enumerate from.")
+	flag.Var(&incrementalSinceFlag, "incremental-since", "the `date` to search for pushed repositories from with -incremental. Defaults to when the previous output was written.")
 	flag.Var(&logLevel, "log", "set the `level` of logging.")
-	flag.TextVar(&format, "format", repowriter.WriterTypeText, "set output file `format`.")
+	flag.TextVar(&format, "format", repowriter.WriterTypeText, "set output file `format`. Can be 'text', 'scorecard', 'csv' or 'jsonl'.")
+	flag.TextVar(&resolveFormat, "resolve-format", repowriter.ResolutionWriterTypeCSV, "set output file `format` when resolving package names. Can be 'csv' or 'json'.")
+	flag.TextVar(&crawlFormat, "crawl-format", repowriter.CrawlWriterTypeCSV, "set output file `format` when crawling dependencies. Can be 'csv' or 'json'.")
+	flag.TextVar(&packagesFormat, "packages-format", registry.FormatDepsDev, "the `format` of the -packages file. Can be 'npm', 'pypi', 'crates' or 'depsdev'.")
 	flag.TextVar(&logEnv, "log-env", log.DefaultEnv, "set logging `env`.")
-	flag.TextVar(&markerType, "marker-type", marker.TypeFull, "format of the contents in the marker file. Can be 'full', 'dir' or 'file'.")
+	flag.TextVar(&hostFlag, "host", enumerator.HostGitHub, "the source code `host` to enumerate. Can be 'github' or 'gitlab'.")
+	flag.TextVar(&forksFlag, "forks", githubsearch.InclusionInclude, "whether to `include`, 'exclude' or 'only' include forked repositories.")
+	flag.TextVar(&archivedFlag, "archived", githubsearch.InclusionInclude, "whether to `include`, 'exclude' or 'only' include archived repositories.")
+	flag.TextVar(&mirrorsFlag, "mirrors", githubsearch.InclusionInclude, "whether to `include`, 'exclude' or 'only' include mirror repositories.")
+	flag.TextVar(&markerType, "marker-type", marker.TypeFull, "format of the contents in the marker file. Can be 'full', 'dir', 'file' or 'manifest'.")
 	outfile.DefineFlags(flag.CommandLine, "out", "force", "append", "FILE")
+	outfile.DefineRotationFlags(flag.CommandLine, "out", "FILE")
 	flag.Usage = func() {
 		cmdName := path.Base(os.Args[0])
 		w := flag.CommandLine.Output()
 		fmt.Fprintf(w, "Usage:\n  %s [FLAGS]...\n\n", cmdName)
 		fmt.Fprintf(w, "Enumerates GitHub repositories between -start date and -end date, with -min-stars\n")
 		fmt.Fprintf(w, "or higher. Writes each repository URL in the specified format.\n")
+		fmt.Fprintf(w, "\nIf -host is 'gitlab', GitLab projects with their last activity between -start\n")
+		fmt.Fprintf(w, "and -end are enumerated instead.\n")
+		fmt.Fprintf(w, "\nIf -resolve is set, each package name in the file is instead resolved to ranked\n")
+		fmt.Fprintf(w, "candidate repositories.\n")
+		fmt.Fprintf(w, "\nIf -incremental is set, only repositories pushed since the previous run are\n")
+		fmt.Fprintf(w, "enumerated and merged with the previous run's output.\n")
+		fmt.Fprintf(w, "\nIf -checkpoint is set, progress is recorded so that a failed enumeration can be\n")
+		fmt.Fprintf(w, "continued using -resume.\n")
+		fmt.Fprintf(w, "\nIf -packages or -packages-depsdev is set, the repositories of the most\n")
+		fmt.Fprintf(w, "downloaded or depended on packages in each ecosystem are enumerated instead.\n")
+		fmt.Fprintf(w, "\nIf -crawl is set, the dependencies of each seed repository in the file are\n")
+		fmt.Fprintf(w, "crawled instead, up to -crawl-depth edges away.\n")
 		fmt.Fprintf(w, "\nFlags:\n")
 		flag.PrintDefaults()
 	}
 }
 
-// searchWorker waits for a query on the queries channel, starts a search with that query using s
-// and returns each repository on the results channel.
-func searchWorker(s *githubsearch.Searcher, logger *zap.Logger, queries, results chan []string) {
-	for qu := range queries {
-		packageName := qu[0]
-		q := qu[1]
+// searchRange is a single search for repositories matching query, created
+// between start and end, with between minStars and maxStars.
+type searchRange struct {
+	query      string
+	start, end time.Time
+	minStars   int
+	// maxStars is the maximum number of stars, or -1 for no maximum.
+	maxStars int
+
+	// key identifies the range in the -checkpoint file. If it is empty the
+	// range's progress is not recorded.
+	key string
+	// progress is set if the range is being resumed.
+	progress *githubsearch.Progress
+}
+
+// searchResult is either a repository found by a search, or the progress of
+// the search range key.
+type searchResult struct {
+	repo     enumerator.Repo
+	key      string
+	progress *githubsearch.Progress
+}
+
+// searchWorker waits for a range on the ranges channel, starts a search for
+// repositories in that range using s and returns each repository on the
+// results channel.
+//
+// If the range has a key, its progress is also returned on the results
+// channel, after the repositories found before it.
+func searchWorker(s *githubsearch.Searcher, logger *zap.Logger, ranges chan searchRange, results chan searchResult) {
+	for r := range ranges {
 		total := 0
-		err := s.ReposByStars(q, *minStarsFlag, *starOverlapFlag, func(repo string) {
-			results <- []string{packageName, repo}
+		progress := githubsearch.NewProgress(r.start, r.end, r.minStars, r.maxStars)
+		if r.progress != nil {
+			progress = *r.progress
+		}
+		var checkpoint func(githubsearch.Progress)
+		if r.key != "" {
+			checkpoint = func(p githubsearch.Progress) {
+				results <- searchResult{key: r.key, progress: &p}
+			}
+		}
+		err := s.ResumeReposByPartition(r.query, progress, *starOverlapFlag, func(repo enumerator.Repo) {
+			results <- searchResult{repo: repo}
 			total++
-		})
+		}, checkpoint)
 		if err != nil {
 			// TODO: this error handling is not at all graceful, and hard to recover from.
 			logger.With(
-				zap.String("query", q),
+				zap.String("created", r.start.Format(githubDateFormat)),
 				zap.Error(err),
-			).Error("Enumeration failed for query")
+			).Error("Enumeration failed for range")
 			if errors.Is(err, githubsearch.ErrorUnableToListAllResult) {
 				if *requireMinStarsFlag {
 					os.Exit(1)
@@ -154,9 +294,9 @@ func searchWorker(s *githubsearch.Searcher, logger *zap.Logger, queries, results
 			}
 		}
 		logger.With(
-			zap.String("query", q),
+			zap.String("created", r.start.Format(githubDateFormat)),
 			zap.Int("repo_count", total),
-		).Info("Enumeration for query done")
+		).Info("Enumeration for range done")
 	}
 }
 
@@ -184,46 +324,296 @@ func main() {
 	innerLogger := zapr.NewLogger(logger)
 	scLogger := &sclog.Logger{Logger: &innerLogger}
 
-	//// Warn if the -start date is before the epoch.
-	//if startDateFlag.Time().Before(epochDate) {
-	//	logger.With(
-	//		zap.String("start", startDateFlag.String()),
-	//		zap.String("epoch", epochDate.Format(githubDateFormat)),
-	//	).Warn("-start date is before epoch")
-	//}
-	//
-	//// Ensure -start is before -end
-	//if endDateFlag.Time().Before(startDateFlag.Time()) {
-	//	logger.With(
-	//		zap.String("start", startDateFlag.String()),
-	//		zap.String("end", endDateFlag.String()),
-	//	).Error("-start date must be before -end date")
-	//	os.Exit(2)
-	//}
+	// Warn if the -start date is before the epoch.
+	if startDateFlag.Time().Before(epochDate) {
+		logger.With(
+			zap.String("start", startDateFlag.String()),
+			zap.String("epoch", epochDate.Format(githubDateFormat)),
+		).Warn("-start date is before epoch")
+	}
+
+	// Ensure -start is before -end
+	if endDateFlag.Time().Before(startDateFlag.Time()) {
+		logger.With(
+			zap.String("start", startDateFlag.String()),
+			zap.String("end", endDateFlag.String()),
+		).Error("-start date must be before -end date")
+		os.Exit(2)
+	}
 
 	// We need a context to support a bunch of operations.
 	ctx := context.Background()
 
-	// Prepare a client for communicating with GitHub's GraphQL API.
+	// Prepare a client for communicating with the host's API.
 	// Do this before opening the output file to avoid creating an empty file
 	// if we fail to authenticate, or connect to the authentication server.
-	rt := githubapi.NewRetryRoundTripper(roundtripper.NewTransport(ctx, scLogger), logger)
-	httpClient := &http.Client{
-		Transport: rt,
+	var client *githubv4.Client
+	var e enumerator.Enumerator
+	switch {
+	case *packagesFlag != "" || *packagesDepsDevFlag:
+		if *packagesFlag != "" && *packagesDepsDevFlag {
+			logger.Error("-packages and -packages-depsdev can not both be set")
+			os.Exit(2)
+		}
+		if *resolveFlag != "" || *incrementalFlag != "" || *crawlFlag != "" {
+			logger.Error("-resolve, -incremental and -crawl can not be used with -packages")
+			os.Exit(2)
+		}
+		// Packages are resolved using registry metadata, so no client is
+		// needed.
+		e = &packageEnumerator{logger: logger}
+	case hostFlag == enumerator.HostGitLab:
+		if *resolveFlag != "" || *incrementalFlag != "" || *crawlFlag != "" {
+			logger.With(
+				zap.Stringer("host", hostFlag),
+			).Error("-resolve, -incremental and -crawl are only supported for GitHub")
+			os.Exit(2)
+		}
+		e = newGitLabEnumerator(logger)
+	default:
+		rt := githubapi.NewRetryRoundTripper(roundtripper.NewTransport(ctx, scLogger), logger)
+		httpClient := &http.Client{
+			Transport: rt,
+		}
+		client = githubv4.NewClient(httpClient)
+		e = &githubEnumerator{client: client, logger: logger}
+	}
+
+	if *resumeFlag && *checkpointFlag == "" {
+		logger.Error("-resume requires -checkpoint")
+		os.Exit(2)
+	}
+	if *checkpointFlag != "" {
+		if hostFlag != enumerator.HostGitHub || *resolveFlag != "" || *incrementalFlag != "" || *crawlFlag != "" || *packagesFlag != "" || *packagesDepsDevFlag {
+			logger.Error("-checkpoint is only supported when enumerating GitHub by date")
+			os.Exit(2)
+		}
+		if out := flag.Lookup("out").Value.String(); out == "" || strings.Contains(out, "://") {
+			logger.Error("-checkpoint requires -out to be a local file")
+			os.Exit(2)
+		}
+		if outfile.DefaultOpener.Compression.Resolve(flag.Lookup("out").Value.String()) != compress.FormatNone {
+			// Rows buffered by the compressor would be recorded in the
+			// checkpoint before they reach the output.
+			logger.Error("-checkpoint can not be used with compressed output")
+			os.Exit(2)
+		}
+		checkpoints, err = newCheckpointer(ctx, logger)
+		if err != nil {
+			logger.Error("Failed to prepare checkpoint", zap.Error(err))
+			os.Exit(2)
+		}
+		if *resumeFlag {
+			// The output is appended to, rather than overwritten.
+			flag.Set("append", "true")
+		}
+		// The output must be written in place so that it matches the
+		// checkpoint if enumeration is interrupted.
+		outfile.DefaultOpener.InPlace = true
+	}
+
+	if outfile.DefaultOpener.Rotating() {
+		if *checkpointFlag != "" || *resolveFlag != "" || *incrementalFlag != "" || *crawlFlag != "" {
+			logger.Error("-out-max-rows and -out-max-bytes can not be used with -checkpoint, -resolve, -incremental or -crawl")
+			os.Exit(2)
+		}
+		if *markerFileFlag != "" && markerType != marker.TypeManifest {
+			logger.Error("-out-max-rows and -out-max-bytes require -marker-type=manifest")
+			os.Exit(2)
+		}
+	}
+
+	// The rows of a resolution file don't correspond to the package names
+	// written, so the manifest could not be verified.
+	if *markerFileFlag != "" && markerType == marker.TypeManifest && *resolveFlag != "" {
+		logger.Error("-marker-type=manifest can not be used with -resolve")
+		os.Exit(2)
 	}
-	client := githubv4.NewClient(httpClient)
 
-	// Open the output file
-	out, err := outfile.Open(ctx)
+	repoFilter, err = newRepoFilter(ctx)
+	if err != nil {
+		logger.Error("Failed to load filters", zap.Error(err))
+		os.Exit(2)
+	}
+	// The filters are only applied to the results of a GitHub search.
+	if repoFilter != nil && (client == nil || *resolveFlag != "" || *crawlFlag != "") {
+		logger.Error("filter flags are only supported when searching GitHub, and can not be used with -host gitlab, -packages, -packages-depsdev, -resolve or -crawl")
+		os.Exit(2)
+	}
+
+	// Locate the previous output before the output is opened, as they must
+	// not be the same file.
+	var prevFile string
+	if *incrementalFlag != "" {
+		prevFile, err = previousOutput(ctx, outfile.DefaultOpener.Name())
+		if err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("marker_filename", *incrementalFlag),
+			).Error("Failed to locate previous output")
+			os.Exit(2)
+		}
+	}
+
+	// Open the output file. If the output is rotated the shards are written
+	// by the Rotator in shards, and out is nil.
+	var out outfile.NameWriteCloser
+	var shards *outfile.Rotator[repowriter.Writer]
+	if outfile.DefaultOpener.Rotating() {
+		shards, err = outfile.NewRotator(ctx, outfile.DefaultOpener, format.New)
+	} else {
+		out, err = outfile.Open(ctx)
+	}
 	if err != nil {
 		// File failed to open
 		logger.Error("Failed to open output file", zap.Error(err))
 		os.Exit(2)
 	}
-	defer out.Close()
-	w := format.New(out)
+	closeOutput := func() error {
+		if shards != nil {
+			return shards.Close()
+		}
+		return out.Close()
+	}
+	defer closeOutput()
 
+	if checkpoints != nil {
+		appendOutput, err = checkpoints.open(out.Name())
+		if err != nil {
+			logger.Error("Failed to resume output file", zap.Error(err))
+			os.Exit(2)
+		}
+		checkpoints.write()
+	}
+
+	// Track how long it takes to enumerate the repositories
+	startTime := time.Now()
+
+	var totalRepos int
+	switch {
+	case *resolveFlag != "":
+		totalRepos = resolvePackages(ctx, client, logger, out)
+	case *crawlFlag != "":
+		totalRepos = crawl(ctx, client, logger, out)
+	case *incrementalFlag != "":
+		totalRepos = enumerateIncremental(ctx, client, logger, out, prevFile)
+	case shards != nil:
+		totalRepos = enumerate(ctx, e, logger, &shardWriter{shards: shards})
+	case appendOutput:
+		totalRepos = enumerate(ctx, e, logger, format.Append(out))
+	default:
+		totalRepos = enumerate(ctx, e, logger, format.New(out))
+	}
+
+	// Trigger Close() early to ensure the data exists before the marker file.
+	if err := closeOutput(); err != nil {
+		logger.Fatal("Failed write data", zap.Error(err))
+	}
+
+	if checkpoints != nil {
+		checkpoints.write()
+	}
+
+	if *markerFileFlag != "" {
+		logger = logger.With(zap.String("marker_filename", *markerFileFlag))
+		logger.Debug("Writing the marker file")
+
+		switch {
+		case shards != nil:
+			err = marker.WriteShardedManifest(ctx, *markerFileFlag, manifestShards(shards.Shards()), manifestInfo(totalRepos))
+		case markerType == marker.TypeManifest:
+			rows := totalRepos
+			if checkpoints != nil {
+				rows += checkpoints.existing
+			}
+			err = marker.WriteManifest(ctx, *markerFileFlag, out.Name(), manifestInfo(rows))
+		default:
+			err = marker.Write(ctx, markerType, *markerFileFlag, out.Name())
+		}
+		if err != nil {
+			logger.Error("Failed creating marker file", zap.Error(err))
+			// Don't exit after a failure to create the marker file. Just fail
+			// to write the marker file.
+		}
+	}
+
+	if repoFilter != nil {
+		excluded := repoFilter.Excluded()
+		totalExcluded := 0
+		for _, n := range excluded {
+			totalExcluded += n
+		}
+		logger = logger.With(
+			zap.Int("total_excluded", totalExcluded),
+			zap.Any("excluded", excluded),
+		)
+	}
+
+	if shards != nil {
+		logger = logger.With(zap.Int("shards", len(shards.Shards())))
+	} else {
+		logger = logger.With(zap.String("filename", out.Name()))
+	}
 	logger.With(
+		zap.Int("total_repos", totalRepos),
+		zap.Duration("duration", time.Since(startTime).Truncate(time.Minute)),
+	).Info("Finished enumeration")
+}
+
+// shardWriter implements repowriter.Writer by writing each repository to the
+// current shard of a Rotator.
+type shardWriter struct {
+	shards *outfile.Rotator[repowriter.Writer]
+}
+
+// Write implements the repowriter.Writer interface.
+func (w *shardWriter) Write(repo repowriter.Repo) error {
+	return w.shards.Write(func(rw repowriter.Writer) error {
+		return rw.Write(repo)
+	})
+}
+
+// manifestShards returns the shards to record in a manifest marker file.
+func manifestShards(shards []outfile.Shard) []marker.Shard {
+	ms := make([]marker.Shard, 0, len(shards))
+	for _, s := range shards {
+		ms = append(ms, marker.Shard{Path: s.Name, Rows: s.Rows})
+	}
+	return ms
+}
+
+// manifestInfo returns the details of this run to record in a manifest marker
+// file, given the number of repositories in the output.
+func manifestInfo(rows int) marker.Info {
+	return marker.Info{
+		RunID:  runID,
+		Rows:   rows,
+		Header: outputHeader(),
+		Params: map[string]string{
+			"host":      hostFlag.String(),
+			"query":     *queryFlag,
+			"min_stars": strconv.Itoa(*minStarsFlag),
+			"format":    format.String(),
+		},
+		Start: startDateFlag.Time(),
+		End:   endDateFlag.Time(),
+	}
+}
+
+// outputHeader returns true if the output starts with a header row.
+func outputHeader() bool {
+	if *crawlFlag != "" {
+		return crawlFormat == repowriter.CrawlWriterTypeCSV
+	}
+	return format == repowriter.WriterTypeScorecard || format == repowriter.WriterTypeCSV
+}
+
+// enumerate uses e to find all the repositories between -start and -end with
+// -min-stars or more, and writes each one to w. The total number of
+// repositories written is returned.
+func enumerate(ctx context.Context, e enumerator.Enumerator, logger *zap.Logger, w repowriter.Writer) int {
+	logger.With(
+		zap.Stringer("host", hostFlag),
 		zap.String("start", startDateFlag.String()),
 		zap.String("end", endDateFlag.String()),
 		zap.Int("min_stars", *minStarsFlag),
@@ -231,52 +621,66 @@ func main() {
 		zap.Int("workers", *workersFlag),
 	).Info("Starting enumeration")
 
-	// Track how long it takes to enumerate the repositories
-	startTime := time.Now()
+	totalRepos := 0
+	err := e.Enumerate(ctx, func(repo enumerator.Repo) {
+		if err := w.Write(repo); err != nil {
+			logger.Error("Failed to write repo", zap.String("url", repo.URL), zap.Error(err))
+			os.Exit(1)
+		}
+		totalRepos++
+	})
+	if err != nil {
+		logger.Error("Enumeration failed", zap.Error(err))
+		if (!errors.Is(err, githubsearch.ErrorUnableToListAllResult) &&
+			!errors.Is(err, gitlabsearch.ErrorUnableToListAllResult)) || *requireMinStarsFlag {
+			os.Exit(1)
+		}
+	}
+	return totalRepos
+}
 
-	baseQuery := *queryFlag
-	queries := make(chan []string)
-	results := make(chan []string, (*workersFlag)*reposPerPage)
+// search runs the searches sent to the ranges channel by schedule across
+// -workers workers, calling emit for each repository found. emit is only ever
+// called from a single goroutine.
+//
+// If filter is not nil, repositories it excludes are not emitted and are
+// counted by filter.
+func search(ctx context.Context, client *githubv4.Client, logger *zap.Logger, filter *githubsearch.RepoFilter, schedule func(chan searchRange), emit func(enumerator.Repo)) {
+	ranges := make(chan searchRange)
+	results := make(chan searchResult, (*workersFlag)*reposPerPage)
 
 	// Start the worker goroutines to execute the search queries
 	wait := workerpool.WorkerPool(*workersFlag, func(i int) {
 		workerLogger := logger.With(zap.Int("worker", i))
-		s := githubsearch.NewSearcher(ctx, client, workerLogger, githubsearch.PerPage(reposPerPage))
-		searchWorker(s, workerLogger, queries, results)
+		options := []githubsearch.Option{githubsearch.PerPage(reposPerPage)}
+		if filter != nil {
+			options = append(options, githubsearch.Filter(filter))
+		}
+		s := githubsearch.NewSearcher(ctx, client, workerLogger, options...)
+		searchWorker(s, workerLogger, ranges, results)
 	})
 
 	// Start a separate goroutine to collect results so worker output is always consumed.
 	done := make(chan bool)
-	totalRepos := 0
 	go func() {
-		for repo := range results {
-			w.Write(fmt.Sprintf("%s %s", repo[0], repo[1]))
-			totalRepos++
+		for r := range results {
+			switch {
+			case r.progress != nil:
+				checkpoints.update(r.key, *r.progress)
+			case checkpoints != nil && !checkpoints.add(r.repo.URL):
+				// Already written before resuming.
+			default:
+				emit(r.repo)
+			}
 		}
 		done <- true
 	}()
 
-	// Work happens here. Iterate through the dates from today, until the start date.
-	//for created := endDateFlag.Time(); !startDateFlag.Time().After(created); created = created.Add(-oneDay) {
-	//	logger.With(
-	//		zap.String("created", created.Format(githubDateFormat)),
-	//	).Info("Scheduling day for enumeration")
-	//	queries <- baseQuery + fmt.Sprintf(" created:%s", created.Format(githubDateFormat))
-	//}
-	content, err := os.ReadFile("target.txt")
-	if err != nil {
-		panic(err)
-	}
-	for _, repoName := range strings.Split(string(content), "\n") {
-		if repoName == "" {
-			continue
-		}
-		q := baseQuery + " " + repoName
-		queries <- []string{repoName, q}
-	}
+	schedule(ranges)
+
 	logger.Debug("Waiting for workers to finish")
 	// Indicate to the workers that we're finished.
-	close(queries)
+	close(ranges)
 	// Wait for the workers to be finished.
 	wait()
 
@@ -285,26 +689,4 @@ func main() {
 	close(results)
 	// Wait for the writer to be finished.
 	<-done
-
-	// Trigger Close() early to ensure the data exists before the marker file.
-	if err := out.Close(); err != nil {
-		logger.Fatal("Failed write data", zap.Error(err))
-	}
-
-	if *markerFileFlag != "" {
-		logger = logger.With(zap.String("marker_filename", *markerFileFlag))
-		logger.Debug("Writing the marker file")
-
-		if err := marker.Write(ctx, markerType, *markerFileFlag, out.Name()); err != nil {
-			logger.Error("Failed creating marker file", zap.Error(err))
-			// Don't exit after a failure to create the marker file. Just fail
-			// to write the marker file.
-		}
-	}
-
-	logger.With(
-		zap.Int("total_repos", totalRepos),
-		zap.Duration("duration", time.Since(startTime).Truncate(time.Minute)),
-		zap.String("filename", out.Name()),
-	).Info("Finished enumeration")
 }
@@ -0,0 +1,104 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/registry"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+	"github.com/ossf/criticality_score/internal/depgraph"
+	"github.com/ossf/criticality_score/internal/infile"
+)
+
+// packageEnumerator implements enumerator.Enumerator by resolving the top
+// -packages-top packages in each ecosystem to their source repositories.
+type packageEnumerator struct {
+	logger *zap.Logger
+}
+
+// loadPackages returns the packages read from the -packages file, or the top
+// packages in the deps.dev BigQuery dataset if -packages-depsdev is set.
+func (e *packageEnumerator) loadPackages(ctx context.Context) ([]registry.Package, error) {
+	if *packagesDepsDevFlag {
+		e.logger.Info("Loading top packages from BigQuery")
+		top, err := depsdev.LoadTopPackages(ctx, e.logger, *gcpProjectFlag, *depsdevDatasetFlag, time.Hour*time.Duration(*depsdevTTLFlag), *depsdevKeyFlag, *packagesTopFlag)
+		if err != nil {
+			return nil, err
+		}
+		pkgs := make([]registry.Package, 0, len(top))
+		for _, p := range top {
+			u, _ := depsdev.URLForProject(depgraph.Project{Type: p.ProjectType, Name: p.ProjectName})
+			pkgs = append(pkgs, registry.Package{
+				Ecosystem: p.System,
+				Name:      p.Name,
+				Count:     p.DependentCount,
+				Repo:      u,
+			})
+		}
+		return pkgs, nil
+	}
+
+	e.logger.With(
+		zap.String("filename", *packagesFlag),
+		zap.Stringer("format", packagesFormat),
+	).Info("Loading packages from file")
+	f, err := infile.Open(ctx, *packagesFlag)
+	if err != nil {
+		return nil, fmt.Errorf("opening packages: %w", err)
+	}
+	defer f.Close()
+	return packagesFormat.Read(f)
+}
+
+// Enumerate implements the enumerator.Enumerator interface.
+//
+// Each repository is only emitted once, with the identity of its highest
+// ranked package. Packages that could not be resolved to a repository are
+// skipped.
+func (e *packageEnumerator) Enumerate(ctx context.Context, emitter func(enumerator.Repo)) error {
+	pkgs, err := e.loadPackages(ctx)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	unresolved := make(map[string]int)
+	for _, p := range registry.Top(pkgs, *packagesTopFlag) {
+		if p.Repo == "" {
+			e.logger.With(
+				zap.String("ecosystem", p.Ecosystem),
+				zap.String("package", p.Name),
+			).Debug("Package has no repository")
+			unresolved[p.Ecosystem]++
+			continue
+		}
+		if seen[p.Repo] {
+			continue
+		}
+		seen[p.Repo] = true
+		emitter(enumerator.Repo{URL: p.Repo, Ecosystem: p.Ecosystem, Package: p.Name})
+	}
+	e.logger.With(
+		zap.Int("packages", len(pkgs)),
+		zap.Int("repos", len(seen)),
+		zap.Any("unresolved", unresolved),
+	).Info("Resolved top packages")
+	return nil
+}
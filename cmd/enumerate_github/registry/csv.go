@@ -0,0 +1,117 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// readCSV calls row for each row after the header in r, with a function that
+// returns the value of a column by name. The header must contain every column
+// in required.
+func readCSV(r io.Reader, required []string, row func(col func(string) string) error) error {
+	c := csv.NewReader(r)
+	header, err := c.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, name := range required {
+		if _, ok := index[name]; !ok {
+			return fmt.Errorf("missing %q column", name)
+		}
+	}
+	for {
+		rec, err := c.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		col := func(name string) string {
+			if i, ok := index[name]; ok && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		if err := row(col); err != nil {
+			return err
+		}
+	}
+}
+
+// parseCount parses a count column, treating an empty value as 0.
+func parseCount(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// readCrates reads the crates.csv file from a crates.io database dump
+// (https://static.crates.io/db-dump.tar.gz). The repository is resolved from
+// the "repository" column, falling back to "homepage".
+func readCrates(r io.Reader) ([]Package, error) {
+	var pkgs []Package
+	err := readCSV(r, []string{"name", "downloads"}, func(col func(string) string) error {
+		n, err := parseCount(col("downloads"))
+		if err != nil {
+			return fmt.Errorf("crate %s: %w", col("name"), err)
+		}
+		pkgs = append(pkgs, Package{
+			Ecosystem: EcosystemCargo,
+			Name:      col("name"),
+			Count:     n,
+			Repo:      repoURL(col("repository"), col("homepage")),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("crates: %w", err)
+	}
+	return pkgs, nil
+}
+
+// readDepsDev reads the CSV file of packages written by
+// depsdev.CSVPackageWriter. Packages have already been resolved to their
+// repository by deps.dev, and are ranked by their dependent count.
+func readDepsDev(r io.Reader) ([]Package, error) {
+	var pkgs []Package
+	err := readCSV(r, []string{"repo", "system", "name", "dependent_count"}, func(col func(string) string) error {
+		n, err := parseCount(col("dependent_count"))
+		if err != nil {
+			return fmt.Errorf("package %s: %w", col("name"), err)
+		}
+		pkgs = append(pkgs, Package{
+			Ecosystem: col("system"),
+			Name:      col("name"),
+			Count:     n,
+			Repo:      col("repo"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("deps.dev packages: %w", err)
+	}
+	return pkgs, nil
+}
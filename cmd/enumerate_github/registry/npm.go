@@ -0,0 +1,100 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// npmHostPrefixes maps the shorthand prefixes allowed in the "repository"
+// field to the host they refer to.
+var npmHostPrefixes = map[string]string{
+	"github:":    "github.com/",
+	"gitlab:":    "gitlab.com/",
+	"bitbucket:": "bitbucket.org/",
+}
+
+// npmRepository is the "repository" field of a package document, which may
+// either be a string or an object with a "url" field.
+type npmRepository struct {
+	URL string
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (r *npmRepository) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.URL); err == nil {
+		return nil
+	}
+	var obj struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	r.URL = obj.URL
+	return nil
+}
+
+type npmDocument struct {
+	Name       string        `json:"name"`
+	Repository npmRepository `json:"repository"`
+	Homepage   string        `json:"homepage"`
+	Downloads  int           `json:"downloads"`
+}
+
+// readNPM reads a JSON Lines file, where each line is an npm registry package
+// document with an additional "downloads" count. The repository is resolved
+// from the "repository" field, falling back to "homepage".
+func readNPM(r io.Reader) ([]Package, error) {
+	var pkgs []Package
+	d := json.NewDecoder(r)
+	for {
+		var doc npmDocument
+		if err := d.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("npm document: %w", err)
+		}
+		if doc.Name == "" {
+			continue
+		}
+		pkgs = append(pkgs, Package{
+			Ecosystem: EcosystemNPM,
+			Name:      doc.Name,
+			Count:     doc.Downloads,
+			Repo:      repoURL(npmRepositoryRef(doc.Repository.URL), doc.Homepage),
+		})
+	}
+	return pkgs, nil
+}
+
+// npmRepositoryRef expands the shorthand forms of the "repository" field
+// (e.g. "github:o/r" or "o/r") into a host and path.
+func npmRepositoryRef(ref string) string {
+	for prefix, host := range npmHostPrefixes {
+		if strings.HasPrefix(ref, prefix) {
+			return host + strings.TrimPrefix(ref, prefix)
+		}
+	}
+	if !strings.Contains(ref, ":") && strings.Count(ref, "/") == 1 {
+		// npm treats "owner/repo" as a GitHub repository.
+		return "github.com/" + ref
+	}
+	return ref
+}
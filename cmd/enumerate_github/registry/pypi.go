@@ -0,0 +1,91 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// pypiSourceLabels are the project url labels most likely to refer to the
+// source repository, in order of preference.
+var pypiSourceLabels = []string{"source", "source code", "repository", "code", "github", "homepage"}
+
+type pypiResponse struct {
+	Info struct {
+		Name        string            `json:"name"`
+		HomePage    string            `json:"home_page"`
+		ProjectURLs map[string]string `json:"project_urls"`
+	} `json:"info"`
+	Downloads int `json:"downloads"`
+}
+
+// readPyPI reads a JSON Lines file, where each line is a response from the
+// PyPI JSON API (https://pypi.org/pypi/<name>/json) with an additional
+// "downloads" count. The repository is resolved from the project urls,
+// preferring those labelled as the source, falling back to "home_page".
+func readPyPI(r io.Reader) ([]Package, error) {
+	var pkgs []Package
+	d := json.NewDecoder(r)
+	for {
+		var resp pypiResponse
+		if err := d.Decode(&resp); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("pypi response: %w", err)
+		}
+		if resp.Info.Name == "" {
+			continue
+		}
+		refs := pypiProjectURLs(resp.Info.ProjectURLs)
+		pkgs = append(pkgs, Package{
+			Ecosystem: EcosystemPyPI,
+			Name:      resp.Info.Name,
+			Count:     resp.Downloads,
+			Repo:      repoURL(append(refs, resp.Info.HomePage)...),
+		})
+	}
+	return pkgs, nil
+}
+
+// pypiProjectURLs returns the urls in projectURLs, with those labelled with
+// one of pypiSourceLabels first, followed by the rest ordered by label.
+func pypiProjectURLs(projectURLs map[string]string) []string {
+	byLabel := make(map[string]string)
+	var labels []string
+	for label, u := range projectURLs {
+		label = strings.ToLower(strings.TrimSpace(label))
+		byLabel[label] = u
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	var urls []string
+	for _, label := range pypiSourceLabels {
+		if u, ok := byLabel[label]; ok {
+			urls = append(urls, u)
+			delete(byLabel, label)
+		}
+	}
+	for _, label := range labels {
+		if u, ok := byLabel[label]; ok {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry reads the packages listed in package registry metadata
+// dumps, and resolves each package to its source repository.
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/crawler"
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+)
+
+// Ecosystems use the same names as the deps.dev systems.
+const (
+	EcosystemNPM   = "NPM"
+	EcosystemPyPI  = "PYPI"
+	EcosystemCargo = "CARGO"
+)
+
+// Package is a single package in a registry.
+type Package struct {
+	Ecosystem string
+	Name      string
+
+	// Count is the number of downloads or dependents of the package,
+	// depending on the source it was read from. Packages are ranked by Count.
+	Count int
+
+	// Repo is the url of the package's source repository, or empty if it
+	// could not be resolved to a supported host.
+	Repo string
+}
+
+// Format identifies the format of a registry metadata dump.
+type Format int
+
+const (
+	// FormatNPM is a JSON Lines file of npm registry package documents.
+	FormatNPM = Format(iota)
+
+	// FormatPyPI is a JSON Lines file of PyPI JSON API responses.
+	FormatPyPI
+
+	// FormatCrates is the crates.csv file from a crates.io database dump.
+	FormatCrates
+
+	// FormatDepsDev is a CSV file of the packages for each repository, as
+	// written by "criticality_score -depsdev-packages-out".
+	FormatDepsDev
+)
+
+var ErrorUnknownFormat = errors.New("unknown registry format")
+
+// String implements the fmt.Stringer interface.
+func (f Format) String() string {
+	text, err := f.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (f Format) MarshalText() ([]byte, error) {
+	switch f {
+	case FormatNPM:
+		return []byte("npm"), nil
+	case FormatPyPI:
+		return []byte("pypi"), nil
+	case FormatCrates:
+		return []byte("crates"), nil
+	case FormatDepsDev:
+		return []byte("depsdev"), nil
+	default:
+		return []byte{}, ErrorUnknownFormat
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (f *Format) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("npm")):
+		*f = FormatNPM
+	case bytes.Equal(text, []byte("pypi")):
+		*f = FormatPyPI
+	case bytes.Equal(text, []byte("crates")):
+		*f = FormatCrates
+	case bytes.Equal(text, []byte("depsdev")):
+		*f = FormatDepsDev
+	default:
+		return ErrorUnknownFormat
+	}
+	return nil
+}
+
+// Read returns every package in the dump r, which is in the format f.
+func (f Format) Read(r io.Reader) ([]Package, error) {
+	switch f {
+	case FormatNPM:
+		return readNPM(r)
+	case FormatPyPI:
+		return readPyPI(r)
+	case FormatCrates:
+		return readCrates(r)
+	case FormatDepsDev:
+		return readDepsDev(r)
+	default:
+		return nil, ErrorUnknownFormat
+	}
+}
+
+// Top returns the n packages with the highest Count in each ecosystem,
+// ordered by ecosystem and then from the highest Count to the lowest. Ties
+// are ordered by name. If n is 0 or less every package is returned.
+func Top(pkgs []Package, n int) []Package {
+	sorted := append([]Package(nil), pkgs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Ecosystem != b.Ecosystem {
+			return a.Ecosystem < b.Ecosystem
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	if n <= 0 {
+		return sorted
+	}
+	var top []Package
+	ranked := make(map[string]int)
+	for _, p := range sorted {
+		if ranked[p.Ecosystem] < n {
+			ranked[p.Ecosystem]++
+			top = append(top, p)
+		}
+	}
+	return top
+}
+
+// repoURL returns the url of the first of refs that refers to a repository on
+// a supported host, or an empty string if none do.
+func repoURL(refs ...string) string {
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		p, ok := crawler.ProjectForRef(ref)
+		if !ok {
+			continue
+		}
+		if u, ok := depsdev.URLForProject(p); ok {
+			return u
+		}
+	}
+	return ""
+}
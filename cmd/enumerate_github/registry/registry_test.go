@@ -0,0 +1,144 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/registry"
+)
+
+func TestFormatRead(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		format registry.Format
+		input  string
+		want   []registry.Package
+	}{
+		{
+			format: registry.FormatNPM,
+			input: `{"name":"express","repository":{"type":"git","url":"git+https://github.com/expressjs/express.git"},"downloads":100}
+{"name":"left-pad","repository":"stevemao/left-pad","downloads":10}
+{"name":"lodash","homepage":"https://lodash.com/","downloads":50}
+{"name":"vue","repository":"github:vuejs/core","downloads":70}
+`,
+			want: []registry.Package{
+				{Ecosystem: "NPM", Name: "express", Count: 100, Repo: "https://github.com/expressjs/express"},
+				{Ecosystem: "NPM", Name: "left-pad", Count: 10, Repo: "https://github.com/stevemao/left-pad"},
+				{Ecosystem: "NPM", Name: "lodash", Count: 50},
+				{Ecosystem: "NPM", Name: "vue", Count: 70, Repo: "https://github.com/vuejs/core"},
+			},
+		},
+		{
+			format: registry.FormatPyPI,
+			input: `{"info":{"name":"requests","home_page":"https://requests.readthedocs.io","project_urls":{"Documentation":"https://requests.readthedocs.io","Source":"https://github.com/psf/requests"}},"downloads":100}
+{"info":{"name":"six","home_page":"https://github.com/benjaminp/six"},"downloads":20}
+`,
+			want: []registry.Package{
+				{Ecosystem: "PYPI", Name: "requests", Count: 100, Repo: "https://github.com/psf/requests"},
+				{Ecosystem: "PYPI", Name: "six", Count: 20, Repo: "https://github.com/benjaminp/six"},
+			},
+		},
+		{
+			format: registry.FormatCrates,
+			input: "created_at,downloads,homepage,id,name,repository\n" +
+				"2014-01-01,300,,1,serde,https://github.com/serde-rs/serde\n" +
+				"2014-01-01,200,https://gitlab.com/example/crate,2,example,\n",
+			want: []registry.Package{
+				{Ecosystem: "CARGO", Name: "serde", Count: 300, Repo: "https://github.com/serde-rs/serde"},
+				{Ecosystem: "CARGO", Name: "example", Count: 200, Repo: "https://gitlab.com/example/crate"},
+			},
+		},
+		{
+			format: registry.FormatDepsDev,
+			input: "repo,project_type,project_name,system,name,dependent_count\n" +
+				"https://github.com/babel/babel,GITHUB,babel/babel,NPM,@babel/core,100\n",
+			want: []registry.Package{
+				{Ecosystem: "NPM", Name: "@babel/core", Count: 100, Repo: "https://github.com/babel/babel"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.format.String(), func(t *testing.T) {
+			got, err := test.format.Read(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("Read() = %v, want no error", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("Read() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatReadMissingColumn(t *testing.T) {
+	_, err := registry.FormatCrates.Read(strings.NewReader("id,name\n1,serde\n"))
+	if err == nil {
+		t.Fatal("Read() = nil, want an error")
+	}
+}
+
+func TestTop(t *testing.T) {
+	pkgs := []registry.Package{
+		{Ecosystem: "PYPI", Name: "a", Count: 1},
+		{Ecosystem: "NPM", Name: "b", Count: 5},
+		{Ecosystem: "NPM", Name: "c", Count: 10},
+		{Ecosystem: "NPM", Name: "a", Count: 5},
+	}
+	want := []registry.Package{
+		{Ecosystem: "NPM", Name: "c", Count: 10},
+		{Ecosystem: "NPM", Name: "a", Count: 5},
+		{Ecosystem: "PYPI", Name: "a", Count: 1},
+	}
+	if diff := cmp.Diff(want, registry.Top(pkgs, 2)); diff != "" {
+		t.Fatalf("Top() mismatch (-want +got):\n%s", diff)
+	}
+	if got := registry.Top(pkgs, 0); len(got) != len(pkgs) {
+		t.Fatalf("Top(0) returned %d packages, want %d", len(got), len(pkgs))
+	}
+}
+
+func TestFormatUnmarshalText(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		input   string
+		want    registry.Format
+		wantErr bool
+	}{
+		{input: "npm", want: registry.FormatNPM},
+		{input: "pypi", want: registry.FormatPyPI},
+		{input: "crates", want: registry.FormatCrates},
+		{input: "depsdev", want: registry.FormatDepsDev},
+		{input: "maven", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var got registry.Format
+			err := got.UnmarshalText([]byte(test.input))
+			if test.wantErr && err == nil {
+				t.Fatal("UnmarshalText() = nil, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("UnmarshalText() = %v, want no error", err)
+			}
+			if got != test.want {
+				t.Fatalf("UnmarshalText() parsed %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/crawler"
+)
+
+// CrawlWriter is used to output the repositories reached by a dependency
+// crawl.
+type CrawlWriter interface {
+	// WriteCrawl outputs a single repository reached by the crawl.
+	WriteCrawl(r crawler.Result) error
+}
+
+// crawlPathSeparator separates each repository in the path of a CSV row.
+const crawlPathSeparator = " > "
+
+var crawlHeader = []string{"repo", "depth", "path"}
+
+type csvCrawlWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// CrawlCSV returns a CrawlWriter that writes a CSV file with one row for each
+// repository. The path is written as a single column, with each url separated
+// by " > ".
+func CrawlCSV(w io.Writer) CrawlWriter {
+	return &csvCrawlWriter{w: csv.NewWriter(w)}
+}
+
+// WriteCrawl implements the CrawlWriter interface.
+func (w *csvCrawlWriter) WriteCrawl(r crawler.Result) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.w.Write(crawlHeader); err != nil {
+			return err
+		}
+	}
+	rec := []string{r.URL, strconv.Itoa(r.Depth), strings.Join(r.Path, crawlPathSeparator)}
+	if err := w.w.Write(rec); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonCrawlWriter struct {
+	encoder *json.Encoder
+}
+
+type jsonCrawlResult struct {
+	Repo  string   `json:"repo"`
+	Depth int      `json:"depth"`
+	Path  []string `json:"path"`
+}
+
+// CrawlJSON returns a CrawlWriter that writes a JSON object for each
+// repository.
+func CrawlJSON(w io.Writer) CrawlWriter {
+	return &jsonCrawlWriter{encoder: json.NewEncoder(w)}
+}
+
+// WriteCrawl implements the CrawlWriter interface.
+func (w *jsonCrawlWriter) WriteCrawl(r crawler.Result) error {
+	return w.encoder.Encode(jsonCrawlResult{Repo: r.URL, Depth: r.Depth, Path: r.Path})
+}
+
+type CrawlWriterType int
+
+const (
+	// CrawlWriterTypeCSV corresponds to the CrawlWriter returned by CrawlCSV.
+	CrawlWriterTypeCSV = CrawlWriterType(iota)
+
+	// CrawlWriterTypeJSON corresponds to the CrawlWriter returned by
+	// CrawlJSON.
+	CrawlWriterTypeJSON
+)
+
+var ErrorUnknownCrawlWriterType = errors.New("unknown crawl writer type")
+
+func (t CrawlWriterType) String() string {
+	text, err := t.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+func (t CrawlWriterType) MarshalText() ([]byte, error) {
+	switch t {
+	case CrawlWriterTypeCSV:
+		return []byte("csv"), nil
+	case CrawlWriterTypeJSON:
+		return []byte("json"), nil
+	default:
+		return []byte{}, ErrorUnknownCrawlWriterType
+	}
+}
+
+func (t *CrawlWriterType) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("csv")):
+		*t = CrawlWriterTypeCSV
+	case bytes.Equal(text, []byte("json")):
+		*t = CrawlWriterTypeJSON
+	default:
+		return ErrorUnknownCrawlWriterType
+	}
+	return nil
+}
+
+func (t *CrawlWriterType) New(w io.Writer) CrawlWriter {
+	switch *t {
+	case CrawlWriterTypeCSV:
+		return CrawlCSV(w)
+	case CrawlWriterTypeJSON:
+		return CrawlJSON(w)
+	default:
+		return nil
+	}
+}
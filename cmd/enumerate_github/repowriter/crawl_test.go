@@ -0,0 +1,96 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/crawler"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+)
+
+var testCrawlResults = []crawler.Result{
+	{
+		URL:  "https://github.com/example/app",
+		Path: []string{"https://github.com/example/app"},
+	},
+	{
+		URL:   "https://github.com/example/lib",
+		Depth: 1,
+		Path:  []string{"https://github.com/example/app", "https://github.com/example/lib"},
+	},
+}
+
+func TestCrawlCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := repowriter.CrawlCSV(&buf)
+	for _, r := range testCrawlResults {
+		w.WriteCrawl(r)
+	}
+
+	want := "repo,depth,path\n" +
+		"https://github.com/example/app,0,https://github.com/example/app\n" +
+		"https://github.com/example/lib,1,https://github.com/example/app > https://github.com/example/lib\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("CrawlCSV() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCrawlJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := repowriter.CrawlJSON(&buf)
+	for _, r := range testCrawlResults {
+		w.WriteCrawl(r)
+	}
+
+	want := `{"repo":"https://github.com/example/app","depth":0,"path":["https://github.com/example/app"]}` + "\n" +
+		`{"repo":"https://github.com/example/lib","depth":1,"path":["https://github.com/example/app","https://github.com/example/lib"]}` + "\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("CrawlJSON() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCrawlWriterTypeUnmarshalText(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		input   string
+		want    repowriter.CrawlWriterType
+		wantErr bool
+	}{
+		{input: "csv", want: repowriter.CrawlWriterTypeCSV},
+		{input: "json", want: repowriter.CrawlWriterTypeJSON},
+		{input: "text", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var got repowriter.CrawlWriterType
+			err := got.UnmarshalText([]byte(test.input))
+			if test.wantErr && err == nil {
+				t.Fatal("UnmarshalText() = nil, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("UnmarshalText() = %v, want no error", err)
+			}
+			if got != test.want {
+				t.Fatalf("UnmarshalText() parsed %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+var csvHeader = []string{"repo", "stars", "language", "created", "pushed", "ecosystem", "package"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// CSV creates a new Writer instance that is used to write a csv file of
+// repositories and their metadata.
+//
+// The csv file has a header row with columns "repo", "stars", "language",
+// "created", "pushed", "ecosystem" and "package". Dates are formatted using
+// RFC 3339, and are left blank if they are not known.
+func CSV(w io.Writer) Writer {
+	c := csv.NewWriter(w)
+	c.Write(csvHeader)
+	return &csvWriter{w: c}
+}
+
+// Write implements the Writer interface.
+func (w *csvWriter) Write(repo Repo) error {
+	rec := []string{
+		repo.URL,
+		strconv.Itoa(repo.Stars),
+		repo.Language,
+		formatTime(repo.CreatedAt),
+		formatTime(repo.PushedAt),
+		repo.Ecosystem,
+		repo.Package,
+	}
+	if err := w.w.Write(rec); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
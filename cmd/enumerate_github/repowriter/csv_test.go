@@ -0,0 +1,41 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+)
+
+func TestCSVRepoWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := repowriter.CSV(&buf)
+	for _, repo := range testRepos {
+		w.Write(repo)
+	}
+
+	want := "repo,stars,language,created,pushed,ecosystem,package\n" +
+		"https://github.com/example/example,0,,,,,\n" +
+		"https://github.com/ossf/criticality_score,1000,Go,2020-11-25T18:30:00Z,2022-06-14T12:00:00Z,,\n" +
+		"https://github.com/expressjs/express,0,,,,NPM,express\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("CSV() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonRepo is the JSON representation of a Repo.
+type jsonRepo struct {
+	Repo      string `json:"repo"`
+	Stars     int    `json:"stars"`
+	Language  string `json:"language,omitempty"`
+	Created   string `json:"created,omitempty"`
+	Pushed    string `json:"pushed,omitempty"`
+	Ecosystem string `json:"ecosystem,omitempty"`
+	Package   string `json:"package,omitempty"`
+}
+
+type jsonlWriter struct {
+	e *json.Encoder
+}
+
+// JSONL creates a new Writer instance that is used to write a JSON Lines file
+// of repositories and their metadata, with one JSON object per line.
+//
+// Each object has the fields "repo" and "stars", and, if they are known,
+// "language", "created", "pushed", "ecosystem" and "package". Dates are
+// formatted using RFC 3339.
+func JSONL(w io.Writer) Writer {
+	return &jsonlWriter{e: json.NewEncoder(w)}
+}
+
+// Write implements the Writer interface.
+func (w *jsonlWriter) Write(repo Repo) error {
+	return w.e.Encode(jsonRepo{
+		Repo:      repo.URL,
+		Stars:     repo.Stars,
+		Language:  repo.Language,
+		Created:   formatTime(repo.CreatedAt),
+		Pushed:    formatTime(repo.PushedAt),
+		Ecosystem: repo.Ecosystem,
+		Package:   repo.Package,
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+)
+
+func TestJSONLRepoWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := repowriter.JSONL(&buf)
+	for _, repo := range testRepos {
+		w.Write(repo)
+	}
+
+	want := `{"repo":"https://github.com/example/example","stars":0}` + "\n" +
+		`{"repo":"https://github.com/ossf/criticality_score","stars":1000,"language":"Go","created":"2020-11-25T18:30:00Z","pushed":"2022-06-14T12:00:00Z"}` + "\n" +
+		`{"repo":"https://github.com/expressjs/express","stars":0,"ecosystem":"NPM","package":"express"}` + "\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("JSONL() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadRepos reads back the repositories from r, which was written by the
+// Writer corresponding to t. Only the metadata written by the Writer is set.
+func ReadRepos(r io.Reader, t WriterType) ([]Repo, error) {
+	switch t {
+	case WriterTypeText:
+		return readText(r)
+	case WriterTypeScorecard:
+		return readCSV(r, header, func(row []string) (Repo, error) {
+			repo := Repo{URL: row[0]}
+			if len(row) > 1 {
+				if err := parseScorecardMetadata(row[1], &repo); err != nil {
+					return Repo{}, err
+				}
+			}
+			return repo, nil
+		})
+	case WriterTypeCSV:
+		return readCSV(r, csvHeader, parseCSVRow)
+	case WriterTypeJSONL:
+		return readJSONL(r)
+	default:
+		return nil, ErrorUnknownRepoWriterType
+	}
+}
+
+func readText(r io.Reader) ([]Repo, error) {
+	var repos []Repo
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if url := strings.TrimSpace(scanner.Text()); url != "" {
+			repos = append(repos, Repo{URL: url})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// readCSV reads each row after the header using parse. The first column of
+// the header is checked to ensure the file is in the expected format.
+func readCSV(r io.Reader, header []string, parse func([]string) (Repo, error)) ([]Repo, error) {
+	var repos []Repo
+	c := csv.NewReader(r)
+	c.FieldsPerRecord = -1
+	first := true
+	for {
+		row, err := c.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if first {
+			first = false
+			if len(row) == 0 || row[0] != header[0] {
+				return nil, fmt.Errorf("missing %q header", header[0])
+			}
+			continue
+		}
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+		repo, err := parse(row)
+		if err != nil {
+			return nil, fmt.Errorf("repo %s: %w", row[0], err)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// csvBaseColumns is the number of columns written before the package identity
+// columns were added. Files with only these columns can still be read.
+const csvBaseColumns = 5
+
+func parseCSVRow(row []string) (Repo, error) {
+	if len(row) != len(csvHeader) && len(row) != csvBaseColumns {
+		return Repo{}, fmt.Errorf("got %d columns, want %d", len(row), len(csvHeader))
+	}
+	repo := Repo{URL: row[0], Language: row[2]}
+	var err error
+	if repo.Stars, err = strconv.Atoi(row[1]); err != nil {
+		return Repo{}, err
+	}
+	if repo.CreatedAt, err = parseTime(row[3]); err != nil {
+		return Repo{}, err
+	}
+	if repo.PushedAt, err = parseTime(row[4]); err != nil {
+		return Repo{}, err
+	}
+	if len(row) == len(csvHeader) {
+		repo.Ecosystem, repo.Package = row[5], row[6]
+	}
+	return repo, nil
+}
+
+func readJSONL(r io.Reader) ([]Repo, error) {
+	var repos []Repo
+	d := json.NewDecoder(r)
+	for {
+		var jr jsonRepo
+		if err := d.Decode(&jr); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		repo := Repo{URL: jr.Repo, Stars: jr.Stars, Language: jr.Language, Ecosystem: jr.Ecosystem, Package: jr.Package}
+		var err error
+		if repo.CreatedAt, err = parseTime(jr.Created); err != nil {
+			return nil, fmt.Errorf("repo %s: %w", jr.Repo, err)
+		}
+		if repo.PushedAt, err = parseTime(jr.Pushed); err != nil {
+			return nil, fmt.Errorf("repo %s: %w", jr.Repo, err)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
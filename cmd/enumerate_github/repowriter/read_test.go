@@ -0,0 +1,110 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+)
+
+var testRepos = []repowriter.Repo{
+	{URL: "https://github.com/example/example"},
+	{
+		URL:       "https://github.com/ossf/criticality_score",
+		Stars:     1000,
+		Language:  "Go",
+		CreatedAt: time.Date(2020, 11, 25, 18, 30, 0, 0, time.UTC),
+		PushedAt:  time.Date(2022, 6, 14, 12, 0, 0, 0, time.UTC),
+	},
+	{URL: "https://github.com/expressjs/express", Ecosystem: "NPM", Package: "express"},
+}
+
+func TestReadRepos(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		writerType repowriter.WriterType
+		want       []repowriter.Repo
+	}{
+		{
+			writerType: repowriter.WriterTypeText,
+			want: []repowriter.Repo{
+				{URL: testRepos[0].URL},
+				{URL: testRepos[1].URL},
+				{URL: testRepos[2].URL},
+			},
+		},
+		{writerType: repowriter.WriterTypeScorecard, want: testRepos},
+		{writerType: repowriter.WriterTypeCSV, want: testRepos},
+		{writerType: repowriter.WriterTypeJSONL, want: testRepos},
+	}
+	for _, test := range tests {
+		t.Run(test.writerType.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := test.writerType.New(&buf)
+			for _, repo := range testRepos {
+				w.Write(repo)
+			}
+			got, err := repowriter.ReadRepos(&buf, test.writerType)
+			if err != nil {
+				t.Fatalf("ReadRepos() = %v, want no error", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("ReadRepos() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadReposScorecardMissingHeader(t *testing.T) {
+	_, err := repowriter.ReadRepos(bytes.NewBufferString("https://github.com/example/example,\n"), repowriter.WriterTypeScorecard)
+	if err == nil {
+		t.Fatal("ReadRepos() = nil, want an error")
+	}
+}
+
+func TestReadReposCSVWithoutPackage(t *testing.T) {
+	in := "repo,stars,language,created,pushed\n" +
+		"https://github.com/ossf/criticality_score,1000,Go,,\n"
+	got, err := repowriter.ReadRepos(bytes.NewBufferString(in), repowriter.WriterTypeCSV)
+	if err != nil {
+		t.Fatalf("ReadRepos() = %v, want no error", err)
+	}
+	want := []repowriter.Repo{{URL: "https://github.com/ossf/criticality_score", Stars: 1000, Language: "Go"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ReadRepos() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAppendReadRepos(t *testing.T) {
+	for _, writerType := range []repowriter.WriterType{repowriter.WriterTypeText, repowriter.WriterTypeScorecard, repowriter.WriterTypeCSV, repowriter.WriterTypeJSONL} {
+		t.Run(writerType.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			writerType.New(&buf).Write(testRepos[0])
+			writerType.Append(&buf).Write(testRepos[0])
+			got, err := repowriter.ReadRepos(&buf, writerType)
+			if err != nil {
+				t.Fatalf("ReadRepos() = %v, want no error", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("ReadRepos() returned %d repos, want 2", len(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/resolver"
+)
+
+// ResolutionWriter is used to output the ranked candidate repositories for a
+// package name.
+type ResolutionWriter interface {
+	// WriteResolution outputs the result of resolving a single package name.
+	WriteResolution(r resolver.Result) error
+}
+
+var resolutionHeader = []string{"name", "status", "rank", "repo", "stars", "name_match", "manifest_match", "score", "confidence"}
+
+type csvResolutionWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// ResolutionCSV returns a ResolutionWriter that writes a CSV file with one row
+// for each candidate. A name without any candidates is written as a single
+// row with only the name and status set.
+func ResolutionCSV(w io.Writer) ResolutionWriter {
+	return &csvResolutionWriter{w: csv.NewWriter(w)}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
+
+// WriteResolution implements the ResolutionWriter interface.
+func (w *csvResolutionWriter) WriteResolution(r resolver.Result) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.w.Write(resolutionHeader); err != nil {
+			return err
+		}
+	}
+	if len(r.Candidates) == 0 {
+		if err := w.w.Write([]string{r.Name, string(r.Status), "", "", "", "", "", "", ""}); err != nil {
+			return err
+		}
+	}
+	for i, c := range r.Candidates {
+		rec := []string{
+			r.Name,
+			string(r.Status),
+			strconv.Itoa(i + 1),
+			c.Repo,
+			strconv.Itoa(c.Stars),
+			formatFloat(c.NameMatch),
+			strconv.FormatBool(c.ManifestMatch),
+			formatFloat(c.Score),
+			formatFloat(c.Confidence),
+		}
+		if err := w.w.Write(rec); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonResolutionWriter struct {
+	encoder *json.Encoder
+}
+
+// ResolutionJSON returns a ResolutionWriter that writes a JSON object for each
+// name, containing the candidates in ranked order.
+func ResolutionJSON(w io.Writer) ResolutionWriter {
+	return &jsonResolutionWriter{encoder: json.NewEncoder(w)}
+}
+
+// WriteResolution implements the ResolutionWriter interface.
+func (w *jsonResolutionWriter) WriteResolution(r resolver.Result) error {
+	if r.Candidates == nil {
+		r.Candidates = []resolver.Candidate{}
+	}
+	return w.encoder.Encode(r)
+}
+
+type ResolutionWriterType int
+
+const (
+	// ResolutionWriterTypeCSV corresponds to the ResolutionWriter returned by
+	// ResolutionCSV.
+	ResolutionWriterTypeCSV = ResolutionWriterType(iota)
+
+	// ResolutionWriterTypeJSON corresponds to the ResolutionWriter returned by
+	// ResolutionJSON.
+	ResolutionWriterTypeJSON
+)
+
+var ErrorUnknownResolutionWriterType = errors.New("unknown resolution writer type")
+
+func (t ResolutionWriterType) String() string {
+	text, err := t.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+func (t ResolutionWriterType) MarshalText() ([]byte, error) {
+	switch t {
+	case ResolutionWriterTypeCSV:
+		return []byte("csv"), nil
+	case ResolutionWriterTypeJSON:
+		return []byte("json"), nil
+	default:
+		return []byte{}, ErrorUnknownResolutionWriterType
+	}
+}
+
+func (t *ResolutionWriterType) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("csv")):
+		*t = ResolutionWriterTypeCSV
+	case bytes.Equal(text, []byte("json")):
+		*t = ResolutionWriterTypeJSON
+	default:
+		return ErrorUnknownResolutionWriterType
+	}
+	return nil
+}
+
+func (t *ResolutionWriterType) New(w io.Writer) ResolutionWriter {
+	switch *t {
+	case ResolutionWriterTypeCSV:
+		return ResolutionCSV(w)
+	case ResolutionWriterTypeJSON:
+		return ResolutionJSON(w)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repowriter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/repowriter"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/resolver"
+)
+
+var testResults = []resolver.Result{
+	{
+		Name:   "express",
+		Status: resolver.StatusMatched,
+		Candidates: []resolver.Candidate{
+			{Repo: "https://github.com/expressjs/express", Stars: 60000, NameMatch: 1, ManifestMatch: true, Score: 0.99, Confidence: 0.8},
+			{Repo: "https://github.com/example/express-fork", Stars: 1, NameMatch: 0.5, Score: 0.2, Confidence: 0.03},
+		},
+	},
+	{
+		Name:   "unknown",
+		Status: resolver.StatusNoMatch,
+	},
+}
+
+func TestResolutionCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := repowriter.ResolutionCSV(&buf)
+	for _, r := range testResults {
+		w.WriteResolution(r)
+	}
+
+	want := "name,status,rank,repo,stars,name_match,manifest_match,score,confidence\n" +
+		"express,matched,1,https://github.com/expressjs/express,60000,1.0000,true,0.9900,0.8000\n" +
+		"express,matched,2,https://github.com/example/express-fork,1,0.5000,false,0.2000,0.0300\n" +
+		"unknown,no_match,,,,,,,\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("ResolutionCSV() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolutionJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := repowriter.ResolutionJSON(&buf)
+	for _, r := range testResults {
+		w.WriteResolution(r)
+	}
+
+	want := `{"name":"express","status":"matched","candidates":[` +
+		`{"repo":"https://github.com/expressjs/express","stars":60000,"name_match":1,"manifest_match":true,"score":0.99,"confidence":0.8},` +
+		`{"repo":"https://github.com/example/express-fork","stars":1,"name_match":0.5,"manifest_match":false,"score":0.2,"confidence":0.03}]}` + "\n" +
+		`{"name":"unknown","status":"no_match","candidates":[]}` + "\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("ResolutionJSON() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolutionWriterTypeUnmarshalText(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		input   string
+		want    repowriter.ResolutionWriterType
+		wantErr bool
+	}{
+		{input: "csv", want: repowriter.ResolutionWriterTypeCSV},
+		{input: "json", want: repowriter.ResolutionWriterTypeJSON},
+		{input: "text", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var got repowriter.ResolutionWriterType
+			err := got.UnmarshalText([]byte(test.input))
+			if test.wantErr && err == nil {
+				t.Fatal("UnmarshalText() = nil, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("UnmarshalText() = %v, want no error", err)
+			}
+			if got != test.want {
+				t.Fatalf("UnmarshalText() parsed %s, want %s", got, test.want)
+			}
+		})
+	}
+}
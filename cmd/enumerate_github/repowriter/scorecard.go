@@ -2,7 +2,20 @@ package repowriter
 
 import (
 	"encoding/csv"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
+)
+
+// Keys used in the scorecard metadata column.
+const (
+	metadataStars    = "stars"
+	metadataLanguage = "language"
+	metadataCreated  = "created"
+	metadataPushed   = "pushed"
+	metadataEco      = "ecosystem"
+	metadataPackage  = "package"
 )
 
 var header = []string{"repo", "metadata"}
@@ -16,7 +29,9 @@ type scorecardWriter struct {
 // project.
 //
 // The csv file has a header row with columns "repo" and "metadata". Each
-// row consists of the repository url and blank metadata.
+// row consists of the repository url and its metadata, as a comma separated
+// list of key=value pairs. Only metadata that is known is included. For
+// example: "stars=10,language=Go,created=2022-06-14T00:00:00Z".
 func Scorecard(w io.Writer) Writer {
 	csvWriter := csv.NewWriter(w)
 	csvWriter.Write(header)
@@ -24,10 +39,63 @@ func Scorecard(w io.Writer) Writer {
 }
 
 // Write implements the Writer interface.
-func (w *scorecardWriter) Write(repo string) error {
-	if err := w.w.Write([]string{repo, ""}); err != nil {
+func (w *scorecardWriter) Write(repo Repo) error {
+	if err := w.w.Write([]string{repo.URL, scorecardMetadata(repo)}); err != nil {
 		return err
 	}
 	w.w.Flush()
 	return w.w.Error()
 }
+
+func scorecardMetadata(repo Repo) string {
+	var md []string
+	if repo.Stars != 0 {
+		md = append(md, metadataStars+"="+strconv.Itoa(repo.Stars))
+	}
+	if repo.Language != "" {
+		md = append(md, metadataLanguage+"="+repo.Language)
+	}
+	if created := formatTime(repo.CreatedAt); created != "" {
+		md = append(md, metadataCreated+"="+created)
+	}
+	if pushed := formatTime(repo.PushedAt); pushed != "" {
+		md = append(md, metadataPushed+"="+pushed)
+	}
+	if repo.Ecosystem != "" {
+		md = append(md, metadataEco+"="+repo.Ecosystem)
+	}
+	if repo.Package != "" {
+		md = append(md, metadataPackage+"="+repo.Package)
+	}
+	return strings.Join(md, ",")
+}
+
+// parseScorecardMetadata sets the fields in repo from the scorecard metadata
+// in md. Unknown keys are ignored.
+func parseScorecardMetadata(md string, repo *Repo) error {
+	if md == "" {
+		return nil
+	}
+	for _, kv := range strings.Split(md, ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		var err error
+		switch k {
+		case metadataStars:
+			repo.Stars, err = strconv.Atoi(v)
+		case metadataLanguage:
+			repo.Language = v
+		case metadataCreated:
+			repo.CreatedAt, err = parseTime(v)
+		case metadataPushed:
+			repo.PushedAt, err = parseTime(v)
+		case metadataEco:
+			repo.Ecosystem = v
+		case metadataPackage:
+			repo.Package = v
+		}
+		if err != nil {
+			return fmt.Errorf("metadata %q: %w", k, err)
+		}
+	}
+	return nil
+}
@@ -12,12 +12,14 @@ import (
 func TestScorecardRepoWriter(t *testing.T) {
 	var buf bytes.Buffer
 	w := repowriter.Scorecard(&buf)
-	w.Write("https://github.com/example/example")
-	w.Write("https://github.com/ossf/criticality_score")
+	for _, repo := range testRepos {
+		w.Write(repo)
+	}
 
 	want := "repo,metadata\n" +
 		"https://github.com/example/example,\n" +
-		"https://github.com/ossf/criticality_score,\n"
+		"https://github.com/ossf/criticality_score,\"stars=1000,language=Go,created=2020-11-25T18:30:00Z,pushed=2022-06-14T12:00:00Z\"\n" +
+		"https://github.com/expressjs/express,\"ecosystem=NPM,package=express\"\n"
 
 	if diff := cmp.Diff(want, buf.String()); diff != "" {
 		t.Fatalf("Scorecard() mismatch (-want +got):\n%s", diff)
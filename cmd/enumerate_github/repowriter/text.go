@@ -16,7 +16,7 @@ func Text(w io.Writer) Writer {
 }
 
 // Write implements the Writer interface.
-func (w *textWriter) Write(repo string) error {
-	_, err := fmt.Fprintln(w.w, repo)
+func (w *textWriter) Write(repo Repo) error {
+	_, err := fmt.Fprintln(w.w, repo.URL)
 	return err
 }
@@ -12,8 +12,8 @@ import (
 func TestTextRepoWriter(t *testing.T) {
 	var buf bytes.Buffer
 	w := repowriter.Text(&buf)
-	w.Write("https://github.com/example/example")
-	w.Write("https://github.com/ossf/criticality_score")
+	w.Write(repowriter.Repo{URL: "https://github.com/example/example"})
+	w.Write(repowriter.Repo{URL: "https://github.com/ossf/criticality_score", Stars: 10})
 
 	want := "https://github.com/example/example\n" +
 		"https://github.com/ossf/criticality_score\n"
@@ -2,6 +2,7 @@ package repowriter
 
 import (
 	"bytes"
+	"encoding/csv"
 	"errors"
 	"io"
 )
@@ -14,6 +15,12 @@ const (
 
 	// WriterTypeScorecard corresponds to the Writer returned by Scorecard.
 	WriterTypeScorecard
+
+	// WriterTypeCSV corresponds to the Writer returned by CSV.
+	WriterTypeCSV
+
+	// WriterTypeJSONL corresponds to the Writer returned by JSONL.
+	WriterTypeJSONL
 )
 
 var ErrorUnknownRepoWriterType = errors.New("unknown repo writer type")
@@ -34,6 +41,10 @@ func (t WriterType) MarshalText() ([]byte, error) {
 		return []byte("text"), nil
 	case WriterTypeScorecard:
 		return []byte("scorecard"), nil
+	case WriterTypeCSV:
+		return []byte("csv"), nil
+	case WriterTypeJSONL:
+		return []byte("jsonl"), nil
 	default:
 		return []byte{}, ErrorUnknownRepoWriterType
 	}
@@ -46,6 +57,10 @@ func (t *WriterType) UnmarshalText(text []byte) error {
 		*t = WriterTypeText
 	case bytes.Equal(text, []byte("scorecard")):
 		*t = WriterTypeScorecard
+	case bytes.Equal(text, []byte("csv")):
+		*t = WriterTypeCSV
+	case bytes.Equal(text, []byte("jsonl")):
+		*t = WriterTypeJSONL
 	default:
 		return ErrorUnknownRepoWriterType
 	}
@@ -60,7 +75,25 @@ func (t *WriterType) New(w io.Writer) Writer {
 		return Text(w)
 	case WriterTypeScorecard:
 		return Scorecard(w)
+	case WriterTypeCSV:
+		return CSV(w)
+	case WriterTypeJSONL:
+		return JSONL(w)
 	default:
 		return nil
 	}
 }
+
+// Append will return a new instance of the corresponding implementation of
+// Writer for the given WriterType, for appending to output previously written
+// by a Writer of the same type. Unlike New, no header is written.
+func (t *WriterType) Append(w io.Writer) Writer {
+	switch *t {
+	case WriterTypeScorecard:
+		return &scorecardWriter{w: csv.NewWriter(w)}
+	case WriterTypeCSV:
+		return &csvWriter{w: csv.NewWriter(w)}
+	default:
+		return t.New(w)
+	}
+}
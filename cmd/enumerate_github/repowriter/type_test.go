@@ -16,6 +16,8 @@ func TestTypeString(t *testing.T) {
 	}{
 		{name: "text", writerType: repowriter.WriterTypeText, want: "text"},
 		{name: "scorecard", writerType: repowriter.WriterTypeScorecard, want: "scorecard"},
+		{name: "csv", writerType: repowriter.WriterTypeCSV, want: "csv"},
+		{name: "jsonl", writerType: repowriter.WriterTypeJSONL, want: "jsonl"},
 		{name: "unknown", writerType: repowriter.WriterType(10), want: ""},
 	}
 	for _, test := range tests {
@@ -38,6 +40,8 @@ func TestTypeMarshalText(t *testing.T) {
 	}{
 		{name: "text", writerType: repowriter.WriterTypeText, want: "text"},
 		{name: "scorecard", writerType: repowriter.WriterTypeScorecard, want: "scorecard"},
+		{name: "csv", writerType: repowriter.WriterTypeCSV, want: "csv"},
+		{name: "jsonl", writerType: repowriter.WriterTypeJSONL, want: "jsonl"},
 		{name: "unknown", writerType: repowriter.WriterType(10), want: "", err: repowriter.ErrorUnknownRepoWriterType},
 	}
 	for _, test := range tests {
@@ -67,6 +71,8 @@ func TestTypeUnmarshalText(t *testing.T) {
 	}{
 		{input: "text", want: repowriter.WriterTypeText},
 		{input: "scorecard", want: repowriter.WriterTypeScorecard},
+		{input: "csv", want: repowriter.WriterTypeCSV},
+		{input: "jsonl", want: repowriter.WriterTypeJSONL},
 		{input: "", want: 0, err: repowriter.ErrorUnknownRepoWriterType},
 		{input: "unknown", want: 0, err: repowriter.ErrorUnknownRepoWriterType},
 	}
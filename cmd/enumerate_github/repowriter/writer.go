@@ -1,8 +1,34 @@
 package repowriter
 
+import (
+	"time"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/enumerator"
+)
+
+// Repo is a single repository along with its metadata.
+type Repo = enumerator.Repo
+
 // Writer is a simple interface for writing a repo. This interface is to
 // abstract output formats for lists of repository urls.
 type Writer interface {
-	// Write outputs a single repository url.
-	Write(repo string) error
+	// Write outputs a single repository.
+	Write(repo Repo) error
+}
+
+// timeFormat is used for the dates written in the repository metadata.
+const timeFormat = time.RFC3339
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeFormat)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(timeFormat, s)
 }
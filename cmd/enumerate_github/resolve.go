@@ -0,0 +1,184 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shurcooL/githubv4"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/resolver"
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+	"github.com/ossf/criticality_score/internal/infile"
+	"github.com/ossf/criticality_score/internal/workerpool"
+)
+
+// resolveSearchLimit is the number of search results that are ranked for each
+// package name.
+const resolveSearchLimit = 20
+
+// resolveWorker waits for a package name on the names channel, searches for
+// candidate repositories using s and returns the ranked candidates on the
+// results channel.
+func resolveWorker(s *githubsearch.Searcher, logger *zap.Logger, names chan string, results chan resolver.Result) {
+	for name := range names {
+		q := *queryFlag + " " + resolver.Query(name)
+		repos, err := s.Candidates(q, resolveSearchLimit)
+		if err != nil {
+			logger.With(
+				zap.String("name", name),
+				zap.String("query", q),
+				zap.Error(err),
+			).Error("Resolution failed for package name")
+			results <- resolver.Result{Name: name, Status: resolver.StatusUnresolved}
+			continue
+		}
+		r := resolver.Rank(name, repos, *resolveMinConfidenceFlag)
+		if len(r.Candidates) > *resolveCandidatesFlag {
+			r.Candidates = r.Candidates[:*resolveCandidatesFlag]
+		}
+		logger.With(
+			zap.String("name", name),
+			zap.String("status", string(r.Status)),
+			zap.Int("candidates", len(r.Candidates)),
+		).Debug("Resolved package name")
+		results <- r
+	}
+}
+
+// resolvePackages resolves each package name in the -resolve file to ranked
+// candidate repositories.
+//
+// Matched names are written to out. Ambiguous and unmatched names are written
+// to the -resolve-review file if it is set, otherwise they are also written to
+// out. The total number of names written to out is returned.
+func resolvePackages(ctx context.Context, client *githubv4.Client, logger *zap.Logger, out io.Writer) int {
+	in, err := infile.Open(ctx, *resolveFlag)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+			zap.String("filename", *resolveFlag),
+		).Error("Failed to open package names file")
+		os.Exit(2)
+	}
+	defer in.Close()
+
+	w := resolveFormat.New(out)
+	review := w
+	var reviewOut io.WriteCloser
+	if *resolveReviewFlag != "" {
+		reviewOut, err = cloudstorage.NewWriter(ctx, *resolveReviewFlag)
+		if err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("filename", *resolveReviewFlag),
+			).Error("Failed to open review file")
+			os.Exit(2)
+		}
+		review = resolveFormat.New(reviewOut)
+	}
+
+	logger.With(
+		zap.String("filename", *resolveFlag),
+		zap.Int("candidates", *resolveCandidatesFlag),
+		zap.Float64("min_confidence", *resolveMinConfidenceFlag),
+		zap.Int("workers", *workersFlag),
+	).Info("Starting package name resolution")
+
+	names := make(chan string)
+	results := make(chan resolver.Result, *workersFlag)
+
+	// Start the worker goroutines to execute the search queries
+	wait := workerpool.WorkerPool(*workersFlag, func(i int) {
+		workerLogger := logger.With(zap.Int("worker", i))
+		s := githubsearch.NewSearcher(ctx, client, workerLogger)
+		resolveWorker(s, workerLogger, names, results)
+	})
+
+	// Start a separate goroutine to collect results so worker output is always consumed.
+	done := make(chan bool)
+	counts := make(map[resolver.Status]int)
+	totalWritten := 0
+	go func() {
+		for r := range results {
+			counts[r.Status]++
+			dst := w
+			if r.Status != resolver.StatusMatched {
+				dst = review
+			}
+			if err := dst.WriteResolution(r); err != nil {
+				logger.With(
+					zap.Error(err),
+					zap.String("name", r.Name),
+				).Error("Failed to write resolution")
+				os.Exit(2)
+			}
+			if dst == w {
+				totalWritten++
+			}
+		}
+		done <- true
+	}()
+
+	// Each line of the input contains a single package name. Blank lines and
+	// lines starting with "#" are ignored.
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		names <- name
+	}
+	if err := scanner.Err(); err != nil {
+		logger.With(
+			zap.Error(err),
+			zap.String("filename", *resolveFlag),
+		).Error("Failed to read package names file")
+		os.Exit(2)
+	}
+
+	logger.Debug("Waiting for workers to finish")
+	close(names)
+	wait()
+
+	logger.Debug("Waiting for writer to finish")
+	close(results)
+	<-done
+
+	if reviewOut != nil {
+		if err := reviewOut.Close(); err != nil {
+			logger.With(
+				zap.Error(err),
+				zap.String("filename", *resolveReviewFlag),
+			).Error("Failed to write review file")
+			os.Exit(2)
+		}
+	}
+
+	logger.With(
+		zap.Int("matched", counts[resolver.StatusMatched]),
+		zap.Int("ambiguous", counts[resolver.StatusAmbiguous]),
+		zap.Int("no_match", counts[resolver.StatusNoMatch]),
+		zap.Int("unresolved", counts[resolver.StatusUnresolved]),
+	).Info("Finished package name resolution")
+	return totalWritten
+}
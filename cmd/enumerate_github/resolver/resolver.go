@@ -0,0 +1,265 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resolver ranks the GitHub repositories that may be the source of a
+// package, given only the package's name.
+package resolver
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+)
+
+// Weights of each component of a candidate's score. They sum to 1.
+const (
+	nameWeight     = 0.4
+	manifestWeight = 0.4
+	starsWeight    = 0.2
+
+	// maxStarsLog10 is the log10 of the star count at which the stars
+	// component of the score reaches 1.
+	maxStarsLog10 = 5
+)
+
+// Status describes how confidently a package name was resolved.
+type Status string
+
+const (
+	// StatusMatched means the best candidate is confidently the package's
+	// repository.
+	StatusMatched = Status("matched")
+
+	// StatusAmbiguous means there are candidates that match the package, but
+	// none of them is clearly better than the others.
+	StatusAmbiguous = Status("ambiguous")
+
+	// StatusNoMatch means no candidate's name or manifest matches the package.
+	StatusNoMatch = Status("no_match")
+
+	// StatusUnresolved means the search for candidates failed, so the package
+	// was not resolved.
+	StatusUnresolved = Status("unresolved")
+)
+
+// Candidate is a repository that may be the source of a package.
+type Candidate struct {
+	Repo  string `json:"repo"`
+	Stars int    `json:"stars"`
+
+	// NameMatch is how well the repository's name matches the package name,
+	// between 0 and 1.
+	NameMatch float64 `json:"name_match"`
+
+	// ManifestMatch is true if a manifest in the repository declares the
+	// package.
+	ManifestMatch bool `json:"manifest_match"`
+
+	// Score combines the name match, manifest match and stars, between 0 and
+	// 1.
+	Score float64 `json:"score"`
+
+	// Confidence is the likelihood, between 0 and 1, that this candidate is
+	// the package's repository, taking the other candidates into account.
+	Confidence float64 `json:"confidence"`
+}
+
+// Result is the ranked candidates for a package name.
+type Result struct {
+	Name       string      `json:"name"`
+	Status     Status      `json:"status"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Query returns the search terms used to find candidates for the package
+// name.
+//
+// Scopes and paths are split into separate terms, so "@babel/core" becomes
+// "babel core".
+func Query(name string) string {
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '@' || r == '/'
+	}), " ")
+}
+
+// Rank scores each repository as a candidate for the package name, and
+// returns them ordered from the best candidate to the worst.
+//
+// The status is StatusMatched if the best candidate's confidence is at least
+// minConfidence.
+func Rank(name string, repos []githubsearch.Candidate, minConfidence float64) Result {
+	res := Result{Name: name}
+	for _, r := range repos {
+		c := Candidate{
+			Repo:          r.URL,
+			Stars:         r.Stars,
+			NameMatch:     NameMatch(name, r.Name),
+			ManifestMatch: ManifestMatch(name, r.Manifests),
+		}
+		c.Score = nameWeight*c.NameMatch + starsWeight*starsScore(c.Stars)
+		if c.ManifestMatch {
+			c.Score += manifestWeight
+		}
+		res.Candidates = append(res.Candidates, c)
+	}
+	sort.SliceStable(res.Candidates, func(i, j int) bool {
+		return res.Candidates[i].Score > res.Candidates[j].Score
+	})
+
+	// The confidence of each candidate is its score, discounted by the score
+	// of the best other candidate.
+	for i := range res.Candidates {
+		c := &res.Candidates[i]
+		other := 0.0
+		if i == 0 && len(res.Candidates) > 1 {
+			other = res.Candidates[1].Score
+		} else if i > 0 {
+			other = res.Candidates[0].Score
+		}
+		if c.Score > 0 {
+			c.Confidence = c.Score * c.Score / (c.Score + other)
+		}
+	}
+
+	switch {
+	case len(res.Candidates) == 0:
+		res.Status = StatusNoMatch
+	case res.Candidates[0].NameMatch == 0 && !res.Candidates[0].ManifestMatch:
+		res.Status = StatusNoMatch
+	case res.Candidates[0].Confidence < minConfidence:
+		res.Status = StatusAmbiguous
+	default:
+		res.Status = StatusMatched
+	}
+	return res
+}
+
+func starsScore(stars int) float64 {
+	return math.Min(math.Log10(float64(stars)+1)/maxStarsLog10, 1)
+}
+
+// normalize returns name in a form that allows names to be compared
+// regardless of case and separators.
+func normalize(name string) string {
+	return strings.NewReplacer("_", "-", ".", "-").Replace(strings.ToLower(name))
+}
+
+// baseName returns the package name without any scope or path prefix, e.g.
+// "@babel/core" returns "core", and "golang.org/x/net" returns "net".
+func baseName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+var (
+	repoNamePrefixes = []string{"python-", "py-", "node-", "go-", "rust-"}
+	repoNameSuffixes = []string{"-js", "-py", "-rs", "-go", "-rb"}
+)
+
+// NameMatch returns how well the repository name repo matches the package
+// name, between 0 and 1.
+//
+// An exact match is 1, a match after removing a common language prefix or
+// suffix (e.g. "python-" or "-js") is 0.8, and a partial match is 0.5.
+func NameMatch(name, repo string) float64 {
+	n := normalize(baseName(name))
+	r := normalize(repo)
+	if n == "" || r == "" {
+		return 0
+	}
+	if n == r {
+		return 1
+	}
+	for _, p := range repoNamePrefixes {
+		if strings.TrimPrefix(r, p) == n {
+			return 0.8
+		}
+	}
+	for _, s := range repoNameSuffixes {
+		if strings.TrimSuffix(r, s) == n {
+			return 0.8
+		}
+	}
+	if strings.Contains(r, n) || strings.Contains(n, r) {
+		return 0.5
+	}
+	return 0
+}
+
+var (
+	tomlNameRE    = regexp.MustCompile(`(?m)^\s*name\s*=\s*["']([^"']+)["']`)
+	setupCfgRE    = regexp.MustCompile(`(?m)^\s*name\s*=\s*(\S+)`)
+	setupPyRE     = regexp.MustCompile(`\bname\s*=\s*["']([^"']+)["']`)
+	goModModuleRE = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+)
+
+// ManifestMatch returns true if any of the manifests declares a package with
+// the given name.
+//
+// manifests maps the manifest filename to its contents, as returned by
+// githubsearch.Candidate.
+func ManifestMatch(name string, manifests map[string]string) bool {
+	want := normalize(name)
+	for filename, contents := range manifests {
+		for _, declared := range declaredNames(filename, contents) {
+			if normalize(declared) == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// declaredNames returns the package names declared in a manifest.
+func declaredNames(filename, contents string) []string {
+	match := func(re *regexp.Regexp) []string {
+		var names []string
+		for _, m := range re.FindAllStringSubmatch(contents, -1) {
+			names = append(names, m[1])
+		}
+		return names
+	}
+	switch filename {
+	case githubsearch.ManifestPackageJSON:
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal([]byte(contents), &pkg); err != nil || pkg.Name == "" {
+			return nil
+		}
+		return []string{pkg.Name}
+	case githubsearch.ManifestCargoToml, githubsearch.ManifestPyprojectToml:
+		return match(tomlNameRE)
+	case githubsearch.ManifestSetupCfg:
+		return match(setupCfgRE)
+	case githubsearch.ManifestSetupPy:
+		return match(setupPyRE)
+	case githubsearch.ManifestGoMod:
+		// Go packages may be referred to by their full module path or by the
+		// last element of the path.
+		var names []string
+		for _, m := range match(goModModuleRE) {
+			names = append(names, m, baseName(m))
+		}
+		return names
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolver_test
+
+import (
+	"testing"
+
+	"github.com/ossf/criticality_score/cmd/enumerate_github/githubsearch"
+	"github.com/ossf/criticality_score/cmd/enumerate_github/resolver"
+)
+
+func TestNameMatch(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name string
+		repo string
+		want float64
+	}{
+		{name: "kubernetes", repo: "kubernetes", want: 1},
+		{name: "Flask_Login", repo: "flask-login", want: 1},
+		{name: "@babel/core", repo: "core", want: 1},
+		{name: "requests", repo: "python-requests", want: 0.8},
+		{name: "moment", repo: "moment-js", want: 0.8},
+		{name: "react", repo: "react-native", want: 0.5},
+		{name: "linux", repo: "free-programming-books", want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name+"/"+test.repo, func(t *testing.T) {
+			if got := resolver.NameMatch(test.name, test.repo); got != test.want {
+				t.Fatalf("NameMatch() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestManifestMatch(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name      string
+		manifests map[string]string
+		want      bool
+	}{
+		{
+			name:      "@babel/core",
+			manifests: map[string]string{githubsearch.ManifestPackageJSON: `{"name": "@babel/core", "version": "1.0.0"}`},
+			want:      true,
+		},
+		{
+			name:      "serde",
+			manifests: map[string]string{githubsearch.ManifestCargoToml: "[package]\nname = \"serde\"\n"},
+			want:      true,
+		},
+		{
+			name:      "flask_login",
+			manifests: map[string]string{githubsearch.ManifestSetupPy: "setup(\n    name='Flask-Login',\n)"},
+			want:      true,
+		},
+		{
+			name:      "kubernetes",
+			manifests: map[string]string{githubsearch.ManifestGoMod: "module k8s.io/kubernetes\n\ngo 1.19\n"},
+			want:      true,
+		},
+		{
+			name:      "express",
+			manifests: map[string]string{githubsearch.ManifestPackageJSON: `{"name": "express-session"}`},
+		},
+		{
+			name: "express",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := resolver.ManifestMatch(test.name, test.manifests); got != test.want {
+				t.Fatalf("ManifestMatch() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	repos := []githubsearch.Candidate{
+		{URL: "https://github.com/example/fork-of-express", Name: "fork-of-express", Stars: 10},
+		{URL: "https://github.com/expressjs/express", Name: "express", Stars: 60000, Manifests: map[string]string{
+			githubsearch.ManifestPackageJSON: `{"name": "express"}`,
+		}},
+	}
+	got := resolver.Rank("express", repos, 0.5)
+	if got.Status != resolver.StatusMatched {
+		t.Fatalf("Rank() status = %s, want %s", got.Status, resolver.StatusMatched)
+	}
+	if got.Candidates[0].Repo != "https://github.com/expressjs/express" {
+		t.Fatalf("Rank() best = %s, want https://github.com/expressjs/express", got.Candidates[0].Repo)
+	}
+	if got.Candidates[0].Confidence <= got.Candidates[1].Confidence {
+		t.Fatalf("Rank() confidence %v <= %v, want greater", got.Candidates[0].Confidence, got.Candidates[1].Confidence)
+	}
+}
+
+func TestRankAmbiguous(t *testing.T) {
+	repos := []githubsearch.Candidate{
+		{URL: "https://github.com/a/widget", Name: "widget", Stars: 100},
+		{URL: "https://github.com/b/widget", Name: "widget", Stars: 100},
+	}
+	if got := resolver.Rank("widget", repos, 0.5); got.Status != resolver.StatusAmbiguous {
+		t.Fatalf("Rank() status = %s, want %s", got.Status, resolver.StatusAmbiguous)
+	}
+}
+
+func TestRankNoMatch(t *testing.T) {
+	repos := []githubsearch.Candidate{
+		{URL: "https://github.com/justjavac/free-programming-books-zh_CN", Name: "free-programming-books-zh_CN", Stars: 100000},
+	}
+	if got := resolver.Rank("angular", repos, 0.5); got.Status != resolver.StatusNoMatch {
+		t.Fatalf("Rank() status = %s, want %s", got.Status, resolver.StatusNoMatch)
+	}
+	if got := resolver.Rank("angular", nil, 0.5); got.Status != resolver.StatusNoMatch {
+		t.Fatalf("Rank() status = %s, want %s", got.Status, resolver.StatusNoMatch)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	if got, want := resolver.Query("@babel/core"), "babel core"; got != want {
+		t.Fatalf("Query() = %q, want %q", got, want)
+	}
+}
@@ -147,7 +147,7 @@ func main() {
 		s = scorer.FromDefaultConfig()
 	} else {
 		// Prepare the scorer from the config file
-		cf, err := os.Open(*configFlag)
+		cf, err := infile.Open(context.Background(), *configFlag)
 		if err != nil {
 			logger.With(
 				zap.Error(err),
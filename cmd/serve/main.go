@@ -0,0 +1,223 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ossf/criticality_score/cmd/serve/server"
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/envflag"
+	"github.com/ossf/criticality_score/internal/infile"
+	log "github.com/ossf/criticality_score/internal/log"
+	"github.com/ossf/criticality_score/internal/scorer"
+)
+
+const defaultLogLevel = zapcore.InfoLevel
+
+var (
+	addrFlag              = flag.String("addr", ":8080", "the `address` to listen on.")
+	gcpProjectFlag        = flag.String("gcp-project-id", "", "the Google Cloud Project ID to use. Auto-detects by default.")
+	depsdevDisableFlag    = flag.Bool("depsdev-disable", false, "disables the collection of signals from deps.dev.")
+	depsdevDatasetFlag    = flag.String("depsdev-dataset", collector.DefaultGCPDatasetName, "the BigQuery dataset name to use.")
+	depsdevTTLFlag        = flag.Int("depsdev-expiration", 0, "the default expiration (`hours`) to use for deps.dev tables. No expiration by default.")
+	scoringDisableFlag    = flag.Bool("scoring-disable", false, "disables the generation of scores, unless a request sends a scoring config.")
+	scoringConfigFlag     = flag.String("scoring-config", "", "path to a YAML file for configuring the scoring algorithm.")
+	cacheTTLFlag          = flag.Duration("cache-ttl", time.Hour, "the `duration` collected signals are cached for. Set to 0 to disable caching.")
+	cacheSizeFlag         = flag.Int("cache-size", server.DefaultCacheSize, "the maximum number of repositories to cache.")
+	collectTimeoutFlag    = flag.Duration("collect-timeout", server.DefaultCollectTimeout, "the maximum `duration` to spend collecting a single repository.")
+	clientConcurrencyFlag = flag.Int("client-concurrency", 4, "the maximum number of requests and jobs each client can run at once. Set to 0 for no limit.")
+	clientHeaderFlag      = flag.String("client-header", "", "the request `header` identifying each client. By default clients are identified by their address.")
+	batchWorkersFlag      = flag.Int("batch-workers", server.DefaultBatchWorkers, "the number of repositories to collect concurrently for each batch or job.")
+	maxBatchSizeFlag      = flag.Int("max-batch-size", server.DefaultMaxBatchSize, "the maximum number of urls in a synchronous batch request.")
+	maxJobSizeFlag        = flag.Int("max-job-size", server.DefaultMaxJobSize, "the maximum number of urls in a job.")
+	jobTTLFlag            = flag.Duration("job-ttl", server.DefaultJobTTL, "the `duration` a finished job's results are kept for.")
+	maxJobsFlag           = flag.Int("max-jobs", server.DefaultMaxJobs, "the maximum number of finished jobs kept.")
+	maxClientJobsFlag     = flag.Int("max-client-jobs", server.DefaultMaxClientJobs, "the maximum number of finished jobs kept for each client.")
+	shutdownTimeoutFlag   = flag.Duration("shutdown-timeout", 30*time.Second, "the `duration` to wait for requests to finish when shutting down.")
+	logLevel              = defaultLogLevel
+	logEnv                log.Env
+
+	// Maps environment variables to the flags they correspond to.
+	envFlagMap = envflag.Map{
+		"CRITICALITY_SCORE_LOG_ENV":            "log-env",
+		"CRITICALITY_SCORE_LOG_LEVEL":          "log",
+		"CRITICALITY_SCORE_ADDR":               "addr",
+		"CRITICALITY_SCORE_GCP_PROJECT_ID":     "gcp-project-id",
+		"CRITICALITY_SCORE_DEPSDEV_DISABLE":    "depsdev-disable",
+		"CRITICALITY_SCORE_DEPSDEV_DATASET":    "depsdev-dataset",
+		"CRITICALITY_SCORE_SCORING_DISABLE":    "scoring-disable",
+		"CRITICALITY_SCORE_SCORING_CONFIG":     "scoring-config",
+		"CRITICALITY_SCORE_CACHE_TTL":          "cache-ttl",
+		"CRITICALITY_SCORE_CACHE_SIZE":         "cache-size",
+		"CRITICALITY_SCORE_COLLECT_TIMEOUT":    "collect-timeout",
+		"CRITICALITY_SCORE_CLIENT_CONCURRENCY": "client-concurrency",
+		"CRITICALITY_SCORE_CLIENT_HEADER":      "client-header",
+		"CRITICALITY_SCORE_BATCH_WORKERS":      "batch-workers",
+		"CRITICALITY_SCORE_MAX_BATCH_SIZE":     "max-batch-size",
+		"CRITICALITY_SCORE_MAX_JOB_SIZE":       "max-job-size",
+		"CRITICALITY_SCORE_JOB_TTL":            "job-ttl",
+		"CRITICALITY_SCORE_MAX_JOBS":           "max-jobs",
+		"CRITICALITY_SCORE_MAX_CLIENT_JOBS":    "max-client-jobs",
+	}
+)
+
+// initFlags prepares any runtime flags, usage information and parses the flags.
+func initFlags() {
+	flag.Var(&logLevel, "log", "set the `level` of logging.")
+	flag.TextVar(&logEnv, "log-env", log.DefaultEnv, "set logging `env`.")
+	flag.Usage = func() {
+		cmdName := path.Base(os.Args[0])
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage:\n  %s [FLAGS]...\n\n", cmdName)
+		fmt.Fprintf(w, "Serves a JSON HTTP API for collecting signals and scores for project\n")
+		fmt.Fprintf(w, "repository urls.\n")
+		fmt.Fprintf(w, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	envflag.Parse(envFlagMap)
+}
+
+// getScorer prepares a Scorer based on the flags passed to the command.
+//
+// nil will be returned if scoring is disabled.
+func getScorer(logger *zap.Logger) *scorer.Scorer {
+	if *scoringDisableFlag {
+		logger.Info("Scoring disabled")
+		return nil
+	}
+	if *scoringConfigFlag == "" {
+		logger.Info("Preparing default scorer")
+		return scorer.FromDefaultConfig()
+	}
+	// Prepare the scorer from the config file
+	logger = logger.With(
+		zap.String("filename", *scoringConfigFlag),
+	)
+	logger.Info("Preparing scorer from config")
+	cf, err := infile.Open(context.Background(), *scoringConfigFlag)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to open scoring config file")
+		os.Exit(2)
+	}
+	defer cf.Close()
+
+	s, err := scorer.FromConfig(scorer.NameFromFilepath(*scoringConfigFlag), cf)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to initialize scorer")
+		os.Exit(2)
+	}
+	return s
+}
+
+func main() {
+	initFlags()
+
+	logger, err := log.NewLogger(logEnv, logLevel)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+
+	s := getScorer(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Bump the # idle conns per host
+	http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost = *batchWorkersFlag * 5
+
+	opts := []collector.Option{
+		collector.EnableAllSources(),
+		collector.GCPProject(*gcpProjectFlag),
+		collector.GCPDatasetName(*depsdevDatasetFlag),
+		collector.GCPDatasetTTL(time.Hour * time.Duration(*depsdevTTLFlag)),
+	}
+	if *depsdevDisableFlag {
+		opts = append(opts, collector.DisableSource(collector.SourceTypeDepsDev))
+	}
+
+	c, err := collector.New(ctx, logger, opts...)
+	if err != nil {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to create collector")
+		os.Exit(2)
+	}
+
+	srv := server.New(c, logger, server.Options{
+		Scorer:            s,
+		CacheTTL:          *cacheTTLFlag,
+		CacheSize:         *cacheSizeFlag,
+		CollectTimeout:    *collectTimeoutFlag,
+		ClientConcurrency: *clientConcurrencyFlag,
+		ClientHeader:      *clientHeaderFlag,
+		BatchWorkers:      *batchWorkersFlag,
+		MaxBatchSize:      *maxBatchSizeFlag,
+		MaxJobSize:        *maxJobSizeFlag,
+		JobTTL:            *jobTTLFlag,
+		MaxJobs:           *maxJobsFlag,
+		MaxClientJobs:     *maxClientJobsFlag,
+	})
+	httpServer := &http.Server{
+		Addr:              *addrFlag,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		logger.With(
+			zap.String("addr", *addrFlag),
+		).Info("Serving")
+		errc <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to serve")
+		os.Exit(2)
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeoutFlag)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		logger.With(
+			zap.Error(err),
+		).Error("Failed to shut down")
+	}
+	// Cancel any jobs that are still running.
+	srv.Close()
+}
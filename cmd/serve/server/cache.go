@@ -0,0 +1,134 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+// cache holds the signals collected for each repository for a period of time,
+// and ensures each repository is only collected once at a time.
+type cache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List // The most recently used entry is at the front.
+	inflight map[string]*call
+}
+
+type entry struct {
+	key     string
+	sets    []signal.Set
+	expires time.Time
+}
+
+// call is a collection that is in progress.
+type call struct {
+	done chan struct{}
+	sets []signal.Set
+	err  error
+}
+
+// newCache returns a cache that holds up to size entries for ttl. If ttl is
+// zero nothing is cached, but concurrent collections are still shared.
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{
+		ttl:      ttl,
+		size:     size,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		inflight: make(map[string]*call),
+	}
+}
+
+// get returns the signals for key, calling collect if they are not cached.
+// cached is true if the signals were already in the cache.
+//
+// Concurrent calls for the same key share a single call to collect, which
+// runs in its own goroutine so that it completes even if ctx is done. A new
+// call to collect is not started if ctx is already done. Errors are not
+// cached.
+func (c *cache) get(ctx context.Context, key string, collect func() ([]signal.Set, error)) (sets []signal.Set, cached bool, err error) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry)
+		if c.now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			return e.sets, true, nil
+		}
+		c.remove(el)
+	}
+	cl, ok := c.inflight[key]
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			c.mu.Unlock()
+			return nil, false, err
+		}
+		cl = &call{done: make(chan struct{})}
+		c.inflight[key] = cl
+		go c.run(key, cl, collect)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.sets, false, cl.err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// run calls collect for key, caching the result if it succeeds.
+func (c *cache) run(key string, cl *call, collect func() ([]signal.Set, error)) {
+	cl.sets, cl.err = collect()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if cl.err == nil && c.ttl > 0 && c.size > 0 {
+		c.entries[key] = c.order.PushFront(&entry{
+			key:     key,
+			sets:    cl.sets,
+			expires: c.now().Add(c.ttl),
+		})
+		for c.order.Len() > c.size {
+			c.remove(c.order.Back())
+		}
+	}
+	c.mu.Unlock()
+	close(cl.done)
+}
+
+// remove deletes the entry el from the cache. c.mu must be held.
+func (c *cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
+
+// len returns the number of entries in the cache.
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+// countingCollect returns a collect function for cache.get that counts the
+// number of times it is called.
+func countingCollect(n *int, err error) func() ([]signal.Set, error) {
+	return func() ([]signal.Set, error) {
+		*n++
+		if err != nil {
+			return nil, err
+		}
+		return []signal.Set{&signal.RepoSet{}}, nil
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	now := time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)
+	c := newCache(time.Hour, 10)
+	c.now = func() time.Time { return now }
+
+	var calls int
+	for _, test := range []struct {
+		advance    time.Duration
+		wantCached bool
+		wantCalls  int
+	}{
+		{wantCached: false, wantCalls: 1},
+		{advance: 30 * time.Minute, wantCached: true, wantCalls: 1},
+		{advance: 30 * time.Minute, wantCached: false, wantCalls: 2},
+	} {
+		now = now.Add(test.advance)
+		_, cached, err := c.get(context.Background(), "key", countingCollect(&calls, nil))
+		if err != nil {
+			t.Fatalf("get() = %v, want no error", err)
+		}
+		if cached != test.wantCached || calls != test.wantCalls {
+			t.Fatalf("get() cached = %t with %d calls, want %t with %d calls", cached, calls, test.wantCached, test.wantCalls)
+		}
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCache(time.Hour, 2)
+	var calls int
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, _, err := c.get(context.Background(), key, countingCollect(&calls, nil)); err != nil {
+			t.Fatalf("get() = %v, want no error", err)
+		}
+	}
+	// "b" is evicted by "c", as "a" was used more recently.
+	if calls != 4 {
+		t.Fatalf("collect called %d times, want 4", calls)
+	}
+	if got := c.len(); got != 2 {
+		t.Fatalf("len() = %d, want 2", got)
+	}
+}
+
+func TestCacheErrorsNotCached(t *testing.T) {
+	c := newCache(time.Hour, 10)
+	wantErr := errors.New("test error")
+	var calls int
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.get(context.Background(), "key", countingCollect(&calls, wantErr)); !errors.Is(err, wantErr) {
+			t.Fatalf("get() = %v, want %v", err, wantErr)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("collect called %d times, want 2", calls)
+	}
+}
+
+func TestCacheSharesInflight(t *testing.T) {
+	c := newCache(time.Hour, 10)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var calls int
+	collect := func() ([]signal.Set, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return []signal.Set{&signal.RepoSet{}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := c.get(context.Background(), "key", collect); err != nil {
+				t.Errorf("get() = %v, want no error", err)
+			}
+		}()
+	}
+	// A canceled caller stops waiting, but does not stop the collection.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := c.get(ctx, "key", collect); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get() = %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("collect called %d times, want 1", calls)
+	}
+	if got := c.len(); got != 1 {
+		t.Fatalf("len() = %d, want 1", got)
+	}
+}
+
+func TestCacheCanceledNotCollected(t *testing.T) {
+	c := newCache(time.Hour, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls int
+	if _, _, err := c.get(ctx, "key", countingCollect(&calls, nil)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get() = %v, want %v", err, context.Canceled)
+	}
+	if calls != 0 {
+		t.Fatalf("collect called %d times, want 0", calls)
+	}
+}
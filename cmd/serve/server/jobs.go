@@ -0,0 +1,240 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobStatus is the state of an asynchronous batch job.
+type JobStatus int
+
+const (
+	// JobRunning means the job is still collecting repositories.
+	JobRunning = JobStatus(iota)
+
+	// JobDone means every repository in the job has been collected. The
+	// Result for each repository may still contain an error.
+	JobDone
+
+	// JobCanceled means the job was canceled before it was done.
+	JobCanceled
+)
+
+var ErrorUnknownJobStatus = errors.New("unknown job status")
+
+// String implements the fmt.Stringer interface.
+func (s JobStatus) String() string {
+	text, err := s.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s JobStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case JobRunning:
+		return []byte("running"), nil
+	case JobDone:
+		return []byte("done"), nil
+	case JobCanceled:
+		return []byte("canceled"), nil
+	default:
+		return []byte{}, ErrorUnknownJobStatus
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (s *JobStatus) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("running")):
+		*s = JobRunning
+	case bytes.Equal(text, []byte("done")):
+		*s = JobDone
+	case bytes.Equal(text, []byte("canceled")):
+		*s = JobCanceled
+	default:
+		return ErrorUnknownJobStatus
+	}
+	return nil
+}
+
+// Job is the state of an asynchronous batch job returned by the API.
+type Job struct {
+	ID         string     `json:"id"`
+	Status     JobStatus  `json:"status"`
+	Total      int        `json:"total"`
+	Completed  int        `json:"completed"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Results holds a Result for each url in the job, in the same order.
+	// It is only set once the job is no longer running.
+	Results []Result `json:"results,omitempty"`
+}
+
+// job tracks a running Job.
+type job struct {
+	client string
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	job Job
+}
+
+// snapshot returns a copy of the current state of the job.
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.job
+}
+
+// progress records that another repository has been collected.
+func (j *job) progress() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.job.Completed++
+}
+
+// finish records the results of the job. The job is canceled if err is not
+// nil.
+func (j *job) finish(now time.Time, results []Result, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.job.Status = JobDone
+	if err != nil {
+		j.job.Status = JobCanceled
+	}
+	j.job.FinishedAt = &now
+	j.job.Results = results
+}
+
+// finishedAt returns when the job finished, or nil if it is still running.
+func (j *job) finishedAt() *time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.job.FinishedAt
+}
+
+// jobStore holds the running jobs, and the finished jobs until they expire.
+//
+// At most max finished jobs are kept, and at most maxClient for each client.
+// When there are more the oldest finished jobs are removed early.
+type jobStore struct {
+	ttl       time.Duration
+	max       int
+	maxClient int
+	now       func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newJobStore(ttl time.Duration, max, maxClient int) *jobStore {
+	return &jobStore{
+		ttl:       ttl,
+		max:       max,
+		maxClient: maxClient,
+		now:       time.Now,
+		jobs:      make(map[string]*job),
+	}
+}
+
+// create adds a new running job for total repositories, sent by client.
+func (s *jobStore) create(client string, total int, cancel context.CancelFunc) (*job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	j := &job{
+		client: client,
+		cancel: cancel,
+		job: Job{
+			ID:        id,
+			Status:    JobRunning,
+			Total:     total,
+			CreatedAt: s.now().UTC(),
+		},
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	s.jobs[id] = j
+	return j, nil
+}
+
+// get returns the job with the given id.
+func (s *jobStore) get(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// expire removes jobs that finished more than ttl ago, and the oldest
+// finished jobs over max or maxClient. s.mu must be held.
+func (s *jobStore) expire() {
+	type finished struct {
+		id  string
+		job *job
+		at  time.Time
+	}
+	var jobs []finished
+	cutoff := s.now().Add(-s.ttl)
+	for id, j := range s.jobs {
+		at := j.finishedAt()
+		switch {
+		case at == nil:
+		case at.Before(cutoff):
+			delete(s.jobs, id)
+		default:
+			jobs = append(jobs, finished{id: id, job: j, at: *at})
+		}
+	}
+
+	// Keep the most recently finished jobs.
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].at.After(jobs[j].at)
+	})
+	kept := 0
+	clients := make(map[string]int)
+	for _, f := range jobs {
+		if kept >= s.max || clients[f.job.client] >= s.maxClient {
+			delete(s.jobs, f.id)
+			continue
+		}
+		kept++
+		clients[f.job.client]++
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobStoreLimits(t *testing.T) {
+	now := time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)
+	s := newJobStore(time.Hour, 3, 2)
+	s.now = func() time.Time { return now }
+
+	create := func(client string) *job {
+		t.Helper()
+		j, err := s.create(client, 1, func() {})
+		if err != nil {
+			t.Fatalf("create() = %v, want no error", err)
+		}
+		return j
+	}
+	finish := func(jobs ...*job) {
+		for _, j := range jobs {
+			now = now.Add(time.Minute)
+			j.finish(now, nil, nil)
+		}
+	}
+	check := func(want map[*job]bool) {
+		t.Helper()
+		for j, want := range want {
+			if _, got := s.get(j.job.ID); got != want {
+				t.Errorf("get(%s) found = %t, want %t", j.job.ID, got, want)
+			}
+		}
+	}
+
+	b1, a1, a2, a3 := create("b"), create("a"), create("a"), create("a")
+	running := create("a")
+	finish(b1, a1, a2, a3)
+	// The oldest of client a's jobs is removed, as it has more than 2.
+	check(map[*job]bool{b1: true, a1: false, a2: true, a3: true, running: true})
+
+	b2 := create("b")
+	finish(b2)
+	// The oldest job is removed, as there are more than 3.
+	check(map[*job]bool{b1: false, a2: true, a3: true, b2: true, running: true})
+
+	// Finished jobs still expire after the ttl.
+	now = now.Add(2 * time.Hour)
+	check(map[*job]bool{a2: false, a3: false, b2: false, running: true})
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import "sync"
+
+// limiter limits the number of requests and jobs each client has running at
+// once.
+type limiter struct {
+	max int
+
+	mu     sync.Mutex
+	active map[string]int
+}
+
+// newLimiter returns a limiter that allows each client max requests at once.
+// If max is zero there is no limit.
+func newLimiter(max int) *limiter {
+	return &limiter{
+		max:    max,
+		active: make(map[string]int),
+	}
+}
+
+// acquire returns true if client can start a request, which must be followed
+// by a call to release once the request is finished.
+func (l *limiter) acquire(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.active[client] >= l.max {
+		return false
+	}
+	l.active[client]++
+	return true
+}
+
+// release records that a request started by client has finished.
+func (l *limiter) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[client]--; l.active[client] <= 0 {
+		delete(l.active, client)
+	}
+}
@@ -0,0 +1,627 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server implements a JSON HTTP API for collecting the signals and
+// calculating the criticality score of repositories on demand.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/scorer"
+	"github.com/ossf/criticality_score/internal/signalio"
+	"github.com/ossf/criticality_score/internal/workerpool"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+const (
+	DefaultCacheSize      = 10000
+	DefaultCollectTimeout = 10 * time.Minute
+	DefaultBatchWorkers   = 4
+	DefaultMaxBatchSize   = 100
+	DefaultMaxJobSize     = 10000
+	DefaultJobTTL         = 24 * time.Hour
+	DefaultMaxJobs        = 1000
+	DefaultMaxClientJobs  = 100
+
+	// requestScoreName is the name of the score calculated using a scoring
+	// config sent with a request.
+	requestScoreName = "request_score"
+
+	// maxBodySize limits the size of a request body.
+	maxBodySize = 10 << 20
+)
+
+var errInvalidURL = errors.New("invalid url")
+
+// Collector collects the signals for a repository. It is implemented by
+// *collector.Collector.
+type Collector interface {
+	EmptySets() []signal.Set
+	Collect(ctx context.Context, u *url.URL, jobID string) ([]signal.Set, error)
+}
+
+// Options configures a Server. Zero values are replaced with the defaults.
+type Options struct {
+	// Scorer is used to score the signals collected, unless a request sends
+	// its own scoring config. If it is nil signals are only scored for
+	// requests that send a scoring config.
+	Scorer *scorer.Scorer
+
+	// CacheTTL is how long the signals collected for a repository are reused
+	// for. Signals are not cached if it is zero.
+	CacheTTL time.Duration
+
+	// CacheSize is the maximum number of repositories cached.
+	CacheSize int
+
+	// CollectTimeout limits the time spent collecting a single repository.
+	CollectTimeout time.Duration
+
+	// ClientConcurrency limits the number of requests and jobs each client
+	// can have running at once. There is no limit if it is zero.
+	ClientConcurrency int
+
+	// ClientHeader is the request header used to identify a client. If it is
+	// empty, or a request does not have the header, the client is identified
+	// by its remote address.
+	ClientHeader string
+
+	// BatchWorkers is the number of repositories collected at once for each
+	// batch request or job.
+	BatchWorkers int
+
+	// MaxBatchSize is the maximum number of urls in a synchronous batch
+	// request. Larger batches must be submitted as a job.
+	MaxBatchSize int
+
+	// MaxJobSize is the maximum number of urls in a job.
+	MaxJobSize int
+
+	// JobTTL is how long a job's results are kept once it has finished.
+	JobTTL time.Duration
+
+	// MaxJobs is the maximum number of finished jobs kept. Once there are
+	// more the oldest are removed before JobTTL.
+	MaxJobs int
+
+	// MaxClientJobs is the maximum number of finished jobs kept for each
+	// client.
+	MaxClientJobs int
+}
+
+func (o *Options) setDefaults() {
+	if o.CacheSize == 0 {
+		o.CacheSize = DefaultCacheSize
+	}
+	if o.CollectTimeout == 0 {
+		o.CollectTimeout = DefaultCollectTimeout
+	}
+	if o.BatchWorkers == 0 {
+		o.BatchWorkers = DefaultBatchWorkers
+	}
+	if o.MaxBatchSize == 0 {
+		o.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if o.MaxJobSize == 0 {
+		o.MaxJobSize = DefaultMaxJobSize
+	}
+	if o.JobTTL == 0 {
+		o.JobTTL = DefaultJobTTL
+	}
+	if o.MaxJobs == 0 {
+		o.MaxJobs = DefaultMaxJobs
+	}
+	if o.MaxClientJobs == 0 {
+		o.MaxClientJobs = DefaultMaxClientJobs
+	}
+}
+
+// Server is an http.Handler serving the API.
+//
+// Every request shares the same Collector, and the signals it collects are
+// cached for Options.CacheTTL.
+type Server struct {
+	collector Collector
+	logger    *zap.Logger
+	opts      Options
+	cache     *cache
+	limiter   *limiter
+	jobs      *jobStore
+	mux       *http.ServeMux
+
+	// ctx is canceled by Close to stop any collection in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a Server that collects signals using c.
+func New(c Collector, logger *zap.Logger, opts Options) *Server {
+	opts.setDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		collector: c,
+		logger:    logger,
+		opts:      opts,
+		cache:     newCache(opts.CacheTTL, opts.CacheSize),
+		limiter:   newLimiter(opts.ClientConcurrency),
+		jobs:      newJobStore(opts.JobTTL, opts.MaxJobs, opts.MaxClientJobs),
+		mux:       http.NewServeMux(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/v1/signals", s.handleSignals)
+	s.mux.HandleFunc("/v1/collect", s.handleCollect)
+	s.mux.HandleFunc("/v1/collect/batch", s.handleBatch)
+	s.mux.HandleFunc("/v1/jobs", s.handleJobs)
+	s.mux.HandleFunc("/v1/jobs/", s.handleJob)
+	s.mux.HandleFunc("/v1/scoring/validate", s.handleValidate)
+	return s
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops any collection in progress, and waits for running jobs to be
+// canceled.
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Result holds the signals and score collected for a single repository.
+type Result struct {
+	URL string `json:"url"`
+
+	// Signals maps each namespace to the signals in that namespace, in the
+	// same structure as the JSON output of the criticality_score command.
+	Signals map[string]any `json:"signals,omitempty"`
+
+	Score     *float64 `json:"score,omitempty"`
+	ScoreName string   `json:"score_name,omitempty"`
+
+	// Cached is true if the signals were collected by an earlier request.
+	Cached bool `json:"cached"`
+
+	// Error is set if the repository could not be collected.
+	Error string `json:"error,omitempty"`
+}
+
+type collectRequest struct {
+	URL  string   `json:"url"`
+	URLs []string `json:"urls"`
+
+	// ScoringConfig is the YAML scoring config used to score the signals,
+	// in place of the Server's Scorer.
+	ScoringConfig string `json:"scoring_config"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// collect returns the Result for rawURL, scored using sc if it is not nil.
+func (s *Server) collect(ctx context.Context, rawURL string, sc *scorer.Scorer) (Result, error) {
+	res := Result{URL: rawURL}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return res, fmt.Errorf("%w: %q", errInvalidURL, rawURL)
+	}
+	ss, cached, err := s.cache.get(ctx, cacheKey(u), func() ([]signal.Set, error) {
+		ctx, cancel := context.WithTimeout(s.ctx, s.opts.CollectTimeout)
+		defer cancel()
+		// An empty jobID is used for every collection so that sources
+		// share the same cached data. As a result the deps.dev counts are
+		// not refreshed while the server is running.
+		return s.collector.Collect(ctx, u, "")
+	})
+	if err != nil {
+		return res, err
+	}
+	res.Cached = cached
+	if res.Signals, err = signalio.JSONMap(ss); err != nil {
+		return res, err
+	}
+	if sc != nil {
+		if score := sc.Score(ss); !math.IsNaN(score) && !math.IsInf(score, 0) {
+			res.Score = &score
+			res.ScoreName = sc.Name()
+		}
+	}
+	return res, nil
+}
+
+// collectAll returns the Result for each url, collecting them across
+// Options.BatchWorkers workers. done is called as each url is collected.
+//
+// Once ctx is done the remaining urls are not collected, and their Result
+// holds the error from ctx.
+func (s *Server) collectAll(ctx context.Context, urls []string, sc *scorer.Scorer, done func()) []Result {
+	results := make([]Result, len(urls))
+	next := make(chan int)
+	wait := workerpool.WorkerPool(s.opts.BatchWorkers, func(int) {
+		for i := range next {
+			res, err := s.collect(ctx, urls[i], sc)
+			if err != nil {
+				res.Error = err.Error()
+			}
+			results[i] = res
+			done()
+		}
+	})
+	for i := range urls {
+		if ctx.Err() == nil {
+			select {
+			case next <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		results[i] = Result{URL: urls[i], Error: ctx.Err().Error()}
+		done()
+	}
+	close(next)
+	wait()
+	return results
+}
+
+// cacheKey returns the key used to cache the signals for u, so that
+// different forms of the same repository url share the same signals.
+func cacheKey(u *url.URL) string {
+	p := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+	return strings.ToLower(u.Host + p)
+}
+
+// client returns the identity of the client that sent r.
+func (s *Server) client(r *http.Request) string {
+	if s.opts.ClientHeader != "" {
+		if id := r.Header.Get(s.opts.ClientHeader); id != "" {
+			return id
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// acquire reserves one of the client's concurrent requests, writing an error
+// response and returning false if the client has too many running.
+func (s *Server) acquire(w http.ResponseWriter, client string) bool {
+	if s.limiter.acquire(client) {
+		return true
+	}
+	s.writeError(w, http.StatusTooManyRequests, fmt.Errorf("client %s has too many requests running", client))
+	return false
+}
+
+// scorerFor returns the Scorer to use for req.
+func (s *Server) scorerFor(req *collectRequest) (*scorer.Scorer, error) {
+	if req.ScoringConfig == "" {
+		return s.opts.Scorer, nil
+	}
+	return scorer.FromConfig(requestScoreName, strings.NewReader(req.ScoringConfig))
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// SignalField describes a single signal in the catalog.
+type SignalField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SignalSet describes the signals collected by a single source.
+type SignalSet struct {
+	Namespace string        `json:"namespace"`
+	Fields    []SignalField `json:"fields"`
+}
+
+func (s *Server) handleSignals(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"signals": s.catalog()})
+}
+
+// catalog returns the signals that may be collected.
+func (s *Server) catalog() []SignalSet {
+	var sets []SignalSet
+	for _, es := range s.collector.EmptySets() {
+		names := signal.SetFields(es, true)
+		types := signal.SetFieldTypes(es)
+		set := SignalSet{Namespace: es.Namespace().String()}
+		for i := range names {
+			set.Fields = append(set.Fields, SignalField{Name: names[i], Type: types[i]})
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+func (s *Server) handleCollect(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req collectRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	if req.URL == "" {
+		s.writeError(w, http.StatusBadRequest, errors.New("url must be set"))
+		return
+	}
+	sc, err := s.scorerFor(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("scoring config: %w", err))
+		return
+	}
+	client := s.client(r)
+	if !s.acquire(w, client) {
+		return
+	}
+	defer s.limiter.release(client)
+
+	res, err := s.collect(r.Context(), req.URL, sc)
+	if err != nil {
+		s.logger.With(
+			zap.String("url", req.URL),
+			zap.String("client", client),
+			zap.Error(err),
+		).Warn("Failed to collect repo")
+		s.writeError(w, collectErrorStatus(err), err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+// collectErrorStatus returns the HTTP status code for an error returned by
+// collect.
+func collectErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidURL), errors.Is(err, collector.ErrUnsupportedURL):
+		return http.StatusBadRequest
+	case errors.Is(err, collector.ErrRepoNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, collector.ErrUncollectableRepo):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// readBatch reads a batch of urls from the request, writing an error response
+// and returning false if the batch is invalid.
+func (s *Server) readBatch(w http.ResponseWriter, r *http.Request, maxSize int) (*collectRequest, *scorer.Scorer, bool) {
+	var req collectRequest
+	if !s.readJSON(w, r, &req) {
+		return nil, nil, false
+	}
+	if len(req.URLs) == 0 {
+		s.writeError(w, http.StatusBadRequest, errors.New("urls must be set"))
+		return nil, nil, false
+	}
+	if len(req.URLs) > maxSize {
+		s.writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("%d urls is more than the limit of %d", len(req.URLs), maxSize))
+		return nil, nil, false
+	}
+	sc, err := s.scorerFor(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("scoring config: %w", err))
+		return nil, nil, false
+	}
+	return &req, sc, true
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	req, sc, ok := s.readBatch(w, r, s.opts.MaxBatchSize)
+	if !ok {
+		return
+	}
+	client := s.client(r)
+	if !s.acquire(w, client) {
+		return
+	}
+	defer s.limiter.release(client)
+
+	results := s.collectAll(r.Context(), req.URLs, sc, func() {})
+	s.writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	req, sc, ok := s.readBatch(w, r, s.opts.MaxJobSize)
+	if !ok {
+		return
+	}
+	client := s.client(r)
+	if !s.acquire(w, client) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	j, err := s.jobs.create(client, len(req.URLs), cancel)
+	if err != nil {
+		cancel()
+		s.limiter.release(client)
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	logger := s.logger.With(
+		zap.String("job", j.job.ID),
+		zap.String("client", client),
+		zap.Int("total", len(req.URLs)),
+	)
+	logger.Info("Starting job")
+
+	// The job holds one of the client's concurrent requests until it is
+	// finished.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.limiter.release(client)
+		defer cancel()
+		results := s.collectAll(ctx, req.URLs, sc, j.progress)
+		j.finish(s.jobs.now().UTC(), results, ctx.Err())
+		logger.Info("Finished job")
+	}()
+
+	w.Header().Set("Location", "/v1/jobs/"+j.job.ID)
+	s.writeJSON(w, http.StatusAccepted, j.snapshot())
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/v1/jobs/")
+	j, ok := s.jobs.get(id)
+	if !ok {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", id))
+		return
+	}
+	if r.Method == http.MethodDelete {
+		j.cancel()
+	}
+	s.writeJSON(w, http.StatusOK, j.snapshot())
+}
+
+// validation is the response to a request to validate a scoring config.
+type validation struct {
+	Valid     bool   `json:"valid"`
+	Error     string `json:"error,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Inputs    int    `json:"inputs,omitempty"`
+
+	// UnknownFields lists the fields used by the config that are not in the
+	// signal catalog. They do not make the config invalid, as the config may
+	// be used to score data from elsewhere.
+	UnknownFields []string `json:"unknown_fields,omitempty"`
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	cfg, err := scorer.LoadConfig(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err == nil {
+		_, err = cfg.Algorithm()
+	}
+	if err != nil {
+		s.writeJSON(w, http.StatusUnprocessableEntity, validation{Error: err.Error()})
+		return
+	}
+
+	known := make(map[string]bool)
+	for _, set := range s.catalog() {
+		for _, f := range set.Fields {
+			known[f.Name] = true
+		}
+	}
+	unknown := make(map[string]bool)
+	for _, i := range cfg.Inputs {
+		fields := []string{i.Field}
+		for c := i.Condition; c != nil; c = c.Not {
+			if c.FieldExists != "" {
+				fields = append(fields, c.FieldExists)
+			}
+		}
+		for _, f := range fields {
+			if !known[f] {
+				unknown[f] = true
+			}
+		}
+	}
+	v := validation{
+		Valid:     true,
+		Algorithm: cfg.Name,
+		Inputs:    len(cfg.Inputs),
+	}
+	for f := range unknown {
+		v.UnknownFields = append(v.UnknownFields, f)
+	}
+	sort.Strings(v.UnknownFields)
+	s.writeJSON(w, http.StatusOK, v)
+}
+
+// allowMethod writes an error response and returns false if the method of r
+// is not one of methods.
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf("method %s not allowed", r.Method)})
+	return false
+}
+
+// readJSON decodes the JSON request body into v, writing an error response
+// and returning false if it fails.
+func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	d := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("empty request body")
+		}
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Warn("Failed to write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,481 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/internal/scorer"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+const testScoringConfig = `algorithm: weighted_arithmetic_mean
+inputs:
+  - field: repo.star_count
+    bounds:
+      upper: 100
+`
+
+// testCollector returns a RepoSet for each url. Repositories with the path
+// "/missing" are not found, and those with the path "/slow" block until
+// release is closed.
+type testCollector struct {
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newTestCollector() *testCollector {
+	return &testCollector{
+		release: make(chan struct{}),
+		calls:   make(map[string]int),
+	}
+}
+
+func (c *testCollector) EmptySets() []signal.Set {
+	return []signal.Set{&signal.RepoSet{}}
+}
+
+func (c *testCollector) Collect(ctx context.Context, u *url.URL, jobID string) ([]signal.Set, error) {
+	c.mu.Lock()
+	c.calls[u.String()]++
+	c.mu.Unlock()
+	switch u.Path {
+	case "/missing":
+		return nil, fmt.Errorf("%w: %s", collector.ErrRepoNotFound, u)
+	case "/slow":
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return []signal.Set{&signal.RepoSet{
+		URL:       signal.Val(u.String()),
+		StarCount: signal.Val(50),
+	}}, nil
+}
+
+func (c *testCollector) callCount(u string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[u]
+}
+
+func newTestServer(t *testing.T, opts Options) (*Server, *testCollector) {
+	t.Helper()
+	c := newTestCollector()
+	s := New(c, zap.NewNop(), opts)
+	t.Cleanup(func() {
+		select {
+		case <-c.release:
+		default:
+			close(c.release)
+		}
+		s.Close()
+	})
+	return s, c
+}
+
+// do sends a request to s, decoding the JSON response into v if it is not
+// nil, and returns the status code.
+func do(t *testing.T, s *Server, method, target, client string, body any, v any) int {
+	t.Helper()
+	var b []byte
+	switch body := body.(type) {
+	case nil:
+	case string:
+		b = []byte(body)
+	default:
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			t.Fatalf("Marshal() = %v, want no error", err)
+		}
+	}
+	r := httptest.NewRequest(method, target, bytes.NewReader(b))
+	if client != "" {
+		r.Header.Set("X-Client", client)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", got)
+	}
+	if v != nil {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("Decode() = %v, want no error", err)
+		}
+	}
+	return w.Code
+}
+
+func TestCollect(t *testing.T) {
+	sc, err := scorer.FromConfig("test_score", strings.NewReader(testScoringConfig))
+	if err != nil {
+		t.Fatalf("FromConfig() = %v, want no error", err)
+	}
+	s, c := newTestServer(t, Options{
+		Scorer:   sc,
+		CacheTTL: time.Hour,
+	})
+	const u = "https://github.com/ossf/criticality_score"
+
+	for i, wantCached := range []bool{false, true} {
+		var res Result
+		if code := do(t, s, http.MethodPost, "/v1/collect", "", map[string]string{"url": u}, &res); code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want %d", i, code, http.StatusOK)
+		}
+		if res.Cached != wantCached {
+			t.Errorf("request %d Cached = %t, want %t", i, res.Cached, wantCached)
+		}
+		repo, _ := res.Signals["repo"].(map[string]any)
+		if got := repo["url"]; got != u {
+			t.Errorf("request %d repo.url = %v, want %s", i, got, u)
+		}
+		if res.Score == nil || *res.Score != 0.5 || res.ScoreName != "test_score" {
+			t.Errorf("request %d score = %v %q, want 0.5 test_score", i, res.Score, res.ScoreName)
+		}
+	}
+	if got := c.callCount(u); got != 1 {
+		t.Errorf("Collect() called %d times, want 1", got)
+	}
+}
+
+func TestCollectScoringConfig(t *testing.T) {
+	s, _ := newTestServer(t, Options{})
+	var res Result
+	code := do(t, s, http.MethodPost, "/v1/collect", "", map[string]string{
+		"url":            "https://github.com/ossf/criticality_score",
+		"scoring_config": testScoringConfig,
+	}, &res)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if res.Score == nil || *res.Score != 0.5 || res.ScoreName != requestScoreName {
+		t.Fatalf("score = %v %q, want 0.5 %q", res.Score, res.ScoreName, requestScoreName)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   any
+		want   int
+	}{
+		{
+			name:   "wrong method",
+			method: http.MethodGet,
+			want:   http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "empty body",
+			method: http.MethodPost,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "unknown field",
+			method: http.MethodPost,
+			body:   map[string]string{"repo": "https://github.com/ossf/criticality_score"},
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "invalid url",
+			method: http.MethodPost,
+			body:   map[string]string{"url": "not a url"},
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "not found",
+			method: http.MethodPost,
+			body:   map[string]string{"url": "https://github.com/missing"},
+			want:   http.StatusNotFound,
+		},
+		{
+			name:   "invalid scoring config",
+			method: http.MethodPost,
+			body:   map[string]string{"url": "https://github.com/ossf/criticality_score", "scoring_config": "algorithm: unknown"},
+			want:   http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, _ := newTestServer(t, Options{})
+			var res errorResponse
+			if code := do(t, s, test.method, "/v1/collect", "", test.body, &res); code != test.want {
+				t.Fatalf("status = %d, want %d", code, test.want)
+			}
+			if res.Error == "" {
+				t.Fatal("error is empty, want an error message")
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s, _ := newTestServer(t, Options{MaxBatchSize: 3})
+	urls := []string{
+		"https://github.com/ossf/criticality_score",
+		"https://github.com/missing",
+		"https://github.com/ossf/scorecard",
+	}
+	var resp struct {
+		Results []Result `json:"results"`
+	}
+	if code := do(t, s, http.MethodPost, "/v1/collect/batch", "", map[string]any{"urls": urls}, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if len(resp.Results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(urls))
+	}
+	for i, res := range resp.Results {
+		if res.URL != urls[i] {
+			t.Errorf("Results[%d].URL = %s, want %s", i, res.URL, urls[i])
+		}
+		if gotErr := res.Error != ""; gotErr != (i == 1) {
+			t.Errorf("Results[%d].Error = %q", i, res.Error)
+		}
+	}
+
+	code := do(t, s, http.MethodPost, "/v1/collect/batch", "", map[string]any{"urls": append(urls, "https://github.com/ossf/other")}, nil)
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+// waitForJob polls the job until it is no longer running.
+func waitForJob(t *testing.T, s *Server, id string) Job {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		var j Job
+		if code := do(t, s, http.MethodGet, "/v1/jobs/"+id, "", nil, &j); code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+		if j.Status != JobRunning {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("job is still running")
+	return Job{}
+}
+
+func TestJob(t *testing.T) {
+	s, _ := newTestServer(t, Options{})
+	urls := []string{"https://github.com/ossf/criticality_score", "https://github.com/missing"}
+
+	var j Job
+	if code := do(t, s, http.MethodPost, "/v1/jobs", "", map[string]any{"urls": urls}, &j); code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", code, http.StatusAccepted)
+	}
+	if j.ID == "" || j.Total != len(urls) {
+		t.Fatalf("job = %+v, want an id and %d urls", j, len(urls))
+	}
+
+	j = waitForJob(t, s, j.ID)
+	if j.Status != JobDone || j.Completed != len(urls) || j.FinishedAt == nil {
+		t.Fatalf("job = %+v, want done", j)
+	}
+	var got []string
+	for _, res := range j.Results {
+		got = append(got, res.URL)
+	}
+	if diff := cmp.Diff(urls, got); diff != "" {
+		t.Fatalf("job result urls mismatch (-want +got):\n%s", diff)
+	}
+
+	if code := do(t, s, http.MethodGet, "/v1/jobs/unknown", "", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	s, c := newTestServer(t, Options{BatchWorkers: 1})
+	urls := []string{"https://github.com/slow", "https://gitlab.com/slow", "https://example.com/slow"}
+	var j Job
+	if code := do(t, s, http.MethodPost, "/v1/jobs", "", map[string]any{"urls": urls}, &j); code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", code, http.StatusAccepted)
+	}
+	for i := 0; c.callCount(urls[0]) == 0; i++ {
+		if i == 500 {
+			t.Fatal("first url was not collected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if code := do(t, s, http.MethodDelete, "/v1/jobs/"+j.ID, "", nil, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if j = waitForJob(t, s, j.ID); j.Status != JobCanceled {
+		t.Fatalf("job status = %s, want %s", j.Status, JobCanceled)
+	}
+	// The urls after the cancel are not collected.
+	for i, u := range urls {
+		want := 0
+		if i == 0 {
+			want = 1
+		}
+		if got := c.callCount(u); got != want {
+			t.Errorf("Collect(%q) called %d times, want %d", u, got, want)
+		}
+	}
+	if j.Completed != len(urls) {
+		t.Errorf("job completed = %d, want %d", j.Completed, len(urls))
+	}
+}
+
+func TestClientConcurrency(t *testing.T) {
+	s, c := newTestServer(t, Options{
+		ClientConcurrency: 1,
+		ClientHeader:      "X-Client",
+	})
+	body := map[string]string{"url": "https://github.com/ossf/criticality_score"}
+
+	// A running job holds the client's only request.
+	var j Job
+	if code := do(t, s, http.MethodPost, "/v1/jobs", "a", map[string]any{"urls": []string{"https://github.com/slow"}}, &j); code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", code, http.StatusAccepted)
+	}
+	if code := do(t, s, http.MethodPost, "/v1/collect", "a", body, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := do(t, s, http.MethodPost, "/v1/collect", "b", body, nil); code != http.StatusOK {
+		t.Fatalf("other client status = %d, want %d", code, http.StatusOK)
+	}
+
+	// Once the job is done the client can send requests again.
+	close(c.release)
+	waitForJob(t, s, j.ID)
+	if code := do(t, s, http.MethodPost, "/v1/collect", "a", body, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestSignals(t *testing.T) {
+	s, _ := newTestServer(t, Options{})
+	var resp struct {
+		Signals []SignalSet `json:"signals"`
+	}
+	if code := do(t, s, http.MethodGet, "/v1/signals", "", nil, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if len(resp.Signals) != 1 || resp.Signals[0].Namespace != "repo" {
+		t.Fatalf("signals = %+v, want the repo namespace", resp.Signals)
+	}
+	got := make(map[string]string)
+	for _, f := range resp.Signals[0].Fields {
+		got[f.Name] = f.Type
+	}
+	for name, want := range map[string]string{
+		"repo.url":                "string",
+		"repo.star_count":         "int",
+		"repo.created_at":         "time",
+		"legacy.commit_frequency": "float64",
+	} {
+		if got[name] != want {
+			t.Errorf("type of %s = %q, want %q", name, got[name], want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		status int
+		want   validation
+	}{
+		{
+			name:   "valid",
+			config: testScoringConfig,
+			status: http.StatusOK,
+			want:   validation{Valid: true, Algorithm: "weighted_arithmetic_mean", Inputs: 1},
+		},
+		{
+			name: "unknown fields",
+			config: testScoringConfig + `  - field: depsdev.dependent_count
+    condition:
+      not:
+        field_exists: other.field
+`,
+			status: http.StatusOK,
+			want: validation{
+				Valid:         true,
+				Algorithm:     "weighted_arithmetic_mean",
+				Inputs:        2,
+				UnknownFields: []string{"depsdev.dependent_count", "other.field"},
+			},
+		},
+		{
+			name:   "unknown algorithm",
+			config: "algorithm: unknown\n",
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "invalid yaml",
+			config: "inputs: [",
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, _ := newTestServer(t, Options{})
+			var got validation
+			if code := do(t, s, http.MethodPost, "/v1/scoring/validate", "", test.config, &got); code != test.status {
+				t.Fatalf("status = %d, want %d", code, test.status)
+			}
+			if test.status != http.StatusOK {
+				if got.Valid || got.Error == "" {
+					t.Fatalf("validation = %+v, want an error", got)
+				}
+				return
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("validation mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	want := "github.com/ossf/criticality_score"
+	for _, raw := range []string{
+		"https://github.com/ossf/criticality_score",
+		"https://GitHub.com/OSSF/criticality_score/",
+		"https://github.com/ossf/criticality_score.git",
+	} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse() = %v, want no error", err)
+		}
+		if got := cacheKey(u); got != want {
+			t.Errorf("cacheKey(%s) = %s, want %s", raw, got, want)
+		}
+	}
+}
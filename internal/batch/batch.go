@@ -0,0 +1,226 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batch implements a queue of shards of repositories, stored in a
+// local directory or cloud storage bucket, that can be collected by a pool of
+// workers without any other infrastructure.
+//
+// A controller uses Create to split the list of repositories into shards.
+// Each worker then repeatedly uses Claim to lease a shard, collects the
+// repositories in it, writes the results using NewResultWriter, and finally
+// marks the shard as done using Complete. A lease that is not renewed expires,
+// allowing another worker to claim the shard if a worker fails.
+//
+// The queue is stored using the following layout:
+//
+//	job.json              the Job created by the controller
+//	shards/shard-NNNNN    the repositories in each shard, one url per line
+//	leases/shard-NNNNN    the current lease on each shard, if any
+//	results/shard-NNNNN.* the results written for each shard
+//	done/shard-NNNNN      the Record written when each shard is complete
+//	complete.json         the Summary written once every shard is complete
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+)
+
+const (
+	jobKey      = "job.json"
+	completeKey = "complete.json"
+	shardsDir   = "shards/"
+	leasesDir   = "leases/"
+	resultsDir  = "results/"
+	doneDir     = "done/"
+
+	// DefaultLeaseTTL is the default time a lease lasts without being renewed.
+	DefaultLeaseTTL = 10 * time.Minute
+
+	// DefaultSettle is the default time Claim waits after writing a lease
+	// before checking that it was not overwritten by another worker.
+	DefaultSettle = 2 * time.Second
+)
+
+var (
+	ErrorJobExists   = errors.New("job already exists")
+	ErrorNoJob       = errors.New("job does not exist")
+	ErrorInvalidSize = errors.New("shard size must be greater than 0")
+)
+
+// Job describes how the repositories were split into shards.
+type Job struct {
+	ID        string    `json:"id"`
+	Repos     int       `json:"repos"`
+	Shards    int       `json:"shards"`
+	ShardSize int       `json:"shard_size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Queue is a queue of shards stored in a bucket.
+//
+// Queue is safe to use from multiple goroutines.
+type Queue struct {
+	b *blob.Bucket
+
+	// LeaseTTL is the time a lease lasts without being renewed.
+	LeaseTTL time.Duration
+
+	// Settle is the time Claim waits after writing a lease before reading it
+	// back to check that no other worker claimed the same shard.
+	Settle time.Duration
+}
+
+// Open opens the Queue stored at rawURL, which can be either a bucket URL or
+// a local directory.
+func Open(ctx context.Context, rawURL string) (*Queue, error) {
+	b, err := cloudstorage.OpenBucket(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{
+		b:        b,
+		LeaseTTL: DefaultLeaseTTL,
+		Settle:   DefaultSettle,
+	}, nil
+}
+
+// Close releases the resources used by the Queue.
+func (q *Queue) Close() error {
+	return q.b.Close()
+}
+
+// Create splits the repository urls read from r into shards of up to
+// shardSize repositories, and writes them to the queue as a new job with the
+// given id. Blank lines and lines starting with # are ignored.
+//
+// If the queue already contains a job ErrorJobExists is returned.
+func (q *Queue) Create(ctx context.Context, id string, r io.Reader, shardSize int) (*Job, error) {
+	if shardSize <= 0 {
+		return nil, ErrorInvalidSize
+	}
+	if exists, err := q.b.Exists(ctx, jobKey); err != nil {
+		return nil, fmt.Errorf("checking job: %w", err)
+	} else if exists {
+		return nil, ErrorJobExists
+	}
+
+	job := &Job{ID: id, ShardSize: shardSize}
+	var shard []string
+	flush := func() error {
+		if len(shard) == 0 {
+			return nil
+		}
+		contents := strings.Join(shard, "\n") + "\n"
+		if err := q.b.WriteAll(ctx, shardKey(shardsDir, job.Shards), []byte(contents), nil); err != nil {
+			return fmt.Errorf("writing shard %d: %w", job.Shards, err)
+		}
+		job.Shards++
+		shard = shard[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		shard = append(shard, line)
+		job.Repos++
+		if len(shard) == shardSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading repos: %w", err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	// The job is written last, so workers never see a partial set of shards.
+	job.CreatedAt = time.Now().UTC()
+	if err := q.writeJSON(ctx, jobKey, job); err != nil {
+		return nil, fmt.Errorf("writing job: %w", err)
+	}
+	return job, nil
+}
+
+// Job returns the job in the queue, or ErrorNoJob if Create has not been
+// called.
+func (q *Queue) Job(ctx context.Context) (*Job, error) {
+	var job Job
+	if err := q.readJSON(ctx, jobKey, &job); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, ErrorNoJob
+		}
+		return nil, fmt.Errorf("reading job: %w", err)
+	}
+	return &job, nil
+}
+
+// Repos returns the repository urls in shard.
+func (q *Queue) Repos(ctx context.Context, shard int) ([]string, error) {
+	b, err := q.b.ReadAll(ctx, shardKey(shardsDir, shard))
+	if err != nil {
+		return nil, fmt.Errorf("reading shard %d: %w", shard, err)
+	}
+	return strings.Fields(string(b)), nil
+}
+
+// NewResultWriter returns a writer for the results of shard. ext is appended
+// to the name of the result, e.g. "csv".
+//
+// The results only become visible once the writer is closed.
+func (q *Queue) NewResultWriter(ctx context.Context, shard int, ext string) (io.WriteCloser, error) {
+	w, err := q.b.NewWriter(ctx, shardKey(resultsDir, shard)+"."+ext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating result writer for shard %d: %w", shard, err)
+	}
+	return w, nil
+}
+
+func (q *Queue) writeJSON(ctx context.Context, key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return q.b.WriteAll(ctx, key, append(b, '\n'), nil)
+}
+
+func (q *Queue) readJSON(ctx context.Context, key string, v any) error {
+	b, err := q.b.ReadAll(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func shardKey(dir string, shard int) string {
+	return fmt.Sprintf("%sshard-%05d", dir, shard)
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ossf/criticality_score/internal/batch"
+)
+
+const testRepos = `# seeds
+https://github.com/ossf/criticality_score
+https://github.com/ossf/scorecard
+
+https://github.com/golang/go
+https://github.com/rust-lang/rust
+https://gitlab.com/gitlab-org/gitlab
+`
+
+func newTestQueue(t *testing.T, dir string) *batch.Queue {
+	t.Helper()
+	q, err := batch.Open(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Open() = %v, want no error", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	q.Settle = 0
+	return q
+}
+
+func newTestJob(t *testing.T) (*batch.Queue, string) {
+	t.Helper()
+	dir := t.TempDir()
+	q := newTestQueue(t, dir)
+	if _, err := q.Create(context.Background(), "test", strings.NewReader(testRepos), 2); err != nil {
+		t.Fatalf("Create() = %v, want no error", err)
+	}
+	return q, dir
+}
+
+func TestCreate(t *testing.T) {
+	q, _ := newTestJob(t)
+	ctx := context.Background()
+
+	job, err := q.Job(ctx)
+	if err != nil {
+		t.Fatalf("Job() = %v, want no error", err)
+	}
+	wantJob := &batch.Job{ID: "test", Repos: 5, Shards: 3, ShardSize: 2}
+	if diff := cmp.Diff(wantJob, job, cmpopts.IgnoreFields(batch.Job{}, "CreatedAt")); diff != "" {
+		t.Fatalf("Job() mismatch (-want +got):\n%s", diff)
+	}
+
+	var got [][]string
+	for shard := 0; shard < job.Shards; shard++ {
+		repos, err := q.Repos(ctx, shard)
+		if err != nil {
+			t.Fatalf("Repos(%d) = %v, want no error", shard, err)
+		}
+		got = append(got, repos)
+	}
+	want := [][]string{
+		{"https://github.com/ossf/criticality_score", "https://github.com/ossf/scorecard"},
+		{"https://github.com/golang/go", "https://github.com/rust-lang/rust"},
+		{"https://gitlab.com/gitlab-org/gitlab"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Repos() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := q.Create(ctx, "again", strings.NewReader(testRepos), 2); !errors.Is(err, batch.ErrorJobExists) {
+		t.Fatalf("Create() = %v, want %v", err, batch.ErrorJobExists)
+	}
+}
+
+func TestNoJob(t *testing.T) {
+	q := newTestQueue(t, t.TempDir())
+	if _, err := q.Claim(context.Background(), "worker"); !errors.Is(err, batch.ErrorNoJob) {
+		t.Fatalf("Claim() = %v, want %v", err, batch.ErrorNoJob)
+	}
+}
+
+func TestClaimAndComplete(t *testing.T) {
+	q, dir := newTestJob(t)
+	ctx := context.Background()
+
+	// Use a second queue to check workers on other machines see the leases.
+	other := newTestQueue(t, dir)
+
+	var leases []*batch.Lease
+	for i := 0; i < 3; i++ {
+		l, err := q.Claim(ctx, "worker-1")
+		if err != nil {
+			t.Fatalf("Claim() = %v, want no error", err)
+		}
+		leases = append(leases, l)
+	}
+	if _, err := other.Claim(ctx, "worker-2"); !errors.Is(err, batch.ErrorNoShards) {
+		t.Fatalf("Claim() = %v, want %v", err, batch.ErrorNoShards)
+	}
+	status, err := other.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() = %v, want no error", err)
+	}
+	if diff := cmp.Diff(batch.Status{Shards: 3, Leased: 3}, status); diff != "" {
+		t.Fatalf("Status() mismatch (-want +got):\n%s", diff)
+	}
+	if s, err := q.Finish(ctx); err != nil || s != nil {
+		t.Fatalf("Finish() = %v, %v, want nil, nil", s, err)
+	}
+
+	for i, l := range leases {
+		w, err := q.NewResultWriter(ctx, l.Shard, "csv")
+		if err != nil {
+			t.Fatalf("NewResultWriter() = %v, want no error", err)
+		}
+		io.WriteString(w, "repo.url\n")
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() = %v, want no error", err)
+		}
+		r := batch.Record{Repos: 2, Written: 2}
+		if i == 0 {
+			r = batch.Record{Repos: 2, Written: 1, Failed: []string{"https://github.com/ossf/failed"}}
+		}
+		if err := q.Complete(ctx, l, r); err != nil {
+			t.Fatalf("Complete() = %v, want no error", err)
+		}
+	}
+
+	status, err = other.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() = %v, want no error", err)
+	}
+	if !status.Complete() {
+		t.Fatalf("Status() = %+v, want complete", status)
+	}
+	if _, err := other.Claim(ctx, "worker-2"); !errors.Is(err, batch.ErrorNoShards) {
+		t.Fatalf("Claim() = %v, want %v", err, batch.ErrorNoShards)
+	}
+	s, err := q.Finish(ctx)
+	if err != nil {
+		t.Fatalf("Finish() = %v, want no error", err)
+	}
+	if s == nil || s.Written != 5 || s.Failed != 1 || s.Job.Shards != 3 {
+		t.Fatalf("Finish() = %+v, want 5 written and 1 failed from 3 shards", s)
+	}
+}
+
+func TestExpiredLease(t *testing.T) {
+	q, dir := newTestJob(t)
+	ctx := context.Background()
+	other := newTestQueue(t, dir)
+
+	// A lease that expires immediately can be claimed by another worker.
+	q.LeaseTTL = -time.Second
+	lost, err := q.Claim(ctx, "worker-1")
+	if err != nil {
+		t.Fatalf("Claim() = %v, want no error", err)
+	}
+
+	var l *batch.Lease
+	for l == nil || l.Shard != lost.Shard {
+		if l, err = other.Claim(ctx, "worker-2"); err != nil {
+			t.Fatalf("Claim() = %v, want no error", err)
+		}
+	}
+	if err := lost.Renew(ctx); !errors.Is(err, batch.ErrorLeaseLost) {
+		t.Fatalf("Renew() = %v, want %v", err, batch.ErrorLeaseLost)
+	}
+	if err := l.Renew(ctx); err != nil {
+		t.Fatalf("Renew() = %v, want no error", err)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"time"
+
+	"gocloud.dev/gcerrors"
+)
+
+var (
+	// ErrorNoShards is returned by Claim when every shard is either complete
+	// or leased by another worker.
+	ErrorNoShards = errors.New("no shards available")
+
+	// ErrorLeaseLost is returned when a lease has expired and been claimed by
+	// another worker.
+	ErrorLeaseLost = errors.New("lease lost")
+)
+
+// Lease is held by a worker while it collects a shard.
+type Lease struct {
+	Shard   int       `json:"shard"`
+	Worker  string    `json:"worker"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+
+	q *Queue
+}
+
+// Claim leases a shard that is neither complete nor leased by another worker,
+// returning ErrorNoShards if there are none.
+//
+// Buckets do not support conditional writes, so a lease is claimed by writing
+// it, waiting for Settle, and then reading it back to check that no other
+// worker claimed the shard at the same time. Claims that race for longer than
+// Settle may result in a shard being collected twice, which is harmless as the
+// results of a shard are always written to the same location.
+func (q *Queue) Claim(ctx context.Context, worker string) (*Lease, error) {
+	job, err := q.Job(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done, err := q.keys(ctx, doneDir)
+	if err != nil {
+		return nil, err
+	}
+	// Start at a random shard to reduce contention between workers.
+	start := 0
+	if job.Shards > 0 {
+		start = mathrand.Intn(job.Shards)
+	}
+	for i := 0; i < job.Shards; i++ {
+		shard := (start + i) % job.Shards
+		if done[shardKey("", shard)] {
+			continue
+		}
+		l, err := q.claim(ctx, worker, shard)
+		if errors.Is(err, ErrorLeaseLost) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			return l, nil
+		}
+	}
+	return nil, ErrorNoShards
+}
+
+// claim attempts to lease shard, returning nil if it is already leased.
+func (q *Queue) claim(ctx context.Context, worker string, shard int) (*Lease, error) {
+	current, err := q.lease(ctx, shard)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && time.Now().Before(current.Expires) {
+		return nil, nil
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	l := &Lease{Shard: shard, Worker: worker, Token: token, q: q}
+	if err := l.write(ctx); err != nil {
+		return nil, err
+	}
+	if q.Settle > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(q.Settle):
+		}
+	}
+	if err := l.check(ctx); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Renew extends the lease by the queue's LeaseTTL. If the lease has been
+// claimed by another worker ErrorLeaseLost is returned.
+func (l *Lease) Renew(ctx context.Context) error {
+	if err := l.check(ctx); err != nil {
+		return err
+	}
+	return l.write(ctx)
+}
+
+// Release removes the lease, allowing the shard to be claimed by another
+// worker immediately.
+func (l *Lease) Release(ctx context.Context) error {
+	if err := l.check(ctx); err != nil {
+		return err
+	}
+	if err := l.q.b.Delete(ctx, shardKey(leasesDir, l.Shard)); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return fmt.Errorf("deleting lease for shard %d: %w", l.Shard, err)
+	}
+	return nil
+}
+
+func (l *Lease) write(ctx context.Context) error {
+	l.Expires = time.Now().Add(l.q.LeaseTTL).UTC()
+	if err := l.q.writeJSON(ctx, shardKey(leasesDir, l.Shard), l); err != nil {
+		return fmt.Errorf("writing lease for shard %d: %w", l.Shard, err)
+	}
+	return nil
+}
+
+// check returns ErrorLeaseLost if the lease stored for the shard is not l.
+func (l *Lease) check(ctx context.Context) error {
+	current, err := l.q.lease(ctx, l.Shard)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.Token != l.Token {
+		return ErrorLeaseLost
+	}
+	return nil
+}
+
+// lease returns the lease stored for shard, or nil if there is none.
+func (q *Queue) lease(ctx context.Context, shard int) (*Lease, error) {
+	var l Lease
+	if err := q.readJSON(ctx, shardKey(leasesDir, shard), &l); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading lease for shard %d: %w", shard, err)
+	}
+	return &l, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+)
+
+// Record is written when a shard is complete.
+type Record struct {
+	Shard    int       `json:"shard"`
+	Worker   string    `json:"worker"`
+	Repos    int       `json:"repos"`
+	Written  int       `json:"written"`
+	Skipped  int       `json:"skipped"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	// Failed lists the repositories that could not be collected because of
+	// an error. They are not written to the results.
+	Failed []string `json:"failed,omitempty"`
+}
+
+// Summary is written once every shard in a job is complete.
+type Summary struct {
+	Job      Job       `json:"job"`
+	Written  int       `json:"written"`
+	Skipped  int       `json:"skipped"`
+	Failed   int       `json:"failed"`
+	Finished time.Time `json:"finished"`
+}
+
+// Status is the progress of the shards in a job.
+type Status struct {
+	Shards  int `json:"shards"`
+	Done    int `json:"done"`
+	Leased  int `json:"leased"`
+	Pending int `json:"pending"`
+}
+
+// Complete reports whether every shard is done.
+func (s Status) Complete() bool {
+	return s.Done == s.Shards
+}
+
+// Complete records that the shard leased by l is done and releases the lease.
+// The results for the shard must be written before calling Complete.
+//
+// If the lease was lost the shard is still recorded as done, as the results
+// have been written, but ErrorLeaseLost is returned.
+func (q *Queue) Complete(ctx context.Context, l *Lease, r Record) error {
+	r.Shard = l.Shard
+	r.Worker = l.Worker
+	if r.Finished.IsZero() {
+		r.Finished = time.Now().UTC()
+	}
+	if err := q.writeJSON(ctx, shardKey(doneDir, l.Shard), r); err != nil {
+		return fmt.Errorf("writing record for shard %d: %w", l.Shard, err)
+	}
+	return l.Release(ctx)
+}
+
+// Status returns the progress of the shards in the job.
+func (q *Queue) Status(ctx context.Context) (Status, error) {
+	job, err := q.Job(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	done, err := q.keys(ctx, doneDir)
+	if err != nil {
+		return Status{}, err
+	}
+	s := Status{Shards: job.Shards}
+	for shard := 0; shard < job.Shards; shard++ {
+		if done[shardKey("", shard)] {
+			s.Done++
+			continue
+		}
+		l, err := q.lease(ctx, shard)
+		if err != nil {
+			return Status{}, err
+		}
+		if l != nil && time.Now().Before(l.Expires) {
+			s.Leased++
+		} else {
+			s.Pending++
+		}
+	}
+	return s, nil
+}
+
+// Records returns the Record for each complete shard, in shard order.
+func (q *Queue) Records(ctx context.Context) ([]Record, error) {
+	job, err := q.Job(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for shard := 0; shard < job.Shards; shard++ {
+		var r Record
+		if err := q.readJSON(ctx, shardKey(doneDir, shard), &r); err != nil {
+			if gcerrors.Code(err) == gcerrors.NotFound {
+				continue
+			}
+			return nil, fmt.Errorf("reading record for shard %d: %w", shard, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Finish writes the Summary of the job if every shard is complete, returning
+// nil if it is not. It is safe to call Finish more than once.
+func (q *Queue) Finish(ctx context.Context) (*Summary, error) {
+	job, err := q.Job(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := q.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < job.Shards {
+		return nil, nil
+	}
+	s := &Summary{Job: *job}
+	for _, r := range records {
+		s.Written += r.Written
+		s.Skipped += r.Skipped
+		s.Failed += len(r.Failed)
+		if r.Finished.After(s.Finished) {
+			s.Finished = r.Finished
+		}
+	}
+	if err := q.writeJSON(ctx, completeKey, s); err != nil {
+		return nil, fmt.Errorf("writing summary: %w", err)
+	}
+	return s, nil
+}
+
+// keys returns the set of names of the blobs in dir.
+func (q *Queue) keys(ctx context.Context, dir string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	iter := q.b.List(&blob.ListOptions{Prefix: dir})
+	for {
+		obj, err := iter.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", dir, err)
+		}
+		keys[strings.TrimPrefix(obj.Key, dir)] = true
+	}
+}
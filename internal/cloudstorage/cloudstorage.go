@@ -19,14 +19,19 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"os"
 	"path"
 	"strings"
+	"time"
 
 	"gocloud.dev/blob"
 	_ "gocloud.dev/blob/fileblob"
 	_ "gocloud.dev/blob/gcsblob"
 	_ "gocloud.dev/blob/memblob"
 	_ "gocloud.dev/blob/s3blob"
+	"gocloud.dev/gcerrors"
+
+	"github.com/ossf/criticality_score/internal/compress"
 )
 
 const fileScheme = "file"
@@ -70,7 +75,19 @@ func parseBucketAndPrefix(rawURL string) (bucket, prefix string, _ error) {
 	return bucket, prefix, nil
 }
 
+// NewWriter opens the blob at rawURL for writing. rawURL can be either a
+// bucket URL or a local path.
+//
+// The data written is compressed if rawURL ends in an extension recognized by
+// compress.FormatForName.
 func NewWriter(ctx context.Context, rawURL string) (io.WriteCloser, error) {
+	return NewCompressedWriter(ctx, rawURL, compress.FormatAuto)
+}
+
+// NewCompressedWriter is like NewWriter, but compresses the data written using
+// format f. If f is compress.FormatAuto the format is chosen from the
+// extension of rawURL.
+func NewCompressedWriter(ctx context.Context, rawURL string, f compress.Format) (io.WriteCloser, error) {
 	bucket, prefix, err := parseBucketAndPrefix(rawURL)
 	if err != nil {
 		return nil, err
@@ -84,5 +101,129 @@ func NewWriter(ctx context.Context, rawURL string) (io.WriteCloser, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed creating writer for %s: %w", rawURL, err)
 	}
-	return w, nil
+	cw, err := compress.NewWriter(w, f.Resolve(prefix))
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Reader is used to read the contents of a blob.
+type Reader interface {
+	io.ReadCloser
+
+	// ModTime returns the time the blob was last modified.
+	ModTime() time.Time
+}
+
+type bucketReader struct {
+	*blob.Reader
+	b *blob.Bucket
+}
+
+// Close closes the reader and the bucket it was read from.
+func (r *bucketReader) Close() error {
+	err := r.Reader.Close()
+	if berr := r.b.Close(); err == nil {
+		err = berr
+	}
+	return err
+}
+
+type decompressReader struct {
+	io.ReadCloser
+	modTime time.Time
+}
+
+// ModTime implements the Reader interface.
+func (r *decompressReader) ModTime() time.Time {
+	return r.modTime
+}
+
+// NewReader opens the blob at rawURL for reading. Like NewWriter, rawURL can
+// be either a bucket URL or a local path.
+//
+// The data read is decompressed if rawURL ends in an extension recognized by
+// compress.FormatForName, matching the data written by NewWriter.
+//
+// If the blob does not exist the error returned will match os.ErrNotExist
+// when tested with errors.Is.
+func NewReader(ctx context.Context, rawURL string) (Reader, error) {
+	r, err := NewRawReader(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if compress.FormatForName(rawURL) == compress.FormatNone {
+		return r, nil
+	}
+	dr, err := compress.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed decompressing %s: %w", rawURL, err)
+	}
+	return &decompressReader{ReadCloser: dr, modTime: r.ModTime()}, nil
+}
+
+// NewRawReader is like NewReader, but the data is never decompressed.
+func NewRawReader(ctx context.Context, rawURL string) (Reader, error) {
+	bucket, prefix, err := parseBucketAndPrefix(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := blob.OpenBucket(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening %s: %w", bucket, err)
+	}
+	r, err := b.NewReader(ctx, prefix, nil)
+	if err != nil {
+		b.Close()
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, fmt.Errorf("failed creating reader for %s: %w (%v)", rawURL, os.ErrNotExist, err)
+		}
+		return nil, fmt.Errorf("failed creating reader for %s: %w", rawURL, err)
+	}
+	return &bucketReader{Reader: r, b: b}, nil
+}
+
+// IsBucketURL returns true if rawURL is a URL with a scheme supported for
+// blob storage, such as "gs://bucket/path" or "file:///path". Local paths, and
+// URLs with other schemes such as "https", return false.
+func IsBucketURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() {
+		return false
+	}
+	return blob.DefaultURLMux().ValidBucketScheme(u.Scheme)
+}
+
+// OpenBucket opens rawURL as a directory of blobs, with any path in rawURL
+// used as a prefix for the keys in the returned bucket. Like NewWriter, rawURL
+// can be either a bucket URL or a local path. Local directories are created
+// if they do not exist.
+func OpenBucket(ctx context.Context, rawURL string) (*blob.Bucket, error) {
+	bucket, prefix, err := parseBucketAndPrefix(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(bucket, fileScheme+":") {
+		u, err := url.Parse(bucket)
+		if err != nil {
+			return nil, fmt.Errorf("url parse: %w", err)
+		}
+		q := u.Query()
+		q.Set("create_dir", "true")
+		u.RawQuery = q.Encode()
+		bucket = u.String()
+	}
+
+	b, err := blob.OpenBucket(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening %s: %w", bucket, err)
+	}
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		b = blob.PrefixedBucket(b, prefix+"/")
+	}
+	return b, nil
 }
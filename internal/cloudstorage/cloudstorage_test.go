@@ -15,9 +15,15 @@
 package cloudstorage
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/url"
+	"os"
+	"path"
 	"testing"
+
+	"github.com/ossf/criticality_score/internal/compress"
 )
 
 func TestParseBucketAndPrefixAbsLocalFile(t *testing.T) {
@@ -84,6 +90,93 @@ func TestNewWriter(t *testing.T) {
 	}
 }
 
+func TestNewWriterCompressed(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		format compress.Format
+		magic  []byte
+	}{
+		{name: "out.csv.gz", format: compress.FormatAuto, magic: []byte{0x1f, 0x8b}},
+		{name: "out.csv", format: compress.FormatZstd, magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+		{name: "out.csv.zst", format: compress.FormatNone, magic: []byte("data")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := path.Join(dir, test.name)
+			w, err := NewCompressedWriter(context.Background(), name, test.format)
+			if err != nil {
+				t.Fatalf("NewCompressedWriter() = %v, want no error", err)
+			}
+			if _, err := w.Write([]byte("data")); err != nil {
+				t.Fatalf("Write() = %v, want no error", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+			got, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("ReadFile() = %v, want no error", err)
+			}
+			if !bytes.HasPrefix(got, test.magic) {
+				t.Fatalf("ReadFile() = %v, want prefix %v", got, test.magic)
+			}
+		})
+	}
+}
+
+func TestNewReaderCompressed(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"out.csv", "out.csv.gz", "out.csv.zst"} {
+		t.Run(name, func(t *testing.T) {
+			name := path.Join(dir, name)
+			w, err := NewWriter(context.Background(), name)
+			if err != nil {
+				t.Fatalf("NewWriter() = %v, want no error", err)
+			}
+			if _, err := w.Write([]byte("data")); err != nil {
+				t.Fatalf("Write() = %v, want no error", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+
+			r, err := NewReader(context.Background(), name)
+			if err != nil {
+				t.Fatalf("NewReader() = %v, want no error", err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() = %v, want no error", err)
+			}
+			if string(got) != "data" {
+				t.Fatalf("ReadAll() = %q, want %q", got, "data")
+			}
+			if r.ModTime().IsZero() {
+				t.Fatalf("ModTime() is zero, want the time the file was written")
+			}
+
+			raw, err := NewRawReader(context.Background(), name)
+			if err != nil {
+				t.Fatalf("NewRawReader() = %v, want no error", err)
+			}
+			defer raw.Close()
+			gotRaw, err := io.ReadAll(raw)
+			if err != nil {
+				t.Fatalf("ReadAll() = %v, want no error", err)
+			}
+			want, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("ReadFile() = %v, want no error", err)
+			}
+			if !bytes.Equal(gotRaw, want) {
+				t.Fatalf("ReadAll() = %v, want %v", gotRaw, want)
+			}
+		})
+	}
+}
+
 func assertBucket(t *testing.T, bucket, wantScheme, wantHost, wantPath string, wantQuery map[string]string) {
 	t.Helper()
 	u, err := url.Parse(bucket)
@@ -108,3 +201,24 @@ func assertBucket(t *testing.T, bucket, wantScheme, wantHost, wantPath string, w
 		}
 	}
 }
+
+func TestOpenBucketLocalDir(t *testing.T) {
+	dir := path.Join(t.TempDir(), "does", "not", "exist")
+	for _, rawURL := range []string{dir, dir + "/"} {
+		b, err := OpenBucket(context.Background(), rawURL)
+		if err != nil {
+			t.Fatalf("OpenBucket(%q) = %v, want no error", rawURL, err)
+		}
+		if err := b.WriteAll(context.Background(), "a/b", []byte("test"), nil); err != nil {
+			t.Fatalf("WriteAll() = %v, want no error", err)
+		}
+		b.Close()
+		got, err := os.ReadFile(path.Join(dir, "a", "b"))
+		if err != nil {
+			t.Fatalf("ReadFile() = %v, want no error", err)
+		}
+		if string(got) != "test" {
+			t.Fatalf("ReadFile() = %q, want %q", got, "test")
+		}
+	}
+}
@@ -27,9 +27,9 @@ import (
 	"github.com/ossf/criticality_score/internal/collector/depsdev"
 	"github.com/ossf/criticality_score/internal/collector/github"
 	"github.com/ossf/criticality_score/internal/collector/githubmentions"
-	"github.com/ossf/criticality_score/internal/collector/projectrepo"
-	"github.com/ossf/criticality_score/internal/collector/signal"
 	"github.com/ossf/criticality_score/internal/githubapi"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 // ErrUncollectableRepo is the base error returned when there is a problem with
@@ -64,7 +64,10 @@ func New(ctx context.Context, logger *zap.Logger, opts ...Option) (*Collector, e
 
 	ghClient := githubapi.NewClient(c.config.gitHubHTTPClient)
 
-	// Register all the Repo factories.
+	// Register all the Repo factories, starting with any custom factories.
+	for _, f := range c.config.factories {
+		c.resolver.Register(f)
+	}
 	c.resolver.Register(github.NewRepoFactory(ghClient, logger))
 
 	// Register all the sources that are supported and enabled.
@@ -81,7 +84,7 @@ func New(ctx context.Context, logger *zap.Logger, opts ...Option) (*Collector, e
 		// deps.dev collection source has been disabled, so skip it.
 		logger.Warn("deps.dev signal source is disabled.")
 	} else {
-		ddsource, err := depsdev.NewSource(ctx, logger, c.config.gcpProject, c.config.gcpDatasetName, c.config.gcpDatasetTTL)
+		ddsource, err := depsdev.NewSource(ctx, logger, c.config.gcpProject, c.config.gcpDatasetName, c.config.gcpDatasetTTL, c.config.depsdevPackageWriter)
 		if err != nil {
 			return nil, fmt.Errorf("init deps.dev source: %w", err)
 		}
@@ -89,6 +92,20 @@ func New(ctx context.Context, logger *zap.Logger, opts ...Option) (*Collector, e
 		c.registry.Register(ddsource)
 	}
 
+	// Register any custom sources last.
+	for _, s := range c.config.sources {
+		if err := signal.ValidateSet(s.EmptySet()); err != nil {
+			return nil, fmt.Errorf("invalid source %s: %w", s.EmptySet().Namespace(), err)
+		}
+		if c.registry.containsSource(s) {
+			return nil, fmt.Errorf("source %s has already been registered", s.EmptySet().Namespace())
+		}
+		if ns := s.EmptySet().Namespace(); ns == signal.NamespaceLegacy || c.registry.containsNamespace(ns) {
+			return nil, fmt.Errorf("source namespace %s is already in use", ns)
+		}
+		c.registry.Register(s)
+	}
+
 	return c, nil
 }
 
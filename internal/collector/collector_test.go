@@ -0,0 +1,202 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+type testRepo struct {
+	u *url.URL
+}
+
+func (r *testRepo) URL() *url.URL {
+	return r.u
+}
+
+type testFactory struct{}
+
+func (f *testFactory) New(ctx context.Context, u *url.URL) (projectrepo.Repo, error) {
+	if u.Path == "/missing" {
+		return nil, projectrepo.ErrNoRepoFound
+	}
+	return &testRepo{u: u}, nil
+}
+
+func (f *testFactory) Match(u *url.URL) bool {
+	return u.Hostname() == "example.com"
+}
+
+type testSet struct {
+	Name signal.Field[string]
+}
+
+func (s *testSet) Namespace() signal.Namespace {
+	return "test"
+}
+
+type testSource struct {
+	set signal.Set
+}
+
+func (s *testSource) EmptySet() signal.Set {
+	if s.set != nil {
+		return s.set
+	}
+	return &testSet{}
+}
+
+func (s *testSource) IsSupported(r projectrepo.Repo) bool {
+	_, ok := r.(*testRepo)
+	return ok
+}
+
+func (s *testSource) Get(ctx context.Context, r projectrepo.Repo, jobID string) (signal.Set, error) {
+	return &testSet{Name: signal.Val(r.URL().Path)}, nil
+}
+
+func TestCollect_Custom(t *testing.T) {
+	c, err := New(context.Background(), zaptest.NewLogger(t),
+		DisableAllSources(),
+		RepoFactory(&testFactory{}),
+		Source(&testSource{}))
+	if err != nil {
+		t.Fatalf("New() = %v, want no error", err)
+	}
+	if diff := cmp.Diff([]signal.Set{&testSet{}}, c.EmptySets(), cmp.AllowUnexported(signal.Field[string]{})); diff != "" {
+		t.Errorf("EmptySets() mismatch (-want +got):\n%s", diff)
+	}
+
+	u, _ := url.Parse("https://example.com/foo")
+	got, err := c.Collect(context.Background(), u, "")
+	if err != nil {
+		t.Fatalf("Collect() = %v, want no error", err)
+	}
+	want := []signal.Set{&testSet{Name: signal.Val("/foo")}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(signal.Field[string]{})); diff != "" {
+		t.Errorf("Collect() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCollect_CustomErrors(t *testing.T) {
+	c, err := New(context.Background(), zaptest.NewLogger(t),
+		DisableAllSources(),
+		RepoFactory(&testFactory{}),
+		Source(&testSource{}))
+	if err != nil {
+		t.Fatalf("New() = %v, want no error", err)
+	}
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "unsupported", url: "https://example.org/foo", wantErr: ErrUnsupportedURL},
+		{name: "not found", url: "https://example.com/missing", wantErr: ErrRepoNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, _ := url.Parse(test.url)
+			if _, err := c.Collect(context.Background(), u, ""); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Collect() = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+type invalidSet struct {
+	Name signal.Field[string]
+}
+
+func (s *invalidSet) Namespace() signal.Namespace {
+	return "Invalid Namespace"
+}
+
+func TestNew_InvalidSource(t *testing.T) {
+	_, err := New(context.Background(), zaptest.NewLogger(t),
+		DisableAllSources(),
+		Source(&testSource{set: &invalidSet{}}))
+	if err == nil {
+		t.Fatalf("New() = nil, want an error")
+	}
+}
+
+func TestNew_DuplicateSource(t *testing.T) {
+	s := &testSource{}
+	_, err := New(context.Background(), zaptest.NewLogger(t),
+		DisableAllSources(),
+		Source(s),
+		Source(s))
+	if err == nil {
+		t.Fatalf("New() = nil, want an error")
+	}
+}
+
+// namespaceSet is a valid Set with the namespace ns.
+type namespaceSet struct {
+	ns signal.Namespace
+
+	Name signal.Field[string]
+}
+
+func (s *namespaceSet) Namespace() signal.Namespace {
+	return s.ns
+}
+
+func TestNew_DuplicateNamespace(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "built-in source",
+			opts: []Option{
+				EnableSource(SourceTypeGithubRepo),
+				Source(&testSource{set: &namespaceSet{ns: signal.NamespaceRepo}}),
+			},
+		},
+		{
+			name: "legacy",
+			opts: []Option{
+				Source(&testSource{set: &namespaceSet{ns: signal.NamespaceLegacy}}),
+			},
+		},
+		{
+			name: "custom source",
+			opts: []Option{
+				Source(&testSource{}),
+				Source(&testSource{}),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]Option{DisableAllSources()}, test.opts...)
+			if _, err := New(context.Background(), zaptest.NewLogger(t), opts...); err == nil {
+				t.Fatalf("New() = nil, want an error")
+			}
+		})
+	}
+}
@@ -25,7 +25,10 @@ import (
 	sclog "github.com/ossf/scorecard/v4/log"
 	"go.uber.org/zap"
 
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
 	"github.com/ossf/criticality_score/internal/githubapi"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 // DefaultGCPDatasetName is the default name to use for GCP BigQuery Datasets.
@@ -75,6 +78,11 @@ type config struct {
 	gcpDatasetName string
 	gcpDatasetTTL  time.Duration
 
+	depsdevPackageWriter depsdev.PackageWriter
+
+	sources   []signal.Source
+	factories []projectrepo.Factory
+
 	sourceStatuses      map[SourceType]sourceStatus
 	defaultSourceStatus sourceStatus
 }
@@ -186,3 +194,35 @@ func GCPDatasetTTL(ttl time.Duration) Option {
 		c.gcpDatasetTTL = ttl
 	})
 }
+
+// DepsDevPackageWriter sets a writer used to output the packages that
+// contribute to each repository's deps.dev dependent count.
+//
+// By default the packages are not written.
+func DepsDevPackageWriter(w depsdev.PackageWriter) Option {
+	return option(func(c *config) {
+		c.depsdevPackageWriter = w
+	})
+}
+
+// Source adds a custom signal Source that is used for collection alongside
+// the enabled SourceTypes. Sources are used in the order they are added, after
+// the built-in sources.
+//
+// The Source's namespace must not be used by any other Source, or be
+// signal.NamespaceLegacy.
+func Source(s signal.Source) Option {
+	return option(func(c *config) {
+		c.sources = append(c.sources, s)
+	})
+}
+
+// RepoFactory adds a custom projectrepo Factory used to resolve repository
+// urls. Factories are tried in the order they are added, before the built-in
+// factories, so a Factory can be used to take over urls that would otherwise
+// be resolved by a built-in Factory.
+func RepoFactory(f projectrepo.Factory) Option {
+	return option(func(c *config) {
+		c.factories = append(c.factories, f)
+	})
+}
@@ -89,6 +89,22 @@ func TestGCPDatasetTTL(t *testing.T) {
 	}
 }
 
+func TestSource(t *testing.T) {
+	s := &testSource{}
+	c := makeTestConfig(t, Source(s))
+	if len(c.sources) != 1 || c.sources[0] != s {
+		t.Fatalf("config.sources = %v, want [%v]", c.sources, s)
+	}
+}
+
+func TestRepoFactory(t *testing.T) {
+	f := &testFactory{}
+	c := makeTestConfig(t, RepoFactory(f))
+	if len(c.factories) != 1 || c.factories[0] != f {
+		t.Fatalf("config.factories = %v, want [%v]", c.factories, f)
+	}
+}
+
 func makeTestConfig(t *testing.T, opts ...Option) *config {
 	t.Helper()
 	return makeConfig(context.Background(), zaptest.NewLogger(t), opts...)
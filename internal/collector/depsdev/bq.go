@@ -28,22 +28,36 @@ import (
 var ErrorNoResults = errors.New("no results returned")
 
 type Dataset struct {
-	ds *bigquery.Dataset
+	ds       *bigquery.Dataset
+	location string
 }
 
 type Table struct {
+	id string
 	md *bigquery.TableMetadata
 }
 
+// RowIterator is used to iterate over the rows in a Table.
+//
+// Next returns iterator.Done when there are no more rows.
+type RowIterator interface {
+	Next(dst any) error
+}
+
 // bqAPI wraps the BigQuery Go API to make the deps.dev implementation easier to unit test.
 type bqAPI interface {
 	Project() string
 	OneResultQuery(ctx context.Context, query string, params map[string]any, result any) error
-	NoResultQuery(ctx context.Context, query string, params map[string]any) error
+	Query(ctx context.Context, query string, params map[string]any, labels map[string]string) (RowIterator, error)
+	NoResultQuery(ctx context.Context, query string, params map[string]any, labels map[string]string) error
 	GetDataset(ctx context.Context, id string) (*Dataset, error)
 	CreateDataset(ctx context.Context, id string, ttl time.Duration) (*Dataset, error)
 	UpdateDataset(ctx context.Context, d *Dataset, ttl time.Duration) error
 	GetTable(ctx context.Context, d *Dataset, id string) (*Table, error)
+	ReadTable(ctx context.Context, d *Dataset, id string) RowIterator
+	ListTables(ctx context.Context, d *Dataset) ([]*Table, error)
+	UpdateTableLabels(ctx context.Context, d *Dataset, id string, labels map[string]string) error
+	DeleteTable(ctx context.Context, d *Dataset, id string) error
 }
 
 type bq struct {
@@ -73,11 +87,21 @@ func (b *bq) OneResultQuery(ctx context.Context, query string, params map[string
 	return nil
 }
 
-func (b *bq) NoResultQuery(ctx context.Context, query string, params map[string]any) error {
+func (b *bq) Query(ctx context.Context, query string, params map[string]any, labels map[string]string) (RowIterator, error) {
 	q := b.client.Query(query)
 	for k, v := range params {
 		q.Parameters = append(q.Parameters, bigquery.QueryParameter{Name: k, Value: v})
 	}
+	q.Labels = labels
+	return q.Read(ctx)
+}
+
+func (b *bq) NoResultQuery(ctx context.Context, query string, params map[string]any, labels map[string]string) error {
+	q := b.client.Query(query)
+	for k, v := range params {
+		q.Parameters = append(q.Parameters, bigquery.QueryParameter{Name: k, Value: v})
+	}
+	q.Labels = labels
 	j, err := q.Run(ctx)
 	if err != nil {
 		return err
@@ -94,7 +118,7 @@ func (b *bq) NoResultQuery(ctx context.Context, query string, params map[string]
 
 func (b *bq) GetDataset(ctx context.Context, id string) (*Dataset, error) {
 	ds := b.client.Dataset(id)
-	_, err := ds.Metadata(ctx)
+	md, err := ds.Metadata(ctx)
 	if isNotFound(err) {
 		return nil, nil
 	}
@@ -102,7 +126,8 @@ func (b *bq) GetDataset(ctx context.Context, id string) (*Dataset, error) {
 		return nil, fmt.Errorf("dataset metadata: %w", err)
 	}
 	return &Dataset{
-		ds: ds,
+		ds:       ds,
+		location: md.Location,
 	}, nil
 }
 
@@ -112,7 +137,8 @@ func (b *bq) CreateDataset(ctx context.Context, id string, ttl time.Duration) (*
 		return nil, err
 	}
 	return &Dataset{
-		ds: ds,
+		ds:       ds,
+		location: b.client.Location,
 	}, nil
 }
 
@@ -144,7 +170,49 @@ func (b *bq) GetTable(ctx context.Context, d *Dataset, id string) (*Table, error
 	if err != nil {
 		return nil, err
 	}
-	return &Table{md: md}, nil
+	return &Table{id: id, md: md}, nil
+}
+
+func (b *bq) ReadTable(ctx context.Context, d *Dataset, id string) RowIterator {
+	return d.ds.Table(id).Read(ctx)
+}
+
+func (b *bq) ListTables(ctx context.Context, d *Dataset) ([]*Table, error) {
+	var tables []*Table
+	it := d.ds.Tables(ctx)
+	for {
+		t, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		md, err := t.Metadata(ctx)
+		if isNotFound(err) {
+			// The table was deleted while listing.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("table metadata: %w", err)
+		}
+		tables = append(tables, &Table{id: t.TableID, md: md})
+	}
+}
+
+func (b *bq) UpdateTableLabels(ctx context.Context, d *Dataset, id string, labels map[string]string) error {
+	var update bigquery.TableMetadataToUpdate
+	for k, v := range labels {
+		update.SetLabel(k, v)
+	}
+	if _, err := d.ds.Table(id).Update(ctx, update, ""); err != nil {
+		return fmt.Errorf("table update metadata: %w", err)
+	}
+	return nil
+}
+
+func (b *bq) DeleteTable(ctx context.Context, d *Dataset, id string) error {
+	return d.ds.Table(id).Delete(ctx)
 }
 
 func isNotFound(err error) bool {
@@ -19,16 +19,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 	"time"
 
 	"cloud.google.com/go/bigquery"
 	"go.uber.org/zap"
 	_ "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/iterator"
+
+	"github.com/ossf/criticality_score/internal/depgraph"
 )
 
 const (
-	dependentCountsTableName = "dependent_counts"
+	dependentCountsTableName        = "dependent_counts"
+	packageDependentCountsTableName = "package_dependent_counts"
+
+	// maxCountTables is the number of dependent counts tables kept in memory.
+	// Each table holds millions of rows.
+	maxCountTables = 3
 
 	snapshotQuery = "SELECT MAX(Time) AS SnapshotTime FROM `bigquery-public-data.deps_dev_v1.Snapshots`"
 )
@@ -36,7 +46,7 @@ const (
 // TODO: prune root dependents that come from the same project.
 // TODO: count "# packages per project" to determine dependent ratio
 
-const dataQuery = `
+const rawDependentCountsQuery = `
 CREATE TEMP TABLE rawDependentCounts(Name STRING, Version STRING, System STRING, DependentCount INT)
 AS
   SELECT d.Dependency.Name as Name, d.Dependency.Version as Version, d.Dependency.System as System, COUNT(1) AS DependentCount
@@ -46,7 +56,9 @@ AS
    WHERE SnapshotAt = @part) AS lv ON (lv.RowNumber = 1 AND lv.Name = d.Name AND lv.Version = d.Version AND lv.System = d.System)
   WHERE d.SnapshotAt = @part
   GROUP BY Name, Version, System;
+`
 
+const dataQuery = rawDependentCountsQuery + `
 CREATE TABLE IF NOT EXISTS ` + "`{{.ProjectID}}.{{.DatasetName}}.{{.TableName}}`" + `
 AS
 WITH pvp AS (
@@ -61,12 +73,38 @@ SELECT pvp.ProjectName AS ProjectName, pvp.ProjectType AS ProjectType, SUM(d.Dep
 GROUP BY ProjectName, ProjectType;
 `
 
-const countQuery = `
-SELECT DependentCount
+// packageDataQuery breaks down the dependent counts for each project by the
+// packages that contribute to it.
+const packageDataQuery = rawDependentCountsQuery + `
+CREATE TABLE IF NOT EXISTS ` + "`{{.ProjectID}}.{{.DatasetName}}.{{.TableName}}`" + `
+AS
+WITH pvp AS (
+    SELECT System, Name, Version, ProjectName, ProjectType
+    FROM ` + "`bigquery-public-data.deps_dev_v1.PackageVersionToProject`" + `
+    WHERE SnapshotAt = @part
+)
+SELECT pvp.ProjectName AS ProjectName, pvp.ProjectType AS ProjectType, d.System AS System, d.Name AS Name, SUM(d.DependentCount) AS DependentCount
+ FROM pvp
+ JOIN rawDependentCounts AS d
+      ON (pvp.System = d.System AND pvp.Name = d.Name AND pvp.Version = d.Version)
+GROUP BY ProjectName, ProjectType, System, Name;
+`
+
+const packagesQuery = `
+SELECT System, Name, DependentCount
 FROM ` + "`{{.ProjectID}}.{{.DatasetName}}.{{.TableName}}`" + `
-WHERE ProjectName = @projectname AND ProjectType = @projecttype;
+WHERE ProjectName = @projectname AND ProjectType = @projecttype
+ORDER BY DependentCount DESC, System, Name;
 `
 
+// countRow is a single row in the dependent counts table created by
+// dataQuery.
+type countRow struct {
+	ProjectName    string
+	ProjectType    string
+	DependentCount int
+}
+
 func NewDependents(ctx context.Context, client *bigquery.Client, logger *zap.Logger, datasetName string, datasetTTL time.Duration) (*dependents, error) {
 	b := &bq{client: client}
 	c := &dependents{
@@ -77,6 +115,7 @@ func NewDependents(ctx context.Context, client *bigquery.Client, logger *zap.Log
 		),
 		datasetName: datasetName,
 		datasetTTL:  datasetTTL,
+		counts:      keyedOnce[map[depgraph.Project]int]{limit: maxCountTables},
 	}
 	var err error
 
@@ -89,18 +128,20 @@ func NewDependents(ctx context.Context, client *bigquery.Client, logger *zap.Log
 	return c, nil
 }
 
-type cache struct {
-	countQuery string
-	tableKey   string
-}
-
 type dependents struct {
-	b            bqAPI
-	logger       *zap.Logger
-	ds           *Dataset
-	lastUseCache *cache
-	datasetName  string
-	datasetTTL   time.Duration
+	b           bqAPI
+	logger      *zap.Logger
+	ds          *Dataset
+	datasetName string
+	datasetTTL  time.Duration
+
+	// tables tracks the names of the tables that are known to exist, so each
+	// table is only checked and created once.
+	tables keyedOnce[struct{}]
+
+	// counts holds the dependent count of every project, loaded from the
+	// dependent counts table for the most recently used tableKeys.
+	counts keyedOnce[map[depgraph.Project]int]
 }
 
 func (c *dependents) generateQuery(queryTemplate, tableName string) string {
@@ -110,31 +151,94 @@ func (c *dependents) generateQuery(queryTemplate, tableName string) string {
 		ProjectID   string
 		DatasetName string
 		TableName   string
-	}{c.b.Project(), c.datasetName, tableName})
+		Region      string
+	}{c.b.Project(), c.datasetName, tableName, c.region()})
 	return b.String()
 }
 
+// region returns the region qualifier for the dataset's location, such as
+// "us" for the "US" multi-region or "europe-west1" for a single region.
+func (c *dependents) region() string {
+	location := defaultLocation
+	if c.ds != nil && c.ds.location != "" {
+		location = c.ds.location
+	}
+	return strings.ToLower(location)
+}
+
+// Count returns the dependent count for the given project.
+//
+// The first call for a tableKey loads the entire dependent counts table into
+// memory, and all later calls for the same tableKey are answered from memory.
+// The counts for up to maxCountTables tableKeys are kept, so that a few jobs
+// can run concurrently without reloading each other's table.
 func (c *dependents) Count(ctx context.Context, projectName, projectType, tableKey string) (int, bool, error) {
-	query, err := c.prepareCountQuery(ctx, tableKey)
+	counts, err := c.counts.Do(tableKey, func() (map[depgraph.Project]int, error) {
+		return c.loadCounts(ctx, tableKey)
+	})
 	if err != nil {
-		return 0, false, fmt.Errorf("prepare count query: %w", err)
+		return 0, false, fmt.Errorf("load dependent counts: %w", err)
 	}
+	n, ok := counts[depgraph.Project{Type: projectType, Name: projectName}]
+	return n, ok, nil
+}
 
-	var rec struct {
-		DependentCount int
+// loadCounts reads every row of the dependent counts table for tableKey,
+// creating the table if needed.
+func (c *dependents) loadCounts(ctx context.Context, tableKey string) (map[depgraph.Project]int, error) {
+	tableName := getTableName(dependentCountsTableName, tableKey)
+	if err := c.prepareTable(ctx, dataQuery, tableName); err != nil {
+		return nil, err
 	}
+
+	logger := c.logger.With(zap.String("table", tableName))
+	logger.Info("Loading dependent counts")
+	counts := make(map[depgraph.Project]int)
+	it := c.b.ReadTable(ctx, c.ds, tableName)
+	for {
+		var row countRow
+		err := it.Next(&row)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read dependent counts: %w", err)
+		}
+		counts[depgraph.Project{Type: row.ProjectType, Name: row.ProjectName}] = row.DependentCount
+	}
+	logger.With(zap.Int("projects", len(counts))).Info("Loaded dependent counts")
+	return counts, nil
+}
+
+// Packages returns the packages that contribute to the dependent count of the
+// given project, ordered from the most dependents to the least.
+func (c *dependents) Packages(ctx context.Context, projectName, projectType, tableKey string) ([]Package, error) {
+	tableName := getTableName(packageDependentCountsTableName, tableKey)
+	if err := c.prepareTable(ctx, packageDataQuery, tableName); err != nil {
+		return nil, fmt.Errorf("prepare packages table: %w", err)
+	}
+	query := c.generateQuery(packagesQuery, tableName)
+
 	params := map[string]any{
 		"projectname": projectName,
 		"projecttype": projectType,
 	}
-	err = c.b.OneResultQuery(ctx, query, params, &rec)
-	if err == nil {
-		return rec.DependentCount, true, nil
+	it, err := c.b.Query(ctx, query, params, queryLabels(tableName))
+	if err != nil {
+		return nil, fmt.Errorf("packages query: %w", err)
 	}
-	if errors.Is(err, ErrorNoResults) {
-		return 0, false, nil
+	var pkgs []Package
+	for {
+		var p Package
+		err := it.Next(&p)
+		if errors.Is(err, iterator.Done) {
+			return pkgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("packages query: %w", err)
+		}
+		pkgs = append(pkgs, p)
 	}
-	return 0, false, fmt.Errorf("count query: %w", err)
 }
 
 func (c *dependents) getLatestSnapshotTime(ctx context.Context) (time.Time, error) {
@@ -171,50 +275,55 @@ func (c *dependents) getOrCreateDataset(ctx context.Context) (*Dataset, error) {
 	return ds, nil
 }
 
-func (c *dependents) prepareCountQuery(ctx context.Context, tableKey string) (string, error) {
-	// If the last use is the same as this usage, avoid needlessly checking if
-	// the table exists.
-	if c.lastUseCache != nil && c.lastUseCache.tableKey == tableKey {
-		c.logger.Debug("Using cached dependent count query")
-		return c.lastUseCache.countQuery, nil
-	}
-
-	// Generate the table name
-	tableName := getTableName(tableKey)
+// prepareTable ensures the table tableName exists, creating it with
+// queryTemplate if needed.
+//
+// The table is only checked once, even when called from multiple goroutines.
+func (c *dependents) prepareTable(ctx context.Context, queryTemplate, tableName string) error {
+	_, err := c.tables.Do(tableName, func() (struct{}, error) {
+		return struct{}{}, c.ensureTable(ctx, queryTemplate, tableName)
+	})
+	return err
+}
 
-	// Ensure the dependent count table exists and is populated
+// ensureTable creates and populates the table tableName using queryTemplate
+// if the table does not already exist.
+func (c *dependents) ensureTable(ctx context.Context, queryTemplate, tableName string) error {
+	logger := c.logger.With(zap.String("table", tableName))
 	t, err := c.b.GetTable(ctx, c.ds, tableName)
 	if err != nil {
-		return "", fmt.Errorf("get table: %w", err)
+		return fmt.Errorf("get table: %w", err)
 	}
 	if t != nil {
-		c.logger.Info("Dependent count table exists")
-	} else {
-		c.logger.Info("Creating dependent count table")
-		// Always get the latest snapshot time to ensure the partition used is
-		// the latest possible partition.
-		snapshotTime, err := c.getLatestSnapshotTime(ctx)
-		if err != nil {
-			return "", fmt.Errorf("get latest snapshot time: %w", err)
-		}
-		// This query use "IF NOT EXISTS" to avoid failing if two or more
-		// workers execute this code at the same time.
-		err = c.b.NoResultQuery(ctx, c.generateQuery(dataQuery, tableName), map[string]any{"part": snapshotTime})
-		if err != nil {
-			return "", fmt.Errorf("create table: %w", err)
-		}
+		logger.Info("Table exists")
+		return nil
 	}
-
-	c.lastUseCache = &cache{
-		tableKey:   tableKey,
-		countQuery: c.generateQuery(countQuery, tableName),
+	logger.Info("Creating table")
+	// Always get the latest snapshot time to ensure the partition used is
+	// the latest possible partition.
+	snapshotTime, err := c.getLatestSnapshotTime(ctx)
+	if err != nil {
+		return fmt.Errorf("get latest snapshot time: %w", err)
+	}
+	// This query use "IF NOT EXISTS" to avoid failing if two or more
+	// workers execute this code at the same time.
+	err = c.b.NoResultQuery(ctx, c.generateQuery(queryTemplate, tableName), map[string]any{"part": snapshotTime}, queryLabels(tableName))
+	if err != nil {
+		return fmt.Errorf("create table: %w", err)
+	}
+	// Record the snapshot used so the table's age can be reported later.
+	err = c.b.UpdateTableLabels(ctx, c.ds, tableName, map[string]string{
+		snapshotLabel: strconv.FormatInt(snapshotTime.Unix(), 10),
+	})
+	if err != nil {
+		return fmt.Errorf("label table: %w", err)
 	}
-	return c.lastUseCache.countQuery, nil
+	return nil
 }
 
-func getTableName(tableKey string) string {
+func getTableName(baseName, tableKey string) string {
 	if tableKey == "" {
-		return dependentCountsTableName
+		return baseName
 	}
-	return dependentCountsTableName + "_" + tableKey
+	return baseName + "_" + tableKey
 }
@@ -0,0 +1,207 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+type fakeRows struct {
+	rows []any
+}
+
+func (r *fakeRows) Next(dst any) error {
+	if len(r.rows) == 0 {
+		return iterator.Done
+	}
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(r.rows[0]))
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeBQ implements bqAPI with a fixed set of tables.
+type fakeBQ struct {
+	tables map[string][]any
+
+	mu      sync.Mutex
+	creates int
+	reads   int
+}
+
+func (b *fakeBQ) Project() string { return "project" }
+
+func (b *fakeBQ) OneResultQuery(ctx context.Context, query string, params map[string]any, result any) error {
+	reflect.ValueOf(result).Elem().Field(0).Set(reflect.ValueOf(time.Unix(0, 0)))
+	return nil
+}
+
+func (b *fakeBQ) Query(ctx context.Context, query string, params map[string]any, labels map[string]string) (RowIterator, error) {
+	return &fakeRows{}, nil
+}
+
+func (b *fakeBQ) NoResultQuery(ctx context.Context, query string, params map[string]any, labels map[string]string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.creates++
+	return nil
+}
+
+func (b *fakeBQ) GetDataset(ctx context.Context, id string) (*Dataset, error) {
+	return &Dataset{}, nil
+}
+
+func (b *fakeBQ) CreateDataset(ctx context.Context, id string, ttl time.Duration) (*Dataset, error) {
+	return &Dataset{}, nil
+}
+
+func (b *fakeBQ) UpdateDataset(ctx context.Context, d *Dataset, ttl time.Duration) error {
+	return nil
+}
+
+func (b *fakeBQ) GetTable(ctx context.Context, d *Dataset, id string) (*Table, error) {
+	return nil, nil
+}
+
+func (b *fakeBQ) ReadTable(ctx context.Context, d *Dataset, id string) RowIterator {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reads++
+	return &fakeRows{rows: append([]any(nil), b.tables[id]...)}
+}
+
+func (b *fakeBQ) ListTables(ctx context.Context, d *Dataset) ([]*Table, error) {
+	return nil, nil
+}
+
+func (b *fakeBQ) UpdateTableLabels(ctx context.Context, d *Dataset, id string, labels map[string]string) error {
+	return nil
+}
+
+func (b *fakeBQ) DeleteTable(ctx context.Context, d *Dataset, id string) error {
+	return nil
+}
+
+func TestCountLoadsTableOnce(t *testing.T) {
+	b := &fakeBQ{
+		tables: map[string][]any{
+			"dependent_counts_job": {
+				countRow{ProjectName: "a/a", ProjectType: "GITHUB", DependentCount: 10},
+				countRow{ProjectName: "b/b", ProjectType: "GITHUB", DependentCount: 20},
+			},
+		},
+	}
+	c := &dependents{b: b, logger: zap.NewNop(), ds: &Dataset{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, ok, err := c.Count(context.Background(), "b/b", "GITHUB", "job")
+			if err != nil || !ok || n != 20 {
+				t.Errorf("Count() = %d, %t, %v; want 20, true, nil", n, ok, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, ok, _ := c.Count(context.Background(), "c/c", "GITHUB", "job"); ok {
+		t.Error("Count(c/c) found, want not found")
+	}
+	if b.creates != 1 {
+		t.Errorf("tables created = %d, want 1", b.creates)
+	}
+	if b.reads != 1 {
+		t.Errorf("tables read = %d, want 1", b.reads)
+	}
+}
+
+func TestKeyedOnceRetriesErrors(t *testing.T) {
+	var o keyedOnce[int]
+	calls := 0
+	fn := func() (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errors.New("failed")
+		}
+		return calls, nil
+	}
+	if _, err := o.Do("k", fn); err == nil {
+		t.Fatal("Do() = nil, want an error")
+	}
+	if v, err := o.Do("k", fn); err != nil || v != 2 {
+		t.Fatalf("Do() = %d, %v; want 2, nil", v, err)
+	}
+	if v, _ := o.Do("k", fn); v != 2 {
+		t.Fatalf("Do() = %d, want 2", v)
+	}
+}
+
+func TestKeyedOnceLimit(t *testing.T) {
+	o := keyedOnce[string]{limit: 2}
+	calls := 0
+	do := func(key string) {
+		t.Helper()
+		v, err := o.Do(key, func() (string, error) {
+			calls++
+			return key, nil
+		})
+		if err != nil || v != key {
+			t.Fatalf("Do(%q) = %q, %v; want %q, nil", key, v, err, key)
+		}
+	}
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		do(key)
+	}
+	// "b" is forgotten when "c" is added, as "a" was used more recently.
+	if calls != 4 {
+		t.Fatalf("calls = %d, want 4", calls)
+	}
+	if len(o.entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(o.entries))
+	}
+}
+
+func TestKeyedOnceLimitEmptyKey(t *testing.T) {
+	// The empty key is a valid key, and must not be mistaken for there being
+	// no key chosen for eviction. Map iteration order is random, so repeat
+	// the check.
+	for i := 0; i < 20; i++ {
+		o := keyedOnce[string]{limit: 3}
+		for _, key := range []string{"", "a", "b", "a", "c"} {
+			if _, err := o.Do(key, func() (string, error) { return key, nil }); err != nil {
+				t.Fatalf("Do(%q) = %v, want no error", key, err)
+			}
+		}
+		// "" is the least recently used when "c" is added.
+		var got []string
+		for k := range o.entries {
+			got = append(got, k)
+		}
+		sort.Strings(got)
+		if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("entries = %q, want %q", got, want)
+		}
+	}
+}
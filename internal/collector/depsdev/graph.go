@@ -0,0 +1,141 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+
+	"github.com/ossf/criticality_score/internal/depgraph"
+)
+
+const dependencyEdgesTableName = "dependency_edges"
+
+// edgesQuery creates a table of direct dependency edges between projects,
+// using the latest version of each package.
+//
+// The column names match depgraph.EdgeColumns so the table can be exported
+// to CSV and read with depgraph.ReadCSV.
+const edgesQuery = `
+CREATE TABLE IF NOT EXISTS ` + "`{{.ProjectID}}.{{.DatasetName}}.{{.TableName}}`" + `
+AS
+WITH pvp AS (
+    SELECT System, Name, Version, ProjectName, ProjectType
+    FROM ` + "`bigquery-public-data.deps_dev_v1.PackageVersionToProject`" + `
+    WHERE SnapshotAt = @part
+), lv AS (
+    SELECT System, Name, Version, ROW_NUMBER() OVER (PARTITION BY System, Name ORDER BY VersionInfo.Ordinal Desc) AS RowNumber
+    FROM ` + "`bigquery-public-data.deps_dev_v1.PackageVersions`" + `
+    WHERE SnapshotAt = @part
+)
+SELECT DISTINCT src.ProjectType AS from_type, src.ProjectName AS from_name, dst.ProjectType AS to_type, dst.ProjectName AS to_name
+ FROM ` + "`bigquery-public-data.deps_dev_v1.Dependencies`" + ` AS d
+ JOIN lv ON (lv.RowNumber = 1 AND lv.System = d.System AND lv.Name = d.Name AND lv.Version = d.Version)
+ JOIN pvp AS src ON (src.System = d.System AND src.Name = d.Name AND src.Version = d.Version)
+ JOIN pvp AS dst ON (dst.System = d.Dependency.System AND dst.Name = d.Dependency.Name AND dst.Version = d.Dependency.Version)
+WHERE d.SnapshotAt = @part
+  AND d.MinimumDepth = 1
+  AND NOT (src.ProjectType = dst.ProjectType AND src.ProjectName = dst.ProjectName);
+`
+
+type edgeRow struct {
+	FromType string `bigquery:"from_type"`
+	FromName string `bigquery:"from_name"`
+	ToType   string `bigquery:"to_type"`
+	ToName   string `bigquery:"to_name"`
+}
+
+// readGraph ensures the dependency edges table for tableKey exists and adds
+// every edge in it to g.
+func (c *dependents) readGraph(ctx context.Context, g *depgraph.Graph, tableKey string) error {
+	tableName := getTableName(dependencyEdgesTableName, tableKey)
+	if err := c.prepareTable(ctx, edgesQuery, tableName); err != nil {
+		return err
+	}
+	it := c.b.ReadTable(ctx, c.ds, tableName)
+	for {
+		var row edgeRow
+		err := it.Next(&row)
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read edges: %w", err)
+		}
+		g.AddEdge(
+			depgraph.Project{Type: row.FromType, Name: row.FromName},
+			depgraph.Project{Type: row.ToType, Name: row.ToName},
+		)
+	}
+}
+
+// LoadGraph returns the project dependency graph built from the latest
+// deps.dev snapshot in BigQuery.
+//
+// The edges are stored in a table in the dataset datasetName, which is
+// reused for future calls with the same tableKey.
+func LoadGraph(ctx context.Context, logger *zap.Logger, projectID, datasetName string, datasetTTL time.Duration, tableKey string) (*depgraph.Graph, error) {
+	gcpClient, err := newBigQueryClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	dependents, err := NewDependents(ctx, gcpClient, logger, datasetName, datasetTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deps.dev dependents: %w", err)
+	}
+	g := depgraph.New()
+	if err := dependents.readGraph(ctx, g, tableKey); err != nil {
+		return nil, fmt.Errorf("failed to read deps.dev dependency graph: %w", err)
+	}
+	return g, nil
+}
+
+// ProjectForURL returns the deps.dev project for the repository url u.
+//
+// If the url's host is not supported by deps.dev false will be returned.
+func ProjectForURL(u *url.URL) (depgraph.Project, bool) {
+	n, t := parseRepoURL(u)
+	if t == "" {
+		return depgraph.Project{}, false
+	}
+	return depgraph.Project{Type: t, Name: n}, true
+}
+
+// URLForProject returns the repository url for the deps.dev project p.
+//
+// If the project's type is not a supported host false will be returned.
+func URLForProject(p depgraph.Project) (string, bool) {
+	var host string
+	switch p.Type {
+	case "GITHUB":
+		host = "github.com"
+	case "GITLAB":
+		host = "gitlab.com"
+	case "BITBUCKET":
+		host = "bitbucket.org"
+	default:
+		return "", false
+	}
+	if p.Name == "" {
+		return "", false
+	}
+	return "https://" + host + "/" + p.Name, true
+}
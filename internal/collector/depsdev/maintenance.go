@@ -0,0 +1,289 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// tableLabel is applied to every query job, with the name of the table the
+	// query created or read, so that costs can be attributed to each table.
+	tableLabel = "depsdev_table"
+
+	// snapshotLabel is applied to each table with the unix time of the deps.dev
+	// snapshot the table was created from.
+	snapshotLabel = "depsdev_snapshot"
+
+	maxLabelLength = 63
+)
+
+var ErrorDatasetNotFound = errors.New("dataset not found")
+
+// baseTableNames contains the base name of every table created in the
+// dataset.
+var baseTableNames = []string{
+	dependentCountsTableName,
+	packageDependentCountsTableName,
+	dependencyEdgesTableName,
+}
+
+// costsQuery sums the bytes processed and billed by the query jobs for each
+// table. Jobs are only visible in the region of the dataset they ran in.
+const costsQuery = `
+SELECT l.value AS TableLabel,
+       COUNT(1) AS Queries,
+       SUM(IFNULL(j.total_bytes_processed, 0)) AS BytesProcessed,
+       SUM(IFNULL(j.total_bytes_billed, 0)) AS BytesBilled,
+       MIN(j.creation_time) AS FirstQuery,
+       MAX(j.creation_time) AS LastQuery
+FROM ` + "`{{.ProjectID}}.region-{{.Region}}.INFORMATION_SCHEMA.JOBS_BY_PROJECT`" + ` AS j, UNNEST(j.labels) AS l
+WHERE l.key = '` + tableLabel + `'
+  AND j.parent_job_id IS NULL
+  AND j.creation_time >= @since
+GROUP BY TableLabel
+ORDER BY LastQuery DESC;
+`
+
+// labelValue converts s into a valid BigQuery label value.
+func labelValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, s)
+	if len(s) > maxLabelLength {
+		s = s[:maxLabelLength]
+	}
+	return s
+}
+
+func queryLabels(tableName string) map[string]string {
+	return map[string]string{tableLabel: labelValue(tableName)}
+}
+
+// parseTableName splits tableName into the base name and table key used to
+// create it.
+//
+// If tableName was not created by this package false will be returned.
+func parseTableName(tableName string) (baseName, tableKey string, ok bool) {
+	for _, b := range baseTableNames {
+		if tableName == b {
+			return b, "", true
+		}
+		if k := strings.TrimPrefix(tableName, b+"_"); k != tableName {
+			return b, k, true
+		}
+	}
+	return "", "", false
+}
+
+// TableInfo describes a table in the deps.dev dataset.
+type TableInfo struct {
+	// Name is the full name of the table.
+	Name string
+
+	// BaseName is the kind of table, e.g. "dependent_counts".
+	BaseName string
+
+	// Key is the table key, usually the job ID, used to create the table.
+	Key string
+
+	Created    time.Time
+	Expiration time.Time
+
+	// Snapshot is the time of the deps.dev snapshot the table was created
+	// from. It is zero if the snapshot is not known.
+	Snapshot time.Time
+
+	NumBytes int64
+	NumRows  uint64
+}
+
+// TableCost describes the BigQuery usage of the queries for a table.
+type TableCost struct {
+	// Table is the name of the table, as it appears in the query labels.
+	Table string
+
+	Queries        int64
+	BytesProcessed int64
+	BytesBilled    int64
+	FirstQuery     time.Time
+	LastQuery      time.Time
+}
+
+// DatasetManager is used to maintain the tables in the deps.dev dataset.
+type DatasetManager struct {
+	d *dependents
+}
+
+// NewDatasetManager returns a new DatasetManager for the existing dataset
+// datasetName.
+//
+// Unlike NewDependents, the dataset's settings are left unchanged.
+func NewDatasetManager(ctx context.Context, logger *zap.Logger, projectID, datasetName string) (*DatasetManager, error) {
+	gcpClient, err := newBigQueryClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	b := &bq{client: gcpClient}
+	ds, err := b.GetDataset(ctx, datasetName)
+	if err != nil {
+		return nil, fmt.Errorf("get dataset: %w", err)
+	}
+	if ds == nil {
+		return nil, fmt.Errorf("%w: %s", ErrorDatasetNotFound, datasetName)
+	}
+	return &DatasetManager{
+		d: &dependents{
+			b: b,
+			logger: logger.With(
+				zap.String("project_id", b.Project()),
+				zap.String("dataset", datasetName),
+			),
+			ds:          ds,
+			datasetName: datasetName,
+		},
+	}, nil
+}
+
+// Tables returns the tables created by this package in the dataset, ordered
+// from the most recently created to the least.
+func (m *DatasetManager) Tables(ctx context.Context) ([]TableInfo, error) {
+	tables, err := m.d.b.ListTables(ctx, m.d.ds)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	var infos []TableInfo
+	for _, t := range tables {
+		baseName, key, ok := parseTableName(t.id)
+		if !ok {
+			continue
+		}
+		info := TableInfo{
+			Name:       t.id,
+			BaseName:   baseName,
+			Key:        key,
+			Created:    t.md.CreationTime,
+			Expiration: t.md.ExpirationTime,
+			NumBytes:   t.md.NumBytes,
+			NumRows:    t.md.NumRows,
+		}
+		if s, err := strconv.ParseInt(t.md.Labels[snapshotLabel], 10, 64); err == nil {
+			info.Snapshot = time.Unix(s, 0).UTC()
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Created.After(infos[j].Created)
+	})
+	return infos, nil
+}
+
+// DeleteTable deletes the table with the given name from the dataset.
+func (m *DatasetManager) DeleteTable(ctx context.Context, name string) error {
+	if err := m.d.b.DeleteTable(ctx, m.d.ds, name); err != nil {
+		return fmt.Errorf("delete table: %w", err)
+	}
+	return nil
+}
+
+// Warm creates the dependent counts table for tableKey ahead of time, so that
+// it is ready to use when a job with tableKey starts.
+//
+// If packages is true, the table of per-package dependent counts is also
+// created.
+func (m *DatasetManager) Warm(ctx context.Context, tableKey string, packages bool) error {
+	if err := m.d.prepareTable(ctx, dataQuery, getTableName(dependentCountsTableName, tableKey)); err != nil {
+		return err
+	}
+	if !packages {
+		return nil
+	}
+	return m.d.prepareTable(ctx, packageDataQuery, getTableName(packageDependentCountsTableName, tableKey))
+}
+
+// Costs returns the BigQuery usage for each table since the given time.
+//
+// Usage is only available for queries run after tables started being
+// labelled, and for up to 180 days.
+func (m *DatasetManager) Costs(ctx context.Context, since time.Time) ([]TableCost, error) {
+	query := m.d.generateQuery(costsQuery, "")
+	it, err := m.d.b.Query(ctx, query, map[string]any{"since": since}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("costs query: %w", err)
+	}
+	var costs []TableCost
+	for {
+		var row struct {
+			TableLabel     string
+			Queries        int64
+			BytesProcessed int64
+			BytesBilled    int64
+			FirstQuery     time.Time
+			LastQuery      time.Time
+		}
+		err := it.Next(&row)
+		if errors.Is(err, iterator.Done) {
+			return costs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("costs query: %w", err)
+		}
+		costs = append(costs, TableCost{
+			Table:          row.TableLabel,
+			Queries:        row.Queries,
+			BytesProcessed: row.BytesProcessed,
+			BytesBilled:    row.BytesBilled,
+			FirstQuery:     row.FirstQuery,
+			LastQuery:      row.LastQuery,
+		})
+	}
+}
+
+// TablesToPrune returns the tables that should be deleted so that, for each
+// base name, at most keep tables remain and no table is older than maxAge.
+//
+// The tables must be ordered from the most recently created to the least, as
+// returned by DatasetManager.Tables. A keep or maxAge of zero disables the
+// corresponding limit.
+func TablesToPrune(tables []TableInfo, keep int, maxAge time.Duration, now time.Time) []TableInfo {
+	var prune []TableInfo
+	seen := make(map[string]int)
+	for _, t := range tables {
+		seen[t.BaseName]++
+		switch {
+		case keep > 0 && seen[t.BaseName] > keep:
+			prune = append(prune, t)
+		case maxAge > 0 && now.Sub(t.Created) > maxAge:
+			prune = append(prune, t)
+		}
+	}
+	return prune
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTableName(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name     string
+		wantBase string
+		wantKey  string
+		wantOK   bool
+	}{
+		{name: "dependent_counts", wantBase: "dependent_counts", wantOK: true},
+		{name: "dependent_counts_20230101_000000", wantBase: "dependent_counts", wantKey: "20230101_000000", wantOK: true},
+		{name: "package_dependent_counts_job", wantBase: "package_dependent_counts", wantKey: "job", wantOK: true},
+		{name: "dependency_edges_job", wantBase: "dependency_edges", wantKey: "job", wantOK: true},
+		{name: "dependent_countsx"},
+		{name: "other"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			base, key, ok := parseTableName(test.name)
+			if base != test.wantBase || key != test.wantKey || ok != test.wantOK {
+				t.Fatalf("parseTableName() = %q, %q, %t; want %q, %q, %t", base, key, ok, test.wantBase, test.wantKey, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	if got, want := labelValue("Dependent_Counts.2023-01"), "dependent_counts_2023-01"; got != want {
+		t.Fatalf("labelValue() = %q, want %q", got, want)
+	}
+}
+
+func TestCostsQueryRegion(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{name: "default", want: "`project.region-us.INFORMATION_SCHEMA"},
+		{name: "multi-region", location: "EU", want: "`project.region-eu.INFORMATION_SCHEMA"},
+		{name: "region", location: "europe-west1", want: "`project.region-europe-west1.INFORMATION_SCHEMA"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &dependents{b: &fakeBQ{}, ds: &Dataset{location: test.location}}
+			if got := d.generateQuery(costsQuery, ""); !strings.Contains(got, test.want) {
+				t.Fatalf("generateQuery() = %q, want it to contain %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTablesToPrune(t *testing.T) {
+	now := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tables := []TableInfo{
+		{Name: "dependent_counts_c", BaseName: "dependent_counts", Created: now.Add(-1 * day)},
+		{Name: "dependency_edges_c", BaseName: "dependency_edges", Created: now.Add(-2 * day)},
+		{Name: "dependent_counts_b", BaseName: "dependent_counts", Created: now.Add(-8 * day)},
+		{Name: "dependent_counts_a", BaseName: "dependent_counts", Created: now.Add(-15 * day)},
+		{Name: "dependency_edges_a", BaseName: "dependency_edges", Created: now.Add(-40 * day)},
+	}
+	names := func(ts []TableInfo) []string {
+		var n []string
+		for _, t := range ts {
+			n = append(n, t.Name)
+		}
+		return n
+	}
+	//nolint:govet
+	tests := []struct {
+		name   string
+		keep   int
+		maxAge time.Duration
+		want   []string
+	}{
+		{name: "no limits"},
+		{name: "keep", keep: 1, want: []string{"dependent_counts_b", "dependent_counts_a", "dependency_edges_a"}},
+		{name: "max age", maxAge: 10 * day, want: []string{"dependent_counts_a", "dependency_edges_a"}},
+		{name: "both", keep: 2, maxAge: 30 * day, want: []string{"dependent_counts_a", "dependency_edges_a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := names(TablesToPrune(tables, test.keep, test.maxAge, now))
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("TablesToPrune() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
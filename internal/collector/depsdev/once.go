@@ -0,0 +1,102 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import "sync"
+
+// keyedOnce runs a function to completion at most once for each key, and
+// remembers the value it returned.
+//
+// Unlike sync.Once, a call that returns an error is not remembered, so a
+// later call for the same key will try again.
+//
+// If limit is greater than zero, at most limit keys are remembered, and the
+// least recently used key is forgotten first.
+//
+// keyedOnce is safe to use from multiple goroutines. The zero value is ready
+// to use, and remembers every key.
+type keyedOnce[V any] struct {
+	limit int
+
+	entries map[string]*onceEntry[V]
+	tick    uint64
+
+	// Prevents concurrent access to entries.
+	mu sync.Mutex
+}
+
+type onceEntry[V any] struct {
+	done     bool
+	value    V
+	lastUsed uint64 // Guarded by keyedOnce.mu.
+
+	// Held while the function is running so that concurrent callers for the
+	// same key wait for the result.
+	mu sync.Mutex
+}
+
+// Do returns the value remembered for key. If there is no value for key, fn is
+// called and its value remembered if it does not return an error.
+//
+// Calls for different keys do not block each other.
+func (o *keyedOnce[V]) Do(key string, fn func() (V, error)) (V, error) {
+	o.mu.Lock()
+	if o.entries == nil {
+		o.entries = make(map[string]*onceEntry[V])
+	}
+	e, ok := o.entries[key]
+	if !ok {
+		e = &onceEntry[V]{}
+		o.entries[key] = e
+		o.evict(key)
+	}
+	o.tick++
+	e.lastUsed = o.tick
+	o.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done {
+		return e.value, nil
+	}
+	v, err := fn()
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	e.value = v
+	e.done = true
+	return v, nil
+}
+
+// evict forgets the least recently used keys other than key until there are
+// no more than limit keys. o.mu must be held.
+//
+// A forgotten entry may still be in use by callers of Do, but later calls
+// will start again with a new entry.
+func (o *keyedOnce[V]) evict(key string) {
+	for o.limit > 0 && len(o.entries) > o.limit {
+		var oldest string
+		var oldestUsed uint64
+		found := false
+		for k, e := range o.entries {
+			if k != key && (!found || e.lastUsed < oldestUsed) {
+				oldest, oldestUsed = k, e.lastUsed
+				found = true
+			}
+		}
+		delete(o.entries, oldest)
+	}
+}
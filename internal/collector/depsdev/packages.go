@@ -0,0 +1,167 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Package is a single package that contributes to a project's dependent
+// count.
+type Package struct {
+	System         string `json:"system"`
+	Name           string `json:"name"`
+	DependentCount int    `json:"dependent_count"`
+}
+
+// PackageWriter is used to output the packages that contribute to the
+// dependent count of a repository.
+//
+// Implementations must be safe to call from multiple goroutines.
+type PackageWriter interface {
+	// WritePackages outputs the packages for the repository url repo, which
+	// corresponds to the deps.dev project with projectType and projectName.
+	WritePackages(repo, projectType, projectName string, pkgs []Package) error
+}
+
+var packagesHeader = []string{"repo", "project_type", "project_name", "system", "name", "dependent_count"}
+
+type csvPackageWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+
+	// Prevents concurrent writes to w, and headerWritten.
+	mu sync.Mutex
+}
+
+// CSVPackageWriter returns a PackageWriter that writes a CSV file with one row
+// for each package.
+func CSVPackageWriter(w io.Writer) PackageWriter {
+	return &csvPackageWriter{
+		w: csv.NewWriter(w),
+	}
+}
+
+// WritePackages implements the PackageWriter interface.
+func (w *csvPackageWriter) WritePackages(repo, projectType, projectName string, pkgs []Package) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.w.Write(packagesHeader); err != nil {
+			return err
+		}
+	}
+	for _, p := range pkgs {
+		rec := []string{repo, projectType, projectName, p.System, p.Name, strconv.Itoa(p.DependentCount)}
+		if err := w.w.Write(rec); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonPackageWriter struct {
+	encoder *json.Encoder
+
+	// Prevents concurrent writes to encoder.
+	mu sync.Mutex
+}
+
+// JSONPackageWriter returns a PackageWriter that writes a JSON object for each
+// repository, containing all of the repository's packages.
+func JSONPackageWriter(w io.Writer) PackageWriter {
+	return &jsonPackageWriter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// WritePackages implements the PackageWriter interface.
+func (w *jsonPackageWriter) WritePackages(repo, projectType, projectName string, pkgs []Package) error {
+	if pkgs == nil {
+		pkgs = []Package{}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.encoder.Encode(struct {
+		Repo        string    `json:"repo"`
+		ProjectType string    `json:"project_type"`
+		ProjectName string    `json:"project_name"`
+		Packages    []Package `json:"packages"`
+	}{repo, projectType, projectName, pkgs})
+}
+
+type PackageWriterType int
+
+const (
+	PackageWriterTypeCSV = PackageWriterType(iota)
+	PackageWriterTypeJSON
+)
+
+var ErrorUnknownPackageWriterType = errors.New("unknown package writer type")
+
+// String implements the fmt.Stringer interface.
+func (t PackageWriterType) String() string {
+	text, err := t.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t PackageWriterType) MarshalText() ([]byte, error) {
+	switch t {
+	case PackageWriterTypeCSV:
+		return []byte("csv"), nil
+	case PackageWriterTypeJSON:
+		return []byte("json"), nil
+	default:
+		return []byte{}, ErrorUnknownPackageWriterType
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *PackageWriterType) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("csv")):
+		*t = PackageWriterTypeCSV
+	case bytes.Equal(text, []byte("json")):
+		*t = PackageWriterTypeJSON
+	default:
+		return ErrorUnknownPackageWriterType
+	}
+	return nil
+}
+
+// New will return a new instance of the corresponding implementation of
+// PackageWriter for the given PackageWriterType.
+func (t *PackageWriterType) New(w io.Writer) PackageWriter {
+	switch *t {
+	case PackageWriterTypeCSV:
+		return CSVPackageWriter(w)
+	case PackageWriterTypeJSON:
+		return JSONPackageWriter(w)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/internal/collector/depsdev"
+)
+
+var testPackages = []depsdev.Package{
+	{System: "NPM", Name: "@babel/core", DependentCount: 100},
+	{System: "NPM", Name: "@babel/parser", DependentCount: 20},
+}
+
+func TestCSVPackageWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := depsdev.CSVPackageWriter(&buf)
+	w.WritePackages("https://github.com/babel/babel", "GITHUB", "babel/babel", testPackages)
+	w.WritePackages("https://github.com/example/example", "GITHUB", "example/example", nil)
+
+	want := "repo,project_type,project_name,system,name,dependent_count\n" +
+		"https://github.com/babel/babel,GITHUB,babel/babel,NPM,@babel/core,100\n" +
+		"https://github.com/babel/babel,GITHUB,babel/babel,NPM,@babel/parser,20\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("CSVPackageWriter() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJSONPackageWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := depsdev.JSONPackageWriter(&buf)
+	w.WritePackages("https://github.com/babel/babel", "GITHUB", "babel/babel", testPackages)
+	w.WritePackages("https://github.com/example/example", "GITHUB", "example/example", nil)
+
+	want := `{"repo":"https://github.com/babel/babel","project_type":"GITHUB","project_name":"babel/babel","packages":[` +
+		`{"system":"NPM","name":"@babel/core","dependent_count":100},` +
+		`{"system":"NPM","name":"@babel/parser","dependent_count":20}]}` + "\n" +
+		`{"repo":"https://github.com/example/example","project_type":"GITHUB","project_name":"example/example","packages":[]}` + "\n"
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("JSONPackageWriter() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPackageWriterTypeUnmarshalText(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		input   string
+		want    depsdev.PackageWriterType
+		wantErr bool
+	}{
+		{input: "csv", want: depsdev.PackageWriterTypeCSV},
+		{input: "json", want: depsdev.PackageWriterTypeJSON},
+		{input: "text", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var got depsdev.PackageWriterType
+			err := got.UnmarshalText([]byte(test.input))
+			if test.wantErr && err == nil {
+				t.Fatal("UnmarshalText() = nil, want an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatalf("UnmarshalText() = %v, want no error", err)
+			}
+			if got != test.want {
+				t.Fatalf("UnmarshalText() parsed %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -24,8 +24,8 @@ import (
 	"cloud.google.com/go/bigquery"
 	"go.uber.org/zap"
 
-	"github.com/ossf/criticality_score/internal/collector/projectrepo"
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 const (
@@ -41,8 +41,9 @@ func (s *depsDevSet) Namespace() signal.Namespace {
 }
 
 type depsDevSource struct {
-	logger     *zap.Logger
-	dependents *dependents
+	logger        *zap.Logger
+	dependents    *dependents
+	packageWriter PackageWriter
 }
 
 func (c *depsDevSource) EmptySet() signal.Set {
@@ -68,24 +69,39 @@ func (c *depsDevSource) Get(ctx context.Context, r projectrepo.Repo, jobID strin
 	if found {
 		s.DependentCount.Set(deps)
 	}
+	if c.packageWriter != nil {
+		if err := c.writePackages(ctx, r, n, t, jobID); err != nil {
+			return nil, err
+		}
+	}
 	return &s, nil
 }
 
+func (c *depsDevSource) writePackages(ctx context.Context, r projectrepo.Repo, projectName, projectType, jobID string) error {
+	c.logger.With(zap.String("url", r.URL().String())).Debug("Fetching deps.dev packages")
+	pkgs, err := c.dependents.Packages(ctx, projectName, projectType, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deps.dev packages: %w", err)
+	}
+	if err := c.packageWriter.WritePackages(r.URL().String(), projectType, projectName, pkgs); err != nil {
+		return fmt.Errorf("failed to write deps.dev packages: %w", err)
+	}
+	return nil
+}
+
 // NewSource creates a new Source for gathering data from deps.dev.
 //
+// If packageWriter is not nil, the packages contributing to the dependent
+// count of each repository are also written to packageWriter.
+//
 // TODO add options to configure the dataset:
 //   - force dataset re-creation (-update-strategy = always,stale,weekly,monthly,never)
 //   - force dataset destruction (-depsdev-destroy-data)
-func NewSource(ctx context.Context, logger *zap.Logger, projectID, datasetName string, datasetTTL time.Duration) (signal.Source, error) {
-	if projectID == "" {
-		projectID = bigquery.DetectProjectID
-	}
-	gcpClient, err := bigquery.NewClient(ctx, projectID)
+func NewSource(ctx context.Context, logger *zap.Logger, projectID, datasetName string, datasetTTL time.Duration, packageWriter PackageWriter) (signal.Source, error) {
+	gcpClient, err := newBigQueryClient(ctx, projectID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create bigquery client: %w", err)
+		return nil, err
 	}
-	// Set the location
-	gcpClient.Location = defaultLocation
 
 	dependents, err := NewDependents(ctx, gcpClient, logger, datasetName, datasetTTL)
 	if err != nil {
@@ -93,16 +109,69 @@ func NewSource(ctx context.Context, logger *zap.Logger, projectID, datasetName s
 	}
 
 	return &depsDevSource{
-		logger:     logger,
-		dependents: dependents,
+		logger:        logger,
+		dependents:    dependents,
+		packageWriter: packageWriter,
 	}, nil
 }
 
+func newBigQueryClient(ctx context.Context, projectID string) (*bigquery.Client, error) {
+	if projectID == "" {
+		projectID = bigquery.DetectProjectID
+	}
+	gcpClient, err := bigquery.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bigquery client: %w", err)
+	}
+	// Set the location
+	gcpClient.Location = defaultLocation
+	// Use the Storage Read API to efficiently read entire tables.
+	if err := gcpClient.EnableStorageReadClient(ctx); err != nil {
+		return nil, fmt.Errorf("failed to enable bigquery storage read client: %w", err)
+	}
+	return gcpClient, nil
+}
+
+// parseRepoURL returns the deps.dev project name and type for the repository
+// url u.
+//
+// If the host is not supported by deps.dev, or the path is not a valid
+// project, an empty projectType is returned.
 func parseRepoURL(u *url.URL) (projectName, projectType string) {
-	switch hn := u.Hostname(); hn {
+	switch hn := strings.ToLower(u.Hostname()); hn {
 	case "github.com":
-		return strings.Trim(u.Path, "/"), "GITHUB"
-	default:
+		projectName, projectType = strings.Trim(u.Path, "/"), "GITHUB"
+	case "gitlab.com":
+		projectName, projectType = parseGitLabPath(u.Path), "GITLAB"
+	case "bitbucket.org":
+		projectName, projectType = parseBitbucketPath(u.Path), "BITBUCKET"
+	}
+	if projectName == "" {
 		return "", ""
 	}
+	return projectName, projectType
+}
+
+// parseGitLabPath returns the deps.dev project name for a GitLab url path.
+//
+// GitLab projects can be nested in any number of subgroups, so the name is the
+// full path to the project (e.g. "group/subgroup/project"). Any trailing
+// "/-/..." route (e.g. "/-/tree/main") or ".git" suffix is removed.
+func parseGitLabPath(p string) string {
+	p, _, _ = strings.Cut(p, "/-/")
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	if strings.Count(p, "/") < 1 {
+		return ""
+	}
+	return p
+}
+
+// parseBitbucketPath returns the deps.dev project name for a Bitbucket url
+// path, which is always "workspace/repo".
+func parseBitbucketPath(p string) string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + strings.TrimSuffix(parts[1], ".git")
 }
@@ -0,0 +1,86 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ossf/criticality_score/internal/depgraph"
+)
+
+func TestParseRepoURL(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		url      string
+		wantName string
+		wantType string
+	}{
+		{url: "https://github.com/ossf/criticality_score", wantName: "ossf/criticality_score", wantType: "GITHUB"},
+		{url: "https://GitHub.com/ossf/criticality_score/", wantName: "ossf/criticality_score", wantType: "GITHUB"},
+		{url: "https://gitlab.com/gitlab-org/gitlab", wantName: "gitlab-org/gitlab", wantType: "GITLAB"},
+		{url: "https://gitlab.com/gitlab-org/charts/gitlab-runner", wantName: "gitlab-org/charts/gitlab-runner", wantType: "GITLAB"},
+		{url: "https://gitlab.com/gitlab-org/charts/gitlab-runner/-/tree/main", wantName: "gitlab-org/charts/gitlab-runner", wantType: "GITLAB"},
+		{url: "https://gitlab.com/gitlab-org/gitlab.git", wantName: "gitlab-org/gitlab", wantType: "GITLAB"},
+		{url: "https://gitlab.com/gitlab-org"},
+		{url: "https://bitbucket.org/atlassian/python-bitbucket", wantName: "atlassian/python-bitbucket", wantType: "BITBUCKET"},
+		{url: "https://bitbucket.org/atlassian/python-bitbucket/src/master/", wantName: "atlassian/python-bitbucket", wantType: "BITBUCKET"},
+		{url: "https://bitbucket.org/atlassian"},
+		{url: "https://example.com/a/b"},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			u, err := url.Parse(test.url)
+			if err != nil {
+				t.Fatalf("url.Parse() = %v", err)
+			}
+			gotName, gotType := parseRepoURL(u)
+			if gotName != test.wantName || gotType != test.wantType {
+				t.Fatalf("parseRepoURL() = %q, %q; want %q, %q", gotName, gotType, test.wantName, test.wantType)
+			}
+		})
+	}
+}
+
+func TestURLForProject(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		p      depgraph.Project
+		want   string
+		wantOK bool
+	}{
+		{p: depgraph.Project{Type: "GITHUB", Name: "ossf/criticality_score"}, want: "https://github.com/ossf/criticality_score", wantOK: true},
+		{p: depgraph.Project{Type: "GITLAB", Name: "gitlab-org/charts/gitlab-runner"}, want: "https://gitlab.com/gitlab-org/charts/gitlab-runner", wantOK: true},
+		{p: depgraph.Project{Type: "BITBUCKET", Name: "atlassian/python-bitbucket"}, want: "https://bitbucket.org/atlassian/python-bitbucket", wantOK: true},
+		{p: depgraph.Project{Type: "GITHUB"}},
+		{p: depgraph.Project{Type: "UNKNOWN", Name: "a/b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.p.Type+"/"+test.p.Name, func(t *testing.T) {
+			got, ok := URLForProject(test.p)
+			if got != test.want || ok != test.wantOK {
+				t.Fatalf("URLForProject() = %q, %t; want %q, %t", got, ok, test.want, test.wantOK)
+			}
+			if !ok {
+				return
+			}
+			// The url must map back to the same project.
+			u, _ := url.Parse(got)
+			if p, ok := ProjectForURL(u); !ok || p != test.p {
+				t.Fatalf("ProjectForURL(%q) = %v, %t; want %v, true", got, p, ok, test.p)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsdev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// topPackagesQuery returns the packages with the most dependents in each
+// system, along with the project each package belongs to. Every package is
+// returned if @limit is not positive.
+const topPackagesQuery = `
+SELECT ProjectName, ProjectType, System, Name, DependentCount
+FROM (
+    SELECT ProjectName, ProjectType, System, Name, DependentCount,
+        ROW_NUMBER() OVER (PARTITION BY System ORDER BY DependentCount DESC, Name) AS RowNumber
+    FROM ` + "`{{.ProjectID}}.{{.DatasetName}}.{{.TableName}}`" + `
+)
+WHERE @limit <= 0 OR RowNumber <= @limit
+ORDER BY System, RowNumber;
+`
+
+// ProjectPackage is a package along with the deps.dev project it belongs to.
+type ProjectPackage struct {
+	Package
+	ProjectType string
+	ProjectName string
+}
+
+type topPackageRow struct {
+	ProjectName    string
+	ProjectType    string
+	System         string
+	Name           string
+	DependentCount int
+}
+
+// TopPackages returns the limit packages with the most dependents in each
+// system, ordered by system and then from the most dependents to the least.
+// If limit <= 0 every package is returned.
+func (c *dependents) TopPackages(ctx context.Context, tableKey string, limit int) ([]ProjectPackage, error) {
+	tableName := getTableName(packageDependentCountsTableName, tableKey)
+	if err := c.prepareTable(ctx, packageDataQuery, tableName); err != nil {
+		return nil, fmt.Errorf("prepare packages table: %w", err)
+	}
+	query := c.generateQuery(topPackagesQuery, tableName)
+
+	it, err := c.b.Query(ctx, query, map[string]any{"limit": limit}, queryLabels(tableName))
+	if err != nil {
+		return nil, fmt.Errorf("top packages query: %w", err)
+	}
+	var pkgs []ProjectPackage
+	for {
+		var row topPackageRow
+		err := it.Next(&row)
+		if errors.Is(err, iterator.Done) {
+			return pkgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("top packages query: %w", err)
+		}
+		pkgs = append(pkgs, ProjectPackage{
+			Package:     Package{System: row.System, Name: row.Name, DependentCount: row.DependentCount},
+			ProjectType: row.ProjectType,
+			ProjectName: row.ProjectName,
+		})
+	}
+}
+
+// LoadTopPackages returns the limit packages with the most dependents in each
+// system, using the latest deps.dev snapshot in BigQuery. If limit <= 0 every
+// package is returned.
+//
+// The package dependent counts are stored in a table in the dataset
+// datasetName, which is reused for future calls with the same tableKey.
+func LoadTopPackages(ctx context.Context, logger *zap.Logger, projectID, datasetName string, datasetTTL time.Duration, tableKey string, limit int) ([]ProjectPackage, error) {
+	gcpClient, err := newBigQueryClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	dependents, err := NewDependents(ctx, gcpClient, logger, datasetName, datasetTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deps.dev dependents: %w", err)
+	}
+	pkgs, err := dependents.TopPackages(ctx, tableKey, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deps.dev top packages: %w", err)
+	}
+	return pkgs, nil
+}
@@ -20,8 +20,8 @@ import (
 	"time"
 
 	"github.com/ossf/criticality_score/internal/collector/github/legacy"
-	"github.com/ossf/criticality_score/internal/collector/projectrepo"
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 type RepoSource struct{}
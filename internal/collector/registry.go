@@ -18,8 +18,8 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/ossf/criticality_score/internal/collector/projectrepo"
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 // empty is a convenience wrapper for the empty struct.
@@ -44,6 +44,17 @@ func (r *registry) containsSource(s signal.Source) bool {
 	return false
 }
 
+// containsNamespace returns true if a Source with the namespace ns has
+// already been registered.
+func (r *registry) containsNamespace(ns signal.Namespace) bool {
+	for _, regS := range r.ss {
+		if regS.EmptySet().Namespace() == ns {
+			return true
+		}
+	}
+	return false
+}
+
 // Register adds the Source s to the registry to be used when Collect is called.
 //
 // This method may panic if the Source's signal Set is not valid, or if the
@@ -0,0 +1,194 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compress adds transparent gzip and zstd compression to readers and
+// writers.
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Format int
+
+const (
+	// FormatAuto chooses the format using the extension of the filename.
+	FormatAuto = Format(iota)
+	FormatNone
+	FormatGzip
+	FormatZstd
+)
+
+var ErrorUnknownFormat = errors.New("unknown compression format")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// String implements the fmt.Stringer interface.
+func (f Format) String() string {
+	text, err := f.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (f Format) MarshalText() ([]byte, error) {
+	switch f {
+	case FormatAuto:
+		return []byte("auto"), nil
+	case FormatNone:
+		return []byte("none"), nil
+	case FormatGzip:
+		return []byte("gzip"), nil
+	case FormatZstd:
+		return []byte("zstd"), nil
+	default:
+		return []byte{}, ErrorUnknownFormat
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (f *Format) UnmarshalText(text []byte) error {
+	switch {
+	case bytes.Equal(text, []byte("auto")):
+		*f = FormatAuto
+	case bytes.Equal(text, []byte("none")):
+		*f = FormatNone
+	case bytes.Equal(text, []byte("gzip")):
+		*f = FormatGzip
+	case bytes.Equal(text, []byte("zstd")):
+		*f = FormatZstd
+	default:
+		return ErrorUnknownFormat
+	}
+	return nil
+}
+
+// FormatForName returns the format matching the extension of name, which may
+// be a filename or a URL. Names ending in ".gz" use gzip, names ending in
+// ".zst" or ".zstd" use zstd, and all other names are not compressed.
+func FormatForName(name string) Format {
+	if u, err := url.Parse(name); err == nil && u.IsAbs() {
+		name = u.Path
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".gz":
+		return FormatGzip
+	case ".zst", ".zstd":
+		return FormatZstd
+	default:
+		return FormatNone
+	}
+}
+
+// Resolve returns the format to use for name. If f is FormatAuto the format
+// is chosen using FormatForName, otherwise f is returned.
+func (f Format) Resolve(name string) Format {
+	if f == FormatAuto {
+		return FormatForName(name)
+	}
+	return f
+}
+
+type writeCloser struct {
+	io.WriteCloser
+	underlying io.Closer
+}
+
+// Close closes the compressor, flushing any buffered data, and then closes the
+// underlying writer.
+func (w *writeCloser) Close() error {
+	err := w.WriteCloser.Close()
+	if cerr := w.underlying.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewWriter returns a writer that compresses data written to it with the
+// format f, and writes it to w. Closing the returned writer also closes w.
+//
+// If f is FormatNone or FormatAuto, w is returned unchanged.
+func NewWriter(w io.WriteCloser, f Format) (io.WriteCloser, error) {
+	switch f {
+	case FormatAuto, FormatNone:
+		return w, nil
+	case FormatGzip:
+		return &writeCloser{WriteCloser: gzip.NewWriter(w), underlying: w}, nil
+	case FormatZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &writeCloser{WriteCloser: zw, underlying: w}, nil
+	default:
+		return nil, ErrorUnknownFormat
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+// Close implements the io.Closer interface.
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+// NewReader returns a reader that decompresses the data read from r if it is
+// compressed with gzip or zstd. The format is detected from the first bytes
+// of the data. Closing the returned reader also closes r.
+func NewReader(r io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &readCloser{Reader: gr, close: func() error {
+			gr.Close()
+			return r.Close()
+		}}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &readCloser{Reader: zr, close: func() error {
+			zr.Close()
+			return r.Close()
+		}}, nil
+	default:
+		return &readCloser{Reader: br, close: r.Close}, nil
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ossf/criticality_score/internal/compress"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+	closed bool
+}
+
+func (w *nopWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestFormatForName(t *testing.T) {
+	tests := []struct {
+		name string
+		want compress.Format
+	}{
+		{name: "out.csv", want: compress.FormatNone},
+		{name: "out.csv.gz", want: compress.FormatGzip},
+		{name: "out.JSON.GZ", want: compress.FormatGzip},
+		{name: "out.csv.zst", want: compress.FormatZstd},
+		{name: "out.csv.zstd", want: compress.FormatZstd},
+		{name: "gs://bucket/out.csv.gz?x=y", want: compress.FormatGzip},
+		{name: "", want: compress.FormatNone},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := compress.FormatForName(test.name); got != test.want {
+				t.Fatalf("FormatForName() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got := compress.FormatAuto.Resolve("a.gz"); got != compress.FormatGzip {
+		t.Fatalf("Resolve() = %v, want %v", got, compress.FormatGzip)
+	}
+	if got := compress.FormatZstd.Resolve("a.gz"); got != compress.FormatZstd {
+		t.Fatalf("Resolve() = %v, want %v", got, compress.FormatZstd)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	for _, f := range []compress.Format{compress.FormatAuto, compress.FormatNone, compress.FormatGzip, compress.FormatZstd} {
+		var got compress.Format
+		if err := got.UnmarshalText([]byte(f.String())); err != nil {
+			t.Fatalf("UnmarshalText(%q) = %v, want no error", f.String(), err)
+		}
+		if got != f {
+			t.Fatalf("UnmarshalText(%q) = %v, want %v", f.String(), got, f)
+		}
+	}
+	var f compress.Format
+	if err := f.UnmarshalText([]byte("bzip2")); !errors.Is(err, compress.ErrorUnknownFormat) {
+		t.Fatalf("UnmarshalText() = %v, want %v", err, compress.ErrorUnknownFormat)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("repo.url,default_score\nhttps://github.com/a/a,0.5\n"), 100)
+	tests := []struct {
+		format     compress.Format
+		compressed bool
+	}{
+		{format: compress.FormatNone},
+		{format: compress.FormatGzip, compressed: true},
+		{format: compress.FormatZstd, compressed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			out := &nopWriteCloser{Writer: &buf}
+			w, err := compress.NewWriter(out, test.format)
+			if err != nil {
+				t.Fatalf("NewWriter() = %v, want no error", err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() = %v, want no error", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+			if !out.closed {
+				t.Fatal("Close() did not close the underlying writer")
+			}
+			if got := buf.Len() < len(data); got != test.compressed {
+				t.Fatalf("compressed = %v, want %v", got, test.compressed)
+			}
+
+			r, err := compress.NewReader(io.NopCloser(&buf))
+			if err != nil {
+				t.Fatalf("NewReader() = %v, want no error", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() = %v, want no error", err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatal("ReadAll() returned different data")
+			}
+		})
+	}
+}
+
+func TestNewReaderShort(t *testing.T) {
+	r, err := compress.NewReader(io.NopCloser(bytes.NewReader([]byte("a"))))
+	if err != nil {
+		t.Fatalf("NewReader() = %v, want no error", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "a" {
+		t.Fatalf("ReadAll() = %q, %v, want \"a\", no error", got, err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depgraph
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EdgeColumns are the columns that must be present in the header row of a
+// CSV file read by ReadCSV.
+var EdgeColumns = []string{"from_type", "from_name", "to_type", "to_name"}
+
+var ErrMissingColumn = errors.New("missing column")
+
+// ReadCSV reads edges from the CSV data in r and adds them to the graph g.
+//
+// The first row must be a header that contains each of EdgeColumns. Other
+// columns are ignored. This matches the layout of the edges table exported
+// from deps.dev.
+func ReadCSV(g *Graph, r io.Reader) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	idx := make(map[string]int)
+	for i, h := range header {
+		idx[h] = i
+	}
+	var cols []int
+	for _, c := range EdgeColumns {
+		i, ok := idx[c]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrMissingColumn, c)
+		}
+		cols = append(cols, i)
+	}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading row: %w", err)
+		}
+		g.AddEdge(
+			Project{Type: row[cols[0]], Name: row[cols[1]]},
+			Project{Type: row[cols[2]], Name: row[cols[3]]},
+		)
+	}
+}
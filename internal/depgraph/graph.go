@@ -0,0 +1,181 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package depgraph provides a project level dependency graph, and algorithms
+// for measuring the transitive importance of each project in the graph.
+package depgraph
+
+import (
+	"math"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// DefaultDamping is the damping factor commonly used for PageRank.
+	DefaultDamping = 0.85
+
+	// DefaultMaxIterations is the maximum number of iterations PageRank will
+	// run for if it fails to converge.
+	DefaultMaxIterations = 100
+
+	// DefaultTolerance is the total change in rank between two iterations
+	// below which PageRank is considered to have converged.
+	DefaultTolerance = 1e-9
+)
+
+// Project identifies a node in the graph.
+//
+// Type and Name follow the conventions used by deps.dev. For example, a
+// GitHub repository has the Type "GITHUB" and the Name "owner/repo".
+type Project struct {
+	Type string
+	Name string
+}
+
+// Graph is a directed graph of projects, where each edge points from a
+// dependent project to the project it depends on.
+type Graph struct {
+	ids      map[Project]int
+	projects []Project
+	deps     [][]int
+}
+
+// New returns a new, empty Graph.
+func New() *Graph {
+	return &Graph{
+		ids: make(map[Project]int),
+	}
+}
+
+func (g *Graph) id(p Project) int {
+	if id, ok := g.ids[p]; ok {
+		return id
+	}
+	id := len(g.projects)
+	g.ids[p] = id
+	g.projects = append(g.projects, p)
+	g.deps = append(g.deps, nil)
+	return id
+}
+
+// AddEdge records that the project from depends on the project to.
+//
+// Self-dependencies are ignored, as are duplicate edges.
+func (g *Graph) AddEdge(from, to Project) {
+	if from == to {
+		return
+	}
+	f := g.id(from)
+	t := g.id(to)
+	g.deps[f] = append(g.deps[f], t)
+}
+
+// Len returns the number of projects in the graph.
+func (g *Graph) Len() int {
+	return len(g.projects)
+}
+
+// Contains returns true if p is present in the graph.
+func (g *Graph) Contains(p Project) bool {
+	_, ok := g.ids[p]
+	return ok
+}
+
+// Dependencies returns the projects that p directly depends on, in the order
+// they were added. Nil is returned if p is not present in the graph.
+func (g *Graph) Dependencies(p Project) []Project {
+	id, ok := g.ids[p]
+	if !ok {
+		return nil
+	}
+	seen := make(map[int]bool, len(g.deps[id]))
+	var deps []Project
+	for _, d := range g.deps[id] {
+		if !seen[d] {
+			seen[d] = true
+			deps = append(deps, g.projects[d])
+		}
+	}
+	return deps
+}
+
+// compact removes duplicate edges.
+func (g *Graph) compact() {
+	for i, d := range g.deps {
+		slices.Sort(d)
+		g.deps[i] = slices.Compact(d)
+	}
+}
+
+// PageRank calculates the PageRank of each project in the graph.
+//
+// Rank flows from each dependent to its dependencies, so a project that is
+// depended on by projects which are themselves highly ranked will receive a
+// higher rank than a project with the same number of low ranked dependents.
+//
+// Projects without any dependencies distribute their rank evenly across all
+// the projects in the graph.
+//
+// The rank for all projects sums to 1. Iteration stops after maxIterations,
+// or once the total change in rank falls below tolerance.
+func (g *Graph) PageRank(damping float64, maxIterations int, tolerance float64) map[Project]float64 {
+	n := len(g.projects)
+	res := make(map[Project]float64, n)
+	if n == 0 {
+		return res
+	}
+	g.compact()
+
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for iter := 0; iter < maxIterations; iter++ {
+		// Rank held by projects with no dependencies is spread evenly.
+		var dangling float64
+		for i, d := range g.deps {
+			if len(d) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, d := range g.deps {
+			if len(d) == 0 {
+				continue
+			}
+			share := damping * rank[i] / float64(len(d))
+			for _, j := range d {
+				next[j] += share
+			}
+		}
+		var delta float64
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < tolerance {
+			break
+		}
+	}
+
+	for i, p := range g.projects {
+		res[p] = rank[i]
+	}
+	return res
+}
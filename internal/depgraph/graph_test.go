@@ -0,0 +1,133 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depgraph_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/internal/depgraph"
+)
+
+func p(name string) depgraph.Project {
+	return depgraph.Project{Type: "GITHUB", Name: name}
+}
+
+func TestPageRankEmpty(t *testing.T) {
+	got := depgraph.New().PageRank(depgraph.DefaultDamping, depgraph.DefaultMaxIterations, depgraph.DefaultTolerance)
+	if len(got) != 0 {
+		t.Fatalf("PageRank() = %v, want empty", got)
+	}
+}
+
+func TestPageRankSumsToOne(t *testing.T) {
+	g := depgraph.New()
+	g.AddEdge(p("a"), p("b"))
+	g.AddEdge(p("a"), p("c"))
+	g.AddEdge(p("b"), p("c"))
+	g.AddEdge(p("c"), p("d"))
+	g.AddEdge(p("e"), p("d"))
+
+	var sum float64
+	for _, r := range g.PageRank(depgraph.DefaultDamping, depgraph.DefaultMaxIterations, depgraph.DefaultTolerance) {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Fatalf("sum of ranks = %f, want 1", sum)
+	}
+}
+
+func TestPageRankTransitive(t *testing.T) {
+	g := depgraph.New()
+	// "lib" has three dependents, which are each depended on by many others.
+	for _, d := range []string{"x", "y", "z"} {
+		g.AddEdge(p(d), p("lib"))
+		for i := 0; i < 10; i++ {
+			g.AddEdge(p(d+strings.Repeat("!", i+1)), p(d))
+		}
+	}
+	// "toy" has more direct dependents, none of which are depended upon.
+	for i := 0; i < 6; i++ {
+		g.AddEdge(p("leaf"+strings.Repeat("!", i+1)), p("toy"))
+	}
+
+	r := g.PageRank(depgraph.DefaultDamping, depgraph.DefaultMaxIterations, depgraph.DefaultTolerance)
+	if r[p("lib")] <= r[p("toy")] {
+		t.Fatalf("rank(lib) = %f, want > rank(toy) = %f", r[p("lib")], r[p("toy")])
+	}
+}
+
+func TestAddEdgeIgnoresDuplicatesAndSelf(t *testing.T) {
+	g := depgraph.New()
+	g.AddEdge(p("a"), p("a"))
+	if g.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", g.Len())
+	}
+	g.AddEdge(p("a"), p("b"))
+	g.AddEdge(p("a"), p("b"))
+	g.AddEdge(p("a"), p("c"))
+
+	r := g.PageRank(depgraph.DefaultDamping, depgraph.DefaultMaxIterations, depgraph.DefaultTolerance)
+	if math.Abs(r[p("b")]-r[p("c")]) > 1e-9 {
+		t.Fatalf("rank(b) = %f, want rank(c) = %f", r[p("b")], r[p("c")])
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	g := depgraph.New()
+	g.AddEdge(p("a"), p("c"))
+	g.AddEdge(p("a"), p("b"))
+	g.AddEdge(p("a"), p("c"))
+	g.AddEdge(p("b"), p("c"))
+
+	want := []depgraph.Project{p("c"), p("b")}
+	if diff := cmp.Diff(want, g.Dependencies(p("a"))); diff != "" {
+		t.Fatalf("Dependencies(a) mismatch (-want +got):\n%s", diff)
+	}
+	if got := g.Dependencies(p("c")); len(got) != 0 {
+		t.Fatalf("Dependencies(c) = %v, want none", got)
+	}
+	if got := g.Dependencies(p("missing")); got != nil {
+		t.Fatalf("Dependencies(missing) = %v, want nil", got)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	in := "to_type,to_name,from_type,from_name,extra\n" +
+		"GITHUB,lib/a,GITHUB,app/b,1\n" +
+		"GITHUB,lib/a,GITLAB,app/c,2\n"
+	g := depgraph.New()
+	if err := depgraph.ReadCSV(g, strings.NewReader(in)); err != nil {
+		t.Fatalf("ReadCSV() = %v, want no error", err)
+	}
+	if g.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", g.Len())
+	}
+	if !g.Contains(depgraph.Project{Type: "GITLAB", Name: "app/c"}) {
+		t.Fatal("Contains(GITLAB app/c) = false, want true")
+	}
+}
+
+func TestReadCSVMissingColumn(t *testing.T) {
+	in := "from_type,from_name,to_name\n"
+	err := depgraph.ReadCSV(depgraph.New(), strings.NewReader(in))
+	if !errors.Is(err, depgraph.ErrMissingColumn) {
+		t.Fatalf("ReadCSV() = %v, want %v", err, depgraph.ErrMissingColumn)
+	}
+}
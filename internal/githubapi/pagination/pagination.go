@@ -31,6 +31,21 @@ type PagedQuery interface {
 	NextPageVars() map[string]any
 }
 
+// EndCursorVar is the name of the variable a PagedQuery must use for the
+// cursor of the page to query, for a Cursor to be resumed from a Position.
+const EndCursorVar = "endCursor"
+
+// Position is the position of a Cursor in the results of a query. It can be
+// saved and later used with Resume to continue from the same result.
+type Position struct {
+	// After is the end cursor of the page before the current page, or empty
+	// if the current page is the first.
+	After string `json:"after,omitempty"`
+
+	// Offset is the number of results already returned from the current page.
+	Offset int `json:"offset"`
+}
+
 type Cursor struct {
 	ctx    context.Context
 	client *githubv4.Client
@@ -52,18 +67,52 @@ func Query(ctx context.Context, client *githubv4.Client, query PagedQuery, vars
 	return c, nil
 }
 
+// Resume returns a Cursor for query that starts from the result at pos, which
+// was returned by Position for a Cursor with the same query and vars.
+func Resume(ctx context.Context, client *githubv4.Client, query PagedQuery, vars map[string]any, pos Position) (*Cursor, error) {
+	c := &Cursor{
+		ctx:    ctx,
+		client: client,
+		query:  query,
+		vars:   vars,
+	}
+	if pos.After == "" {
+		c.vars[EndCursorVar] = (*githubv4.String)(nil)
+	} else {
+		c.vars[EndCursorVar] = githubv4.String(pos.After)
+	}
+	if err := c.queryPage(); err != nil {
+		return nil, err
+	}
+	c.cur = pos.Offset
+	return c, nil
+}
+
 func (c *Cursor) queryNextPage() error {
 	// Merge the next page vars with the current vars
 	newVars := c.query.NextPageVars()
 	for k, v := range newVars {
 		c.vars[k] = v
 	}
+	return c.queryPage()
+}
+
+func (c *Cursor) queryPage() error {
 	// Reset the current position
 	c.cur = 0
 	// Execute the query
 	return c.client.Query(c.ctx, c.query, c.vars)
 }
 
+// Position returns the position of the next result to be returned by Next.
+func (c *Cursor) Position() Position {
+	pos := Position{Offset: c.cur}
+	if after, ok := c.vars[EndCursorVar].(githubv4.String); ok {
+		pos.After = string(after)
+	}
+	return pos
+}
+
 func (c *Cursor) atEndOfPage() bool {
 	return c.cur >= c.query.Length()
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"io"
 	"os"
+
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+	"github.com/ossf/criticality_score/internal/compress"
 )
 
 // fileOpenFunc makes it possible to mock os.Open() for testing.
@@ -21,9 +24,33 @@ var (
 // If filename is equal to o.StdoutName, os.Stdin will be used.
 // If filename does not exist, an error will be returned.
 // If filename does exist, the file will be opened and returned.
+//
+// If filename is a bucket URL (e.g. "gs://bucket/file.csv") the blob will be
+// read from the bucket. If the blob does not exist the error will match
+// os.ErrNotExist.
+//
+// Input compressed with gzip or zstd is decompressed automatically.
 func Open(ctx context.Context, filename string) (io.ReadCloser, error) {
-	if StdinName != "" && filename == StdinName {
-		return os.Stdin, nil
+	var f io.ReadCloser = os.Stdin
+	switch {
+	case StdinName != "" && filename == StdinName:
+	case cloudstorage.IsBucketURL(filename):
+		r, err := cloudstorage.NewReader(ctx, filename)
+		if err != nil {
+			return nil, err
+		}
+		f = r
+	default:
+		r, err := fileOpen(filename)
+		if err != nil {
+			return nil, err
+		}
+		f = r
+	}
+	r, err := compress.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
-	return fileOpen(filename)
+	return r, nil
 }
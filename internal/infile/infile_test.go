@@ -1,31 +1,63 @@
 package infile
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/ossf/criticality_score/internal/compress"
 )
 
+// writeTestFile writes data to a temporary file and returns its name.
+func writeTestFile(t *testing.T, data []byte) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() == %v, want nil", err)
+	}
+	return name
+}
+
+func readAll(t *testing.T, r io.ReadCloser) string {
+	t.Helper()
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() == %v, want nil", err)
+	}
+	return string(data)
+}
+
 func TestOpenStdin(t *testing.T) {
-	origStdinName := StdinName
-	defer func() { StdinName = origStdinName }()
-	StdinName = "-stdin-"
-	f, err := Open(context.Background(), "-stdin-")
+	stdin, err := os.Open(writeTestFile(t, []byte("stdin")))
 	if err != nil {
 		t.Fatalf("Open() == %v, want nil", err)
 	}
-	if f != os.Stdin {
-		t.Fatal("Open() == not stdin, want stdin")
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	f, err := Open(context.Background(), "-")
+	if err != nil {
+		t.Fatalf("Open() == %v, want nil", err)
+	}
+	if got := readAll(t, f); got != "stdin" {
+		t.Fatalf("Open() read %q, want %q", got, "stdin")
 	}
 }
 
 func TestOpen(t *testing.T) {
 	want := "path/to/file"
 	got := ""
+	name := writeTestFile(t, []byte("data"))
 	fileOpen = func(filename string) (*os.File, error) {
 		got = filename
-		return &os.File{}, nil
+		return os.Open(name)
 	}
 
 	f, err := Open(context.Background(), want)
@@ -38,8 +70,70 @@ func TestOpen(t *testing.T) {
 	if got != want {
 		t.Fatalf("Open(%q) opened %q", want, got)
 	}
+	if data := readAll(t, f); data != "data" {
+		t.Fatalf("Open() read %q, want %q", data, "data")
+	}
+}
+
+func TestOpenCompressed(t *testing.T) {
+	for _, format := range []compress.Format{compress.FormatGzip, compress.FormatZstd} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := compress.NewWriter(nopCloser{&buf}, format)
+			if err != nil {
+				t.Fatalf("NewWriter() == %v, want nil", err)
+			}
+			io.WriteString(w, "data")
+			w.Close()
+			name := writeTestFile(t, buf.Bytes())
+			fileOpen = os.Open
+
+			f, err := Open(context.Background(), name)
+			if err != nil {
+				t.Fatalf("Open() == %v, want nil", err)
+			}
+			if got := readAll(t, f); got != "data" {
+				t.Fatalf("Open() read %q, want %q", got, "data")
+			}
+		})
+	}
+}
+
+func TestOpenBucketURL(t *testing.T) {
+	name := writeTestFile(t, []byte("data"))
+	f, err := Open(context.Background(), fileURL(name))
+	if err != nil {
+		t.Fatalf("Open() == %v, want nil", err)
+	}
+	if got := readAll(t, f); got != "data" {
+		t.Fatalf("Open() read %q, want %q", got, "data")
+	}
+}
+
+func TestOpenBucketURLNotExist(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "missing")
+	_, err := Open(context.Background(), fileURL(name))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Open() == %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+// fileURL returns a file:// URL for the local path name.
+func fileURL(name string) string {
+	p := filepath.ToSlash(name)
+	if !strings.HasPrefix(p, "/") {
+		// Windows paths start with a drive letter.
+		p = "/" + p
+	}
+	return "file://" + p
+}
+
+type nopCloser struct {
+	io.Writer
 }
 
+func (nopCloser) Close() error { return nil }
+
 func TestOpenError(t *testing.T) {
 	want := errors.New("test error")
 	fileOpen = func(filename string) (*os.File, error) {
@@ -0,0 +1,328 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package marker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+	"github.com/ossf/criticality_score/internal/compress"
+)
+
+var ErrorManifestMismatch = errors.New("data does not match manifest")
+
+// Manifest is the contents of a TypeManifest marker. It describes the output
+// file in enough detail for downstream jobs to check that it is complete.
+type Manifest struct {
+	// Path is the full location of the output file.
+	Path string `json:"path"`
+
+	// Rows is the number of repositories written to the output file.
+	Rows int `json:"rows"`
+
+	// Header is true if the output file, and each shard, starts with a header
+	// row that is not counted in Rows.
+	Header bool `json:"header,omitempty"`
+
+	// Bytes is the size of the output file.
+	Bytes int64 `json:"bytes"`
+
+	// SHA256 is the hex encoded SHA-256 digest of the output file.
+	SHA256 string `json:"sha256"`
+
+	RunID  string            `json:"run_id,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+
+	// Start and End are the time range that was enumerated.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Shards lists every file written if the output was split into shards.
+	// Path is then the first shard, and Rows, Bytes and SHA256 describe all
+	// the shards together, with the digest taken over the shards in order.
+	Shards []Shard `json:"shards,omitempty"`
+}
+
+// Shard describes a single file of an output that was split into shards.
+type Shard struct {
+	Path   string `json:"path"`
+	Rows   int    `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Info holds the details about a run that are recorded in a Manifest, but
+// can't be determined from the output file.
+type Info struct {
+	RunID  string
+	Rows   int
+	Header bool
+	Params map[string]string
+	Start  time.Time
+	End    time.Time
+}
+
+// WriteManifest writes a Manifest for outFile to markerFile.
+//
+// The size and digest of outFile are calculated by reading it back, so it
+// must be closed before WriteManifest is called.
+func WriteManifest(ctx context.Context, markerFile, outFile string, info Info) error {
+	bytes, digest, err := checksum(ctx, outFile)
+	if err != nil {
+		return fmt.Errorf("checksum output: %w", err)
+	}
+	return writeManifest(ctx, markerFile, Manifest{
+		Path:   outFile,
+		Rows:   info.Rows,
+		Bytes:  bytes,
+		SHA256: digest,
+	}, info)
+}
+
+// WriteShardedManifest writes a Manifest for an output split into shards to
+// markerFile. The Path and Rows of each shard must be set.
+//
+// Like WriteManifest, the size and digest of each shard are calculated by
+// reading it back, so every shard must be closed before it is called.
+func WriteShardedManifest(ctx context.Context, markerFile string, shards []Shard, info Info) error {
+	if len(shards) == 0 {
+		return ErrorEmptyMarker
+	}
+	m := Manifest{
+		Path:   shards[0].Path,
+		Rows:   info.Rows,
+		Shards: make([]Shard, 0, len(shards)),
+	}
+	h := sha256.New()
+	for _, s := range shards {
+		bytes, digest, err := checksum(ctx, s.Path, h)
+		if err != nil {
+			return fmt.Errorf("checksum output: %w", err)
+		}
+		s.Bytes = bytes
+		s.SHA256 = digest
+		m.Bytes += bytes
+		m.Shards = append(m.Shards, s)
+	}
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return writeManifest(ctx, markerFile, m, info)
+}
+
+// writeManifest completes m with info and writes it to markerFile.
+func writeManifest(ctx context.Context, markerFile string, m Manifest, info Info) (err error) {
+	m.RunID = info.RunID
+	m.Header = info.Header
+	m.Params = info.Params
+	m.Start = info.Start
+	m.End = info.End
+	m.CreatedAt = time.Now().UTC()
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	marker, e := cloudstorage.NewWriter(ctx, markerFile)
+	if e != nil {
+		return fmt.Errorf("open marker: %w", e)
+	}
+	defer func() {
+		if e := marker.Close(); e != nil && err == nil {
+			err = fmt.Errorf("closing marker: %w", e)
+		}
+	}()
+	if _, e := fmt.Fprintln(marker, string(b)); e != nil {
+		err = fmt.Errorf("writing marker: %w", e)
+	}
+	return
+}
+
+// ReadManifest returns the Manifest written to markerFile by WriteManifest.
+func ReadManifest(ctx context.Context, markerFile string) (*Manifest, error) {
+	r, err := cloudstorage.NewReader(ctx, markerFile)
+	if err != nil {
+		return nil, fmt.Errorf("open marker: %w", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading marker: %w", err)
+	}
+	return parseManifest(b)
+}
+
+// Verify checks that dataFile has the size, SHA-256 digest and number of rows
+// recorded in the Manifest in markerFile, returning the Manifest if it does. If dataFile is
+// empty the path recorded in the Manifest is checked.
+//
+// It is intended to be called before a data file is used, so that a partially
+// written or corrupt file is detected. ErrorManifestMismatch is returned if
+// dataFile does not match.
+//
+// If the Manifest lists shards, dataFile must be one of the shards and is
+// checked against it. If dataFile is empty every shard is checked.
+func Verify(ctx context.Context, markerFile, dataFile string) (*Manifest, error) {
+	m, err := ReadManifest(ctx, markerFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Shards) == 0 {
+		if dataFile == "" {
+			dataFile = m.Path
+		}
+		if err := verify(ctx, dataFile, m.Rows, m.Bytes, m.SHA256, m.Header); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	rows := 0
+	for _, s := range m.Shards {
+		rows += s.Rows
+		if dataFile == "" {
+			if err := verify(ctx, s.Path, s.Rows, s.Bytes, s.SHA256, m.Header); err != nil {
+				return nil, err
+			}
+		} else if s.Path == dataFile {
+			if err := verify(ctx, dataFile, s.Rows, s.Bytes, s.SHA256, m.Header); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+	}
+	if dataFile != "" {
+		return nil, fmt.Errorf("%w: %s is not a shard", ErrorManifestMismatch, dataFile)
+	}
+	if rows != m.Rows {
+		return nil, fmt.Errorf("%w: shards have %d rows, want %d", ErrorManifestMismatch, rows, m.Rows)
+	}
+	return m, nil
+}
+
+// verify checks that dataFile has the given number of rows, size and hex
+// encoded SHA-256 digest. A row is a non-empty line, excluding the first if
+// header is true.
+//
+// The size and digest are of the bytes stored, while the rows are counted
+// after decompressing dataFile if it has a compressed extension.
+func verify(ctx context.Context, dataFile string, wantRows int, wantBytes int64, wantDigest string, header bool) error {
+	// Count the rows while the checksum is calculated, so dataFile is only
+	// read once.
+	pr, pw := io.Pipe()
+	counted := make(chan error, 1)
+	lines := 0
+	go func() {
+		var err error
+		lines, err = countLines(pr, dataFile)
+		counted <- err
+	}()
+	bytes, digest, err := checksum(ctx, dataFile, pw)
+	pw.CloseWithError(err)
+	countErr := <-counted
+	if err != nil {
+		return fmt.Errorf("checksum data: %w", err)
+	}
+	if countErr != nil {
+		return fmt.Errorf("counting rows: %w", countErr)
+	}
+	if bytes != wantBytes {
+		return fmt.Errorf("%w: %s is %d bytes, want %d", ErrorManifestMismatch, dataFile, bytes, wantBytes)
+	}
+	if digest != wantDigest {
+		return fmt.Errorf("%w: %s has sha256 %s, want %s", ErrorManifestMismatch, dataFile, digest, wantDigest)
+	}
+	rows := lines
+	if header && rows > 0 {
+		rows--
+	}
+	if rows != wantRows {
+		return fmt.Errorf("%w: %s has %d rows, want %d", ErrorManifestMismatch, dataFile, rows, wantRows)
+	}
+	return nil
+}
+
+// countLines returns the number of non-empty lines in r, decompressing it if
+// name has an extension recognized by compress.FormatForName. r is always read
+// to the end.
+func countLines(r io.Reader, name string) (int, error) {
+	defer io.Copy(io.Discard, r)
+	if compress.FormatForName(name) != compress.FormatNone {
+		dr, err := compress.NewReader(io.NopCloser(r))
+		if err != nil {
+			return 0, err
+		}
+		r = dr
+	}
+	var lines lineCounter
+	if _, err := io.Copy(&lines, r); err != nil {
+		return 0, err
+	}
+	return lines.n, nil
+}
+
+// lineCounter is an io.Writer that counts the non-empty lines written to it.
+type lineCounter struct {
+	n      int
+	inLine bool
+}
+
+// Write implements the io.Writer interface.
+func (c *lineCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch {
+		case b == '\n':
+			c.inLine = false
+		case !c.inLine:
+			c.inLine = true
+			c.n++
+		}
+	}
+	return len(p), nil
+}
+
+func parseManifest(b []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if m.Path == "" {
+		return nil, ErrorEmptyMarker
+	}
+	return &m, nil
+}
+
+// checksum returns the size and hex encoded SHA-256 digest of the bytes
+// stored in the file name, without decompressing it. The contents of the file
+// are also written to each of also.
+func checksum(ctx context.Context, name string, also ...io.Writer) (int64, string, error) {
+	r, err := cloudstorage.NewRawReader(ctx, name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(append([]io.Writer{h}, also...)...), r)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package marker_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+	"github.com/ossf/criticality_score/internal/marker"
+)
+
+const testData = "https://github.com/ossf/criticality_score\nhttps://github.com/ossf/scorecard\n"
+
+func writeTestData(t *testing.T, dir string) string {
+	t.Helper()
+	outFile := path.Join(dir, "github.txt")
+	if err := os.WriteFile(outFile, []byte(testData), 0o644); err != nil {
+		t.Fatalf("WriteFile() = %v, want no error", err)
+	}
+	return outFile
+}
+
+func TestWriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	outFile := writeTestData(t, dir)
+	markerFile := path.Join(dir, "marker.json")
+	info := marker.Info{
+		RunID:  "20220614-0000",
+		Rows:   2,
+		Params: map[string]string{"query": "is:public", "min_stars": "10"},
+		Start:  time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC),
+	}
+
+	if err := marker.WriteManifest(context.Background(), markerFile, outFile, info); err != nil {
+		t.Fatalf("WriteManifest() = %v, want no error", err)
+	}
+	got, err := marker.ReadManifest(context.Background(), markerFile)
+	if err != nil {
+		t.Fatalf("ReadManifest() = %v, want no error", err)
+	}
+	digest := sha256.Sum256([]byte(testData))
+	want := &marker.Manifest{
+		Path:   outFile,
+		Rows:   2,
+		Bytes:  int64(len(testData)),
+		SHA256: hex.EncodeToString(digest[:]),
+		RunID:  "20220614-0000",
+		Params: map[string]string{"query": "is:public", "min_stars": "10"},
+		Start:  info.Start,
+		End:    info.End,
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(marker.Manifest{}, "CreatedAt")); diff != "" {
+		t.Fatalf("ReadManifest() mismatch (-want +got):\n%s", diff)
+	}
+	// The manifest type can also be used to locate the output.
+	loc, err := marker.Read(context.Background(), marker.TypeManifest, markerFile, "")
+	if err != nil {
+		t.Fatalf("Read() = %v, want no error", err)
+	}
+	if loc != outFile {
+		t.Fatalf("Read() = %q, want %q", loc, outFile)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name    string
+		info    marker.Info
+		data    string
+		wantErr error
+	}{
+		{
+			name: "unchanged",
+			info: marker.Info{Rows: 2},
+			data: testData,
+		},
+		{
+			name: "header",
+			info: marker.Info{Rows: 1, Header: true},
+			data: testData,
+		},
+		{
+			name:    "truncated",
+			info:    marker.Info{Rows: 2},
+			data:    testData[:10],
+			wantErr: marker.ErrorManifestMismatch,
+		},
+		{
+			name:    "modified",
+			info:    marker.Info{Rows: 2},
+			data:    "https://github.com/ossf/criticality_scorX\nhttps://github.com/ossf/scorecard\n",
+			wantErr: marker.ErrorManifestMismatch,
+		},
+		{
+			name:    "wrong rows",
+			info:    marker.Info{},
+			data:    testData,
+			wantErr: marker.ErrorManifestMismatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			outFile := writeTestData(t, dir)
+			markerFile := path.Join(dir, "marker.json")
+			if err := marker.WriteManifest(context.Background(), markerFile, outFile, test.info); err != nil {
+				t.Fatalf("WriteManifest() = %v, want no error", err)
+			}
+			if err := os.WriteFile(outFile, []byte(test.data), 0o644); err != nil {
+				t.Fatalf("WriteFile() = %v, want no error", err)
+			}
+
+			m, err := marker.Verify(context.Background(), markerFile, "")
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify() = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && m.Path != outFile {
+				t.Fatalf("Verify() path = %q, want %q", m.Path, outFile)
+			}
+		})
+	}
+}
+
+func TestVerifyCompressed(t *testing.T) {
+	for _, name := range []string{"github.txt.gz", "github.txt.zst"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			outFile := path.Join(dir, name)
+			w, err := cloudstorage.NewWriter(context.Background(), outFile)
+			if err != nil {
+				t.Fatalf("NewWriter() = %v, want no error", err)
+			}
+			if _, err := io.WriteString(w, testData); err != nil {
+				t.Fatalf("WriteString() = %v, want no error", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+			raw, err := os.ReadFile(outFile)
+			if err != nil {
+				t.Fatalf("ReadFile() = %v, want no error", err)
+			}
+			markerFile := path.Join(dir, "marker.json")
+			if err := marker.WriteManifest(context.Background(), markerFile, outFile, marker.Info{Rows: 2}); err != nil {
+				t.Fatalf("WriteManifest() = %v, want no error", err)
+			}
+
+			m, err := marker.Verify(context.Background(), markerFile, "")
+			if err != nil {
+				t.Fatalf("Verify() = %v, want no error", err)
+			}
+			// The size and digest are of the compressed bytes.
+			digest := sha256.Sum256(raw)
+			if m.Bytes != int64(len(raw)) || m.SHA256 != hex.EncodeToString(digest[:]) {
+				t.Fatalf("Verify() = %d bytes with sha256 %s, want %d bytes with sha256 %x", m.Bytes, m.SHA256, len(raw), digest)
+			}
+
+			if err := marker.WriteManifest(context.Background(), markerFile, outFile, marker.Info{Rows: 3}); err != nil {
+				t.Fatalf("WriteManifest() = %v, want no error", err)
+			}
+			if _, err := marker.Verify(context.Background(), markerFile, ""); !errors.Is(err, marker.ErrorManifestMismatch) {
+				t.Fatalf("Verify() = %v, want %v", err, marker.ErrorManifestMismatch)
+			}
+		})
+	}
+}
+
+func TestWriteShardedManifest(t *testing.T) {
+	dir := t.TempDir()
+	data := []string{"https://github.com/ossf/criticality_score\n", "https://github.com/ossf/scorecard\n"}
+	var shards []marker.Shard
+	for i, d := range data {
+		p := path.Join(dir, fmt.Sprintf("github-%05d.txt", i))
+		if err := os.WriteFile(p, []byte(d), 0o644); err != nil {
+			t.Fatalf("WriteFile() = %v, want no error", err)
+		}
+		shards = append(shards, marker.Shard{Path: p, Rows: 1})
+	}
+	markerFile := path.Join(dir, "marker.json")
+
+	if err := marker.WriteShardedManifest(context.Background(), markerFile, shards, marker.Info{Rows: 2}); err != nil {
+		t.Fatalf("WriteShardedManifest() = %v, want no error", err)
+	}
+	got, err := marker.ReadManifest(context.Background(), markerFile)
+	if err != nil {
+		t.Fatalf("ReadManifest() = %v, want no error", err)
+	}
+	digest := sha256.Sum256([]byte(testData))
+	want := &marker.Manifest{
+		Path:   shards[0].Path,
+		Rows:   2,
+		Bytes:  int64(len(testData)),
+		SHA256: hex.EncodeToString(digest[:]),
+	}
+	for i, d := range data {
+		digest := sha256.Sum256([]byte(d))
+		want.Shards = append(want.Shards, marker.Shard{
+			Path:   shards[i].Path,
+			Rows:   1,
+			Bytes:  int64(len(d)),
+			SHA256: hex.EncodeToString(digest[:]),
+		})
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(marker.Manifest{}, "CreatedAt")); diff != "" {
+		t.Fatalf("ReadManifest() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Each shard can be verified on its own, or all together.
+	for _, dataFile := range []string{"", shards[0].Path, shards[1].Path} {
+		if _, err := marker.Verify(context.Background(), markerFile, dataFile); err != nil {
+			t.Fatalf("Verify(%q) = %v, want no error", dataFile, err)
+		}
+	}
+	if _, err := marker.Verify(context.Background(), markerFile, path.Join(dir, "other.txt")); !errors.Is(err, marker.ErrorManifestMismatch) {
+		t.Fatalf("Verify() = %v, want %v", err, marker.ErrorManifestMismatch)
+	}
+	// The shards must add up to the total number of rows.
+	wrongRowsFile := path.Join(dir, "wrong-rows.json")
+	if err := marker.WriteShardedManifest(context.Background(), wrongRowsFile, shards, marker.Info{Rows: 3}); err != nil {
+		t.Fatalf("WriteShardedManifest() = %v, want no error", err)
+	}
+	if _, err := marker.Verify(context.Background(), wrongRowsFile, ""); !errors.Is(err, marker.ErrorManifestMismatch) {
+		t.Fatalf("Verify() = %v, want %v", err, marker.ErrorManifestMismatch)
+	}
+	if err := os.WriteFile(shards[1].Path, []byte(data[0]), 0o644); err != nil {
+		t.Fatalf("WriteFile() = %v, want no error", err)
+	}
+	if _, err := marker.Verify(context.Background(), markerFile, ""); !errors.Is(err, marker.ErrorManifestMismatch) {
+		t.Fatalf("Verify() = %v, want %v", err, marker.ErrorManifestMismatch)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package marker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ossf/criticality_score/internal/cloudstorage"
+)
+
+var ErrorEmptyMarker = errors.New("marker is empty")
+
+// Read returns the location of the output file recorded in markerFile by a
+// previous call to Write.
+//
+// For TypeFile and TypeDir the marker only contains a path, so the location is
+// assumed to be in the same bucket as markerFile. For TypeDir the base name of
+// outFile is joined to the directory in the marker. For TypeManifest the path
+// recorded in the manifest is returned.
+func Read(ctx context.Context, t Type, markerFile, outFile string) (string, error) {
+	r, err := cloudstorage.NewReader(ctx, markerFile)
+	if err != nil {
+		return "", fmt.Errorf("open marker: %w", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading marker: %w", err)
+	}
+	contents := strings.TrimSpace(string(b))
+	if contents == "" {
+		return "", ErrorEmptyMarker
+	}
+	if t == TypeManifest {
+		m, err := parseManifest(b)
+		if err != nil {
+			return "", err
+		}
+		return m.Path, nil
+	}
+	return t.locate(markerFile, contents, outFile), nil
+}
+
+// locate reverses transform, returning the full location of a file given the
+// contents of the marker.
+func (t Type) locate(markerFile, contents, outFile string) string {
+	if t == TypeFull {
+		return contents
+	}
+	p := contents
+	if t == TypeDir {
+		p = path.Join(p, path.Base(TypeFile.transform(outFile)))
+	}
+	// Is the marker in a bucket?
+	u, err := url.Parse(markerFile)
+	if err != nil || !u.IsAbs() || u.Scheme == "file" {
+		return p
+	}
+	u.Path = "/" + strings.TrimPrefix(p, "/")
+	return u.String()
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package marker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	//nolint:govet
+	tests := []struct {
+		name       string
+		t          Type
+		markerFile string
+		contents   string
+		outFile    string
+		want       string
+	}{
+		{
+			name:       "full",
+			t:          TypeFull,
+			markerFile: "gs://bucket/latest",
+			contents:   "gs://bucket/20220614-0000/github.csv",
+			outFile:    "gs://bucket/20220621-0000/github.csv",
+			want:       "gs://bucket/20220614-0000/github.csv",
+		},
+		{
+			name:       "file-bucket",
+			t:          TypeFile,
+			markerFile: "gs://bucket/latest",
+			contents:   "20220614-0000/github.csv",
+			outFile:    "gs://bucket/20220621-0000/github.csv",
+			want:       "gs://bucket/20220614-0000/github.csv",
+		},
+		{
+			name:       "dir-bucket",
+			t:          TypeDir,
+			markerFile: "gs://bucket/latest",
+			contents:   "20220614-0000",
+			outFile:    "gs://bucket/20220621-0000/github.csv?arg",
+			want:       "gs://bucket/20220614-0000/github.csv",
+		},
+		{
+			name:       "dir-path",
+			t:          TypeDir,
+			markerFile: "/path/to/latest",
+			contents:   "/path/to/20220614-0000",
+			outFile:    "/path/to/20220621-0000/github.csv",
+			want:       "/path/to/20220614-0000/github.csv",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.t.locate(test.markerFile, test.contents, test.outFile); got != test.want {
+				t.Fatalf("locate() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	markerFile := path.Join(dir, "marker.test")
+	outFile := path.Join(dir, "20220614-0000", "github.csv")
+
+	if err := Write(context.Background(), TypeDir, markerFile, outFile); err != nil {
+		t.Fatalf("Write() = %v, want no error", err)
+	}
+	got, err := Read(context.Background(), TypeDir, markerFile, path.Join(dir, "20220621-0000", "github.csv"))
+	if err != nil {
+		t.Fatalf("Read() = %v, want no error", err)
+	}
+	if got != outFile {
+		t.Fatalf("Read() = %q, want %q", got, outFile)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	markerFile := path.Join(t.TempDir(), "marker.test")
+	if err := os.WriteFile(markerFile, []byte("\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() = %v, want no error", err)
+	}
+	_, err := Read(context.Background(), TypeFull, markerFile, "")
+	if !errors.Is(err, ErrorEmptyMarker) {
+		t.Fatalf("Read() = %v, want %v", err, ErrorEmptyMarker)
+	}
+}
@@ -28,6 +28,7 @@ const (
 	TypeFull = Type(iota)
 	TypeFile
 	TypeDir
+	TypeManifest
 )
 
 var ErrorUnknownType = errors.New("unknown marker type")
@@ -50,6 +51,8 @@ func (t Type) MarshalText() ([]byte, error) {
 		return []byte("file"), nil
 	case TypeDir:
 		return []byte("dir"), nil
+	case TypeManifest:
+		return []byte("manifest"), nil
 	default:
 		return []byte{}, ErrorUnknownType
 	}
@@ -64,6 +67,8 @@ func (t *Type) UnmarshalText(text []byte) error {
 		*t = TypeFile
 	case bytes.Equal(text, []byte("dir")):
 		*t = TypeDir
+	case bytes.Equal(text, []byte("manifest")):
+		*t = TypeManifest
 	default:
 		return ErrorUnknownType
 	}
@@ -71,7 +76,7 @@ func (t *Type) UnmarshalText(text []byte) error {
 }
 
 func (t Type) transform(p string) string {
-	if t == TypeFull {
+	if t == TypeFull || t == TypeManifest {
 		return p
 	}
 	// Is this a bucket URL?
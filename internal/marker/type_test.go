@@ -23,7 +23,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/ossf/criticality_score/cmd/enumerate_github/marker"
+	"github.com/ossf/criticality_score/internal/marker"
 )
 
 func TestTransform(t *testing.T) {
@@ -154,6 +154,10 @@ func TestUnmarshal(t *testing.T) {
 			in:   "dir",
 			want: marker.TypeDir,
 		},
+		{
+			in:   "manifest",
+			want: marker.TypeManifest,
+		},
 		{
 			in:      "notamarker",
 			wantErr: true,
@@ -194,6 +198,10 @@ func TestMarshal(t *testing.T) {
 			in:   marker.TypeDir,
 			want: "dir",
 		},
+		{
+			in:   marker.TypeManifest,
+			want: "manifest",
+		},
 		{
 			in:      marker.Type(99999),
 			wantErr: true,
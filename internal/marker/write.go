@@ -16,12 +16,20 @@ package marker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/ossf/criticality_score/internal/cloudstorage"
 )
 
+// ErrorManifestType is returned by Write for TypeManifest, as the details of
+// the run need to be passed to WriteManifest instead.
+var ErrorManifestType = errors.New("manifest markers must be written with WriteManifest")
+
 func Write(ctx context.Context, t Type, markerFile, outFile string) (err error) {
+	if t == TypeManifest {
+		return ErrorManifestType
+	}
 	marker, e := cloudstorage.NewWriter(ctx, markerFile)
 	if e != nil {
 		return fmt.Errorf("open marker: %w", e)
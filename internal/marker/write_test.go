@@ -18,12 +18,13 @@ package marker_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path"
 	"strings"
 	"testing"
 
-	"github.com/ossf/criticality_score/cmd/enumerate_github/marker"
+	"github.com/ossf/criticality_score/internal/marker"
 )
 
 func TestWrite(t *testing.T) {
@@ -44,3 +45,12 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("marker contents = %q, want %q", got, want)
 	}
 }
+
+func TestWrite_Manifest(t *testing.T) {
+	file := path.Join(t.TempDir(), "marker.json")
+
+	err := marker.Write(context.Background(), marker.TypeManifest, file, "this/is/a/path")
+	if !errors.Is(err, marker.ErrorManifestType) {
+		t.Fatalf("Write() = %v, want %v", err, marker.ErrorManifestType)
+	}
+}
@@ -16,11 +16,14 @@ package outfile
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gocloud.dev/blob"
@@ -28,6 +31,8 @@ import (
 	_ "gocloud.dev/blob/gcsblob"
 	_ "gocloud.dev/blob/memblob"
 	_ "gocloud.dev/blob/s3blob"
+
+	"github.com/ossf/criticality_score/internal/compress"
 )
 
 // fileOpenFunc makes it possible to mock os.OpenFile() for testing.
@@ -66,9 +71,27 @@ type Opener struct {
 	force             bool
 	append            bool
 	Perm              os.FileMode
+
+	// Compression is the format used to compress the output. It defaults to
+	// compress.FormatAuto, which chooses the format from the filename's
+	// extension.
+	Compression compress.Format
+
+	// InPlace writes local files directly, rather than to a temporary file
+	// that is renamed when the file is closed. It is needed if the partially
+	// written file must be visible, for example to resume writing it.
+	InPlace bool
+
+	// MaxRows and MaxBytes limit the size of each shard written by a Rotator.
+	// Output is not rotated if both are zero. See DefineRotationFlags.
+	MaxRows  int
+	MaxBytes int64
 }
 
 // CreateOpener creates an Opener and defines the sepecified flags fileFlag, forceFlag and appendFlag.
+//
+// A flag named fileFlag with the suffix "-compression" is also defined to set
+// the Compression format.
 func CreateOpener(fs *flag.FlagSet, fileFlag, forceFlag, appendFlag, fileHelpName string) *Opener {
 	o := &Opener{
 		Perm:              0o666,
@@ -79,13 +102,74 @@ func CreateOpener(fs *flag.FlagSet, fileFlag, forceFlag, appendFlag, fileHelpNam
 	fs.StringVar(&(o.filename), fileFlag, "", fmt.Sprintf("use the file `%s` for output. Defaults to stdout if not set.", fileHelpName))
 	fs.BoolVar(&(o.force), forceFlag, false, fmt.Sprintf("overwrites %s if it already exists and -%s is not set.", fileHelpName, appendFlag))
 	fs.BoolVar(&(o.append), appendFlag, false, fmt.Sprintf("appends to %s if it already exists.", fileHelpName))
+	fs.TextVar(&(o.Compression), fileFlag+"-compression", compress.FormatAuto, fmt.Sprintf("compresses %s using `FORMAT`: auto, none, gzip or zstd. With auto, gzip is used for names ending in .gz and zstd for .zst or .zstd.", fileHelpName))
 	return o
 }
 
+// DefineRotationFlags defines the flags used to set MaxRows and MaxBytes,
+// named fileFlag with the suffixes "-max-rows" and "-max-bytes".
+func (o *Opener) DefineRotationFlags(fs *flag.FlagSet, fileFlag, fileHelpName string) {
+	fs.IntVar(&(o.MaxRows), fileFlag+"-max-rows", 0, fmt.Sprintf("starts a new shard of %s after `ROWS` rows. %s must contain %s. Zero means no limit.", fileHelpName, fileHelpName, ShardToken))
+	fs.Int64Var(&(o.MaxBytes), fileFlag+"-max-bytes", 0, fmt.Sprintf("starts a new shard of %s once it reaches `BYTES` bytes before compression. %s must contain %s. Zero means no limit.", fileHelpName, fileHelpName, ShardToken))
+}
+
 func (o *Opener) openFile(filename string, extraFlags int) (NameWriteCloser, error) {
 	return o.fileOpener(filename, os.O_WRONLY|os.O_SYNC|os.O_CREATE|extraFlags, o.Perm)
 }
 
+// openAtomicFile opens a temporary file in the same directory as filename,
+// which is renamed to filename when it is closed. If overwrite is false an
+// error is returned if filename already exists.
+func (o *Opener) openAtomicFile(filename string, overwrite bool) (NameWriteCloser, error) {
+	if !overwrite {
+		if _, err := os.Lstat(filename); err == nil {
+			return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrExist}
+		}
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("generating temporary filename: %w", err)
+	}
+	dir, base := filepath.Split(filename)
+	tmp := filepath.Join(dir, "."+base+"."+hex.EncodeToString(suffix)+".tmp")
+	f, err := o.fileOpener(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, o.Perm)
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, name: filename}, nil
+}
+
+// atomicFile is a temporary file that is renamed to name when it is closed, so
+// that a partially written file is never seen at name.
+type atomicFile struct {
+	*os.File
+	name string
+}
+
+// Name implements the NameWriteCloser interface.
+func (f *atomicFile) Name() string {
+	return f.name
+}
+
+// Close implements the io.Closer interface.
+//
+// The temporary file is synced to storage before it is renamed. If this fails
+// the temporary file is removed.
+func (f *atomicFile) Close() error {
+	err := f.File.Sync()
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.File.Name(), f.name)
+	}
+	if err != nil {
+		os.Remove(f.File.Name())
+		return fmt.Errorf("closing %s: %w", f.name, err)
+	}
+	return nil
+}
+
 func (o *Opener) openBlobStore(ctx context.Context, u *url.URL) (NameWriteCloser, error) {
 	if o.append || !o.force {
 		return nil, fmt.Errorf("blob store must use -%s flag", o.forceFlag)
@@ -117,6 +201,7 @@ func (o *Opener) openBlobStore(ctx context.Context, u *url.URL) (NameWriteCloser
 //
 // If filename is empty/unset, os.Stdout will be used.
 // If filename does not exist, it will be created with the mode set in o.Perm.
+// If filename contains ShardToken it is replaced with the first shard number.
 // If filename does exist, the behavior of this function will depend on the
 // flags:
 //
@@ -126,8 +211,47 @@ func (o *Opener) openBlobStore(ctx context.Context, u *url.URL) (NameWriteCloser
 //     truncated.
 //   - if neither forceFlag nor appendFlag are set an error will be
 //     returned.
+//
+// Unless appending or o.InPlace is set, local files are written to a temporary
+// file in the same directory which replaces filename when it is closed. This
+// ensures a partially written file is never left at filename.
+//
+// The output is compressed using the format set by o.Compression. When
+// appending, the compressed output is added to the file as a new gzip member
+// or zstd frame, which readers will decompress as a single stream.
 func (o *Opener) Open(ctx context.Context) (NameWriteCloser, error) {
-	f := o.FilenameTransform(o.filename)
+	return o.openShard(ctx, 0)
+}
+
+// Name returns the filename Open will open, after applying FilenameTransform.
+// It is empty if os.Stdout will be used.
+func (o *Opener) Name() string {
+	return strings.ReplaceAll(o.FilenameTransform(o.filename), ShardToken, shardName(0))
+}
+
+// openShard opens the output for shard n.
+func (o *Opener) openShard(ctx context.Context, n int) (NameWriteCloser, error) {
+	f := strings.ReplaceAll(o.FilenameTransform(o.filename), ShardToken, shardName(n))
+	w, err := o.open(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	format := o.Compression.Resolve(f)
+	if format == compress.FormatNone {
+		return w, nil
+	}
+	cw, err := compress.NewWriter(w, format)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return &writeCloserWrapper{
+		WriteCloser: cw,
+		name:        w.Name(),
+	}, nil
+}
+
+func (o *Opener) open(ctx context.Context, f string) (NameWriteCloser, error) {
 	if f == "" {
 		return os.Stdout, nil
 	} else if u, e := url.Parse(f); e == nil && u.IsAbs() {
@@ -136,13 +260,21 @@ func (o *Opener) Open(ctx context.Context) (NameWriteCloser, error) {
 	switch {
 	case o.append:
 		return o.openFile(f, os.O_APPEND)
-	case o.force:
+	case o.InPlace && o.force:
 		return o.openFile(f, os.O_TRUNC)
-	default:
+	case o.InPlace:
 		return o.openFile(f, os.O_EXCL)
+	default:
+		return o.openAtomicFile(f, o.force)
 	}
 }
 
+// Rotating returns true if MaxRows or MaxBytes are set, so a Rotator will
+// split the output across shards.
+func (o *Opener) Rotating() bool {
+	return o.MaxRows > 0 || o.MaxBytes > 0
+}
+
 var DefaultOpener *Opener
 
 // DefineFlags is a wrapper around CreateOpener for updating a default instance
@@ -151,6 +283,14 @@ func DefineFlags(fs *flag.FlagSet, fileFlag, forceFlag, appendFlag, fileHelpName
 	DefaultOpener = CreateOpener(fs, fileFlag, forceFlag, appendFlag, fileHelpName)
 }
 
+// DefineRotationFlags is a wrapper around Opener.DefineRotationFlags for the
+// default instance of Opener.
+//
+// Must only be called after DefineFlags.
+func DefineRotationFlags(fs *flag.FlagSet, fileFlag, fileHelpName string) {
+	DefaultOpener.DefineRotationFlags(fs, fileFlag, fileHelpName)
+}
+
 // Open is a wrapper around Opener.Open for the default instance of Opener.
 //
 // Must only be called after DefineFlags.
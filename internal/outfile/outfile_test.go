@@ -15,11 +15,14 @@
 package outfile
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -50,18 +53,8 @@ func newTestOpener(t *testing.T) *testOpener {
 		}
 		if o.openErr != nil {
 			return nil, o.openErr
-		} else {
-			dir := t.TempDir()
-			cwd, err := os.Getwd() // Save the CWD so we can restore it later.
-			if err != nil {
-				return nil, err
-			}
-			if err := os.Chdir(dir); err != nil {
-				return nil, err
-			}
-			defer os.Chdir(cwd) // Restore the CWD so the temp dir can be cleaned up on Windows.
-			return os.Create(filename)
 		}
+		return os.OpenFile(filename, flags, perm)
 	}
 	return o
 }
@@ -124,48 +117,104 @@ func TestOpenFlagTest(t *testing.T) {
 	tests := []struct {
 		name         string
 		args         []string
-		openErr      error
+		exists       bool
+		inPlace      bool
 		expectedFlag int
+		atomic       bool
+		want         string
 	}{
 		{
 			name:         "no args",
 			args:         []string{},
 			expectedFlag: os.O_EXCL,
+			atomic:       true,
+			want:         "data",
 		},
 		{
 			name:         "append only flag",
 			args:         []string{"-append"},
+			exists:       true,
 			expectedFlag: os.O_APPEND,
+			want:         "existing data",
 		},
 		{
 			name:         "force only flag",
 			args:         []string{"-force"},
-			expectedFlag: os.O_TRUNC,
+			exists:       true,
+			expectedFlag: os.O_EXCL,
+			atomic:       true,
+			want:         "data",
 		},
 		{
 			name:         "both flags",
 			args:         []string{"-force", "-append"},
+			exists:       true,
 			expectedFlag: os.O_APPEND,
+			want:         "existing data",
+		},
+		{
+			name:         "in place",
+			args:         []string{},
+			inPlace:      true,
+			expectedFlag: os.O_EXCL,
+			want:         "data",
+		},
+		{
+			name:         "in place force flag",
+			args:         []string{"-force"},
+			exists:       true,
+			inPlace:      true,
+			expectedFlag: os.O_TRUNC,
+			want:         "data",
 		},
 	}
 
 	// Test success responses
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "testfile")
+			if test.exists {
+				if err := os.WriteFile(filename, []byte("existing "), 0o600); err != nil {
+					t.Fatalf("WriteFile() == %v, want nil", err)
+				}
+			}
 			o := newTestOpener(t)
-			o.flag.Parse(append(test.args, "-out=testfile"))
+			o.opener.InPlace = test.inPlace
+			o.flag.Parse(append(test.args, "-out="+filename))
 			f, err := o.opener.Open(context.Background())
 			if err != nil {
 				t.Fatalf("Open() == %v, want nil", err)
 			}
-			defer f.Close()
 			if f == nil {
 				t.Fatal("Open() == nil, want a file")
 			}
-			if got := f.Name(); got != "testfile" {
-				t.Fatalf("Open().Name() == %s; want %s", got, "testfile")
+			if got := f.Name(); got != filename {
+				t.Fatalf("Open().Name() == %s; want %s", got, filename)
+			}
+			if test.atomic {
+				if o.lastOpen == nil || o.lastOpen.filename == filename || filepath.Dir(o.lastOpen.filename) != filepath.Dir(filename) {
+					t.Fatalf("Open(...) not called with a temporary file in %s", filepath.Dir(filename))
+				}
+				assertLastOpen(t, o, o.lastOpen.filename, test.expectedFlag, 0o567)
+			} else {
+				assertLastOpen(t, o, filename, test.expectedFlag, 0o567)
+			}
+			if _, err := io.WriteString(f, "data"); err != nil {
+				t.Fatalf("Write() == %v, want nil", err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close() == %v, want nil", err)
+			}
+			got, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("ReadFile() == %v, want nil", err)
+			}
+			if string(got) != test.want {
+				t.Fatalf("ReadFile() == %q, want %q", got, test.want)
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(filename)); len(entries) != 1 {
+				t.Fatalf("ReadDir() returned %d entries, want 1", len(entries))
 			}
-			assertLastOpen(t, o, "testfile", test.expectedFlag, 0o567)
 		})
 	}
 
@@ -173,7 +222,8 @@ func TestOpenFlagTest(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name+" error", func(t *testing.T) {
 			o := newTestOpener(t)
-			o.flag.Parse(append(test.args, "-out=testfile"))
+			o.opener.InPlace = test.inPlace
+			o.flag.Parse(append(test.args, "-out="+filepath.Join(t.TempDir(), "testfile")))
 			o.openErr = errors.New("test error")
 			f, err := o.opener.Open(context.Background())
 			if err == nil {
@@ -184,6 +234,61 @@ func TestOpenFlagTest(t *testing.T) {
 	}
 }
 
+func TestOpenExists(t *testing.T) {
+	for _, inPlace := range []bool{false, true} {
+		t.Run(fmt.Sprintf("in place %t", inPlace), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "testfile")
+			if err := os.WriteFile(filename, []byte("existing"), 0o600); err != nil {
+				t.Fatalf("WriteFile() == %v, want nil", err)
+			}
+			o := newTestOpener(t)
+			o.opener.InPlace = inPlace
+			o.flag.Parse([]string{"-out=" + filename})
+			f, err := o.opener.Open(context.Background())
+			if err == nil {
+				f.Close()
+				t.Fatal("Open() == nil, want an error")
+			}
+			if !errors.Is(err, os.ErrExist) {
+				t.Fatalf("Open() == %v, want %v", err, os.ErrExist)
+			}
+		})
+	}
+}
+
+func TestOpenAtomicNotVisibleUntilClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "testfile")
+	if err := os.WriteFile(filename, []byte("existing"), 0o600); err != nil {
+		t.Fatalf("WriteFile() == %v, want nil", err)
+	}
+	o := newTestOpener(t)
+	o.flag.Parse([]string{"-force", "-out=" + filename})
+	f, err := o.opener.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open() == %v, want nil", err)
+	}
+	if _, err := io.WriteString(f, "data"); err != nil {
+		t.Fatalf("Write() == %v, want nil", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() == %v, want nil", err)
+	}
+	if string(got) != "existing" {
+		t.Fatalf("ReadFile() before Close() == %q, want %q", got, "existing")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() == %v, want nil", err)
+	}
+	got, err = os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() == %v, want nil", err)
+	}
+	if string(got) != "data" {
+		t.Fatalf("ReadFile() after Close() == %q, want %q", got, "data")
+	}
+}
+
 func assertLastOpen(t *testing.T, o *testOpener, filename string, requireFlags int, perm os.FileMode) {
 	t.Helper()
 	if o.lastOpen == nil {
@@ -201,9 +306,11 @@ func assertLastOpen(t *testing.T, o *testOpener, filename string, requireFlags i
 }
 
 func TestFilenameTransform(t *testing.T) {
-	want := "prefix-testfile-suffix"
+	dir := t.TempDir()
+	want := filepath.Join(dir, "prefix-testfile-suffix")
 	o := newTestOpener(t)
-	o.opener.FilenameTransform = func(f string) string { return fmt.Sprintf("prefix-%s-suffix", f) }
+	o.opener.InPlace = true
+	o.opener.FilenameTransform = func(f string) string { return filepath.Join(dir, fmt.Sprintf("prefix-%s-suffix", f)) }
 	o.flag.Parse([]string{"-out=testfile"})
 	f, err := o.opener.Open(context.Background())
 	if err != nil {
@@ -218,3 +325,82 @@ func TestFilenameTransform(t *testing.T) {
 	}
 	assertLastOpen(t, o, want, os.O_EXCL, 0o567)
 }
+
+func TestName(t *testing.T) {
+	o := newTestOpener(t)
+	o.opener.FilenameTransform = func(f string) string { return "prefix-" + f }
+	o.flag.Parse([]string{"-out=testfile-" + ShardToken})
+	if got, want := o.opener.Name(), "prefix-testfile-00000"; got != want {
+		t.Fatalf("Name() == %s; want %s", got, want)
+	}
+	if o.lastOpen != nil {
+		t.Fatalf("Name() opened %s, want no file opened", o.lastOpen.filename)
+	}
+}
+
+func TestCompressionFlagDefined(t *testing.T) {
+	o := newTestOpener(t)
+	f := o.flag.Lookup("out-compression")
+	if f == nil {
+		t.Fatal("Lookup() == nil, wanted a flag.")
+	}
+}
+
+func TestOpenCompressed(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		file  string
+		magic []byte
+	}{
+		{
+			name:  "gzip extension",
+			file:  "testfile.csv.gz",
+			magic: []byte{0x1f, 0x8b},
+		},
+		{
+			name:  "zstd extension",
+			file:  "testfile.csv.zst",
+			magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		},
+		{
+			name:  "zstd flag",
+			args:  []string{"-out-compression=zstd"},
+			file:  "testfile.csv",
+			magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		},
+		{
+			name:  "none flag",
+			args:  []string{"-out-compression=none"},
+			file:  "testfile.csv.gz",
+			magic: []byte("data"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := newTestOpener(t)
+			filename := filepath.Join(t.TempDir(), test.file)
+			o.flag.Parse(append(test.args, "-out="+filename))
+			f, err := o.opener.Open(context.Background())
+			if err != nil {
+				t.Fatalf("Open() == %v, want nil", err)
+			}
+			if got := f.Name(); got != filename {
+				t.Fatalf("Open().Name() == %s; want %s", got, filename)
+			}
+			if _, err := io.WriteString(f, "data"); err != nil {
+				t.Fatalf("Write() == %v, want nil", err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close() == %v, want nil", err)
+			}
+			got, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("ReadFile() == %v, want nil", err)
+			}
+			if !bytes.HasPrefix(got, test.magic) {
+				t.Fatalf("ReadFile() == %v, want prefix %v", got, test.magic)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ShardToken is replaced in the output filename with the number of the shard
+// being written.
+const ShardToken = "[[shard]]"
+
+var (
+	ErrorMissingShardToken = errors.New("filename must contain " + ShardToken + " to rotate output")
+
+	// ErrorClosed is returned by Rotator.Write after Close is called, or
+	// after an error closing a shard.
+	ErrorClosed = errors.New("rotator closed")
+)
+
+// shardName returns the string ShardToken is replaced with for shard n.
+func shardName(n int) string {
+	return fmt.Sprintf("%05d", n)
+}
+
+// Shard is a file written by a Rotator.
+type Shard struct {
+	Name string
+	Rows int
+}
+
+// Rotator writes rows to a sequence of shards, starting a new shard whenever
+// the current shard reaches the Opener's MaxRows or MaxBytes.
+//
+// Each shard is written by a W returned from calling newWriter with the
+// shard's file, so that any header is repeated in every shard. A W must not
+// buffer rows between calls to Write.
+//
+// Rotator is safe to use from multiple goroutines.
+type Rotator[W any] struct {
+	ctx       context.Context
+	o         *Opener
+	newWriter func(io.Writer) W
+
+	mu     sync.Mutex
+	f      NameWriteCloser
+	w      W
+	bytes  int64
+	shards []Shard
+}
+
+// NewRotator returns a Rotator for the output opened by o, with the first
+// shard already open. If o.MaxRows and o.MaxBytes are both zero all the rows
+// are written to a single file.
+//
+// ErrorMissingShardToken is returned if the output is rotated but the
+// filename does not contain ShardToken.
+func NewRotator[W any](ctx context.Context, o *Opener, newWriter func(io.Writer) W) (*Rotator[W], error) {
+	if o.Rotating() && !strings.Contains(o.FilenameTransform(o.filename), ShardToken) {
+		return nil, ErrorMissingShardToken
+	}
+	r := &Rotator[W]{
+		ctx:       ctx,
+		o:         o,
+		newWriter: newWriter,
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// next opens the next shard.
+func (r *Rotator[W]) next() error {
+	f, err := r.o.openShard(r.ctx, len(r.shards))
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.w = r.newWriter(&countingWriter{w: f, n: &r.bytes})
+	r.bytes = 0
+	r.shards = append(r.shards, Shard{Name: f.Name()})
+	return nil
+}
+
+// full returns true if the current shard has reached its limits.
+func (r *Rotator[W]) full() bool {
+	s := r.shards[len(r.shards)-1]
+	return (r.o.MaxRows > 0 && s.Rows >= r.o.MaxRows) || (r.o.MaxBytes > 0 && r.bytes >= r.o.MaxBytes)
+}
+
+// Write calls write with the W for the current shard to write a single row,
+// first starting a new shard if the current one is full.
+func (r *Rotator[W]) Write(write func(W) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return ErrorClosed
+	}
+	if r.full() {
+		err := r.f.Close()
+		r.f = nil
+		if err != nil {
+			return err
+		}
+		if err := r.next(); err != nil {
+			return err
+		}
+	}
+	if err := write(r.w); err != nil {
+		return err
+	}
+	r.shards[len(r.shards)-1].Rows++
+	return nil
+}
+
+// Close closes the current shard.
+func (r *Rotator[W]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// Shards returns every shard written, in order.
+func (r *Rotator[W]) Shards() []Shard {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Shard(nil), r.shards...)
+}
+
+// countingWriter adds the number of bytes written to w to n.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+// Write implements the io.Writer interface.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	*w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// lineWriter writes a header line to each shard, followed by the rows.
+type lineWriter struct {
+	w             io.Writer
+	headerWritten bool
+}
+
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{w: w}
+}
+
+func (w *lineWriter) writeRow(row string) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if _, err := io.WriteString(w.w, "header\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w.w, row+"\n")
+	return err
+}
+
+func TestRotator(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxRows  int
+		maxBytes int64
+		rows     int
+		want     []string
+	}{
+		{
+			name: "no rotation",
+			rows: 3,
+			want: []string{"header\nrow0\nrow1\nrow2\n"},
+		},
+		{
+			name:    "max rows",
+			maxRows: 2,
+			rows:    5,
+			want:    []string{"header\nrow0\nrow1\n", "header\nrow2\nrow3\n", "header\nrow4\n"},
+		},
+		{
+			name:    "max rows exact",
+			maxRows: 2,
+			rows:    4,
+			want:    []string{"header\nrow0\nrow1\n", "header\nrow2\nrow3\n"},
+		},
+		{
+			name:     "max bytes",
+			maxBytes: 15,
+			rows:     3,
+			want:     []string{"header\nrow0\nrow1\n", "header\nrow2\n"},
+		},
+		{
+			name:    "no rows",
+			maxRows: 2,
+			want:    []string{""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			o := newTestOpener(t)
+			o.flag.Parse([]string{"-out=" + filepath.Join(dir, "out-"+ShardToken+".txt")})
+			o.opener.MaxRows = test.maxRows
+			o.opener.MaxBytes = test.maxBytes
+
+			r, err := NewRotator(context.Background(), o.opener, newLineWriter)
+			if err != nil {
+				t.Fatalf("NewRotator() == %v, want nil", err)
+			}
+			for i := 0; i < test.rows; i++ {
+				row := fmt.Sprintf("row%d", i)
+				if err := r.Write(func(w *lineWriter) error { return w.writeRow(row) }); err != nil {
+					t.Fatalf("Write() == %v, want nil", err)
+				}
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() == %v, want nil", err)
+			}
+
+			var got []string
+			var gotRows int
+			for i, s := range r.Shards() {
+				if want := filepath.Join(dir, fmt.Sprintf("out-%05d.txt", i)); s.Name != want {
+					t.Errorf("Shards()[%d].Name == %s, want %s", i, s.Name, want)
+				}
+				gotRows += s.Rows
+				b, err := os.ReadFile(s.Name)
+				if err != nil {
+					t.Fatalf("ReadFile() == %v, want nil", err)
+				}
+				got = append(got, string(b))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("shard contents mismatch (-want +got):\n%s", diff)
+			}
+			if gotRows != test.rows {
+				t.Errorf("Shards() has %d rows, want %d", gotRows, test.rows)
+			}
+		})
+	}
+}
+
+func TestRotatorMissingShardToken(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-out=" + filepath.Join(t.TempDir(), "out.txt")},
+	} {
+		o := newTestOpener(t)
+		o.flag.Parse(args)
+		o.opener.MaxRows = 1
+		r, err := NewRotator(context.Background(), o.opener, newLineWriter)
+		if err == nil {
+			r.Close()
+		}
+		if !errors.Is(err, ErrorMissingShardToken) {
+			t.Fatalf("NewRotator(%v) == %v, want %v", args, err, ErrorMissingShardToken)
+		}
+	}
+}
+
+func TestRotatorWriteAfterClose(t *testing.T) {
+	o := newTestOpener(t)
+	o.flag.Parse([]string{"-out=" + filepath.Join(t.TempDir(), "out.txt")})
+	r, err := NewRotator(context.Background(), o.opener, newLineWriter)
+	if err != nil {
+		t.Fatalf("NewRotator() == %v, want nil", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() == %v, want nil", err)
+	}
+	err = r.Write(func(w *lineWriter) error { return w.writeRow("row") })
+	if !errors.Is(err, ErrorClosed) {
+		t.Fatalf("Write() == %v, want %v", err, ErrorClosed)
+	}
+}
+
+func TestOpenShardToken(t *testing.T) {
+	dir := t.TempDir()
+	o := newTestOpener(t)
+	o.flag.Parse([]string{"-out=" + filepath.Join(dir, "out-"+ShardToken+".txt")})
+	f, err := o.opener.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open() == %v, want nil", err)
+	}
+	defer f.Close()
+	if want := filepath.Join(dir, "out-00000.txt"); f.Name() != want {
+		t.Fatalf("Open().Name() == %s; want %s", f.Name(), want)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// Values from the Parquet format's Thrift definitions.
+const (
+	typeBoolean   int32 = 0
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+
+	convertedUTF8            int32 = 0
+	convertedTimestampMicros int32 = 10
+
+	repetitionOptional int32 = 1
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	codecUncompressed int32 = 0
+
+	pageTypeData int32 = 0
+)
+
+const magic = "PAR1"
+
+// encoder writes Thrift structs using the compact protocol, keeping the
+// first error so that calls can be chained.
+type encoder struct {
+	ctx context.Context
+	buf *thrift.TMemoryBuffer
+	p   *thrift.TCompactProtocol
+	err error
+}
+
+func newEncoder() *encoder {
+	buf := thrift.NewTMemoryBuffer()
+	return &encoder{
+		ctx: context.Background(),
+		buf: buf,
+		p:   thrift.NewTCompactProtocolConf(buf, nil),
+	}
+}
+
+// bytes returns the encoded data.
+func (e *encoder) bytes() ([]byte, error) {
+	if e.err == nil {
+		e.err = e.p.Flush(e.ctx)
+	}
+	return e.buf.Bytes(), e.err
+}
+
+func (e *encoder) check(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *encoder) field(id int16, t thrift.TType) {
+	e.check(e.p.WriteFieldBegin(e.ctx, "", t, id))
+}
+
+func (e *encoder) i32(id int16, v int32) {
+	e.field(id, thrift.I32)
+	e.check(e.p.WriteI32(e.ctx, v))
+}
+
+func (e *encoder) i64(id int16, v int64) {
+	e.field(id, thrift.I64)
+	e.check(e.p.WriteI64(e.ctx, v))
+}
+
+func (e *encoder) str(id int16, v string) {
+	e.field(id, thrift.STRING)
+	e.check(e.p.WriteString(e.ctx, v))
+}
+
+// structField writes a field containing the struct written by fn.
+func (e *encoder) structField(id int16, fn func()) {
+	e.field(id, thrift.STRUCT)
+	e.structValue(fn)
+}
+
+// structValue writes the fields written by fn as a struct.
+func (e *encoder) structValue(fn func()) {
+	e.check(e.p.WriteStructBegin(e.ctx, ""))
+	fn()
+	e.check(e.p.WriteFieldStop(e.ctx))
+	e.check(e.p.WriteStructEnd(e.ctx))
+}
+
+// list writes a field containing a list of n elements of type t, each written
+// by calling fn with the element's index.
+func (e *encoder) list(id int16, t thrift.TType, n int, fn func(i int)) {
+	e.field(id, thrift.LIST)
+	e.check(e.p.WriteListBegin(e.ctx, t, n))
+	for i := 0; i < n; i++ {
+		fn(i)
+	}
+	e.check(e.p.WriteListEnd(e.ctx))
+}
+
+// schemaElement is a node in the flattened schema stored in the footer.
+type schemaElement struct {
+	name        string
+	physical    int32 // -1 for groups
+	converted   int32 // -1 if there is none
+	numChildren int32
+	root        bool
+}
+
+func flattenSchema(fields []*Field) []schemaElement {
+	elems := []schemaElement{{name: "schema", physical: -1, converted: -1, numChildren: int32(len(fields)), root: true}}
+	var walk func([]*Field)
+	walk = func(fields []*Field) {
+		for _, f := range fields {
+			if f.isGroup() {
+				elems = append(elems, schemaElement{name: f.Name, physical: -1, converted: -1, numChildren: int32(len(f.Fields))})
+				walk(f.Fields)
+			} else {
+				elems = append(elems, schemaElement{name: f.Name, physical: f.Type.physical(), converted: f.Type.converted()})
+			}
+		}
+	}
+	walk(fields)
+	return elems
+}
+
+// chunkMeta describes a column chunk written to the file.
+type chunkMeta struct {
+	physical   int32
+	path       []string
+	numValues  int64
+	size       int64
+	pageOffset int64
+}
+
+type rowGroupMeta struct {
+	chunks  []chunkMeta
+	numRows int64
+}
+
+// encodePageHeader returns the PageHeader for a data page of size bytes
+// holding numValues values, including nulls.
+func encodePageHeader(size, numValues int32) ([]byte, error) {
+	e := newEncoder()
+	e.structValue(func() {
+		e.i32(1, pageTypeData)
+		e.i32(2, size)
+		e.i32(3, size)
+		e.structField(5, func() {
+			e.i32(1, numValues)
+			e.i32(2, encodingPlain)
+			e.i32(3, encodingRLE)
+			e.i32(4, encodingRLE)
+		})
+	})
+	return e.bytes()
+}
+
+// encodeFileMetaData returns the FileMetaData stored in the footer.
+func encodeFileMetaData(fields []*Field, rowGroups []rowGroupMeta, createdBy string) ([]byte, error) {
+	elems := flattenSchema(fields)
+	var numRows int64
+	for _, rg := range rowGroups {
+		numRows += rg.numRows
+	}
+
+	e := newEncoder()
+	e.structValue(func() {
+		e.i32(1, 1)
+		e.list(2, thrift.STRUCT, len(elems), func(i int) {
+			el := elems[i]
+			e.structValue(func() {
+				if el.physical >= 0 {
+					e.i32(1, el.physical)
+				}
+				if !el.root {
+					e.i32(3, repetitionOptional)
+				}
+				e.str(4, el.name)
+				if el.numChildren > 0 {
+					e.i32(5, el.numChildren)
+				}
+				if el.converted >= 0 {
+					e.i32(6, el.converted)
+				}
+			})
+		})
+		e.i64(3, numRows)
+		e.list(4, thrift.STRUCT, len(rowGroups), func(i int) {
+			rg := rowGroups[i]
+			var total int64
+			for _, c := range rg.chunks {
+				total += c.size
+			}
+			e.structValue(func() {
+				e.list(1, thrift.STRUCT, len(rg.chunks), func(j int) {
+					c := rg.chunks[j]
+					e.structValue(func() {
+						e.i64(2, c.pageOffset)
+						e.structField(3, func() {
+							e.i32(1, c.physical)
+							e.list(2, thrift.I32, 2, func(k int) {
+								e.check(e.p.WriteI32(e.ctx, []int32{encodingPlain, encodingRLE}[k]))
+							})
+							e.list(3, thrift.STRING, len(c.path), func(k int) {
+								e.check(e.p.WriteString(e.ctx, c.path[k]))
+							})
+							e.i32(4, codecUncompressed)
+							e.i64(5, c.numValues)
+							e.i64(6, c.size)
+							e.i64(7, c.size)
+							e.i64(9, c.pageOffset)
+						})
+					})
+				})
+				e.i64(2, total)
+				e.i64(3, rg.numRows)
+			})
+		})
+		if createdBy != "" {
+			e.str(6, createdBy)
+		}
+	})
+	return e.bytes()
+}
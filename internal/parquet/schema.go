@@ -0,0 +1,120 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parquet writes Parquet files with optional, non-repeated columns
+// that may be nested in groups.
+//
+// Only the features needed to write a flat or nested table are supported.
+// Each row group has a single uncompressed data page per column using the
+// PLAIN encoding.
+//
+// TODO: replace this package with github.com/apache/arrow/go/v11/parquet/pqarrow
+// and read the output back with its reader in tests. Its writer and reader
+// depend on github.com/JohnCGriffin/overflow, which has to be added to go.sum
+// first.
+package parquet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Type is the type of the values in a column.
+type Type int
+
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	String
+	Timestamp
+)
+
+var ErrorInvalidSchema = errors.New("invalid schema")
+
+// physical returns the Parquet physical type used to store t.
+func (t Type) physical() int32 {
+	switch t {
+	case Boolean:
+		return typeBoolean
+	case Int64, Timestamp:
+		return typeInt64
+	case Double:
+		return typeDouble
+	default:
+		return typeByteArray
+	}
+}
+
+// converted returns the Parquet converted type for t, or -1 if there is none.
+func (t Type) converted() int32 {
+	switch t {
+	case String:
+		return convertedUTF8
+	case Timestamp:
+		return convertedTimestampMicros
+	default:
+		return -1
+	}
+}
+
+// Field describes a column, or a group of columns if Fields is not empty.
+//
+// Every field is optional, so any value, including a group, may be missing.
+type Field struct {
+	Name   string
+	Type   Type
+	Fields []*Field
+}
+
+func (f *Field) isGroup() bool {
+	return len(f.Fields) > 0
+}
+
+// leaves calls fn with every column in fields and its path, in the order the
+// columns are stored.
+func leaves(fields []*Field, path []string, fn func(f *Field, path []string)) {
+	for _, f := range fields {
+		p := append(append([]string(nil), path...), f.Name)
+		if f.isGroup() {
+			leaves(f.Fields, p, fn)
+		} else {
+			fn(f, p)
+		}
+	}
+}
+
+func validate(fields []*Field) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: no fields", ErrorInvalidSchema)
+	}
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if f.Name == "" {
+			return fmt.Errorf("%w: empty field name", ErrorInvalidSchema)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("%w: duplicate field %s", ErrorInvalidSchema, f.Name)
+		}
+		seen[f.Name] = true
+		if f.isGroup() {
+			if err := validate(f.Fields); err != nil {
+				return err
+			}
+		} else if f.Type < Boolean || f.Type > Timestamp {
+			return fmt.Errorf("%w: unknown type for field %s", ErrorInvalidSchema, f.Name)
+		}
+	}
+	return nil
+}
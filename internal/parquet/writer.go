@@ -0,0 +1,404 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// DefaultRowGroupSize is the approximate number of bytes of values buffered
+// before a row group is written.
+const DefaultRowGroupSize = 64 << 20
+
+var (
+	ErrorInvalidValue = errors.New("invalid value")
+	ErrorClosed       = errors.New("writer closed")
+)
+
+// column buffers the values for a single column in the current row group.
+type column struct {
+	field  *Field
+	path   []string
+	maxDef int
+
+	// defs holds the definition level of every value, including nulls.
+	defs []uint8
+
+	// values holds the PLAIN encoded values that are not null. Booleans are
+	// held in bools until the row group is written, as they are bit-packed.
+	values bytes.Buffer
+	bools  []bool
+}
+
+func (c *column) add(v any) error {
+	var err error
+	switch c.field.Type {
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return c.invalid(v)
+		}
+		c.bools = append(c.bools, b)
+	case Int64:
+		var i int64
+		if i, err = toInt64(v); err != nil {
+			return c.invalid(v)
+		}
+		c.writeUint64(uint64(i))
+	case Double:
+		var f float64
+		if f, err = toFloat64(v); err != nil {
+			return c.invalid(v)
+		}
+		c.writeUint64(math.Float64bits(f))
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			return c.invalid(v)
+		}
+		var n [4]byte
+		binary.LittleEndian.PutUint32(n[:], uint32(len(s)))
+		c.values.Write(n[:])
+		c.values.WriteString(s)
+	case Timestamp:
+		t, ok := v.(time.Time)
+		if !ok {
+			return c.invalid(v)
+		}
+		c.writeUint64(uint64(t.UnixMicro()))
+	}
+	return nil
+}
+
+func (c *column) writeUint64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	c.values.Write(b[:])
+}
+
+func (c *column) invalid(v any) error {
+	return fmt.Errorf("%w for %s: %T", ErrorInvalidValue, strings.Join(c.path, "."), v)
+}
+
+// size returns the approximate number of bytes buffered.
+func (c *column) size() int {
+	return len(c.defs) + c.values.Len() + len(c.bools)
+}
+
+// page returns the body of the data page for the buffered values.
+func (c *column) page() []byte {
+	levels := encodeLevels(c.defs, bitWidth(c.maxDef))
+	var page bytes.Buffer
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(levels)))
+	page.Write(n[:])
+	page.Write(levels)
+	if c.field.Type == Boolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, b := range c.bools {
+			if b {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		page.Write(packed)
+	} else {
+		page.Write(c.values.Bytes())
+	}
+	return page.Bytes()
+}
+
+func (c *column) reset() {
+	c.defs = c.defs[:0]
+	c.values.Reset()
+	c.bools = c.bools[:0]
+}
+
+// Writer writes rows to a Parquet file.
+type Writer struct {
+	w         io.Writer
+	offset    int64
+	fields    []*Field
+	columns   []*column
+	rows      int64
+	rowGroups []rowGroupMeta
+	closed    bool
+
+	// RowGroupSize is the approximate number of bytes of values buffered
+	// before a row group is written.
+	RowGroupSize int
+
+	// CreatedBy is recorded in the file's metadata.
+	CreatedBy string
+}
+
+// NewWriter returns a Writer that writes rows with the schema in fields to w.
+func NewWriter(w io.Writer, fields []*Field) (*Writer, error) {
+	if err := validate(fields); err != nil {
+		return nil, err
+	}
+	pw := &Writer{
+		w:            w,
+		fields:       fields,
+		RowGroupSize: DefaultRowGroupSize,
+	}
+	leaves(fields, nil, func(f *Field, path []string) {
+		pw.columns = append(pw.columns, &column{field: f, path: path, maxDef: len(path)})
+	})
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// Write adds a row to the file. Groups are represented as a map[string]any
+// keyed by field name, and missing or nil values are null.
+//
+// Int64 fields accept any integer type or json.Number, Double fields also
+// accept these, and Timestamp fields accept a time.Time.
+func (w *Writer) Write(row map[string]any) error {
+	if w.closed {
+		return ErrorClosed
+	}
+	// Check the values before buffering any of them so an invalid row does
+	// not leave the columns with different numbers of values.
+	if err := check(w.fields, row, ""); err != nil {
+		return err
+	}
+	col := 0
+	if err := w.add(w.fields, row, 0, &col); err != nil {
+		return err
+	}
+	w.rows++
+
+	size := 0
+	for _, c := range w.columns {
+		size += c.size()
+	}
+	if size >= w.RowGroupSize {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+func check(fields []*Field, m map[string]any, path string) error {
+	for _, f := range fields {
+		v := m[f.Name]
+		if v == nil {
+			continue
+		}
+		if f.isGroup() {
+			child, ok := v.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%w for %s: %T", ErrorInvalidValue, path+f.Name, v)
+			}
+			if err := check(f.Fields, child, path+f.Name+"."); err != nil {
+				return err
+			}
+		} else if !valid(f.Type, v) {
+			return fmt.Errorf("%w for %s: %T", ErrorInvalidValue, path+f.Name, v)
+		}
+	}
+	return nil
+}
+
+func valid(t Type, v any) bool {
+	var ok bool
+	switch t {
+	case Boolean:
+		_, ok = v.(bool)
+	case Int64:
+		_, err := toInt64(v)
+		ok = err == nil
+	case Double:
+		_, err := toFloat64(v)
+		ok = err == nil
+	case String:
+		_, ok = v.(string)
+	case Timestamp:
+		_, ok = v.(time.Time)
+	}
+	return ok
+}
+
+// add buffers the values in m for fields, where def is the definition level
+// of m and col is the index of the first column of fields.
+func (w *Writer) add(fields []*Field, m map[string]any, def int, col *int) error {
+	for _, f := range fields {
+		v := m[f.Name]
+		if f.isGroup() {
+			child, _ := v.(map[string]any)
+			childDef := def
+			if child != nil {
+				childDef++
+			}
+			if err := w.add(f.Fields, child, childDef, col); err != nil {
+				return err
+			}
+			continue
+		}
+		c := w.columns[*col]
+		*col++
+		if v == nil {
+			c.defs = append(c.defs, uint8(def))
+			continue
+		}
+		if err := c.add(v); err != nil {
+			return err
+		}
+		c.defs = append(c.defs, uint8(def+1))
+	}
+	return nil
+}
+
+// flushRowGroup writes the buffered rows as a row group.
+func (w *Writer) flushRowGroup() error {
+	if w.rows == 0 {
+		return nil
+	}
+	rg := rowGroupMeta{numRows: w.rows}
+	for _, c := range w.columns {
+		page := c.page()
+		header, err := encodePageHeader(int32(len(page)), int32(len(c.defs)))
+		if err != nil {
+			return err
+		}
+		chunk := chunkMeta{
+			physical:   c.field.Type.physical(),
+			path:       c.path,
+			numValues:  int64(len(c.defs)),
+			size:       int64(len(header) + len(page)),
+			pageOffset: w.offset,
+		}
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		rg.chunks = append(rg.chunks, chunk)
+		c.reset()
+	}
+	w.rowGroups = append(w.rowGroups, rg)
+	w.rows = 0
+	return nil
+}
+
+// Close writes any buffered rows and the file's footer. It does not close the
+// underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrorClosed
+	}
+	w.closed = true
+	if err := w.flushRowGroup(); err != nil {
+		return err
+	}
+	meta, err := encodeFileMetaData(w.fields, w.rowGroups, w.CreatedBy)
+	if err != nil {
+		return err
+	}
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(meta)))
+	for _, b := range [][]byte{meta, n[:], []byte(magic)} {
+		if err := w.write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	}
+	return 0, ErrorInvalidValue
+}
+
+func toFloat64(v any) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	}
+	i, err := toInt64(v)
+	return float64(i), err
+}
+
+// bitWidth returns the number of bits needed to store values up to max.
+func bitWidth(max int) int {
+	w := 0
+	for max > 0 {
+		w++
+		max >>= 1
+	}
+	return w
+}
+
+// encodeLevels encodes levels using the RLE/bit-packing hybrid encoding,
+// using only RLE runs.
+func encodeLevels(levels []uint8, width int) []byte {
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	byteWidth := (width + 7) / 8
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(tmp[:], uint64(j-i)<<1)
+		buf.Write(tmp[:n])
+		// Levels are at most 255, so only the first byte is ever non-zero.
+		buf.WriteByte(levels[i])
+		for k := 1; k < byteWidth; k++ {
+			buf.WriteByte(0)
+		}
+		i = j
+	}
+	return buf.Bytes()
+}
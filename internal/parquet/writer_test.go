@@ -0,0 +1,370 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/ossf/criticality_score/internal/parquet"
+)
+
+var testFields = []*parquet.Field{
+	{Name: "repo", Fields: []*parquet.Field{
+		{Name: "url", Type: parquet.String},
+		{Name: "stars", Type: parquet.Int64},
+		{Name: "owner", Fields: []*parquet.Field{
+			{Name: "name", Type: parquet.String},
+		}},
+	}},
+	{Name: "score", Type: parquet.Double},
+	{Name: "archived", Type: parquet.Boolean},
+	{Name: "date", Type: parquet.Timestamp},
+}
+
+var testDate = time.Date(2022, 10, 15, 1, 2, 3, 4000, time.UTC)
+
+func TestWriteRead(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowGroupSize int
+	}{
+		{name: "one row group", rowGroupSize: parquet.DefaultRowGroupSize},
+		{name: "row group per row", rowGroupSize: 1},
+	}
+	rows := []map[string]any{
+		{
+			"repo": map[string]any{
+				"url":   "https://github.com/a/a",
+				"stars": json.Number("12"),
+				"owner": map[string]any{"name": "a"},
+			},
+			"score":    0.5,
+			"archived": true,
+			"date":     testDate,
+		},
+		{
+			"repo":     map[string]any{"url": "https://github.com/b/b", "stars": nil},
+			"score":    json.Number("1"),
+			"archived": false,
+		},
+		{},
+		{
+			"repo":     map[string]any{"owner": map[string]any{}},
+			"archived": true,
+		},
+	}
+	want := []map[string]any{
+		{
+			"repo": map[string]any{
+				"url":   "https://github.com/a/a",
+				"stars": int64(12),
+				"owner": map[string]any{"name": "a"},
+			},
+			"score":    0.5,
+			"archived": true,
+			"date":     testDate,
+		},
+		{
+			"repo":     map[string]any{"url": "https://github.com/b/b"},
+			"score":    1.0,
+			"archived": false,
+		},
+		{},
+		{
+			"repo":     map[string]any{"owner": map[string]any{}},
+			"archived": true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := parquet.NewWriter(&buf, testFields)
+			if err != nil {
+				t.Fatalf("NewWriter() = %v, want no error", err)
+			}
+			w.RowGroupSize = test.rowGroupSize
+			for _, row := range rows {
+				if err := w.Write(row); err != nil {
+					t.Fatalf("Write() = %v, want no error", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+			got := readFile(t, buf.Bytes())
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteInvalidValue(t *testing.T) {
+	tests := []struct {
+		name string
+		row  map[string]any
+	}{
+		{name: "string for int", row: map[string]any{"repo": map[string]any{"stars": "12"}}},
+		{name: "fraction for int", row: map[string]any{"repo": map[string]any{"stars": json.Number("1.5")}}},
+		{name: "value for group", row: map[string]any{"repo": "a"}},
+		{name: "string for timestamp", row: map[string]any{"date": "2022-10-15T00:00:00Z"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := parquet.NewWriter(&buf, testFields)
+			if err != nil {
+				t.Fatalf("NewWriter() = %v, want no error", err)
+			}
+			if err := w.Write(test.row); !errors.Is(err, parquet.ErrorInvalidValue) {
+				t.Fatalf("Write() = %v, want %v", err, parquet.ErrorInvalidValue)
+			}
+			// The invalid row must not be partially written.
+			if err := w.Write(map[string]any{"score": 1.0}); err != nil {
+				t.Fatalf("Write() = %v, want no error", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() = %v, want no error", err)
+			}
+			if diff := cmp.Diff([]map[string]any{{"score": 1.0}}, readFile(t, buf.Bytes())); diff != "" {
+				t.Fatalf("rows mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewWriterInvalidSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []*parquet.Field
+	}{
+		{name: "empty", fields: nil},
+		{name: "no name", fields: []*parquet.Field{{Type: parquet.String}}},
+		{name: "duplicate", fields: []*parquet.Field{{Name: "a"}, {Name: "a"}}},
+		{name: "bad type", fields: []*parquet.Field{{Name: "a", Type: parquet.Type(99)}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parquet.NewWriter(&bytes.Buffer{}, test.fields); !errors.Is(err, parquet.ErrorInvalidSchema) {
+				t.Fatalf("NewWriter() = %v, want %v", err, parquet.ErrorInvalidSchema)
+			}
+		})
+	}
+}
+
+// readFile is a minimal Parquet reader for files written by Writer. It
+// returns each row, leaving out null values.
+func readFile(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	if string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("missing magic")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta, _ := readStruct(t, data[len(data)-8-n:len(data)-8])
+
+	// Find the columns in the schema.
+	type leaf struct {
+		path      []string
+		converted int32
+	}
+	var leaves []leaf
+	elems := meta[2].([]any)
+	pos := 1
+	var walk func(path []string, children int32)
+	walk = func(path []string, children int32) {
+		for i := int32(0); i < children; i++ {
+			el := elems[pos].(map[int16]any)
+			pos++
+			p := append(append([]string(nil), path...), el[4].(string))
+			if c, ok := el[5]; ok {
+				walk(p, c.(int32))
+				continue
+			}
+			conv := int32(-1)
+			if c, ok := el[6]; ok {
+				conv = c.(int32)
+			}
+			leaves = append(leaves, leaf{path: p, converted: conv})
+		}
+	}
+	walk(nil, elems[0].(map[int16]any)[5].(int32))
+
+	var rows []map[string]any
+	for _, rg := range meta[4].([]any) {
+		rg := rg.(map[int16]any)
+		start := len(rows)
+		for i := int64(0); i < rg[3].(int64); i++ {
+			rows = append(rows, map[string]any{})
+		}
+		for i, c := range rg[1].([]any) {
+			cm := c.(map[int16]any)[3].(map[int16]any)
+			l := leaves[i]
+			if diff := cmp.Diff(l.path, toStrings(cm[3].([]any))); diff != "" {
+				t.Fatalf("column path mismatch (-want +got):\n%s", diff)
+			}
+			off := cm[9].(int64)
+			header, size := readStruct(t, data[off:])
+			body := data[int(off)+size:]
+			body = body[:header[2].(int32)]
+			defs, values := readPage(t, body, int(header[5].(map[int16]any)[1].(int32)), len(l.path), cm[1].(int32))
+			for r, def := range defs {
+				m := rows[start+r]
+				for d := 0; d < def && d < len(l.path)-1; d++ {
+					child, ok := m[l.path[d]].(map[string]any)
+					if !ok {
+						child = map[string]any{}
+						m[l.path[d]] = child
+					}
+					m = child
+				}
+				if def == len(l.path) {
+					v := values[0]
+					values = values[1:]
+					if l.converted == 10 {
+						v = time.UnixMicro(v.(int64)).UTC()
+					}
+					m[l.path[len(l.path)-1]] = v
+				}
+			}
+		}
+	}
+	return rows
+}
+
+func readPage(t *testing.T, body []byte, count, maxDef int, physical int32) ([]int, []any) {
+	t.Helper()
+	n := int(binary.LittleEndian.Uint32(body))
+	levels := body[4 : 4+n]
+	body = body[4+n:]
+	var defs []int
+	for len(levels) > 0 {
+		h, k := binary.Uvarint(levels)
+		if h&1 != 0 {
+			t.Fatal("unexpected bit-packed run")
+		}
+		// maxDef is always less than 256, so the value is one byte.
+		for i := uint64(0); i < h>>1; i++ {
+			defs = append(defs, int(levels[k]))
+		}
+		levels = levels[k+1:]
+	}
+	if len(defs) != count {
+		t.Fatalf("got %d levels, want %d", len(defs), count)
+	}
+	var values []any
+	present := 0
+	for _, d := range defs {
+		if d == maxDef {
+			present++
+		}
+	}
+	for i := 0; i < present; i++ {
+		switch physical {
+		case 0:
+			values = append(values, body[i/8]&(1<<(i%8)) != 0)
+		case 2:
+			values = append(values, int64(binary.LittleEndian.Uint64(body)))
+			body = body[8:]
+		case 5:
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(body)))
+			body = body[8:]
+		case 6:
+			l := binary.LittleEndian.Uint32(body)
+			values = append(values, string(body[4:4+l]))
+			body = body[4+l:]
+		default:
+			t.Fatalf("unexpected physical type %d", physical)
+		}
+	}
+	return defs, values
+}
+
+// readStruct decodes a Thrift struct using the compact protocol, returning
+// the fields keyed by id and the number of bytes read.
+func readStruct(t *testing.T, data []byte) (map[int16]any, int) {
+	t.Helper()
+	buf := thrift.NewTMemoryBuffer()
+	buf.Write(data)
+	p := thrift.NewTCompactProtocolConf(buf, nil)
+	v, err := readValue(context.Background(), p, thrift.STRUCT)
+	if err != nil {
+		t.Fatalf("reading struct: %v", err)
+	}
+	return v.(map[int16]any), len(data) - buf.Len()
+}
+
+func readValue(ctx context.Context, p *thrift.TCompactProtocol, typ thrift.TType) (any, error) {
+	switch typ {
+	case thrift.BOOL:
+		return p.ReadBool(ctx)
+	case thrift.I32:
+		return p.ReadI32(ctx)
+	case thrift.I64:
+		return p.ReadI64(ctx)
+	case thrift.STRING:
+		return p.ReadString(ctx)
+	case thrift.LIST:
+		elem, n, err := p.ReadListBegin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var l []any
+		for i := 0; i < n; i++ {
+			v, err := readValue(ctx, p, elem)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, p.ReadListEnd(ctx)
+	case thrift.STRUCT:
+		if _, err := p.ReadStructBegin(ctx); err != nil {
+			return nil, err
+		}
+		m := make(map[int16]any)
+		for {
+			_, ft, id, err := p.ReadFieldBegin(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if ft == thrift.STOP {
+				break
+			}
+			if m[id], err = readValue(ctx, p, ft); err != nil {
+				return nil, err
+			}
+		}
+		return m, p.ReadStructEnd(ctx)
+	}
+	return nil, thrift.Skip(ctx, p, typ, 64)
+}
+
+func toStrings(l []any) []string {
+	var s []string
+	for _, v := range l {
+		s = append(s, v.(string))
+	}
+	return s
+}
@@ -17,14 +17,15 @@ package scorer
 import (
 	"fmt"
 	"io"
+	"net/url"
 	"path"
 	"strconv"
 	"strings"
 	"unicode"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
 	"github.com/ossf/criticality_score/internal/scorer/algorithm"
 	_ "github.com/ossf/criticality_score/internal/scorer/algorithm/wam"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 var ErrEmptyName = fmt.Errorf("name must be non-empty")
@@ -105,6 +106,11 @@ func (s *Scorer) Name() string {
 }
 
 func NameFromFilepath(filepath string) string {
+	// Ignore any query string if filepath is a bucket URL.
+	if u, err := url.Parse(filepath); err == nil && u.IsAbs() {
+		filepath = u.Path
+	}
+
 	// Get the name of the file used, without the path
 	f := path.Base(filepath)
 
@@ -17,8 +17,8 @@ package scorer
 import (
 	"testing"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
 	"github.com/ossf/criticality_score/internal/scorer/algorithm"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 type testAlgo struct {
@@ -113,6 +113,11 @@ func TestNameFromFilepath(t *testing.T) {
 			filepath: "path/to/test.json",
 			want:     "test_score",
 		},
+		{
+			name:     "bucket url",
+			filepath: "gs://bucket/path/to/test.yml?param=value",
+			want:     "test_score",
+		},
 		{
 			name:     "invalid characters",
 			filepath: "configuración-+=_básica.yaml",
@@ -16,7 +16,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 func fieldsFromSignalSets(sets []signal.Set, extra []string) []string {
@@ -16,7 +16,7 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 type testSet struct { //nolint:govet
@@ -20,7 +20,7 @@ import (
 	"io"
 	"sync"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 type jsonWriter struct {
@@ -40,6 +40,19 @@ func JSONWriter(w io.Writer) Writer {
 
 // WriteSignals implements the Writer interface.
 func (w *jsonWriter) WriteSignals(signals []signal.Set, extra ...Field) error {
+	data, err := JSONMap(signals, extra...)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.encoder.Encode(data)
+}
+
+// JSONMap returns the signals as a map from each namespace to the signals in
+// that namespace, with each extra Field added at the top level. This is the
+// structure used for each record written by JSONWriter.
+func JSONMap(signals []signal.Set, extra ...Field) (map[string]any, error) {
 	data := make(map[string]any)
 	for _, s := range signals {
 		m := signal.SetAsMapWithNamespace(s)
@@ -53,7 +66,7 @@ func (w *jsonWriter) WriteSignals(signals []signal.Set, extra ...Field) error {
 			}
 			nsData, ok := d.(map[string]any)
 			if !ok {
-				return fmt.Errorf("failed to get map for namespace: %s", ns)
+				return nil, fmt.Errorf("failed to get map for namespace: %s", ns)
 			}
 			for k, v := range innerM {
 				nsData[k] = v
@@ -63,7 +76,5 @@ func (w *jsonWriter) WriteSignals(signals []signal.Set, extra ...Field) error {
 	for _, f := range extra {
 		data[f.Key] = f.Value
 	}
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	return w.encoder.Encode(data)
+	return data, nil
 }
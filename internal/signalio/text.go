@@ -19,7 +19,7 @@ import (
 	"io"
 	"sync"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 type textWriter struct {
@@ -19,7 +19,7 @@ import (
 	"errors"
 	"io"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 type WriterType int
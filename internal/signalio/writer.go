@@ -17,7 +17,7 @@ package signalio
 import (
 	"errors"
 
-	"github.com/ossf/criticality_score/internal/collector/signal"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
 )
 
 var ErrorMarshalFailure = errors.New("failed to marshal value")
@@ -0,0 +1,266 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package collector collects the signals for project repositories.
+//
+// It is the entry point to the public Go API of criticality_score, which is
+// made up of the packages under pkg/. See pkg/README.md for the compatibility
+// guarantees made for these packages.
+//
+// A Collector is built with a set of Options. Custom signal Sources and
+// projectrepo Factories can be added to collect new signals, or to support
+// new repository hosts:
+//
+//	c, err := collector.New(ctx,
+//		collector.DisableSource(collector.SourceTypeDepsDev),
+//		collector.Source(mySource),
+//		collector.RepoFactory(myFactory))
+package collector
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ossf/criticality_score/internal/collector"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+// DefaultGCPDatasetName is the default name to use for GCP BigQuery Datasets.
+const DefaultGCPDatasetName = collector.DefaultGCPDatasetName
+
+var (
+	// ErrUncollectableRepo is the base error returned when there is a problem
+	// with the repo url passed in to be collected.
+	ErrUncollectableRepo = collector.ErrUncollectableRepo
+
+	// ErrRepoNotFound wraps ErrUncollectableRepo and is used when a repo
+	// cannot be found for collection.
+	ErrRepoNotFound = collector.ErrRepoNotFound
+
+	// ErrUnsupportedURL wraps ErrUncollectableRepo and is used when a repo url
+	// does not match any of the registered Factories.
+	ErrUnsupportedURL = collector.ErrUnsupportedURL
+)
+
+// SourceType identifies one of the built-in sources signals can be collected
+// from.
+type SourceType int
+
+const (
+	SourceTypeGitHubRepo SourceType = iota
+	SourceTypeGitHubIssues
+	SourceTypeGitHubMentions
+	SourceTypeDepsDev
+)
+
+// String implements the fmt.Stringer interface.
+func (t SourceType) String() string {
+	switch t {
+	case SourceTypeGitHubRepo:
+		return "github_repo"
+	case SourceTypeGitHubIssues:
+		return "github_issues"
+	case SourceTypeGitHubMentions:
+		return "github_mentions"
+	case SourceTypeDepsDev:
+		return "depsdev"
+	default:
+		return ""
+	}
+}
+
+// internal returns the internal SourceType that corresponds to t.
+func (t SourceType) internal() collector.SourceType {
+	switch t {
+	case SourceTypeGitHubRepo:
+		return collector.SourceTypeGithubRepo
+	case SourceTypeGitHubIssues:
+		return collector.SourceTypeGithubIssues
+	case SourceTypeGitHubMentions:
+		return collector.SourceTypeGitHubMentions
+	case SourceTypeDepsDev:
+		return collector.SourceTypeDepsDev
+	default:
+		return collector.SourceType(-1)
+	}
+}
+
+type config struct {
+	logger *zap.Logger
+	opts   []collector.Option
+}
+
+// Option is used to configure a Collector.
+type Option interface{ set(*config) }
+
+// option implements Option interface.
+type option func(*config)
+
+// set implements the Option interface.
+func (o option) set(c *config) { o(c) }
+
+// wrap returns an Option that passes o on to the internal collector.
+func wrap(o collector.Option) Option {
+	return option(func(c *config) {
+		c.opts = append(c.opts, o)
+	})
+}
+
+// Logger sets the logger used by the Collector. By default nothing is logged.
+func Logger(l *zap.Logger) Option {
+	return option(func(c *config) {
+		c.logger = l
+	})
+}
+
+// EnableAllSources enables all SourceTypes for collection. This is the
+// default.
+//
+// All built-in sources will be used for collection unless explicitly disabled
+// with DisableSource.
+func EnableAllSources() Option {
+	return wrap(collector.EnableAllSources())
+}
+
+// DisableAllSources will disable all SourceTypes for collection.
+//
+// No built-in sources will be used for collection unless explicitly enabled
+// with EnableSource. Sources added with Source are always used.
+func DisableAllSources() Option {
+	return wrap(collector.DisableAllSources())
+}
+
+// EnableSource will enable the supplied SourceType for collection.
+func EnableSource(s SourceType) Option {
+	return wrap(collector.EnableSource(s.internal()))
+}
+
+// DisableSource will disable the supplied SourceType for collection.
+//
+// SourceTypeDepsDev requires Google Cloud Platform credentials, so it must be
+// disabled if they are not available.
+func DisableSource(s SourceType) Option {
+	return wrap(collector.DisableSource(s.internal()))
+}
+
+// GCPProject is used to set the ID of the GCP project used for sources that
+// depend on GCP.
+//
+// If not supplied, the currently configured project will be used.
+func GCPProject(id string) Option {
+	return wrap(collector.GCPProject(id))
+}
+
+// GCPDatasetName overrides DefaultGCPDatasetName with the supplied dataset
+// name.
+func GCPDatasetName(n string) Option {
+	return wrap(collector.GCPDatasetName(n))
+}
+
+// GCPDatasetTTL sets the time-to-live for tables created with GCP BigQuery
+// datasets.
+func GCPDatasetTTL(ttl time.Duration) Option {
+	return wrap(collector.GCPDatasetTTL(ttl))
+}
+
+// Source adds a custom signal Source that is used for collection alongside
+// the enabled SourceTypes. Sources are used in the order they are added, after
+// the built-in sources.
+//
+// The Set returned by the Source's EmptySet method must be valid according to
+// signal.ValidateSet, and its namespace must not be used by an enabled
+// built-in source, another custom Source, or be signal.NamespaceLegacy.
+func Source(s signal.Source) Option {
+	return wrap(collector.Source(s))
+}
+
+// RepoFactory adds a custom projectrepo Factory used to resolve repository
+// urls. Factories are tried in the order they are added, before the built-in
+// GitHub Factory.
+func RepoFactory(f projectrepo.Factory) Option {
+	return wrap(collector.RepoFactory(f))
+}
+
+// CollectOption is used to configure a single call to Collect.
+type CollectOption interface{ set(*collectConfig) }
+
+type collectConfig struct {
+	jobID string
+}
+
+// collectOption implements the CollectOption interface.
+type collectOption func(*collectConfig)
+
+// set implements the CollectOption interface.
+func (o collectOption) set(c *collectConfig) { o(c) }
+
+// JobID sets the id of the job that the collection is a part of.
+//
+// Sources use the id to manage the data they cache. The deps.dev source
+// creates its BigQuery tables once for each id, so the dependent counts are
+// only refreshed when a new id is used. By default the empty id is used for
+// every collection.
+func JobID(id string) CollectOption {
+	return collectOption(func(c *collectConfig) {
+		c.jobID = id
+	})
+}
+
+// Collector collects the signals for project repositories. It is safe for
+// concurrent use.
+type Collector struct {
+	c *collector.Collector
+}
+
+// New returns a new Collector configured with opts.
+//
+// Collecting signals from GitHub requires a GitHub token to be set in one of
+// the GITHUB_AUTH_TOKEN, GITHUB_TOKEN, GH_TOKEN or GH_AUTH_TOKEN environment
+// variables.
+func New(ctx context.Context, opts ...Option) (*Collector, error) {
+	cfg := &config{
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.set(cfg)
+	}
+	c, err := collector.New(ctx, cfg.logger, cfg.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{c: c}, nil
+}
+
+// EmptySets returns an empty instance of each signal Set that can be
+// collected. They can be used to determine the namespaces and signals
+// supported by the Collector.
+func (c *Collector) EmptySets() []signal.Set {
+	return c.c.EmptySets()
+}
+
+// Collect gathers and returns all the signals for the given project repo url.
+//
+// If the url cannot be collected the error returned will wrap
+// ErrUncollectableRepo.
+func (c *Collector) Collect(ctx context.Context, u *url.URL, opts ...CollectOption) ([]signal.Set, error) {
+	cfg := &collectConfig{}
+	for _, opt := range opts {
+		opt.set(cfg)
+	}
+	return c.c.Collect(ctx, u, cfg.jobID)
+}
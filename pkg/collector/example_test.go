@@ -0,0 +1,141 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ossf/criticality_score/pkg/collector"
+	"github.com/ossf/criticality_score/pkg/collector/projectrepo"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+	"github.com/ossf/criticality_score/pkg/scorer"
+	"github.com/ossf/criticality_score/pkg/signalio"
+)
+
+// exampleRepo is a repository hosted on example.com.
+type exampleRepo struct {
+	u *url.URL
+}
+
+func (r *exampleRepo) URL() *url.URL {
+	return r.u
+}
+
+// exampleFactory resolves urls for repositories hosted on example.com.
+type exampleFactory struct{}
+
+func (f *exampleFactory) New(ctx context.Context, u *url.URL) (projectrepo.Repo, error) {
+	return &exampleRepo{u: u}, nil
+}
+
+func (f *exampleFactory) Match(u *url.URL) bool {
+	return u.Hostname() == "example.com"
+}
+
+// exampleSet holds the signals collected by exampleSource. Each exported
+// signal.Field is a signal, named using snake case (e.g. "example.star_count").
+type exampleSet struct {
+	StarCount signal.Field[int]
+}
+
+func (s *exampleSet) Namespace() signal.Namespace {
+	return "example"
+}
+
+// exampleSource collects signals for repositories hosted on example.com.
+type exampleSource struct{}
+
+func (s *exampleSource) EmptySet() signal.Set {
+	return &exampleSet{}
+}
+
+func (s *exampleSource) IsSupported(r projectrepo.Repo) bool {
+	_, ok := r.(*exampleRepo)
+	return ok
+}
+
+func (s *exampleSource) Get(ctx context.Context, r projectrepo.Repo, jobID string) (signal.Set, error) {
+	return &exampleSet{
+		StarCount: signal.Val(50),
+	}, nil
+}
+
+const exampleConfig = `algorithm: weighted_arithmetic_mean
+inputs:
+  - field: example.star_count
+    bounds:
+      upper: 100
+`
+
+// This example collects signals from a custom source for a custom repository
+// host, scores them, and writes them as CSV.
+func Example() {
+	ctx := context.Background()
+
+	c, err := collector.New(ctx,
+		collector.DisableAllSources(),
+		collector.RepoFactory(&exampleFactory{}),
+		collector.Source(&exampleSource{}))
+	if err != nil {
+		panic(err)
+	}
+
+	s, err := scorer.FromConfig("example_score", strings.NewReader(exampleConfig))
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := signalio.NewWriter(signalio.WriterTypeCSV, os.Stdout, c.EmptySets(), s.Name())
+	if err != nil {
+		panic(err)
+	}
+
+	u, _ := url.Parse("https://example.com/project")
+	sets, err := c.Collect(ctx, u)
+	if err != nil {
+		panic(err)
+	}
+	score := signalio.Field{Key: s.Name(), Value: fmt.Sprintf("%.5f", s.Score(sets))}
+	if err := w.WriteSignals(sets, score); err != nil {
+		panic(err)
+	}
+	// Output:
+	// example.star_count,example_score
+	// 50,0.50000
+}
+
+// This example collects the signals for a GitHub repository, without using
+// deps.dev.
+func ExampleNew() {
+	ctx := context.Background()
+
+	c, err := collector.New(ctx, collector.DisableSource(collector.SourceTypeDepsDev))
+	if err != nil {
+		panic(err)
+	}
+
+	u, _ := url.Parse("https://github.com/ossf/criticality_score")
+	sets, err := c.Collect(ctx, u)
+	if err != nil {
+		panic(err)
+	}
+	for _, s := range sets {
+		fmt.Println(signal.SetAsMap(s, true))
+	}
+}
@@ -12,6 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package projectrepo defines how project repository urls are resolved to a
+// Repo that signals can be collected for.
 package projectrepo
 
 import (
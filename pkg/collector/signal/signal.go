@@ -12,6 +12,11 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package signal defines the signals collected for a project repository.
+//
+// Signals are grouped into a Set, a struct with a Field for each signal that
+// belongs to a Namespace. A Source collects a Set for each repository it
+// supports.
 package signal
 
 import (
@@ -58,6 +63,9 @@ var (
 	// valuerType caches the reflect.Type representation of the valuer
 	// interface.
 	valuerType = reflect.TypeOf((*valuer)(nil)).Elem()
+
+	// timeType caches the reflect.Type representation of time.Time.
+	timeType = reflect.TypeOf(time.Time{})
 )
 
 type SupportedType interface {
@@ -226,6 +234,28 @@ func SetFields(s Set, namespace bool) []string {
 	return fs
 }
 
+// SetFieldTypes returns a slice containing the name of the type of each field
+// for s, in the same order as SetFields.
+//
+// Types are named after their kind (e.g. "int", "float64" or "string"), except
+// for time.Time which is named "time".
+func SetFieldTypes(s Set) []string {
+	var ts []string
+	for _, sf := range reflect.VisibleFields(reflect.TypeOf(s).Elem()) {
+		if parseStructField(sf) == nil {
+			continue
+		}
+		// The type of the value stored in the Field.
+		vf, _ := sf.Type.FieldByName("value")
+		if vf.Type == timeType {
+			ts = append(ts, "time")
+		} else {
+			ts = append(ts, vf.Type.Kind().String())
+		}
+	}
+	return ts
+}
+
 // SetValues returns a slice containing the values for each field for s.
 //
 // The values are either `nil` if the Field is not set, or the value that was
@@ -0,0 +1,48 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scorer_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+	"github.com/ossf/criticality_score/pkg/scorer"
+)
+
+func ExampleFromConfig() {
+	config := `algorithm: weighted_arithmetic_mean
+inputs:
+  - field: repo.star_count
+    weight: 2
+    bounds:
+      upper: 1000
+  - field: legacy.updated_issues_count
+    weight: 1
+    bounds:
+      upper: 100
+`
+	s, err := scorer.FromConfig(scorer.NameFromFilepath("config/stars.yml"), strings.NewReader(config))
+	if err != nil {
+		panic(err)
+	}
+
+	sets := []signal.Set{
+		&signal.RepoSet{StarCount: signal.Val(500)},
+		&signal.IssuesSet{UpdatedCount: signal.Val(20)},
+	}
+	fmt.Printf("%s: %.2f\n", s.Name(), s.Score(sets))
+	// Output: stars_score: 0.40
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scorer calculates a criticality score from the signals collected
+// for a project repository.
+//
+// A Scorer is configured with a YAML config describing the algorithm and the
+// signals used as its inputs. See config/scorer/ in the criticality_score
+// repository for examples.
+package scorer
+
+import (
+	"io"
+
+	"github.com/ossf/criticality_score/internal/scorer"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+// ErrEmptyName is returned by FromConfig if the name is empty.
+var ErrEmptyName = scorer.ErrEmptyName
+
+// Scorer calculates scores. It is safe for concurrent use.
+type Scorer struct {
+	s *scorer.Scorer
+}
+
+// FromConfig returns a Scorer named name, using the YAML config read from r.
+//
+// An error is returned if the config is invalid.
+func FromConfig(name string, r io.Reader) (*Scorer, error) {
+	s, err := scorer.FromConfig(name, r)
+	if err != nil {
+		return nil, err
+	}
+	return &Scorer{s: s}, nil
+}
+
+// FromDefaultConfig returns a Scorer using the default config, which matches
+// the original criticality_score algorithm.
+func FromDefaultConfig() *Scorer {
+	return &Scorer{s: scorer.FromDefaultConfig()}
+}
+
+// NameFromFilepath returns a name for a Scorer based on the filename of its
+// config. For example, "config/scorer/pike.yml" is named "pike_score".
+func NameFromFilepath(filepath string) string {
+	return scorer.NameFromFilepath(filepath)
+}
+
+// Name returns the name of the Scorer.
+func (s *Scorer) Name() string {
+	return s.s.Name()
+}
+
+// Score returns the score for the signals in sets.
+//
+// The score is NaN if it cannot be calculated, for example if the signals
+// the config depends on are missing.
+func (s *Scorer) Score(sets []signal.Set) float64 {
+	return s.s.Score(sets)
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signalio_test
+
+import (
+	"os"
+
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+	"github.com/ossf/criticality_score/pkg/signalio"
+)
+
+func ExampleNewWriter() {
+	sets := []signal.Set{
+		&signal.RepoSet{
+			URL:       signal.Val("https://github.com/ossf/criticality_score"),
+			StarCount: signal.Val(1234),
+		},
+	}
+	w, err := signalio.NewWriter(signalio.WriterTypeJSON, os.Stdout, nil)
+	if err != nil {
+		panic(err)
+	}
+	if err := w.WriteSignals(sets, signalio.Field{Key: "default_score", Value: "0.5"}); err != nil {
+		panic(err)
+	}
+	// Output: {"default_score":"0.5","legacy":{"commit_frequency":null,"contributor_count":null,"created_since":null,"org_count":null,"recent_release_count":null,"updated_since":null},"repo":{"created_at":null,"language":null,"license":null,"star_count":1234,"updated_at":null,"url":"https://github.com/ossf/criticality_score"}}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signalio writes the signals collected for project repositories.
+package signalio
+
+import (
+	"io"
+
+	"github.com/ossf/criticality_score/internal/signalio"
+	"github.com/ossf/criticality_score/pkg/collector/signal"
+)
+
+// WriterType is the format used to write signals.
+type WriterType int
+
+const (
+	WriterTypeCSV WriterType = iota
+	WriterTypeJSON
+	WriterTypeText
+)
+
+// ErrorUnknownWriterType is returned for a WriterType that is not supported.
+var ErrorUnknownWriterType = signalio.ErrorUnknownWriterType
+
+// internal returns the internal WriterType that corresponds to t.
+func (t WriterType) internal() signalio.WriterType {
+	switch t {
+	case WriterTypeCSV:
+		return signalio.WriterTypeCSV
+	case WriterTypeJSON:
+		return signalio.WriterTypeJSON
+	case WriterTypeText:
+		return signalio.WriterTypeText
+	default:
+		return signalio.WriterType(-1)
+	}
+}
+
+// String implements the fmt.Stringer interface.
+func (t WriterType) String() string {
+	return t.internal().String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t WriterType) MarshalText() ([]byte, error) {
+	return t.internal().MarshalText()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *WriterType) UnmarshalText(text []byte) error {
+	var it signalio.WriterType
+	if err := it.UnmarshalText(text); err != nil {
+		return err
+	}
+	switch it {
+	case signalio.WriterTypeCSV:
+		*t = WriterTypeCSV
+	case signalio.WriterTypeJSON:
+		*t = WriterTypeJSON
+	case signalio.WriterTypeText:
+		*t = WriterTypeText
+	default:
+		return ErrorUnknownWriterType
+	}
+	return nil
+}
+
+// Field is an extra value written alongside the signals, such as a score.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Writer writes signals.
+type Writer interface {
+	// WriteSignals writes the signals collected for a single repository,
+	// along with any extra fields.
+	WriteSignals([]signal.Set, ...Field) error
+}
+
+// NewWriter returns a Writer that writes signals to w in the format t.
+//
+// emptySets describes the signals that will be written, and extra the keys
+// of any extra fields. Formats with a header, such as CSV, use them to write
+// the header. They are usually the result of collector.Collector's EmptySets
+// method and a score name.
+func NewWriter(t WriterType, w io.Writer, emptySets []signal.Set, extra ...string) (Writer, error) {
+	it := t.internal()
+	iw := it.New(w, emptySets, extra...)
+	if iw == nil {
+		return nil, ErrorUnknownWriterType
+	}
+	return &writer{w: iw}, nil
+}
+
+// writer adapts a signalio.Writer to the Writer interface.
+type writer struct {
+	w signalio.Writer
+}
+
+// WriteSignals implements the Writer interface.
+func (w *writer) WriteSignals(sets []signal.Set, extra ...Field) error {
+	fields := make([]signalio.Field, 0, len(extra))
+	for _, f := range extra {
+		fields = append(fields, signalio.Field(f))
+	}
+	return w.w.WriteSignals(sets, fields...)
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Criticality Score Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signalio_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ossf/criticality_score/pkg/signalio"
+)
+
+func TestTypeText(t *testing.T) {
+	for _, want := range []signalio.WriterType{signalio.WriterTypeCSV, signalio.WriterTypeJSON, signalio.WriterTypeText} {
+		t.Run(want.String(), func(t *testing.T) {
+			text, err := want.MarshalText()
+			if err != nil {
+				t.Fatalf("MarshalText() = %v, want no error", err)
+			}
+			var got signalio.WriterType
+			if err := got.UnmarshalText(text); err != nil {
+				t.Fatalf("UnmarshalText(%q) = %v, want no error", text, err)
+			}
+			if got != want {
+				t.Fatalf("UnmarshalText(%q) parsed %v, want %v", text, got, want)
+			}
+		})
+	}
+}
+
+func TestTypeUnknown(t *testing.T) {
+	unknown := signalio.WriterType(10)
+	if _, err := unknown.MarshalText(); !errors.Is(err, signalio.ErrorUnknownWriterType) {
+		t.Errorf("MarshalText() = %v, want %v", err, signalio.ErrorUnknownWriterType)
+	}
+	var wt signalio.WriterType
+	if err := wt.UnmarshalText([]byte("unknown")); !errors.Is(err, signalio.ErrorUnknownWriterType) {
+		t.Errorf("UnmarshalText() = %v, want %v", err, signalio.ErrorUnknownWriterType)
+	}
+	if _, err := signalio.NewWriter(unknown, io.Discard, nil); !errors.Is(err, signalio.ErrorUnknownWriterType) {
+		t.Errorf("NewWriter() = %v, want %v", err, signalio.ErrorUnknownWriterType)
+	}
+}